@@ -130,6 +130,11 @@ var doctorCmd = &cobra.Command{
 						continue
 					}
 
+					// We can only ping a player process over TCP.
+					if player.Protocol == "udp" {
+						continue
+					}
+
 					if _, err := system.PingPlayer(player.Port); err != nil {
 						log.Warn().
 							Interface("player", player).
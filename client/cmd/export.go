@@ -3,26 +3,24 @@ package cmd
 import (
 	"fmt"
 	"io"
-	"math/rand"
 	"os"
-	"path/filepath"
 	"time"
 
 	"alda.io/client/color"
 	"alda.io/client/help"
+	"alda.io/client/interop/musicxml/exporter"
 	log "alda.io/client/logging"
 	"alda.io/client/model"
 	"alda.io/client/parser"
+	"alda.io/client/synth"
 	"alda.io/client/system"
 	"alda.io/client/transmitter"
-	"alda.io/client/util"
 	"github.com/spf13/cobra"
 )
 
-const midiExportTimeout = 60 * time.Second
-
 var outputFilename string
 var outputFormat string
+var soundFont string
 
 func init() {
 	exportCmd.Flags().StringVarP(
@@ -56,6 +54,20 @@ func init() {
 	exportCmd.Flags().StringVarP(
 		&outputFormat, "output-format", "O", "midi", "The output format",
 	)
+
+	exportCmd.Flags().StringVar(
+		&soundFont,
+		"soundfont",
+		"",
+		"A SoundFont (.sf2) file to use when exporting to wav",
+	)
+
+	exportCmd.Flags().Int64Var(
+		&randomSeed,
+		"seed",
+		0,
+		"A seed for the random number generator, for reproducible randomness",
+	)
 }
 
 var exportCmd = &cobra.Command{
@@ -81,23 +93,92 @@ redirecting into other files or processes.
 
 ---
 
-Currently, the only output format is MIDI. At some point, there will be other
-output formats like MusicXML.
+The supported output formats (-O / --output-format) are:
+
+  midi (default): Standard MIDI files (.mid).
+
+  musicxml: MusicXML (.musicxml), which can be opened in most music notation
+  software. Repeats are written out in full, and measures follow the barlines in
+  the score (or 4/4, if there are none).
+
+  alda export -O musicxml -c "piano: c d e" -o three-notes.musicxml
+
+  wav: Audio (.wav), rendered by a built-in synthesizer. By default, each
+  instrument is approximated by a simple waveform. For more realistic sound, use
+  --soundfont FILENAME to render with the samples in a SoundFont (.sf2) file.
+
+  alda export -O wav -c "piano: c d e" -o three-notes.wav
+  alda export -O wav --soundfont FluidR3_GM.sf2 -c "piano: c d e" -o three-notes.wav
+
+  osc: The OSC messages that "alda play" would send to a player process, one
+  per line. This is useful for telling whether a playback problem is caused by
+  the client or the player, and the messages can be sent to a player with
+  "alda replay". ("alda play --dry-run" prints the same messages.)
+
+  alda export -O osc -c "piano: c d e" -o three-notes.osc
+  alda replay -f three-notes.osc
+
+  osc-json: The same OSC messages, as JSON.
 
 ---`,
 		sourceCodeInputOptions("export", false),
 	),
-	RunE: func(_ *cobra.Command, args []string) error {
-		if outputFormat != "midi" {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch outputFormat {
+		case "midi", "wav", "osc", "osc-json":
+		case "musicxml":
+			if optionFrom != "" || optionTo != "" {
+				return help.UserFacingErrorf(
+					`The %s and %s options are not supported when exporting to %s.`,
+					color.Aurora.BrightYellow("--from"),
+					color.Aurora.BrightYellow("--to"),
+					color.Aurora.BrightYellow("musicxml"),
+				)
+			}
+		default:
 			return help.UserFacingErrorf(
 				`%s is not a supported output format.
 
-Currently, the only supported output format is %s.`,
+The supported output formats are %s, %s, %s, %s and %s.`,
 				color.Aurora.BrightYellow(outputFormat),
 				color.Aurora.BrightYellow("midi"),
+				color.Aurora.BrightYellow("musicxml"),
+				color.Aurora.BrightYellow("wav"),
+				color.Aurora.BrightYellow("osc"),
+				color.Aurora.BrightYellow("osc-json"),
+			)
+		}
+
+		if soundFont != "" && outputFormat != "wav" {
+			return help.UserFacingErrorf(
+				`The %s option is only supported when exporting to %s.`,
+				color.Aurora.BrightYellow("--soundfont"),
+				color.Aurora.BrightYellow("wav"),
 			)
 		}
 
+		var synthesizer synth.Synthesizer
+		if soundFont != "" {
+			f, err := os.Open(soundFont)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			sf, err := synth.LoadSoundFont(f)
+			if err != nil {
+				return help.UserFacingErrorf(
+					`Failed to load the SoundFont %s:
+
+  %s`,
+					color.Aurora.BrightYellow(soundFont),
+					err.Error(),
+				)
+			}
+
+			synthesizer = sf
+		}
+
 		var ast parser.ASTNode
 		var scoreUpdates []model.ScoreUpdate
 		var err error
@@ -126,134 +207,78 @@ Currently, the only supported output format is %s.`,
 			return err
 		}
 
-		score := model.NewScore()
-		start := time.Now()
-		if err := score.Update(scoreUpdates...); err != nil {
-			return err
-		}
-
-		log.Info().
-			Int("updates", len(scoreUpdates)).
-			Str("took", time.Since(start).String()).
-			Msg("Constructed score.")
-
-		var player system.PlayerState
+		scoreUpdates = withRandomSeed(cmd, scoreUpdates)
 
-		// Find an available player process to use.
-		system.StartingPlayerProcesses()
-		if err := util.Await(
-			func() error {
-				foundPlayer, err := system.FindAvailablePlayer()
-				if err != nil {
-					return err
-				}
-
-				player = foundPlayer
-				return nil
-			},
-			reasonableTimeout,
-		); err != nil {
-			return err
-		}
+		var out io.Writer = os.Stdout
+		var outputFile *os.File
 
-		transmitOpts := []transmitter.TransmissionOption{
-			transmitter.TransmitFrom(optionFrom),
-			transmitter.TransmitTo(optionTo),
-			transmitter.LoadOnly(),
-		}
-
-		transmitter := transmitter.OSCTransmitter{Port: player.Port}
+		if outputFilename != "" {
+			outputFile, err = os.Create(outputFilename)
+			if err != nil {
+				return err
+			}
+			// In case we return early because of an error. Otherwise, we close the
+			// file below, so that we can report an error from writing it.
+			defer outputFile.Close()
 
-		if err := transmitter.TransmitScore(score, transmitOpts...); err != nil {
-			return err
+			out = outputFile
 		}
 
-		log.Info().
-			Interface("player", player).
-			Msg("Transmitted score to player.")
-
-		// Ensure that the player process doesn't hang around with the score loaded.
-		// This avoids an unexpected behavior where if you run `alda export ...`
-		// followed by `alda play` without arguments (i.e. to un-pause playback),
-		// you end up hearing the score that was loaded into the player process that
-		// was used for the export. The desired behavior is that we shut the player
-		// down after using it for this one-off export.
-		defer func() {
-			transmitter.TransmitShutdownMessage(0)
-
-			log.Info().
-				Interface("player", player).
-				Msg("Sent shutdown message to player.")
-		}()
-
-		// When no output filename is specified, we write the result to stdout. But
-		// first, we need to ask the player to export to a file, so we use a
-		// temporary file.
-		tmpFilename := ""
-		if outputFilename == "" {
-			tmpdir, err := os.MkdirTemp("", "alda-export")
+		if outputFormat == "musicxml" {
+			b, err := exporter.ExportMusicXML(scoreUpdates)
 			if err != nil {
 				return err
 			}
 
-			tmpFilename = filepath.Join(
-				tmpdir, fmt.Sprintf(
-					"export-%d-%d.mid",
-					time.Now().Unix(),
-					rand.Intn(10000),
-				),
-			)
-		}
-
-		var targetFilename string
-		if outputFilename != "" {
-			targetFilename = outputFilename
+			if _, err := out.Write(b); err != nil {
+				return err
+			}
 		} else {
-			targetFilename = tmpFilename
-		}
-
-		err = transmitter.TransmitMidiExportMessage(targetFilename)
-		if err != nil {
-			return err
-		}
-
-		log.Info().
-			Interface("player", player).
-			Msg("Transmitted \"export\" message.")
-
-		log.Debug().
-			Str("targetFilename", targetFilename).
-			Msg("Waiting for target file to be written.")
+			score := model.NewScore()
+			start := time.Now()
+			if err := score.Update(scoreUpdates...); err != nil {
+				return err
+			}
 
-		// There can be a noticeable delay while we wait for the player process to
-		// finish writing the target file. So, we display a message here to give the
-		// user some incremental feedback and avoid making it look like Alda is
-		// "hanging."
-		fmt.Fprintln(os.Stderr, "Exporting...")
+			log.Info().
+				Int("updates", len(scoreUpdates)).
+				Str("took", time.Since(start).String()).
+				Msg("Constructed score.")
 
-		var midiFile *os.File
+			opts := []transmitter.TransmissionOption{
+				transmitter.TransmitFrom(optionFrom),
+				transmitter.TransmitTo(optionTo),
+			}
 
-		if err := util.Await(
-			func() error {
-				mf, err := os.Open(targetFilename)
-				if err != nil {
-					return err
+			var xmitter transmitter.Transmitter
+			switch outputFormat {
+			case "wav":
+				xmitter = transmitter.WavFileTransmitter{
+					Writer: out, Synth: synthesizer,
+				}
+			case "osc", "osc-json":
+				format := transmitter.OSCDumpText
+				if outputFormat == "osc-json" {
+					format = transmitter.OSCDumpJSON
 				}
 
-				midiFile = mf
-				return nil
-			},
-			midiExportTimeout,
-		); err != nil {
-			return nil
+				xmitter = transmitter.OSCDumpTransmitter{Writer: out, Format: format}
+				opts = playTransmissionOptions()
+			default:
+				xmitter = transmitter.MidiFileTransmitter{Writer: out}
+			}
+
+			if err := xmitter.TransmitScore(score, opts...); err != nil {
+				return err
+			}
 		}
 
-		if outputFilename != "" {
-			fmt.Fprintf(os.Stderr, "Exported score to %s\n", outputFilename)
-		} else {
-			if _, err := io.Copy(os.Stdout, midiFile); err != nil {
+		if outputFile != nil {
+			if err := outputFile.Close(); err != nil {
 				return err
 			}
+
+			fmt.Fprintf(os.Stderr, "Exported score to %s\n", outputFilename)
 		}
 
 		return nil
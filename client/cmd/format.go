@@ -65,7 +65,7 @@ Currently, formatting cannot handle comments (i.e. all comments are dropped)
 			color.Aurora.BrightYellow("format"),
 		))
 
-		root, err := parser.ParseFile(formatInputFile)
+		root, err := parser.ParseFile(formatInputFile, parser.SkipIncludes)
 		if err != nil {
 			return err
 		}
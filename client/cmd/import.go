@@ -3,6 +3,7 @@ package cmd
 import (
 	"alda.io/client/color"
 	"alda.io/client/help"
+	midiimporter "alda.io/client/interop/midi/importer"
 	"alda.io/client/interop/musicxml/importer"
 	"alda.io/client/model"
 	"alda.io/client/parser"
@@ -15,6 +16,13 @@ import (
 var outputAldaFilename string
 var importFormat string
 
+// importers maps each supported import format to the function that translates
+// data in that format into Alda score updates.
+var importers = map[string]func([]byte) ([]model.ScoreUpdate, error){
+	"musicxml": importer.ImportMusicXML,
+	"midi":     midiimporter.ImportMIDI,
+}
+
 func init() {
 	importCmd.Flags().StringVarP(
 		&file, "file", "f", "", "Read data from a file to convert to Alda",
@@ -40,7 +48,14 @@ var importCmd = &cobra.Command{
 
 ---
 
-Currently, the only supported import format is MusicXML (.musicxml). Most popular software applications support exporting scores to MusicXML.
+The supported import formats are:
+
+  musicxml: MusicXML (.musicxml). Most popular software applications support
+  exporting scores to MusicXML.
+
+  midi: Standard MIDI files (.mid). Each channel is imported as a separate part,
+  using the instrument selected by the channel's program changes. Note timing is
+  quantized to 16th notes.
 
 ---
 
@@ -55,6 +70,9 @@ A string of code (-c, --code):
 Text piped into the process on stdin:
   echo "...some musicxml data..." | alda import -i musicxml -o my-score.alda
 
+Because MIDI files are binary, it's best to provide them via -f / --file:
+  alda import -i midi -f path/to/my-score.mid -o my-score.alda
+
 ---
 
 When -o / --output FILENAME is provided, the results are written into that file.
@@ -69,13 +87,15 @@ redirecting into other files or processes.
 
 ---`,
 	RunE: func(_ *cobra.Command, args []string) error {
-		if importFormat != "musicxml" {
+		importFn, supported := importers[importFormat]
+		if !supported {
 			return help.UserFacingErrorf(
 				`Provided %s is not a supported input format.
 
-Currently, the only supported input format is %s.`,
+The supported input formats are %s and %s.`,
 				color.Aurora.BrightYellow(importFormat),
 				color.Aurora.BrightYellow("musicxml"),
+				color.Aurora.BrightYellow("midi"),
 			)
 		}
 
@@ -93,12 +113,12 @@ Currently, the only supported input format is %s.`,
 				)
 			}
 
-			scoreUpdates, err = importer.ImportMusicXML(b)
+			scoreUpdates, err = importFn(b)
 			if err != nil {
 				return err
 			}
 		case code != "":
-			scoreUpdates, err = importer.ImportMusicXML([]byte(code))
+			scoreUpdates, err = importFn([]byte(code))
 			if err != nil {
 				return err
 			}
@@ -112,7 +132,7 @@ Currently, the only supported input format is %s.`,
 				)
 			}
 
-			scoreUpdates, err = importer.ImportMusicXML(b)
+			scoreUpdates, err = importFn(b)
 			if err != nil {
 				return err
 			}
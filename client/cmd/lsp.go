@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+
+	"alda.io/client/lsp"
+	"github.com/spf13/cobra"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run a language server for editor integration",
+	Long: `Run a language server for editor integration
+
+---
+
+The language server speaks the Language Server Protocol (LSP) over stdin and
+stdout. It is meant to be started by a text editor, not run directly.
+
+Features:
+
+  * Diagnostics: syntax errors and errors in the score are shown as you type.
+  * Completion of instrument names and attribute names.
+  * Go to definition for variables and markers.
+  * Formatting (for scores without comments).
+
+For example, to use it in Neovim:
+
+  vim.lsp.start({ name = "alda", cmd = { "alda", "lsp" } })
+
+---`,
+	RunE: func(_ *cobra.Command, args []string) error {
+		return lsp.NewServer(os.Stdin, os.Stdout).Serve()
+	},
+}
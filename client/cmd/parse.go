@@ -57,7 +57,9 @@ ast-human:
 events:
 
   A JSON array of objects, each of which represents an "event" parsed from the
-  source code.
+  source code, along with the score events that it produces. Each score event
+  includes the chain of places in the source code that led to it, e.g. a
+  variable reference, then a note in the variable definition.
 
 data (default):
 
@@ -87,7 +89,9 @@ Please choose one of:
 
   %s
   A JSON array of objects, each of which represents an "event" parsed from the
-  source code.
+  source code, along with the score events that it produces. Each score event
+  includes the chain of places in the source code that led to it, e.g. a
+  variable reference, then a note in the variable definition.
 
   %s (default)
   A JSON object representing the score that is constructed after parsing the
@@ -107,13 +111,13 @@ Please choose one of:
 
 		switch {
 		case file != "":
-			ast, err = parser.ParseFile(file)
+			ast, err = parser.ParseFile(file, parser.RecoverFromErrors)
 
 		case code != "":
-			ast, err = parser.ParseString(code)
+			ast, err = parser.ParseString(code, parser.RecoverFromErrors)
 
 		default:
-			ast, err = parseStdin()
+			ast, err = parseStdin(parser.RecoverFromErrors)
 		}
 
 		if err == system.ErrNoInputSupplied {
@@ -132,8 +136,11 @@ Please choose one of:
 		// context, e.g. an editor plugin can easily parse out the line and column
 		// number and display the error message at the relevant position in the
 		// file.
+		//
+		// We parse with error recovery, so that if there are multiple syntax
+		// errors, they are all reported at once, one per line.
 		switch err.(type) {
-		case *model.AldaSourceError:
+		case *model.AldaSourceError, parser.ParseErrors:
 			err = &help.UserFacingError{Err: err}
 		}
 
@@ -184,12 +191,7 @@ Please choose one of:
 		}
 
 		if outputType == "events" {
-			updates := json.Array()
-			for _, update := range scoreUpdates {
-				updates.ArrayAppend(update.JSON())
-			}
-
-			fmt.Println(updates.String())
+			fmt.Println(model.ScoreUpdatesJSON(scoreUpdates).String())
 
 			return nil
 		}
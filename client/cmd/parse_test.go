@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"alda.io/client/model"
+	_ "alda.io/client/testing"
+	"github.com/go-test/deep"
+)
+
+type parsedUpdate struct {
+	ScoreEvents []struct {
+		SourceContext []model.AldaSourceContext `json:"source-context"`
+	} `json:"score-events"`
+}
+
+// parseEvents runs `alda parse -c <input> -o events` and returns the updates
+// that it prints.
+func parseEvents(t *testing.T, input string) []parsedUpdate {
+	code, outputType = input, "events"
+	t.Cleanup(func() { code, outputType = "", "data" })
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+
+	err = parseCmd.RunE(parseCmd, nil)
+	os.Stdout = stdout
+	w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var updates []parsedUpdate
+	if err := json.Unmarshal(output, &updates); err != nil {
+		t.Fatalf("%v: %s", err, output)
+	}
+
+	return updates
+}
+
+func TestParseEventsSourceContexts(t *testing.T) {
+	updates := parseEvents(t, "foo = c d\npiano: foo*2")
+
+	if len(updates) != 3 {
+		t.Fatalf("expected 3 updates, got %d: %v", len(updates), updates)
+	}
+
+	// Each note in `foo*2` leads from the variable reference to the note in the
+	// variable definition.
+	reference := model.AldaSourceContext{Line: 2, Column: 8}
+	c := model.AldaSourceContext{Line: 1, Column: 7}
+	d := model.AldaSourceContext{Line: 1, Column: 9}
+	expected := [][]model.AldaSourceContext{
+		{reference, c}, {reference, d}, {reference, c}, {reference, d},
+	}
+
+	actual := [][]model.AldaSourceContext{}
+	for _, event := range updates[2].ScoreEvents {
+		actual = append(actual, event.SourceContext)
+	}
+
+	if diff := deep.Equal(expected, actual); diff != nil {
+		t.Errorf("unexpected source contexts: %v", diff)
+	}
+}
+
+func TestParseEventsUndefinedVariable(t *testing.T) {
+	updates := parseEvents(t, "piano: foo c")
+
+	if len(updates) != 3 {
+		t.Fatalf("expected 3 updates, got %d: %v", len(updates), updates)
+	}
+
+	// The reference to `foo` can't be evaluated, so it has no score events, but
+	// the updates around it are still included.
+	expected := []int{0, 0, 1}
+
+	actual := []int{}
+	for _, update := range updates {
+		actual = append(actual, len(update.ScoreEvents))
+	}
+
+	if diff := deep.Equal(expected, actual); diff != nil {
+		t.Errorf("unexpected score event counts: %v", diff)
+	}
+}
@@ -18,11 +18,15 @@ import (
 
 var playerID string
 var playerPort int
+var playerHost string
+var playerProtocol string
 var file string
 var code string
 var optionFrom string
 var optionTo string
 var wait bool
+var randomSeed int64
+var dryRun bool
 
 func init() {
 	playCmd.Flags().StringVarP(
@@ -33,6 +37,8 @@ func init() {
 		&playerPort, "port", "p", -1, "The port of the player process to use",
 	)
 
+	addPlayerConnectionFlags(playCmd)
+
 	playCmd.Flags().StringVarP(
 		&file, "file", "f", "", "Read Alda source code from a file",
 	)
@@ -60,6 +66,35 @@ func init() {
 	playCmd.Flags().BoolVarP(
 		&wait, "wait", "w", false, "Wait until playback is complete",
 	)
+
+	playCmd.Flags().BoolVar(
+		&dryRun,
+		"dry-run",
+		false,
+		"Print the OSC messages that would be sent to the player, without playing",
+	)
+
+	playCmd.Flags().Int64Var(
+		&randomSeed,
+		"seed",
+		0,
+		"A seed for the random number generator, for reproducible randomness",
+	)
+}
+
+// withRandomSeed returns the provided score updates, preceded by an update
+// that seeds the score's random number generator if the `--seed` option was
+// provided.
+func withRandomSeed(
+	cmd *cobra.Command, updates []model.ScoreUpdate,
+) []model.ScoreUpdate {
+	if !cmd.Flags().Changed("seed") {
+		return updates
+	}
+
+	return append(
+		[]model.ScoreUpdate{model.RandomSeedSet{Seed: randomSeed}}, updates...,
+	)
 }
 
 // Parses Alda source code piped into stdin and returns the parsed AST.
@@ -68,13 +103,138 @@ func init() {
 //
 // Returns a different error if the input couldn't be parsed as valid Alda code,
 // or if something else went wrong.
-func parseStdin() (parser.ASTNode, error) {
+func parseStdin(opts ...parser.ParseOption) (parser.ASTNode, error) {
 	bytes, err := system.ReadStdin()
 	if err != nil {
 		return parser.ASTNode{}, err
 	}
 
-	return parser.ParseString(string(bytes))
+	return parser.ParseString(string(bytes), opts...)
+}
+
+// addPlayerConnectionFlags adds the options for connecting to a player process
+// on another machine, or over UDP, to a command that has a --port option.
+func addPlayerConnectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(
+		&playerHost,
+		"host",
+		"H",
+		"",
+		"The host of the player process to use (requires --port)",
+	)
+
+	cmd.Flags().StringVar(
+		&playerProtocol,
+		"protocol",
+		"tcp",
+		"The network protocol (tcp or udp) of the player process to use",
+	)
+}
+
+// specifiedPlayer returns the state of a player process that is specified by
+// its host, port and network protocol, e.g. one running on another machine.
+func specifiedPlayer(
+	host string, port int, protocol string,
+) (system.PlayerState, error) {
+	switch protocol {
+	case "tcp", "udp":
+	default:
+		return system.PlayerState{}, help.UserFacingErrorf(
+			`%s is not a supported network protocol.
+
+The supported protocols are %s and %s.`,
+			color.Aurora.BrightYellow(protocol),
+			color.Aurora.BrightYellow("tcp"),
+			color.Aurora.BrightYellow("udp"),
+		)
+	}
+
+	return system.PlayerState{
+		ID:       "unknown",
+		State:    "unknown",
+		Host:     host,
+		Port:     port,
+		Protocol: protocol,
+	}, nil
+}
+
+// playerFromOptions returns the player process specified via the --port,
+// --host and --protocol options, and whether a player process was specified.
+func playerFromOptions() (system.PlayerState, bool, error) {
+	if playerPort == -1 {
+		if playerHost != "" || playerProtocol != "tcp" {
+			return system.PlayerState{}, false, help.UserFacingErrorf(
+				`The %s and %s options can only be used together with %s.
+
+For example:
+
+  %s`,
+				color.Aurora.BrightYellow("--host"),
+				color.Aurora.BrightYellow("--protocol"),
+				color.Aurora.BrightYellow("--port"),
+				color.Aurora.BrightYellow("--host studio.local --port 27278"),
+			)
+		}
+
+		return system.PlayerState{}, false, nil
+	}
+
+	player, err := specifiedPlayer(playerHost, playerPort, playerProtocol)
+	return player, err == nil, err
+}
+
+// playerTransmitter returns an OSCTransmitter that sends messages to the
+// provided player process.
+func playerTransmitter(player system.PlayerState) transmitter.OSCTransmitter {
+	return transmitter.OSCTransmitter{
+		Host: player.Host, Port: player.Port, Protocol: player.Protocol,
+	}
+}
+
+// playerToUse returns the player process to use, based on the provided CLI
+// options.
+//
+// When no player process is specified, we find an available player process,
+// starting new player processes if needed.
+func playerToUse() (system.PlayerState, error) {
+	player, specified, err := playerFromOptions()
+	if err != nil || specified {
+		return player, err
+	}
+
+	// Player ID is specified; look up the player by ID and use its port.
+	if playerID != "" {
+		return system.FindPlayerByID(playerID)
+	}
+
+	// Find an available player process to use.
+	system.StartingPlayerProcesses()
+
+	err = util.Await(
+		func() error {
+			available, err := system.FindAvailablePlayer()
+			if err != nil {
+				return err
+			}
+
+			player = available
+			return nil
+		},
+		reasonableTimeout,
+	)
+
+	return player, err
+}
+
+// playTransmissionOptions returns the options with which `alda play` sends a
+// score to a player. `alda play --dry-run` and `alda export -O osc` use the
+// same options, so that their output is exactly what `alda play` would send.
+func playTransmissionOptions() []transmitter.TransmissionOption {
+	return []transmitter.TransmissionOption{
+		transmitter.TransmitFrom(optionFrom),
+		transmitter.TransmitTo(optionTo),
+		transmitter.OneOff(),
+	}
 }
 
 func sourceCodeInputOptions(command string, useColor bool) string {
@@ -137,7 +297,7 @@ var playCmd = &cobra.Command{
 ---`,
 		sourceCodeInputOptions("play", false),
 	),
-	RunE: func(_ *cobra.Command, args []string) error {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// Everything in this command is done via parsed CLI options, never
 		// positional args. It's easy for a new user to try something like:
 		//
@@ -202,6 +362,8 @@ var playCmd = &cobra.Command{
 			if err != nil {
 				return err
 			}
+
+			scoreUpdates = withRandomSeed(cmd, scoreUpdates)
 		}
 
 		score := model.NewScore()
@@ -226,32 +388,24 @@ var playCmd = &cobra.Command{
 			Str("took", time.Since(start).String()).
 			Msg("Constructed score.")
 
-		var players []system.PlayerState
-
-		// Determine the port to use based on the provided CLI options.
-		switch {
-
-		// Port is explicitly specified, so use that port.
-		case playerPort != -1:
-			player := system.PlayerState{
-				ID:    "unknown",
-				State: "unknown",
-				Port:  playerPort,
+		if dryRun {
+			if action == "unpause" {
+				return userFacingNoInputSuppliedError("play")
 			}
-			players = []system.PlayerState{player}
 
-		// Player ID is specified; look up the player by ID and use its port.
-		case playerID != "":
-			player, err := system.FindPlayerByID(playerID)
-			if err != nil {
-				return err
-			}
-			players = []system.PlayerState{player}
+			return transmitter.OSCDumpTransmitter{
+				Writer: os.Stdout, Format: transmitter.OSCDumpText,
+			}.TransmitScore(score, playTransmissionOptions()...)
+		}
+
+		var players []system.PlayerState
 
-		// We're actually unpausing, not playing, so send the message to all active
-		// player processes so that if any of them are paused, they'll resume
-		// playing.
-		case action == "unpause":
+		// Determine the players to use based on the provided CLI options.
+		if action == "unpause" && playerPort == -1 && playerID == "" &&
+			playerHost == "" && playerProtocol == "tcp" {
+			// We're actually unpausing, not playing, so send the message to all
+			// active player processes so that if any of them are paused, they'll
+			// resume playing.
 			allPlayers, err := system.ReadPlayerStates()
 			if err != nil {
 				return err
@@ -262,25 +416,12 @@ var playCmd = &cobra.Command{
 					players = append(players, player)
 				}
 			}
-
-		// Find an available player process to use.
-		default:
-			system.StartingPlayerProcesses()
-
-			if err := util.Await(
-				func() error {
-					player, err := system.FindAvailablePlayer()
-					if err != nil {
-						return err
-					}
-
-					players = []system.PlayerState{player}
-					return nil
-				},
-				reasonableTimeout,
-			); err != nil {
+		} else {
+			player, err := playerToUse()
+			if err != nil {
 				return err
 			}
+			players = []system.PlayerState{player}
 		}
 
 		log.Info().
@@ -289,17 +430,14 @@ var playCmd = &cobra.Command{
 			Msg("Sending messages to players.")
 
 		for _, player := range players {
-			xmitter := transmitter.OSCTransmitter{Port: player.Port}
+			xmitter := playerTransmitter(player)
 
 			var transmissionError error
 			if action == "unpause" {
 				transmissionError = xmitter.TransmitPlayMessage()
 			} else {
 				transmissionError = xmitter.TransmitScore(
-					score,
-					transmitter.TransmitFrom(optionFrom),
-					transmitter.TransmitTo(optionTo),
-					transmitter.OneOff(),
+					score, playTransmissionOptions()...,
 				)
 			}
 			if transmissionError != nil {
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"alda.io/client/fakeplayer"
+	"alda.io/client/system"
+	_ "alda.io/client/testing"
+)
+
+func TestPlayWithPort(t *testing.T) {
+	cacheDir := system.CacheDir
+	system.CacheDir = t.TempDir()
+	t.Cleanup(func() { system.CacheDir = cacheDir })
+
+	player, err := fakeplayer.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { player.Stop() })
+
+	code, playerPort = "piano: c d", player.Port
+	t.Cleanup(func() { code, playerPort = "", -1 })
+
+	if err := playCmd.RunE(playCmd, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := player.AwaitPackets(1, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	stream := player.MessageStream()
+
+	// `alda play` sends the score as a one-off, so the player shuts down after
+	// playing it.
+	for _, expected := range []string{
+		"/track/1/midi/note ,iiiiii 0 0 60",
+		"/track/1/midi/note ,iiiiii 0 500 62",
+		"/system/play",
+		"/system/shutdown",
+	} {
+		if !strings.Contains(stream, expected) {
+			t.Errorf("expected %q, got:\n%s", expected, stream)
+		}
+	}
+}
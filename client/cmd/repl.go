@@ -19,6 +19,9 @@ var replPort int
 var startREPLClient bool
 var startREPLServer bool
 var replMessage string
+var replPlayerHost string
+var replPlayerPort int
+var replPlayerProtocol string
 
 func init() {
 	replCmd.Flags().StringVarP(
@@ -37,6 +40,27 @@ func init() {
 		&startREPLServer, "server", "s", false, "Start an Alda REPL server",
 	)
 
+	replCmd.Flags().StringVar(
+		&replPlayerHost,
+		"player-host",
+		"",
+		"The host of the player process to use (requires --player-port)",
+	)
+
+	replCmd.Flags().IntVar(
+		&replPlayerPort,
+		"player-port",
+		-1,
+		"The port of the player process to use",
+	)
+
+	replCmd.Flags().StringVar(
+		&replPlayerProtocol,
+		"player-protocol",
+		"tcp",
+		"The network protocol (tcp or udp) of the player process to use",
+	)
+
 	replCmd.Flags().StringVarP(
 		&replMessage,
 		"message",
@@ -111,6 +135,32 @@ clients.`,
 	color.Aurora.BrightYellow("alda repl --help"),
 )
 
+// replServerOptions returns the options for the Alda REPL server, based on the
+// provided CLI options.
+func replServerOptions() ([]repl.ServerOption, error) {
+	if replPlayerPort == -1 {
+		if replPlayerHost != "" || replPlayerProtocol != "tcp" {
+			return nil, help.UserFacingErrorf(
+				`The %s and %s options can only be used together with %s.`,
+				color.Aurora.BrightYellow("--player-host"),
+				color.Aurora.BrightYellow("--player-protocol"),
+				color.Aurora.BrightYellow("--player-port"),
+			)
+		}
+
+		return nil, nil
+	}
+
+	player, err := specifiedPlayer(
+		replPlayerHost, replPlayerPort, replPlayerProtocol,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return []repl.ServerOption{repl.WithPlayer(player)}, nil
+}
+
 var replCmd = &cobra.Command{
 	Use:   "repl",
 	Short: "Start an Alda REPL client/server",
@@ -135,6 +185,13 @@ Examples:
     Starts an Alda REPL server without an interactive prompt. Clients can then
     connect by running ` + "`alda repl --client --port 12345`" + `.
 
+  alda repl --server --port 12345 --player-host studio.local --player-port 27278
+    Starts an Alda REPL server that plays scores on a player process running
+    on another machine, e.g. a studio computer running:
+      alda-player -v run -p 27278
+    Add --player-protocol udp if the player process receives OSC over UDP,
+    e.g. alda-player -v run -p 27278 --protocol udp.
+
   alda repl --port 12345 --message '{"op": "eval-and-play", "code": "banjo: c"}'
     Sends an nREPL message to the Alda REPL server running on port 12345.
     This is mainly useful for writing scripts and tools for working with Alda.
@@ -165,7 +222,12 @@ Examples:
 				replPort = port
 			}
 
-			server, err := repl.RunServer(replPort)
+			serverOpts, err := replServerOptions()
+			if err != nil {
+				return err
+			}
+
+			server, err := repl.RunServer(replPort, serverOpts...)
 			if err != nil {
 				return err
 			}
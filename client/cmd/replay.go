@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"alda.io/client/color"
+	"alda.io/client/help"
+	log "alda.io/client/logging"
+	"alda.io/client/system"
+	"alda.io/client/transmitter"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	replayCmd.Flags().StringVarP(
+		&playerID, "player-id", "i", "", "The ID of the player process to use",
+	)
+
+	replayCmd.Flags().IntVarP(
+		&playerPort, "port", "p", -1, "The port of the player process to use",
+	)
+
+	addPlayerConnectionFlags(replayCmd)
+
+	replayCmd.Flags().StringVarP(
+		&file, "file", "f", "", "Read OSC messages from a file",
+	)
+}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Send previously exported OSC messages to a player process",
+	Long: `Send previously exported OSC messages to a player process
+
+---
+
+The messages are sent exactly as they were written by "alda export -O osc",
+"alda export -O osc-json" or "alda play --dry-run". This makes it possible to
+reproduce a playback problem without the Alda source code that caused it, and
+to tell whether the problem is caused by the client or the player.
+
+  alda export -O osc -f my-score.alda -o my-score.osc
+  alda replay -f my-score.osc
+
+The messages can also be piped into the process on stdin:
+
+  alda play --dry-run -f my-score.alda | alda replay
+
+---`,
+	RunE: func(_ *cobra.Command, args []string) error {
+		var input io.Reader
+
+		if file != "" {
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			input = f
+		} else {
+			stdin, err := system.ReadStdin()
+			if err == system.ErrNoInputSupplied {
+				return help.UserFacingErrorf(
+					`No OSC messages supplied.
+
+Provide the path to a file exported via %s:
+  %s`,
+					color.Aurora.BrightYellow("alda export -O osc"),
+					color.Aurora.BrightYellow("alda replay -f my-score.osc"),
+				)
+			}
+			if err != nil {
+				return err
+			}
+
+			input = bytes.NewReader(stdin)
+		}
+
+		bundle, err := transmitter.ReadOSCDump(input)
+		if err != nil {
+			return help.UserFacingErrorf(
+				`Failed to read OSC messages:
+
+  %s`,
+				err.Error(),
+			)
+		}
+
+		player, err := playerToUse()
+		if err != nil {
+			return err
+		}
+
+		if err := playerTransmitter(player).TransmitOSCBundle(
+			bundle,
+		); err != nil {
+			return err
+		}
+
+		log.Info().
+			Interface("player", player).
+			Int("messages", len(bundle.Messages)).
+			Msg("Sent OSC messages to player.")
+
+		fmt.Fprintln(os.Stderr, "Playing...")
+
+		return nil
+	},
+}
@@ -243,15 +243,18 @@ Use "{{.CommandPath}} [command] --help" for more information about a command.{{e
 		formatCmd,
 		importCmd,
 		instrumentsCmd,
+		lspCmd,
 		parseCmd,
 		playCmd,
 		psCmd,
 		replCmd,
+		replayCmd,
 		shutdownCmd,
 		stopCmd,
 		telemetryCmd,
 		updateCmd,
 		versionCmd,
+		watchCmd,
 	} {
 		rootCmd.AddCommand(cmd)
 	}
@@ -319,9 +322,21 @@ Slack: http://slack.alda.io`,
 		// * `alda doctor` spawns its own processes as part of the checks that it
 		//   does, and it simplifies our CI setup if we only spawn those explicit
 		//   ones without also spawning some implicit ones here.
+		//
+		// * `alda export` writes files directly, without involving a player
+		//   process.
+		//
+		// * `alda lsp` is started by an editor and only analyzes source code.
+		//
+		// * `alda play --dry-run` prints the OSC messages that it would send to a
+		//   player process, instead of sending them.
 		switch cmd.Name() {
-		case "ps", "shutdown", "doctor":
+		case "ps", "shutdown", "doctor", "export", "lsp":
 			// Don't fill the player pool.
+		case "play":
+			if !dryRun {
+				fillPlayerPool()
+			}
 		default:
 			fillPlayerPool()
 		}
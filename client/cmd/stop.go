@@ -6,11 +6,12 @@ import (
 
 	log "alda.io/client/logging"
 	"alda.io/client/system"
-	"alda.io/client/transmitter"
 	"github.com/spf13/cobra"
 )
 
 func init() {
+	addPlayerConnectionFlags(stopCmd)
+
 	stopCmd.Flags().StringVarP(
 		&playerID,
 		"player-id",
@@ -41,12 +42,16 @@ var stopCmd = &cobra.Command{
 
 		// Determine the players to which to send a "stop" message based on the
 		// provided CLI options.
+		player, specified, err := playerFromOptions()
+		if err != nil {
+			return err
+		}
+
 		switch {
-		// Port is explicitly specified, so use that port.
-		case playerPort != -1:
-			players = append(players, system.PlayerState{
-				ID: "unknown", State: "unknown", Port: playerPort,
-			})
+		// The player process is explicitly specified by its port (and maybe its
+		// host), so use that.
+		case specified:
+			players = append(players, player)
 		// Player ID is specified; look up the player by ID and use its port.
 		case playerID != "":
 			player, err := system.FindPlayerByID(playerID)
@@ -64,7 +69,7 @@ var stopCmd = &cobra.Command{
 		}
 
 		for _, player := range players {
-			transmitter := transmitter.OSCTransmitter{Port: player.Port}
+			transmitter := playerTransmitter(player)
 			if err := transmitter.TransmitStopMessage(); err != nil {
 				log.Warn().
 					Interface("player", player).
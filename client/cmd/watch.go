@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"alda.io/client/color"
+	"alda.io/client/help"
+	"alda.io/client/repl"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	watchCmd.Flags().StringVarP(
+		&file, "file", "f", "", "The score file to watch",
+	)
+
+	watchCmd.Flags().StringVarP(
+		&optionFrom,
+		"from",
+		"F",
+		"",
+		"A time marking (e.g. 0:30) or marker from which to replay the score "+
+			"(default: where the score was edited)",
+	)
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Play a score file and replay it each time it changes",
+	Long: `Play a score file and replay it each time it changes
+
+---
+
+Each time that the file is saved, the score is reparsed and replayed from the
+point where it was edited. Alternatively, you can choose a time marking or
+marker from which to replay the score each time.
+
+Examples:
+
+  alda watch -f my-score.alda
+    Replays my-score.alda from the point where it was edited.
+
+  alda watch -f my-score.alda --from chorus
+    Replays my-score.alda from the marker "chorus".
+
+Press Ctrl-C to stop.
+
+---`,
+	RunE: func(_ *cobra.Command, args []string) error {
+		if file == "" || len(args) > 0 {
+			return help.UserFacingErrorf(
+				`Please specify the score file to watch, e.g.:
+
+  %s`,
+				color.Aurora.BrightYellow("alda watch -f my-score.alda"),
+			)
+		}
+
+		watcher := repl.NewWatcher(file, optionFrom)
+
+		// Stop playback when the process is interrupted or terminated.
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+		go func() {
+			<-signals
+			fmt.Fprintln(os.Stderr, "Stopping playback.")
+			if err := watcher.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			}
+			os.Exit(0)
+		}()
+
+		fmt.Fprintf(os.Stderr, "Watching %s for changes...\n", file)
+
+		return watcher.Run()
+	},
+}
@@ -0,0 +1,402 @@
+// Package fakeplayer implements a stand-in for an `alda-player` process, for
+// use in tests.
+//
+// A fake player speaks the same OSC-over-TCP protocol as a real player and
+// writes a state file the way that a real player does, so the client can find
+// it, ping it and send it scores. Instead of playing the scores, it records
+// every OSC packet that it receives, so that tests can make assertions about
+// the exact messages that were sent.
+//
+// `alda doctor` is out of scope: it checks the real `alda-player` executable,
+// which it spawns itself and whose log files it reads, so a fake player can't
+// stand in for it.
+package fakeplayer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"alda.io/client/generated"
+	log "alda.io/client/logging"
+	"alda.io/client/system"
+	"alda.io/client/util"
+
+	"github.com/daveyarwood/go-osc/osc"
+)
+
+// A real player shuts down after 5-10 minutes of inactivity. The fake player
+// never shuts itself down, but it reports an expiry for the sake of `alda ps`.
+const inactivityTimeout = 5 * time.Minute
+
+// A Player is a fake player process, running in the current process.
+type Player struct {
+	// The ID of the player, which is also the name of its state file.
+	ID string
+	// The port on which the player is listening for OSC messages.
+	Port int
+	// The network protocol over which the player receives OSC messages, "tcp"
+	// (the default) or "udp".
+	Protocol string
+
+	// A TCP listener or a UDP connection, depending on the protocol, and the
+	// function that receives packets from it.
+	conn    io.Closer
+	receive osc.ReceiveFunc
+
+	done     chan struct{}
+	stopOnce sync.Once
+
+	// `mutex` guards the fields below, which are updated as packets are
+	// received.
+	mutex   sync.Mutex
+	state   string
+	expiry  time.Time
+	packets []osc.Packet
+}
+
+// An Option customizes a fake player.
+type Option func(*Player)
+
+// UDP makes a fake player receive OSC messages over UDP instead of TCP.
+func UDP() Option {
+	return func(player *Player) {
+		player.Protocol = "udp"
+	}
+}
+
+// Start starts a fake player listening on an open port, and writes its state
+// file (in the "ready" state) where system.ReadPlayerStates will find it.
+//
+// Tests will usually want to point system.CacheDir at a temporary directory
+// first, so that real player processes aren't affected.
+func Start(opts ...Option) (*Player, error) {
+	player := &Player{
+		ID:       generateID(),
+		Protocol: "tcp",
+		done:     make(chan struct{}),
+		state:    "ready",
+		expiry:   time.Now().Add(inactivityTimeout),
+	}
+
+	for _, opt := range opts {
+		opt(player)
+	}
+
+	if err := player.listen(); err != nil {
+		return nil, err
+	}
+
+	if err := player.writeStateFile(); err != nil {
+		player.conn.Close()
+		return nil, err
+	}
+
+	go player.serve()
+	go player.touchStateFile()
+
+	return player, nil
+}
+
+// The IDs of real players are 3 random lowercase letters. We prefix the IDs of
+// fake players so that they can't collide with those of real players.
+func generateID() string {
+	const charset = "abcdefghijklmnopqrstuvwxyz"
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	b := make([]byte, 3)
+	for i := range b {
+		b[i] = charset[random.Intn(len(charset))]
+	}
+
+	return "fake-" + string(b)
+}
+
+func (player *Player) listen() error {
+	if player.Protocol == "udp" {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			return err
+		}
+
+		player.Port = conn.LocalAddr().(*net.UDPAddr).Port
+		player.conn = conn
+		player.receive = osc.UDPReceive(conn)
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+
+	player.Port = listener.Addr().(*net.TCPAddr).Port
+	player.conn = listener
+	player.receive = osc.TCPReceive(listener)
+	return nil
+}
+
+// StateFile returns the path to the player's state file.
+func (player *Player) StateFile() string {
+	return system.CachePath(
+		"state", "players", generated.ClientVersion, player.ID+".json",
+	)
+}
+
+func (player *Player) writeStateFile() error {
+	player.mutex.Lock()
+	state := system.PlayerState{
+		State:  player.state,
+		Port:   player.Port,
+		Expiry: player.expiry.UnixMilli(),
+		PID:    os.Getpid(),
+	}
+	player.mutex.Unlock()
+
+	if player.Protocol == "udp" {
+		state.Protocol = player.Protocol
+	}
+
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	stateFile := player.StateFile()
+
+	if err := os.MkdirAll(filepath.Dir(stateFile), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(stateFile, stateJSON, 0644)
+}
+
+// Like a real player, we periodically update the last modified time of the
+// state file, so that it isn't cleaned up as a stale state file.
+func (player *Player) touchStateFile() {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-player.done:
+			return
+		case now := <-ticker.C:
+			if err := os.Chtimes(player.StateFile(), now, now); err != nil {
+				log.Warn().Err(err).Msg("Failed to touch fake player state file.")
+			}
+		}
+	}
+}
+
+// setState updates the state of the player and writes it to the state file.
+func (player *Player) setState(state string) {
+	player.mutex.Lock()
+	changed := player.state != state
+	player.state = state
+	player.mutex.Unlock()
+
+	if !changed {
+		return
+	}
+
+	// Don't recreate the state file of a player that has been stopped.
+	select {
+	case <-player.done:
+		return
+	default:
+	}
+
+	if err := player.writeStateFile(); err != nil {
+		log.Warn().Err(err).Msg("Failed to write fake player state file.")
+	}
+}
+
+// serve receives and handles packets one at a time, in the order in which they
+// are received, until the player is stopped.
+//
+// NB: We don't use osc.Server here because it dispatches each packet in a
+// separate goroutine, so the order of the recorded packets would be
+// nondeterministic.
+func (player *Player) serve() {
+	for {
+		packet, err := player.receive(0)
+
+		select {
+		case <-player.done:
+			return
+		default:
+		}
+
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+
+		if err != nil {
+			log.Warn().Err(err).Msg("Fake player failed to receive packet.")
+			continue
+		}
+
+		player.handle(packet)
+	}
+}
+
+func (player *Player) handle(packet osc.Packet) {
+	shutdown := false
+
+	// The fake player doesn't play anything in real time, so scheduled events
+	// like `/system/playback-finished` and `/system/shutdown` take effect right
+	// away, regardless of their offsets.
+	for _, msg := range messages(packet) {
+		switch msg.Address {
+		case "/ping":
+			// As with a real player, a ping "claims" the player and delays its
+			// expiry.
+			player.mutex.Lock()
+			player.expiry = time.Now().Add(inactivityTimeout)
+			player.mutex.Unlock()
+
+			player.setState("active")
+		case "/system/play":
+			player.setState("active")
+		case "/system/playback-finished":
+			player.setState("finished")
+		case "/system/shutdown":
+			shutdown = true
+		}
+	}
+
+	// We record the packet after updating the state, so that when a test sees
+	// the packet (see AwaitPackets), it can also see the resulting state.
+	player.mutex.Lock()
+	player.packets = append(player.packets, packet)
+	player.mutex.Unlock()
+
+	if shutdown {
+		if err := player.Stop(); err != nil {
+			log.Warn().Err(err).Msg("Failed to stop fake player.")
+		}
+	}
+}
+
+// Stop stops the player and removes its state file. The packets that the
+// player received are still available afterwards.
+//
+// It's safe to call Stop more than once, e.g. in a deferred call after the
+// player has already been shut down via a `/system/shutdown` message.
+func (player *Player) Stop() error {
+	var err error
+
+	player.stopOnce.Do(func() {
+		close(player.done)
+
+		if closeErr := player.conn.Close(); closeErr != nil {
+			err = closeErr
+		}
+
+		removeErr := os.Remove(player.StateFile())
+		if removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			err = removeErr
+		}
+	})
+
+	return err
+}
+
+// Done returns a channel that is closed when the player stops, either because
+// Stop was called or because the player received a `/system/shutdown` message.
+func (player *Player) Done() <-chan struct{} {
+	return player.done
+}
+
+// State returns the current state of the player, e.g. "ready" or "active".
+func (player *Player) State() string {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+
+	return player.state
+}
+
+// Packets returns the OSC packets (bundles and messages) that the player has
+// received so far, in the order in which they were received.
+func (player *Player) Packets() []osc.Packet {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+
+	return append([]osc.Packet{}, player.packets...)
+}
+
+// Messages returns all of the OSC messages that the player has received so far,
+// including the messages inside of bundles, in order.
+func (player *Player) Messages() []*osc.Message {
+	msgs := []*osc.Message{}
+
+	for _, packet := range player.Packets() {
+		msgs = append(msgs, messages(packet)...)
+	}
+
+	return msgs
+}
+
+// MessageStream returns a textual representation of the messages that the
+// player has received so far, one per line, e.g.:
+//
+//	/track/1/midi/note ,iiiiii 0 0 60 500 450 69
+//
+// This is convenient for comparing against an expected message stream in
+// tests.
+func (player *Player) MessageStream() string {
+	lines := []string{}
+
+	for _, msg := range player.Messages() {
+		lines = append(lines, msg.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// AwaitPackets waits until the player has received at least `count` packets,
+// and returns an error if that doesn't happen before the timeout.
+//
+// Sending a packet returns as soon as the packet is written, which can be
+// before the player has handled it, so tests should wait for the packets that
+// they expect before making assertions about them.
+func (player *Player) AwaitPackets(count int, timeout time.Duration) error {
+	return util.Await(
+		func() error {
+			if received := len(player.Packets()); received < count {
+				return fmt.Errorf(
+					"expected %d packets, but only received %d", count, received,
+				)
+			}
+
+			return nil
+		},
+		timeout,
+	)
+}
+
+// messages returns the messages in a packet, which is either a single message
+// or a bundle (which can contain further bundles).
+func messages(packet osc.Packet) []*osc.Message {
+	switch packet := packet.(type) {
+	case *osc.Message:
+		return []*osc.Message{packet}
+	case *osc.Bundle:
+		msgs := append([]*osc.Message{}, packet.Messages...)
+		for _, bundle := range packet.Bundles {
+			msgs = append(msgs, messages(bundle)...)
+		}
+		return msgs
+	default:
+		return nil
+	}
+}
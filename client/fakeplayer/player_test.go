@@ -0,0 +1,229 @@
+package fakeplayer
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"alda.io/client/model"
+	"alda.io/client/parser"
+	"alda.io/client/system"
+	_ "alda.io/client/testing"
+	"alda.io/client/transmitter"
+	"github.com/daveyarwood/go-osc/osc"
+)
+
+const awaitTimeout = 5 * time.Second
+
+// Points the cache directory (where player state files are written) at a
+// temporary directory for the duration of the test.
+func useTempCacheDir(t *testing.T) {
+	cacheDir := system.CacheDir
+	system.CacheDir = t.TempDir()
+	t.Cleanup(func() { system.CacheDir = cacheDir })
+}
+
+func startPlayer(t *testing.T, opts ...Option) *Player {
+	player, err := Start(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { player.Stop() })
+
+	return player
+}
+
+func parseScore(t *testing.T, input string) *model.Score {
+	ast, err := parser.ParseString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updates, err := ast.Updates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	score := model.NewScore()
+	if err := score.Update(updates...); err != nil {
+		t.Fatal(err)
+	}
+
+	return score
+}
+
+func findPlayerState(t *testing.T, id string) (system.PlayerState, bool) {
+	states, err := system.ReadPlayerStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, state := range states {
+		if state.ID == id {
+			return state, true
+		}
+	}
+
+	return system.PlayerState{}, false
+}
+
+func TestPlayerState(t *testing.T) {
+	useTempCacheDir(t)
+	player := startPlayer(t)
+
+	state, found := findPlayerState(t, player.ID)
+	if !found {
+		t.Fatalf("expected to find the state of player %s", player.ID)
+	}
+
+	if state.State != "ready" || state.Port != player.Port {
+		t.Errorf("unexpected player state: %#v", state)
+	}
+
+	available, err := system.FindAvailablePlayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if available.ID != player.ID {
+		t.Errorf("expected player %s to be available", player.ID)
+	}
+
+	// FindAvailablePlayer pings the player, which claims it.
+	if err := player.AwaitPackets(1, awaitTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	if state, _ := findPlayerState(t, player.ID); state.State != "active" {
+		t.Errorf(
+			"expected the player to be active after a ping, got %q", state.State,
+		)
+	}
+
+	if err := player.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found := findPlayerState(t, player.ID); found {
+		t.Error("expected the state file to be removed when the player stops")
+	}
+}
+
+// The messages sent to a player for the one-off score "piano: c d".
+var pianoCDMessages = strings.Join([]string{
+	"/system/tempo ,if 0 120",
+	"/track/1/midi/patch ,iii 0 0 0",
+	"/track/1/midi/volume ,iii 0 0 100",
+	"/track/1/midi/panning ,iii 0 0 64",
+	"/track/1/midi/note ,iiiiii 0 0 60 500 450 69",
+	"/track/1/midi/note ,iiiiii 0 500 62 500 450 69",
+	"/system/play ,",
+	"/system/playback-finished ,i 950",
+	"/system/shutdown ,i 10950",
+}, "\n")
+
+func TestPlayerRecordsMessages(t *testing.T) {
+	useTempCacheDir(t)
+	player := startPlayer(t)
+
+	score := parseScore(t, "piano: c d")
+
+	if err := (transmitter.OSCTransmitter{Port: player.Port}).TransmitScore(
+		score, transmitter.OneOff(),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-player.Done():
+	case <-time.After(awaitTimeout):
+		t.Fatal("expected the player to shut down after a one-off score")
+	}
+
+	if actual := player.MessageStream(); actual != pianoCDMessages {
+		t.Errorf("expected messages:\n%s\n\ngot:\n%s", pianoCDMessages, actual)
+	}
+
+	if packets := len(player.Packets()); packets != 1 {
+		t.Errorf("expected the score to be sent as 1 bundle, got %d", packets)
+	}
+
+	if state := player.State(); state != "finished" {
+		t.Errorf("expected the player to be finished, got %q", state)
+	}
+
+	if _, err := os.Stat(player.StateFile()); !os.IsNotExist(err) {
+		t.Error("expected the state file to be removed after shutdown")
+	}
+}
+
+func TestUDPPlayer(t *testing.T) {
+	useTempCacheDir(t)
+	player := startPlayer(t, UDP())
+
+	state, found := findPlayerState(t, player.ID)
+	if !found || state.Protocol != "udp" {
+		t.Errorf("unexpected player state: %#v", state)
+	}
+
+	// A player that receives OSC messages over UDP can't be pinged, so it isn't
+	// part of the player pool, and its state file is left alone.
+	_, err := system.FindAvailablePlayer()
+	if err != system.ErrNoPlayersAvailable {
+		t.Errorf("expected no players to be available, got %v", err)
+	}
+
+	if _, found := findPlayerState(t, player.ID); !found {
+		t.Errorf("expected the state of player %s to be kept", player.ID)
+	}
+
+	xmitter := transmitter.OSCTransmitter{
+		Host: "localhost", Port: player.Port, Protocol: "udp",
+	}
+
+	score := parseScore(t, "piano: c d")
+
+	if err := xmitter.TransmitScore(score, transmitter.OneOff()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := player.AwaitPackets(1, awaitTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := player.MessageStream(); actual != pianoCDMessages {
+		t.Errorf("expected messages:\n%s\n\ngot:\n%s", pianoCDMessages, actual)
+	}
+
+	// A bundle that doesn't fit in a single UDP datagram can't be sent over UDP.
+	bundle := osc.NewBundle(time.Now())
+	for i := 0; i < 5000; i++ {
+		bundle.Append(osc.NewMessage("/track/1/midi/note", int32(i)))
+	}
+
+	if err := xmitter.TransmitOSCBundle(bundle); err == nil ||
+		!strings.Contains(err.Error(), "too large to send over UDP") {
+		t.Errorf("expected an error about the packet size, got %v", err)
+	}
+}
+
+func TestUnreachablePlayer(t *testing.T) {
+	useTempCacheDir(t)
+	player := startPlayer(t)
+	port := player.Port
+
+	if err := player.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, xmitter := range []transmitter.OSCTransmitter{
+		{Port: port},
+		{Host: "localhost", Port: port, Protocol: "sctp"},
+	} {
+		if err := xmitter.TransmitPingMessage(); err == nil {
+			t.Errorf("expected an error sending to %#v", xmitter)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+
+	"alda.io/client/help"
+	log "alda.io/client/logging"
+	"alda.io/client/model"
+	"gitlab.com/gomidi/midi/midimessage/channel"
+	"gitlab.com/gomidi/midi/midimessage/meta"
+	"gitlab.com/gomidi/midi/smf"
+	"gitlab.com/gomidi/midi/smf/smfreader"
+)
+
+// percussionChannel is the (zero-indexed) MIDI channel that the General MIDI
+// spec reserves for percussion.
+const percussionChannel = 9
+
+// gridDivisions is the number of divisions of a quarter note that we quantize
+// note onsets and durations to, i.e. 4 = 16th notes.
+const gridDivisions = 4
+
+// midiNote is a note read from a MIDI file, with times in ticks.
+type midiNote struct {
+	channel uint8
+	key     uint8
+	start   uint32
+	end     uint32
+}
+
+// midiProgramChange is a program change read from a MIDI file.
+type midiProgramChange struct {
+	channel uint8
+	ticks   uint32
+	program uint8
+}
+
+// midiTempoChange is a tempo change (in BPM) read from a MIDI file.
+type midiTempoChange struct {
+	ticks uint32
+	tempo float64
+}
+
+// midiFile contains the information from a MIDI file that is relevant for
+// import.
+type midiFile struct {
+	ticksPerQuarterNote uint32
+	notes               []midiNote
+	programChanges      []midiProgramChange
+	tempoChanges        []midiTempoChange
+	// When the MIDI file has a key signature with flats, we spell black keys
+	// with flats instead of sharps.
+	preferFlats bool
+}
+
+// readMidiFile reads the notes, program changes, and tempo changes from the
+// bytes of a Standard MIDI File.
+func readMidiFile(b []byte) (midiFile, error) {
+	rd := smfreader.New(bytes.NewReader(b))
+
+	if err := rd.ReadHeader(); err != nil {
+		return midiFile{}, help.UserFacingErrorf(
+			"Failed to read MIDI file header: %s.", err.Error(),
+		)
+	}
+
+	ticks, ok := rd.Header().TimeFormat.(smf.MetricTicks)
+	if !ok {
+		return midiFile{}, help.UserFacingErrorf(
+			"Issue importing MIDI: SMPTE time code is not supported.",
+		)
+	}
+
+	file := midiFile{ticksPerQuarterNote: ticks.Ticks4th()}
+
+	type noteKey struct {
+		channel uint8
+		key     uint8
+	}
+
+	var currentTrack int16 = -1
+	var position uint32
+	var unterminated map[noteKey][]uint32
+
+	// Any note that is still sounding at the end of a track ends there.
+	endTrack := func() {
+		for nk, starts := range unterminated {
+			for _, start := range starts {
+				file.notes = append(file.notes, midiNote{
+					channel: nk.channel, key: nk.key, start: start, end: position,
+				})
+			}
+		}
+	}
+
+	noteOff := func(ch uint8, key uint8) {
+		nk := noteKey{channel: ch, key: key}
+		starts := unterminated[nk]
+		if len(starts) == 0 {
+			return
+		}
+
+		file.notes = append(file.notes, midiNote{
+			channel: ch, key: key, start: starts[0], end: position,
+		})
+		unterminated[nk] = starts[1:]
+	}
+
+	for {
+		msg, err := rd.Read()
+		if err == smf.ErrFinished {
+			break
+		}
+		if err != nil {
+			return midiFile{}, help.UserFacingErrorf(
+				"Failed to read MIDI file: %s.", err.Error(),
+			)
+		}
+
+		if rd.Track() != currentTrack {
+			endTrack()
+			currentTrack = rd.Track()
+			position = 0
+			unterminated = map[noteKey][]uint32{}
+		}
+
+		position += rd.Delta()
+
+		switch msg := msg.(type) {
+		case channel.NoteOn:
+			if msg.Velocity() == 0 {
+				noteOff(msg.Channel(), msg.Key())
+				continue
+			}
+
+			nk := noteKey{channel: msg.Channel(), key: msg.Key()}
+			unterminated[nk] = append(unterminated[nk], position)
+
+		case channel.NoteOff:
+			noteOff(msg.Channel(), msg.Key())
+
+		case channel.NoteOffVelocity:
+			noteOff(msg.Channel(), msg.Key())
+
+		case channel.ProgramChange:
+			file.programChanges = append(file.programChanges, midiProgramChange{
+				channel: msg.Channel(), ticks: position, program: msg.Program(),
+			})
+
+		case meta.Tempo:
+			file.tempoChanges = append(file.tempoChanges, midiTempoChange{
+				ticks: position,
+				// MIDI files store tempos in microseconds per quarter note, so the
+				// BPM is usually not a round number. We round to the nearest
+				// hundredth of a BPM to avoid tempos like 119.99999.
+				tempo: math.Round(msg.FractionalBPM()*100) / 100,
+			})
+
+		case meta.Key:
+			if msg.IsFlat {
+				file.preferFlats = true
+			}
+		}
+	}
+
+	endTrack()
+
+	sort.SliceStable(file.programChanges, func(i, j int) bool {
+		return file.programChanges[i].ticks < file.programChanges[j].ticks
+	})
+
+	sort.SliceStable(file.tempoChanges, func(i, j int) bool {
+		return file.tempoChanges[i].ticks < file.tempoChanges[j].ticks
+	})
+
+	return file, nil
+}
+
+// programAt returns the program (patch number) in effect on a channel at a
+// point in time, defaulting to 0 (piano) if there were no program changes on
+// that channel up to that point.
+func (file midiFile) programAt(ch uint8, ticks uint32) uint8 {
+	var program uint8
+
+	for _, pc := range file.programChanges {
+		if pc.ticks > ticks {
+			break
+		}
+
+		if pc.channel == ch {
+			program = pc.program
+		}
+	}
+
+	return program
+}
+
+// quantize rounds a number of ticks to the nearest grid division.
+func quantize(ticks uint32, grid uint32) uint32 {
+	return uint32(math.Round(float64(ticks)/float64(grid))) * grid
+}
+
+// partKey identifies the notes that make up a part. Each MIDI channel is
+// imported as a separate part, and within a channel, a program change to a
+// different instrument also starts a new part.
+type partKey struct {
+	channel uint8
+	program uint8
+}
+
+// instrumentName returns the name of the stock Alda instrument for a part.
+func (pk partKey) instrumentName() string {
+	if pk.channel == percussionChannel {
+		return "midi-percussion"
+	}
+
+	return model.InstrumentsList()[pk.program]
+}
+
+// midiPart is a part being imported from a MIDI file.
+type midiPart struct {
+	key   partKey
+	alias string
+	notes []midiNote
+}
+
+// groupParts quantizes the notes in a MIDI file and groups them into parts.
+//
+// Parts are returned in the order in which they first play a note.
+func groupParts(file midiFile, grid uint32) []*midiPart {
+	parts := map[partKey]*midiPart{}
+	orderedParts := []*midiPart{}
+
+	notes := append([]midiNote{}, file.notes...)
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].start != notes[j].start {
+			return notes[i].start < notes[j].start
+		}
+
+		return notes[i].channel < notes[j].channel
+	})
+
+	for _, note := range notes {
+		key := partKey{channel: note.channel}
+		if note.channel != percussionChannel {
+			key.program = file.programAt(note.channel, note.start)
+		}
+
+		part, hit := parts[key]
+		if !hit {
+			part = &midiPart{key: key}
+			parts[key] = part
+			orderedParts = append(orderedParts, part)
+		}
+
+		start := quantize(note.start, grid)
+		end := quantize(note.end, grid)
+		// Very short notes would otherwise be quantized out of existence.
+		if end <= start {
+			end = start + grid
+		}
+
+		note.start, note.end = start, end
+		part.notes = append(part.notes, note)
+	}
+
+	// When more than one part has the same instrument, we give each of those
+	// parts an alias so that they can be told apart.
+	instrumentCounts := map[string]int{}
+	for _, part := range orderedParts {
+		instrumentCounts[part.key.instrumentName()]++
+	}
+
+	for _, part := range orderedParts {
+		name := part.key.instrumentName()
+		if instrumentCounts[name] > 1 {
+			part.alias = fmt.Sprintf("%s-ch%d", name, part.key.channel+1)
+		}
+	}
+
+	return orderedParts
+}
+
+// ImportMIDI translates a Standard MIDI File into Alda score updates.
+//
+// Each MIDI channel becomes a part, using the General MIDI instrument selected
+// by the channel's program changes. Note onsets and durations are quantized to
+// 16th notes, measured in beats so that tempo changes do not affect
+// quantization. Notes that start and end together are combined into chords,
+// and overlapping lines within a part are split into voices.
+func ImportMIDI(b []byte) ([]model.ScoreUpdate, error) {
+	file, err := readMidiFile(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(file.notes) == 0 {
+		log.Warn().Msg("The MIDI file does not contain any notes.")
+		return []model.ScoreUpdate{}, nil
+	}
+
+	grid := file.ticksPerQuarterNote / gridDivisions
+	if grid == 0 {
+		grid = 1
+	}
+
+	parts := groupParts(file, grid)
+
+	partVoices := make([][]midiVoice, len(parts))
+	scoreEnd := uint32(0)
+	for i, part := range parts {
+		partVoices[i] = assignVoices(part.notes, grid)
+
+		for _, voice := range partVoices[i] {
+			for _, chord := range voice.chords {
+				if chord.end > scoreEnd {
+					scoreEnd = chord.end
+				}
+			}
+		}
+	}
+
+	// Tempo changes apply to the whole score, so we only need to include them
+	// once. We include them in the first voice of the first part where they
+	// don't fall in the middle of a note. If there is no such voice, we add a
+	// voice that consists only of rests and tempo changes.
+	tempos := tempoMarks(file, grid, scoreEnd)
+	if len(tempos) > 0 {
+		tempoVoice := -1
+		for v, voice := range partVoices[0] {
+			if voice.accommodates(tempos) {
+				tempoVoice = v
+				break
+			}
+		}
+
+		if tempoVoice == -1 {
+			partVoices[0] = append(partVoices[0], midiVoice{})
+			tempoVoice = len(partVoices[0]) - 1
+		}
+
+		partVoices[0][tempoVoice].tempos = tempos
+	}
+
+	updates := []model.ScoreUpdate{}
+
+	for i, part := range parts {
+		updates = append(updates, model.PartDeclaration{
+			Names: []string{part.key.instrumentName()},
+			Alias: part.alias,
+		})
+
+		voices := partVoices[i]
+		translator := newTranslator(grid, file.preferFlats)
+
+		if len(voices) == 1 {
+			updates = append(updates, translator.translateVoice(voices[0])...)
+			continue
+		}
+
+		for v, voice := range voices {
+			updates = append(updates, model.VoiceMarker{VoiceNumber: int32(v + 1)})
+			updates = append(updates, translator.translateVoice(voice)...)
+		}
+	}
+
+	return updates, nil
+}
@@ -0,0 +1,231 @@
+package importer
+
+import (
+	"bytes"
+	"testing"
+
+	_ "alda.io/client/testing"
+	"gitlab.com/gomidi/midi"
+	"gitlab.com/gomidi/midi/midimessage/channel"
+	"gitlab.com/gomidi/midi/midimessage/meta"
+	"gitlab.com/gomidi/midi/smf"
+	"gitlab.com/gomidi/midi/smf/smfwriter"
+)
+
+type timedMessage struct {
+	delta   uint32
+	message midi.Message
+}
+
+// midiFileBytes returns a single-track MIDI file containing the provided
+// messages, at a resolution of 480 ticks per quarter note.
+func midiFileBytes(t *testing.T, messages ...timedMessage) []byte {
+	var buf bytes.Buffer
+
+	wr := smfwriter.New(
+		&buf,
+		smfwriter.Format(smf.SMF0),
+		smfwriter.TimeFormat(smf.MetricTicks(480)),
+	)
+
+	for _, msg := range append(messages, timedMessage{message: meta.EndOfTrack}) {
+		wr.SetDelta(msg.delta)
+		if err := wr.Write(msg.message); err != nil && err != smf.ErrFinished {
+			t.Fatal(err)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func TestNotes(t *testing.T) {
+	executeImporterTestCases(t,
+		importerTestCase{
+			label: "simple notes",
+			alda:  "piano: c8 d e f g4 a b2 > c1",
+			expected: `
+				midi-acoustic-grand-piano:
+					c8 d e f g4 a b2 > c1
+			`,
+		},
+		importerTestCase{
+			label: "rests",
+			alda:  "piano: r4 c8 r d4 r2 e4",
+			expected: `
+				midi-acoustic-grand-piano:
+					r4 c8 r d4 r2 e4
+			`,
+		},
+		importerTestCase{
+			label: "accidentals",
+			alda:  "piano: c+ e- f+ b-",
+			expected: `
+				midi-acoustic-grand-piano:
+					c+4 d+ f+ a+
+			`,
+		},
+		importerTestCase{
+			label: "dotted and tied durations",
+			alda:  "piano: c4. d8 e2. f4~16 g8.",
+			expected: `
+				midi-acoustic-grand-piano:
+					c4. d8 e2. f4~16 g8.
+			`,
+		},
+	)
+}
+
+func TestOctaves(t *testing.T) {
+	executeImporterTestCases(t,
+		importerTestCase{
+			label: "relative octave changes",
+			alda:  "piano: o3 c d > e >> f < g",
+			expected: `
+				midi-acoustic-grand-piano:
+					o3 c4 d > e > > f < g
+			`,
+		},
+		importerTestCase{
+			label: "large octave jumps",
+			alda:  "piano: o1 c o6 d o2 e",
+			expected: `
+				midi-acoustic-grand-piano:
+					o1 c4 o6 d o2 e
+			`,
+		},
+	)
+}
+
+func TestChordsAndVoices(t *testing.T) {
+	executeImporterTestCases(t,
+		importerTestCase{
+			label: "chords",
+			alda:  "piano: c1/e/g/>c < b2/>d/g",
+			expected: `
+				midi-acoustic-grand-piano:
+					c1/e/g/>c < b2/>d/g
+			`,
+		},
+		importerTestCase{
+			label: "voices",
+			alda: `
+				piano:
+					V1: o5 c2 d e1
+					V2: o3 c1 e2 g
+			`,
+			expected: `
+				midi-acoustic-grand-piano:
+					V1: o5 c2 d e1
+					V2: o3 c1 e2 g
+			`,
+		},
+	)
+}
+
+func TestParts(t *testing.T) {
+	executeImporterTestCases(t,
+		importerTestCase{
+			label: "multiple parts",
+			alda: `
+				violin: o5 c2 d
+				cello: o3 c1
+				percussion: o2 c8 d c d
+			`,
+			expected: `
+				midi-violin:
+					o5 c2 d
+				midi-cello:
+					o3 c1
+				midi-percussion:
+					o2 c8 d c d
+			`,
+		},
+		importerTestCase{
+			label: "multiple parts with the same instrument",
+			alda: `
+				piano "piano-1": c1
+				piano "piano-2": e1
+			`,
+			expected: `
+				midi-acoustic-grand-piano "midi-acoustic-grand-piano-ch1":
+					c1
+				midi-acoustic-grand-piano "midi-acoustic-grand-piano-ch2":
+					e1
+			`,
+		},
+		importerTestCase{
+			label: "program changes within a channel",
+			midi: midiFileBytes(t,
+				timedMessage{message: channel.Channel0.ProgramChange(40)},
+				timedMessage{message: channel.Channel0.NoteOn(60, 100)},
+				timedMessage{delta: 480, message: channel.Channel0.NoteOff(60)},
+				timedMessage{message: channel.Channel0.ProgramChange(73)},
+				timedMessage{message: channel.Channel0.NoteOn(62, 100)},
+				timedMessage{delta: 480, message: channel.Channel0.NoteOff(62)},
+			),
+			expected: `
+				midi-violin:
+					c4
+				midi-flute:
+					r4 d
+			`,
+		},
+	)
+}
+
+func TestTempo(t *testing.T) {
+	executeImporterTestCases(t,
+		importerTestCase{
+			label: "initial tempo",
+			alda:  "piano: (tempo! 90) c d e f",
+			expected: `
+				midi-acoustic-grand-piano:
+					(tempo! 90) c4 d e f
+			`,
+		},
+		importerTestCase{
+			label: "tempo changes",
+			alda:  "piano: c d (tempo! 60) e f (tempo! 150) g1",
+			expected: `
+				midi-acoustic-grand-piano:
+					c4 d (tempo! 60) e f (tempo! 150) g1
+			`,
+		},
+		importerTestCase{
+			label: "tempo change during a note",
+			alda: `
+				piano: c1
+				flute: r2 (tempo! 100) d2 e1
+			`,
+			expected: `
+				midi-acoustic-grand-piano:
+					V1: c1
+					V2: r2 (tempo! 100)
+				midi-flute:
+					r2 d e1
+			`,
+		},
+	)
+}
+
+func TestPerformanceTiming(t *testing.T) {
+	executeImporterTestCases(t,
+		importerTestCase{
+			label: "unquantized timing, legato and note-on with velocity 0",
+			midi: midiFileBytes(t,
+				timedMessage{delta: 10, message: channel.Channel0.NoteOn(60, 100)},
+				// The next note starts slightly before this one ends.
+				timedMessage{delta: 465, message: channel.Channel0.NoteOn(64, 100)},
+				timedMessage{delta: 80, message: channel.Channel0.NoteOff(60)},
+				timedMessage{delta: 400, message: channel.Channel0.NoteOn(64, 0)},
+				timedMessage{delta: 15, message: channel.Channel0.NoteOn(67, 100)},
+				// This note is never released, so it lasts until the end of the track.
+				timedMessage{delta: 950, message: channel.Channel0.NoteOn(72, 0)},
+			),
+			expected: `
+				midi-acoustic-grand-piano:
+					c4 e g2
+			`,
+		},
+	)
+}
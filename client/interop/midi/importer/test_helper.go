@@ -0,0 +1,122 @@
+package importer
+
+import (
+	"bytes"
+	"testing"
+
+	"alda.io/client/model"
+	"alda.io/client/parser"
+	"alda.io/client/transmitter"
+	"github.com/go-test/deep"
+)
+
+type importerTestCase struct {
+	label string
+	// The MIDI file to import. When this is nil, the MIDI file is generated from
+	// the Alda code in `alda` instead.
+	midi     []byte
+	alda     string
+	expected string
+}
+
+// parseAlda parses Alda code into score updates, evaluating any Lisp forms.
+func parseAlda(label string, code string) ([]model.ScoreUpdate, error) {
+	ast, err := parser.Parse(label, code, parser.SuppressSourceContext)
+	if err != nil {
+		return nil, err
+	}
+
+	updates, err := ast.Updates()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, update := range updates {
+		if lispList, ok := update.(model.LispList); ok {
+			lispForm, err := lispList.Eval()
+			if err != nil {
+				return nil, err
+			}
+
+			updates[i] = lispForm.(model.LispScoreUpdate).ScoreUpdate
+		}
+	}
+
+	return updates, nil
+}
+
+// exportMidi returns the MIDI file that Alda exports for the provided code.
+func exportMidi(label string, code string) ([]byte, error) {
+	updates, err := parseAlda(label, code)
+	if err != nil {
+		return nil, err
+	}
+
+	score := model.NewScore()
+	if err := score.Update(updates...); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = transmitter.MidiFileTransmitter{Writer: &buf}.TransmitScore(score)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// formatAlda returns the Alda code for the provided score updates, for
+// displaying in test failures.
+func formatAlda(updates []model.ScoreUpdate) string {
+	root, err := parser.GenerateASTFromScoreUpdates(updates)
+	if err != nil {
+		return err.Error()
+	}
+
+	var buf bytes.Buffer
+	if err := parser.FormatASTToCode(root, &buf); err != nil {
+		return err.Error()
+	}
+
+	return buf.String()
+}
+
+func executeImporterTestCases(
+	t *testing.T, testCases ...importerTestCase,
+) {
+	for _, testCase := range testCases {
+		b := testCase.midi
+		if b == nil {
+			var err error
+			b, err = exportMidi(testCase.label, testCase.alda)
+			if err != nil {
+				t.Error(testCase.label)
+				t.Error(err)
+				return
+			}
+		}
+
+		actual, err := ImportMIDI(b)
+		if err != nil {
+			t.Error(testCase.label)
+			t.Error(err)
+			return
+		}
+
+		expected, err := parseAlda(testCase.label, testCase.expected)
+		if err != nil {
+			t.Error(testCase.label)
+			t.Error(err)
+			return
+		}
+
+		if diff := deep.Equal(expected, actual); diff != nil {
+			t.Error(testCase.label)
+			t.Errorf("imported:\n%s", formatAlda(actual))
+			for _, diffItem := range diff {
+				t.Errorf("%v", diffItem)
+			}
+		}
+	}
+}
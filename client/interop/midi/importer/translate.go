@@ -0,0 +1,241 @@
+package importer
+
+import (
+	"alda.io/client/model"
+)
+
+// defaultOctave is the octave that a part starts in, in Alda.
+const defaultOctave = 4
+
+// maxRelativeOctaveJump is the largest octave change that we express using
+// octave up/down (`>` / `<`) operators. Larger jumps use an octave set, which
+// is easier to read.
+const maxRelativeOctaveJump = 2
+
+// sharpSpellings and flatSpellings are the note letters and accidentals used to
+// spell each pitch class, starting from C.
+var sharpSpellings = []model.LetterAndAccidentals{
+	{NoteLetter: model.C},
+	{NoteLetter: model.C, Accidentals: []model.Accidental{model.Sharp}},
+	{NoteLetter: model.D},
+	{NoteLetter: model.D, Accidentals: []model.Accidental{model.Sharp}},
+	{NoteLetter: model.E},
+	{NoteLetter: model.F},
+	{NoteLetter: model.F, Accidentals: []model.Accidental{model.Sharp}},
+	{NoteLetter: model.G},
+	{NoteLetter: model.G, Accidentals: []model.Accidental{model.Sharp}},
+	{NoteLetter: model.A},
+	{NoteLetter: model.A, Accidentals: []model.Accidental{model.Sharp}},
+	{NoteLetter: model.B},
+}
+
+var flatSpellings = []model.LetterAndAccidentals{
+	{NoteLetter: model.C},
+	{NoteLetter: model.D, Accidentals: []model.Accidental{model.Flat}},
+	{NoteLetter: model.D},
+	{NoteLetter: model.E, Accidentals: []model.Accidental{model.Flat}},
+	{NoteLetter: model.E},
+	{NoteLetter: model.F},
+	{NoteLetter: model.G, Accidentals: []model.Accidental{model.Flat}},
+	{NoteLetter: model.G},
+	{NoteLetter: model.A, Accidentals: []model.Accidental{model.Flat}},
+	{NoteLetter: model.A},
+	{NoteLetter: model.B, Accidentals: []model.Accidental{model.Flat}},
+	{NoteLetter: model.B},
+}
+
+// noteLengths are the note lengths that we use to express durations, in
+// descending order, along with their lengths in 16th notes.
+//
+// We stick to plain and single-dotted note lengths. Any duration that can't be
+// expressed by one of these is expressed as a series of tied note lengths.
+var noteLengths = []struct {
+	sixteenths uint32
+	noteLength model.NoteLength
+}{
+	{24, model.NoteLength{Denominator: 1, Dots: 1}},
+	{16, model.NoteLength{Denominator: 1}},
+	{12, model.NoteLength{Denominator: 2, Dots: 1}},
+	{8, model.NoteLength{Denominator: 2}},
+	{6, model.NoteLength{Denominator: 4, Dots: 1}},
+	{4, model.NoteLength{Denominator: 4}},
+	{3, model.NoteLength{Denominator: 8, Dots: 1}},
+	{2, model.NoteLength{Denominator: 8}},
+	{1, model.NoteLength{Denominator: 16}},
+}
+
+// duration returns an Alda duration equivalent to the provided number of 16th
+// notes.
+func duration(sixteenths uint32) model.Duration {
+	components := []model.DurationComponent{}
+
+	for _, nl := range noteLengths {
+		for sixteenths >= nl.sixteenths {
+			components = append(components, nl.noteLength)
+			sixteenths -= nl.sixteenths
+		}
+	}
+
+	return model.Duration{Components: components}
+}
+
+// translator translates voices into Alda score updates, keeping track of the
+// octave and duration so that they are only included when they change.
+type translator struct {
+	grid        uint32
+	preferFlats bool
+	octave      int32
+	// Whether a note has been translated yet in the current voice.
+	notesTranslated bool
+	// The previous duration, in 16th notes, or 0 if nothing has been translated
+	// yet in the current voice.
+	duration uint32
+	updates  []model.ScoreUpdate
+}
+
+func newTranslator(grid uint32, preferFlats bool) *translator {
+	return &translator{grid: grid, preferFlats: preferFlats}
+}
+
+// spell returns the pitch and octave of a MIDI note number.
+func (t *translator) spell(key uint8) (model.LetterAndAccidentals, int32) {
+	spellings := sharpSpellings
+	if t.preferFlats {
+		spellings = flatSpellings
+	}
+
+	return spellings[key%12], int32(key)/12 - 1
+}
+
+// octaveUpdates returns the updates needed to change the octave to the
+// provided octave.
+func (t *translator) octaveUpdates(octave int32) []model.ScoreUpdate {
+	difference := octave - t.octave
+	first := !t.notesTranslated
+	t.octave = octave
+	t.notesTranslated = true
+
+	if difference == 0 {
+		return nil
+	}
+
+	if first || difference > maxRelativeOctaveJump ||
+		difference < -maxRelativeOctaveJump {
+		return []model.ScoreUpdate{model.AttributeUpdate{
+			PartUpdate: model.OctaveSet{OctaveNumber: octave},
+		}}
+	}
+
+	var octaveUpdate model.PartUpdate = model.OctaveUp{}
+	if difference < 0 {
+		octaveUpdate = model.OctaveDown{}
+		difference = -difference
+	}
+
+	updates := []model.ScoreUpdate{}
+	for i := int32(0); i < difference; i++ {
+		updates = append(updates, model.AttributeUpdate{PartUpdate: octaveUpdate})
+	}
+
+	return updates
+}
+
+// nextDuration returns the duration of a note or rest that lasts the provided
+// number of ticks, or an empty duration if it's the same as the previous one.
+func (t *translator) nextDuration(ticks uint32) model.Duration {
+	sixteenths := ticks / t.grid
+	if sixteenths == t.duration {
+		return model.Duration{}
+	}
+
+	t.duration = sixteenths
+	return duration(sixteenths)
+}
+
+func (t *translator) rest(ticks uint32) {
+	t.updates = append(t.updates, model.Rest{Duration: t.nextDuration(ticks)})
+}
+
+func (t *translator) tempo(tempo float64) {
+	t.updates = append(t.updates, model.GlobalAttributeUpdate{
+		PartUpdate: model.TempoSet{Tempo: tempo},
+	})
+}
+
+func (t *translator) chord(chord midiChord) {
+	notes := []model.ScoreUpdate{}
+
+	for i, key := range chord.keys {
+		pitch, octave := t.spell(key)
+		octaveUpdates := t.octaveUpdates(octave)
+
+		note := model.Note{Pitch: pitch}
+		if i == 0 {
+			note.Duration = t.nextDuration(chord.end - chord.start)
+			// The octave change before the first note goes before the chord.
+			t.updates = append(t.updates, octaveUpdates...)
+		} else {
+			notes = append(notes, octaveUpdates...)
+		}
+
+		notes = append(notes, note)
+	}
+
+	if len(chord.keys) == 1 {
+		t.updates = append(t.updates, notes...)
+		return
+	}
+
+	t.updates = append(t.updates, model.Chord{Events: notes})
+}
+
+// translateVoice returns the score updates for a voice.
+//
+// Each voice starts at the beginning of the score, with the default octave.
+// Any tempo changes in the voice are included at the appropriate time.
+func (t *translator) translateVoice(voice midiVoice) []model.ScoreUpdate {
+	t.octave = defaultOctave
+	t.notesTranslated = false
+	t.duration = 0
+	t.updates = []model.ScoreUpdate{}
+
+	position := uint32(0)
+	tempos := voice.tempos
+
+	applyTempos := func() {
+		for len(tempos) > 0 && tempos[0].ticks <= position {
+			t.tempo(tempos[0].tempo)
+			tempos = tempos[1:]
+		}
+	}
+
+	// restUntil fills the time between the current position and the provided
+	// time with rests, splitting the rests wherever there is a tempo change.
+	restUntil := func(ticks uint32) {
+		for position < ticks {
+			applyTempos()
+
+			next := ticks
+			if len(tempos) > 0 && tempos[0].ticks < next {
+				next = tempos[0].ticks
+			}
+
+			t.rest(next - position)
+			position = next
+		}
+	}
+
+	for _, chord := range voice.chords {
+		restUntil(chord.start)
+		applyTempos()
+		t.chord(chord)
+		position = chord.end
+	}
+
+	for len(tempos) > 0 {
+		restUntil(tempos[0].ticks)
+		applyTempos()
+	}
+
+	return t.updates
+}
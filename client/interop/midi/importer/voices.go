@@ -0,0 +1,225 @@
+package importer
+
+import (
+	"sort"
+)
+
+// midiChord is one or more notes that start and end at the same time, after
+// quantization. A chord with a single note is just a note.
+type midiChord struct {
+	start uint32
+	end   uint32
+	// The MIDI note numbers of the notes in the chord, in ascending order.
+	keys []uint8
+}
+
+// top returns the highest note in the chord.
+func (chord midiChord) top() uint8 {
+	return chord.keys[len(chord.keys)-1]
+}
+
+// tempoMark is a tempo change to be included in the imported score.
+type tempoMark struct {
+	ticks uint32
+	tempo float64
+}
+
+// midiVoice is a monophonic (aside from chords) sequence of chords within a
+// part. Any gaps between the chords are rests.
+type midiVoice struct {
+	chords []midiChord
+	tempos []tempoMark
+}
+
+// accommodates returns true if none of the provided tempo changes happen in
+// the middle of a chord in the voice.
+func (voice midiVoice) accommodates(tempos []tempoMark) bool {
+	for _, tempo := range tempos {
+		for _, chord := range voice.chords {
+			if chord.start < tempo.ticks && tempo.ticks < chord.end {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// groupChords combines notes that start and end at the same time into chords.
+//
+// The chords are returned in order of their start times. Chords that start at
+// the same time are ordered from the highest top note to the lowest, so that
+// the melody (usually the highest line) ends up in the first voice.
+func groupChords(notes []midiNote) []midiChord {
+	type span struct {
+		start uint32
+		end   uint32
+	}
+
+	chords := map[span]*midiChord{}
+	orderedChords := []*midiChord{}
+
+	for _, note := range notes {
+		s := span{start: note.start, end: note.end}
+
+		chord, hit := chords[s]
+		if !hit {
+			chord = &midiChord{start: note.start, end: note.end}
+			chords[s] = chord
+			orderedChords = append(orderedChords, chord)
+		}
+
+		// The same key played twice at the same time is the same note as far as
+		// Alda is concerned.
+		duplicate := false
+		for _, key := range chord.keys {
+			if key == note.key {
+				duplicate = true
+			}
+		}
+
+		if !duplicate {
+			chord.keys = append(chord.keys, note.key)
+		}
+	}
+
+	result := []midiChord{}
+	for _, chord := range orderedChords {
+		sort.Slice(chord.keys, func(i, j int) bool {
+			return chord.keys[i] < chord.keys[j]
+		})
+		result = append(result, *chord)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].start != result[j].start {
+			return result[i].start < result[j].start
+		}
+
+		return result[i].top() > result[j].top()
+	})
+
+	return result
+}
+
+// assignVoices splits the notes in a part into voices, such that the chords in
+// each voice do not overlap.
+//
+// Each chord is added to the first voice that is free at the time that the
+// chord starts. In MIDI files recorded from a performance, it's common for a
+// note to be released slightly after the next note starts (i.e. legato
+// playing). To avoid creating a new voice whenever this happens, an overlap of
+// up to one grid division is resolved by shortening the earlier note.
+//
+// There is always at least one voice.
+func assignVoices(notes []midiNote, grid uint32) []midiVoice {
+	voices := []midiVoice{{}}
+
+	lastChord := func(voice midiVoice) *midiChord {
+		if len(voice.chords) == 0 {
+			return nil
+		}
+
+		return &voice.chords[len(voice.chords)-1]
+	}
+
+	for _, chord := range groupChords(notes) {
+		assigned := false
+
+		for i := range voices {
+			last := lastChord(voices[i])
+			if last == nil || last.end <= chord.start {
+				voices[i].chords = append(voices[i].chords, chord)
+				assigned = true
+				break
+			}
+		}
+
+		if assigned {
+			continue
+		}
+
+		for i := range voices {
+			last := lastChord(voices[i])
+			if last.start < chord.start && last.end-chord.start <= grid {
+				last.end = chord.start
+				voices[i].chords = append(voices[i].chords, chord)
+				assigned = true
+				break
+			}
+		}
+
+		if !assigned {
+			voices = append(voices, midiVoice{chords: []midiChord{chord}})
+		}
+	}
+
+	for _, voice := range voices {
+		closeGaps(voice.chords, grid)
+	}
+
+	return voices
+}
+
+// closeGaps lengthens chords that are followed by a short gap.
+//
+// Notes are rarely held for their full written duration. (Alda itself, by
+// default, releases each note after 90% of its duration.) When a chord is
+// followed by a gap that is no more than a fifth of the time until the next
+// chord, we treat the gap as articulation rather than a rest, and extend the
+// chord until the next one starts.
+//
+// The last chord in a voice is extended to the next beat, by the same logic.
+func closeGaps(chords []midiChord, grid uint32) {
+	for i := range chords {
+		chord := &chords[i]
+
+		var next uint32
+		if i+1 < len(chords) {
+			next = chords[i+1].start
+		} else {
+			beat := grid * gridDivisions
+			next = (chord.end + beat - 1) / beat * beat
+		}
+
+		if next > chord.end && (next-chord.end)*5 <= next-chord.start {
+			chord.end = next
+		}
+	}
+}
+
+// tempoMarks returns the tempo changes in a MIDI file, quantized to the grid.
+//
+// Redundant tempo changes are omitted, as are tempo changes that happen after
+// the last note ends. An initial tempo of 120 BPM is also omitted, as that is
+// the default tempo in Alda (and in MIDI).
+func tempoMarks(file midiFile, grid uint32, scoreEnd uint32) []tempoMark {
+	marks := []tempoMark{}
+	currentTempo := 120.0
+
+	for _, change := range file.tempoChanges {
+		ticks := quantize(change.ticks, grid)
+		if ticks >= scoreEnd && ticks > 0 {
+			break
+		}
+
+		// When there are multiple tempo changes at the same time (after
+		// quantization), only the last one matters.
+		if len(marks) > 0 && marks[len(marks)-1].ticks == ticks {
+			marks = marks[:len(marks)-1]
+			currentTempo = 120.0
+			if len(marks) > 0 {
+				currentTempo = marks[len(marks)-1].tempo
+			}
+		}
+
+		if change.tempo == currentTempo {
+			continue
+		}
+
+		marks = append(marks, tempoMark{ticks: ticks, tempo: change.tempo})
+		currentTempo = change.tempo
+	}
+
+	return marks
+}
@@ -0,0 +1,35 @@
+package exporter
+
+import (
+	"alda.io/client/help"
+	"alda.io/client/model"
+)
+
+// ExportMusicXML translates Alda score updates into a MusicXML (partwise)
+// document.
+//
+// Each Alda part becomes a MusicXML part, and voices within a part become
+// MusicXML voices. Barlines in the Alda score determine where measures begin
+// and end; if there are no barlines, the score is divided into measures of 4/4.
+// Notes that cross a barline, or that can't be written as a single note, are
+// written as tied notes.
+//
+// Repeats are written out in full. Attributes that have no MusicXML equivalent
+// (e.g. panning and quantization) are not exported.
+func ExportMusicXML(updates []model.ScoreUpdate) ([]byte, error) {
+	w := newWalker()
+
+	if err := w.score.Update(updates...); err != nil {
+		return nil, err
+	}
+
+	w.finishAll()
+
+	if len(w.parts) == 0 {
+		return nil, help.UserFacingErrorf(
+			"There are no parts in the score, so there is nothing to export.",
+		)
+	}
+
+	return w.render().WriteToBytes()
+}
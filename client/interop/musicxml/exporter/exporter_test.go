@@ -0,0 +1,236 @@
+package exporter
+
+import (
+	"testing"
+
+	_ "alda.io/client/testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	testCases := []roundTripTestCase{}
+	for _, name := range []string{
+		// ties3 is not included because the importer can't tie notes across
+		// chords with notes of different durations, so importing it loses
+		// information the first time around.
+		"note",
+		"key_signature",
+		"accidental",
+		"accidental2",
+		"octave",
+		"rest",
+		"duration",
+		"duration2",
+		"dots",
+		"chord",
+		"ties1",
+		"ties2",
+		"slurs",
+		"dynamics",
+		"attrs",
+		"attrs2",
+		"parts",
+		"percussion",
+		"voices1",
+		"voices2",
+		"repeat1",
+		"repeat2",
+		"repeat3",
+		"repeat4",
+		"repeat5",
+		"repeat6",
+	} {
+		testCases = append(testCases, roundTripTestCase{
+			label: name,
+			file:  "../examples/" + name + ".musicxml",
+		})
+	}
+
+	executeRoundTripTestCases(t, testCases...)
+}
+
+func TestMeasures(t *testing.T) {
+	executeExporterTestCases(t,
+		exporterTestCase{
+			label: "measures of 4/4 when there are no barlines",
+			input: "piano: c1 d e2 f",
+			expected: map[string][]string{
+				"part/measure[3]/note/pitch/step":    {"E", "F"},
+				"part/measure/attributes/time/beats": {"4"},
+			},
+		},
+		exporterTestCase{
+			label: "measures from barlines",
+			input: "piano: c2 d | e1 | f2 g4 a4~ | 4 b2.",
+			expected: map[string][]string{
+				"part/measure/attributes/time/beats":     {"4"},
+				"part/measure/attributes/time/beat-type": {"4"},
+				"part/measure[3]/note/pitch/step":        {"F", "G", "A"},
+				"part/measure[4]/note/pitch/step":        {"A", "B"},
+				"part/measure[4]/note/tie":               {""},
+			},
+		},
+		exporterTestCase{
+			label: "measures of a time signature",
+			input: "piano: (time-signature 3 4) | c4 d e | f g a || b2.",
+			expected: map[string][]string{
+				"part/measure/attributes/time/beats":     {"3"},
+				"part/measure/attributes/time/beat-type": {"4"},
+				"part/measure[1]/note/pitch/step":        {"C", "D", "E"},
+				"part/measure[2]/note/pitch/step":        {"F", "G", "A"},
+				"part/measure[3]/note/pitch/step":        {"B"},
+			},
+		},
+		exporterTestCase{
+			label: "notes that cross a barline are tied",
+			input: "piano: c2 d1 e2",
+			expected: map[string][]string{
+				"part/measure[1]/note/pitch/step": {"C", "D"},
+				"part/measure[1]/note/type":       {"half", "half"},
+				"part/measure[2]/note/pitch/step": {"D", "E"},
+			},
+		},
+	)
+}
+
+func TestDurations(t *testing.T) {
+	executeExporterTestCases(t,
+		exporterTestCase{
+			label: "dotted notes",
+			input: "piano: c4. d8 e4.. f16",
+			expected: map[string][]string{
+				"part/measure/note/type":            {"quarter", "eighth", "quarter", "16th"},
+				"part/measure/note/dot":             {"", "", ""},
+				"part/measure/attributes/divisions": {"4"},
+			},
+		},
+		exporterTestCase{
+			label: "triplets",
+			input: "piano: c8 d e f g6 a b",
+			expected: map[string][]string{
+				"part/measure/note/type": {
+					"eighth", "eighth", "eighth", "eighth",
+					"quarter", "quarter", "quarter",
+				},
+				"part/measure/note/time-modification/actual-notes": {"3", "3", "3"},
+				"part/measure/note/time-modification/normal-notes": {"2", "2", "2"},
+			},
+		},
+		exporterTestCase{
+			label: "crams",
+			input: "piano: {c d e}4 f2.",
+			expected: map[string][]string{
+				"part/measure/note/type":                           {"eighth", "eighth", "eighth", "half"},
+				"part/measure/note/time-modification/actual-notes": {"3", "3", "3"},
+			},
+		},
+	)
+}
+
+func TestPitches(t *testing.T) {
+	executeExporterTestCases(t,
+		exporterTestCase{
+			label: "key signatures and accidentals",
+			input: `piano: (key-sig "b-") o3 b b_ b+ | (key-sig "f+ c+") f`,
+			expected: map[string][]string{
+				"part/measure/attributes/key/fifths": {"-1", "2"},
+				"part/measure/note/pitch/alter":      {"-1", "1", "1"},
+				"part/measure/note/accidental":       {"natural", "sharp"},
+				"part/measure/attributes/clef/sign":  {"F"},
+			},
+		},
+		exporterTestCase{
+			label: "transposition",
+			input: "clarinet: (transpose -2) c d",
+			expected: map[string][]string{
+				"part/measure/attributes/transpose/chromatic": {"-2"},
+				"part/measure/note/pitch/step":                {"C", "D"},
+			},
+		},
+		exporterTestCase{
+			label: "percussion",
+			input: "percussion: o2 c8 d c d",
+			expected: map[string][]string{
+				"part-list/score-part/midi-instrument/midi-unpitched": {"37", "39"},
+				"part-list/score-part/midi-instrument/midi-channel":   {"10", "10"},
+				"part/measure/note/unpitched/display-step":            {"C", "D", "C", "D"},
+				"part/measure/attributes/clef/sign":                   {"percussion"},
+			},
+		},
+	)
+}
+
+func TestChordsAndVoices(t *testing.T) {
+	executeExporterTestCases(t,
+		exporterTestCase{
+			label: "chords with notes of different lengths",
+			input: "piano: c1/e4 f g a",
+			expected: map[string][]string{
+				"part/measure/note/pitch/step": {
+					"C", "E", "F", "C", "G", "C", "A", "C",
+				},
+				"part/measure/note/tie": {"", "", "", "", "", ""},
+			},
+		},
+		exporterTestCase{
+			label: "voices",
+			input: "piano: V1: c1 V2: e2 f V0: g1",
+			expected: map[string][]string{
+				"part/measure[1]/note/voice":      {"1", "2", "2"},
+				"part/measure[1]/backup/duration": {"4"},
+				"part/measure[2]/note/voice":      {"1"},
+			},
+		},
+		exporterTestCase{
+			label: "multiple parts",
+			input: "piano: c1 violin: e1",
+			expected: map[string][]string{
+				"part-list/score-part/part-name":                    {"piano", "violin"},
+				"part-list/score-part/midi-instrument/midi-program": {"1", "41"},
+			},
+		},
+	)
+}
+
+func TestDirections(t *testing.T) {
+	executeExporterTestCases(t,
+		exporterTestCase{
+			label: "dynamics, tempo and slurs",
+			input: "piano: (mp) c d~ (tempo! 90) e (ff) f",
+			expected: map[string][]string{
+				"part/measure/direction/direction-type/metronome/per-minute": {
+					"90",
+				},
+				"part/measure/note/notations/slur": {"", ""},
+			},
+		},
+	)
+}
+
+func TestScoreEvaluation(t *testing.T) {
+	executeExporterTestCases(t,
+		exporterTestCase{
+			label: "variables, repeats and markers",
+			input: "riff = c8 d e f\npiano: riff*2 %chorus g1\nviolin: @chorus b1",
+			expected: map[string][]string{
+				"part[1]/measure[1]/note/pitch/step": {
+					"C", "D", "E", "F", "C", "D", "E", "F",
+				},
+				"part[1]/measure[2]/note/pitch/step": {"G"},
+				"part[2]/measure[1]/note/rest":       {""},
+				"part[2]/measure[2]/note/pitch/step": {"B"},
+			},
+		},
+		exporterTestCase{
+			label: "crams inside of variables",
+			input: "triplet = {c d e}4\npiano: triplet triplet f2",
+			expected: map[string][]string{
+				"part/measure/note/type": {
+					"eighth", "eighth", "eighth", "eighth", "eighth", "eighth", "half",
+				},
+				"part/measure/note/time-modification/actual-notes": {
+					"3", "3", "3", "3", "3", "3",
+				},
+			},
+		},
+	)
+}
@@ -0,0 +1,427 @@
+package exporter
+
+import (
+	"math"
+
+	"alda.io/client/model"
+)
+
+// epsilon is the tolerance used when comparing positions and durations, which
+// are measured in (floating point) quarter note beats.
+const epsilon = 1e-6
+
+// noteType is a MusicXML note type (e.g. "quarter") along with the number of
+// beats that it represents, before applying dots or tuplets.
+type noteType struct {
+	name  string
+	beats float64
+}
+
+// noteTypes are the MusicXML note types that we use, in descending order.
+var noteTypes = []noteType{
+	{"long", 16},
+	{"breve", 8},
+	{"whole", 4},
+	{"half", 2},
+	{"quarter", 1},
+	{"eighth", 0.5},
+	{"16th", 0.25},
+	{"32nd", 0.125},
+	{"64th", 0.0625},
+	{"128th", 0.03125},
+}
+
+// maxDots is the largest number of dots that we will write on a note.
+const maxDots = 3
+
+// tuplet is a MusicXML time modification, e.g. 3 notes in the time of 2 for a
+// triplet.
+type tuplet struct {
+	actual int
+	normal int
+}
+
+// notation describes how a note or rest is written: its type, number of dots,
+// and tuplet ratio, if any.
+//
+// The zero value means that we could not find a way to write the duration as a
+// single note. MusicXML makes the <type> element optional, so in that case we
+// just write the duration.
+type notation struct {
+	noteType string
+	dots     int
+	tuplet   *tuplet
+}
+
+// dottedBeats returns the number of beats in a note with the provided number of
+// dots, e.g. a dotted quarter note is 1.5 beats.
+func dottedBeats(beats float64, dots int) float64 {
+	return beats * (2 - math.Pow(0.5, float64(dots)))
+}
+
+// tupletNormal returns the number of notes in the normal (undivided) grouping
+// for a tuplet with the provided number of notes, e.g. 2 for a triplet and 4
+// for a quintuplet.
+func tupletNormal(actual int) int {
+	normal := 1
+	for normal*2 < actual {
+		normal *= 2
+	}
+
+	return normal
+}
+
+// notationFor returns the notation for a note or rest that lasts the provided
+// number of beats.
+func notationFor(beats float64) notation {
+	for _, nt := range noteTypes {
+		for dots := 0; dots <= maxDots; dots++ {
+			if math.Abs(dottedBeats(nt.beats, dots)-beats) < epsilon {
+				return notation{noteType: nt.name, dots: dots}
+			}
+		}
+	}
+
+	for actual := 3; actual <= 15; actual++ {
+		normal := tupletNormal(actual)
+
+		for _, nt := range noteTypes {
+			if math.Abs(nt.beats*float64(normal)/float64(actual)-beats) < epsilon {
+				return notation{
+					noteType: nt.name,
+					tuplet:   &tuplet{actual: actual, normal: normal},
+				}
+			}
+		}
+	}
+
+	return notation{}
+}
+
+// noteLengthNotation returns the notation for an Alda note length.
+//
+// Note lengths with a denominator that is not a power of 2 (e.g. "6", which is a
+// quarter note triplet) are written as tuplets.
+func noteLengthNotation(nl model.NoteLength) notation {
+	denominator := int(math.Round(nl.Denominator))
+	if math.Abs(nl.Denominator-float64(denominator)) > epsilon ||
+		denominator <= 0 {
+		return notationFor(nl.Beats())
+	}
+
+	normal := 1
+	for normal*2 <= denominator {
+		normal *= 2
+	}
+
+	for _, nt := range noteTypes {
+		if math.Abs(nt.beats-4/float64(normal)) > epsilon {
+			continue
+		}
+
+		result := notation{noteType: nt.name, dots: int(nl.Dots)}
+		if normal != denominator {
+			g := gcd(denominator, normal)
+			result.tuplet = &tuplet{actual: denominator / g, normal: normal / g}
+		}
+
+		return result
+	}
+
+	return notationFor(nl.Beats())
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
+
+// decompose splits a duration into a series of durations that can each be
+// written as a single note, preferring as few notes as possible.
+//
+// If the duration can't be expressed that way, it is returned as-is.
+func decompose(beats float64) []float64 {
+	if notationFor(beats).noteType != "" {
+		return []float64{beats}
+	}
+
+	pieces := []float64{}
+	remaining := beats
+
+	for len(pieces) < 8 && remaining > epsilon {
+		found := false
+
+		for _, nt := range noteTypes {
+			for dots := 1; dots >= 0; dots-- {
+				b := dottedBeats(nt.beats, dots)
+				if b <= remaining+epsilon {
+					pieces = append(pieces, b)
+					remaining -= b
+					found = true
+					break
+				}
+			}
+
+			if found {
+				break
+			}
+		}
+
+		if !found {
+			break
+		}
+	}
+
+	if remaining > epsilon {
+		return []float64{beats}
+	}
+
+	return pieces
+}
+
+// piece is one of the notes that a note or rest is written as. Alda durations
+// can consist of multiple tied note lengths, each of which is written as a
+// separate note, tied to the next one.
+type piece struct {
+	beats    float64
+	notation notation
+	// barline is true when the Alda duration has a barline after this piece.
+	barline bool
+}
+
+// pitch is the written pitch of a note.
+type pitch struct {
+	step       string
+	alter      int
+	octave     int32
+	accidental string
+	// For unpitched percussion notes, the MIDI note number of the percussion
+	// instrument.
+	unpitched bool
+	midiNote  int32
+}
+
+// sharpSpellings are the note letters and alterations used to spell each pitch
+// class, starting from C, when we only have a MIDI note number.
+var sharpSpellings = []struct {
+	step  string
+	alter int
+}{
+	{"C", 0}, {"C", 1}, {"D", 0}, {"D", 1}, {"E", 0}, {"F", 0},
+	{"F", 1}, {"G", 0}, {"G", 1}, {"A", 0}, {"A", 1}, {"B", 0},
+}
+
+// accidentalNames are the MusicXML names of the accidentals that we write,
+// keyed by alteration.
+var accidentalNames = map[int]string{
+	-2: "flat-flat",
+	-1: "flat",
+	0:  "natural",
+	1:  "sharp",
+	2:  "double-sharp",
+}
+
+// writtenPitch returns the written pitch of a note, in the context of a part.
+//
+// An accidental is only displayed when the note has explicit accidentals.
+// Otherwise, the pitch is altered according to the key signature.
+func writtenPitch(identifier model.PitchIdentifier, part *model.Part) pitch {
+	// The walker has already validated the pitch, so there's no error here.
+	midiNote, _ := part.MidiNote(identifier)
+
+	switch identifier := identifier.(type) {
+	case model.LetterAndAccidentals:
+		accidentals := identifier.Accidentals
+		if accidentals == nil {
+			accidentals = part.KeySignature[identifier.NoteLetter]
+		}
+
+		alter := 0
+		for _, accidental := range accidentals {
+			switch accidental {
+			case model.Flat:
+				alter--
+			case model.Sharp:
+				alter++
+			}
+		}
+
+		result := pitch{
+			step:     identifier.NoteLetter.String(),
+			alter:    alter,
+			octave:   part.Octave,
+			midiNote: midiNote,
+		}
+
+		if identifier.Accidentals != nil {
+			result.accidental = accidentalNames[alter]
+		}
+
+		return result
+
+	default:
+		written := midiNote - part.Transposition
+		spelling := sharpSpellings[((written%12)+12)%12]
+
+		return pitch{
+			step:     spelling.step,
+			alter:    spelling.alter,
+			octave:   int32(math.Floor(float64(written)/12)) - 1,
+			midiNote: midiNote,
+		}
+	}
+}
+
+// componentBeats returns the length of a duration component in beats, in the
+// context of a part.
+func componentBeats(component model.DurationComponent, part *model.Part) float64 {
+	switch component := component.(type) {
+	case model.NoteLength, model.NoteLengthBeats, model.Barline:
+		return component.Beats() * part.TimeScale
+	default:
+		return component.Ms(part.Tempo) * part.Tempo / 60000 * part.TimeScale
+	}
+}
+
+// componentNotation returns the notation for a piece with the provided length,
+// which is derived from a duration component.
+func componentNotation(
+	component model.DurationComponent, part *model.Part, beats float64,
+) notation {
+	if nl, ok := component.(model.NoteLength); ok && part.TimeScale == 1 {
+		return noteLengthNotation(nl)
+	}
+
+	return notationFor(beats)
+}
+
+// notePieces returns the pieces that a note with the provided duration is
+// written as. Each duration component is a separate piece.
+//
+// The second return value is true if the duration starts with a barline.
+func notePieces(duration model.Duration, part *model.Part) ([]piece, bool) {
+	pieces := []piece{}
+	leadingBarline := false
+
+	for _, component := range duration.Components {
+		if _, ok := component.(model.Barline); ok {
+			if len(pieces) == 0 {
+				leadingBarline = true
+			} else {
+				pieces[len(pieces)-1].barline = true
+			}
+			continue
+		}
+
+		beats := componentBeats(component, part)
+		if beats < epsilon {
+			continue
+		}
+
+		pieces = append(pieces, piece{
+			beats:    beats,
+			notation: componentNotation(component, part, beats),
+		})
+	}
+
+	return pieces, leadingBarline
+}
+
+// restPieces returns the pieces that a rest with the provided duration is
+// written as.
+//
+// Unlike notes, there is no need to write a separate rest for each duration
+// component, so we write one rest for each measure that the rest occupies (as
+// indicated by barlines).
+func restPieces(duration model.Duration, part *model.Part) ([]piece, bool) {
+	pieces := []piece{}
+	leadingBarline := false
+
+	var current *piece
+	var currentComponents []model.DurationComponent
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+
+		if len(currentComponents) == 1 {
+			current.notation = componentNotation(
+				currentComponents[0], part, current.beats,
+			)
+		} else if n := notationFor(current.beats); n.dots == 0 {
+			// A dotted rest that came from multiple tied note lengths is written
+			// without a type, so that readers (like our importer) can choose how
+			// to express the duration.
+			current.notation = n
+		}
+
+		pieces = append(pieces, *current)
+		current = nil
+		currentComponents = nil
+	}
+
+	for _, component := range duration.Components {
+		if _, ok := component.(model.Barline); ok {
+			if current == nil && len(pieces) == 0 {
+				leadingBarline = true
+			}
+
+			finish()
+
+			if len(pieces) > 0 {
+				pieces[len(pieces)-1].barline = true
+			}
+			continue
+		}
+
+		beats := componentBeats(component, part)
+		if beats < epsilon {
+			continue
+		}
+
+		if current == nil {
+			current = &piece{}
+		}
+
+		current.beats += beats
+		currentComponents = append(currentComponents, component)
+	}
+
+	finish()
+
+	return pieces, leadingBarline
+}
+
+// totalBeats returns the combined length of a list of pieces.
+func totalBeats(pieces []piece) float64 {
+	beats := 0.0
+	for _, p := range pieces {
+		beats += p.beats
+	}
+
+	return beats
+}
+
+// keySignaturesEqual returns true if two key signatures have the same
+// accidentals for every note letter.
+func keySignaturesEqual(a, b model.KeySignature) bool {
+	for _, letter := range []model.NoteLetter{
+		model.A, model.B, model.C, model.D, model.E, model.F, model.G,
+	} {
+		if len(a[letter]) != len(b[letter]) {
+			return false
+		}
+
+		for i := range a[letter] {
+			if a[letter][i] != b[letter][i] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
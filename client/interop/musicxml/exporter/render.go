@@ -0,0 +1,702 @@
+package exporter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"alda.io/client/model"
+	"github.com/beevik/etree"
+)
+
+// autoMeasureBeats is the length of each measure, in beats, when a score does
+// not have any barlines.
+const autoMeasureBeats = 4
+
+// divisionsLimit is the largest number of divisions per quarter note that we
+// use. Its divisors include all of the note lengths that Alda scores commonly
+// use, including triplets, quintuplets, and septuplets.
+const divisionsLimit = 10080
+
+// measure is a span of the score, in beats.
+type measure struct {
+	start float64
+	end   float64
+}
+
+func (m measure) beats() float64 {
+	return m.end - m.start
+}
+
+func (m measure) contains(position float64) bool {
+	return position >= m.start-epsilon && position < m.end-epsilon
+}
+
+// scoreEnd returns the position where the last note or rest in the score ends.
+func (w *walker) scoreEnd() float64 {
+	end := 0.0
+	for _, part := range w.parts {
+		for _, it := range part.items {
+			end = math.Max(end, it.end())
+		}
+	}
+
+	return end
+}
+
+// measures divides the score into measures, using the barlines in the score.
+//
+// If the score doesn't have any barlines, it is divided into measures of 4
+// beats.
+func (w *walker) measures() []measure {
+	end := w.scoreEnd()
+
+	positions := []float64{}
+	for _, b := range w.boundaries {
+		if b > epsilon && b < end-epsilon {
+			positions = append(positions, b)
+		}
+	}
+	sort.Float64s(positions)
+
+	if len(positions) == 0 {
+		end = math.Max(
+			autoMeasureBeats, math.Ceil(end/autoMeasureBeats-epsilon)*autoMeasureBeats,
+		)
+
+		measures := []measure{}
+		for start := 0.0; start < end-epsilon; start += autoMeasureBeats {
+			measures = append(measures, measure{start, start + autoMeasureBeats})
+		}
+
+		return measures
+	}
+
+	measures := []measure{}
+	start := 0.0
+	for _, position := range positions {
+		if position-start > epsilon {
+			measures = append(measures, measure{start, position})
+			start = position
+		}
+	}
+
+	// After the last barline, the score continues in measures of the same
+	// length as the last measure.
+	length := measures[len(measures)-1].beats()
+	for end-start > length+epsilon {
+		measures = append(measures, measure{start, start + length})
+		start += length
+	}
+
+	return append(measures, measure{start, end})
+}
+
+// splitItems splits notes and rests that cross from one measure into the next,
+// and notes and rests that can't be written as a single note.
+func splitItems(items []*item, measures []measure) []*item {
+	result := []*item{}
+
+	for _, it := range items {
+		if it.kind != noteItem {
+			result = append(result, it)
+			continue
+		}
+
+		lengths := []float64{}
+		for _, m := range measures {
+			start := math.Max(m.start, it.position)
+			end := math.Min(m.end, it.end())
+			if end-start > epsilon {
+				lengths = append(lengths, end-start)
+			}
+		}
+
+		if len(lengths) == 1 && it.notation.noteType != "" {
+			result = append(result, it)
+			continue
+		}
+
+		segments := []float64{}
+		for _, length := range lengths {
+			segments = append(segments, decompose(length)...)
+		}
+
+		position := it.position
+		for i, length := range segments {
+			segment := *it
+			segment.position = position
+			segment.beats = length
+			segment.column.segment = i
+			segment.notation = notationFor(length)
+
+			if !it.rest {
+				segment.tieStop = it.tieStop || i > 0
+				segment.tieStart = it.tieStart || i < len(segments)-1
+			}
+
+			if i > 0 {
+				segment.slurStart = false
+				segment.slurStop = false
+			}
+
+			result = append(result, &segment)
+			position += length
+		}
+	}
+
+	// Within a stream, attributes and directions come before any notes at the
+	// same position.
+	sort.SliceStable(result, func(i, j int) bool {
+		if math.Abs(result[i].position-result[j].position) > epsilon {
+			return result[i].position < result[j].position
+		}
+
+		return result[i].kind != noteItem && result[j].kind == noteItem
+	})
+
+	return result
+}
+
+// divisionsFor returns the smallest number of divisions per quarter note that
+// can express the provided number of beats, or 1 if there isn't one.
+func divisionsFor(beats float64) int {
+	for d := 1; d <= divisionsLimit; d++ {
+		if divisionsLimit%d != 0 {
+			continue
+		}
+
+		value := beats * float64(d)
+		if math.Abs(value-math.Round(value)) < 1e-4 {
+			return d
+		}
+	}
+
+	return 1
+}
+
+func lcm(a, b int) int {
+	return a / gcd(a, b) * b
+}
+
+// divisions returns the number of divisions per quarter note needed to express
+// all of the positions and durations in the score.
+func divisions(measures []measure, parts [][]*item) int {
+	result := 1
+
+	for _, m := range measures {
+		result = lcm(result, divisionsFor(m.start))
+		result = lcm(result, divisionsFor(m.end))
+	}
+
+	for _, items := range parts {
+		for _, it := range items {
+			result = lcm(result, divisionsFor(it.position))
+			result = lcm(result, divisionsFor(it.end()))
+		}
+	}
+
+	return result
+}
+
+// fifthsFor returns the position on the circle of fifths of a key signature, if
+// it is a traditional key signature.
+func fifthsFor(keySignature model.KeySignature) (int, bool) {
+	for fifths := -7; fifths <= 7; fifths++ {
+		if keySignaturesEqual(
+			keySignature, model.KeySignatureFromCircleOfFifths(fifths),
+		) {
+			return fifths, true
+		}
+	}
+
+	return 0, false
+}
+
+// timeSignatureFor returns the numerator and denominator of a time signature for
+// a measure with the provided number of beats.
+func timeSignatureFor(beats float64) (int, int, bool) {
+	for _, denominator := range []int{4, 8, 16, 32, 64} {
+		numerator := beats * float64(denominator) / 4
+		if math.Abs(numerator-math.Round(numerator)) < epsilon {
+			return int(math.Round(numerator)), denominator, true
+		}
+	}
+
+	return 0, 0, false
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// renderer writes the MusicXML elements for the parts in a score.
+type renderer struct {
+	divisions int
+	measures  []measure
+}
+
+func (r *renderer) toDivisions(beats float64) int {
+	return int(math.Round(beats * float64(r.divisions)))
+}
+
+// duration returns the length in divisions of a span of the score. Positions
+// are converted to divisions before subtracting, so that rounding errors don't
+// accumulate.
+func (r *renderer) duration(start float64, end float64) int {
+	return r.toDivisions(end) - r.toDivisions(start)
+}
+
+func renderKey(parent *etree.Element, keySignature model.KeySignature) {
+	key := parent.CreateElement("key")
+
+	if fifths, ok := fifthsFor(keySignature); ok {
+		key.CreateElement("fifths").SetText(strconv.Itoa(fifths))
+		return
+	}
+
+	for _, letter := range []model.NoteLetter{
+		model.C, model.D, model.E, model.F, model.G, model.A, model.B,
+	} {
+		accidentals, ok := keySignature[letter]
+		if !ok {
+			continue
+		}
+
+		alter := 0
+		for _, accidental := range accidentals {
+			switch accidental {
+			case model.Flat:
+				alter--
+			case model.Sharp:
+				alter++
+			}
+		}
+
+		key.CreateElement("key-step").SetText(letter.String())
+		key.CreateElement("key-alter").SetText(strconv.Itoa(alter))
+	}
+}
+
+func renderTranspose(parent *etree.Element, semitones int32) {
+	transpose := parent.CreateElement("transpose")
+	transpose.CreateElement("chromatic").SetText(
+		strconv.Itoa(int(semitones)),
+	)
+}
+
+func renderTime(parent *etree.Element, beats float64) {
+	numerator, denominator, ok := timeSignatureFor(beats)
+	if !ok {
+		return
+	}
+
+	time := parent.CreateElement("time")
+	time.CreateElement("beats").SetText(strconv.Itoa(numerator))
+	time.CreateElement("beat-type").SetText(strconv.Itoa(denominator))
+}
+
+func renderClef(parent *etree.Element, part *exportPart) {
+	clef := parent.CreateElement("clef")
+
+	switch {
+	case part.instrument.IsPercussion:
+		clef.CreateElement("sign").SetText("percussion")
+	case part.pitchCount > 0 && part.pitchSum/float64(part.pitchCount) < 60:
+		clef.CreateElement("sign").SetText("F")
+		clef.CreateElement("line").SetText("4")
+	default:
+		clef.CreateElement("sign").SetText("G")
+		clef.CreateElement("line").SetText("2")
+	}
+}
+
+func instrumentID(part *exportPart, midiNote int32) string {
+	return fmt.Sprintf("%s-I%d", part.id, midiNote+1)
+}
+
+func (r *renderer) renderNote(
+	parent *etree.Element, part *exportPart, it *item, chord bool,
+) {
+	note := parent.CreateElement("note")
+
+	if chord {
+		note.CreateElement("chord")
+	}
+
+	switch {
+	case it.rest:
+		note.CreateElement("rest")
+	case it.pitch.unpitched:
+		unpitched := note.CreateElement("unpitched")
+		unpitched.CreateElement("display-step").SetText(it.pitch.step)
+		unpitched.CreateElement("display-octave").SetText(
+			strconv.Itoa(int(it.pitch.octave)),
+		)
+	default:
+		pitch := note.CreateElement("pitch")
+		pitch.CreateElement("step").SetText(it.pitch.step)
+		if it.pitch.alter != 0 {
+			pitch.CreateElement("alter").SetText(strconv.Itoa(it.pitch.alter))
+		}
+		pitch.CreateElement("octave").SetText(strconv.Itoa(int(it.pitch.octave)))
+	}
+
+	note.CreateElement("duration").SetText(
+		strconv.Itoa(r.duration(it.position, it.end())),
+	)
+
+	if it.tieStop {
+		note.CreateElement("tie").CreateAttr("type", "stop")
+	}
+	if it.tieStart {
+		note.CreateElement("tie").CreateAttr("type", "start")
+	}
+
+	if it.pitch.unpitched {
+		note.CreateElement("instrument").CreateAttr(
+			"id", instrumentID(part, it.pitch.midiNote),
+		)
+	}
+
+	note.CreateElement("voice").SetText(strconv.Itoa(int(it.stream)))
+
+	if it.notation.noteType != "" {
+		note.CreateElement("type").SetText(it.notation.noteType)
+		for i := 0; i < it.notation.dots; i++ {
+			note.CreateElement("dot")
+		}
+	}
+
+	if it.pitch.accidental != "" && !it.rest {
+		note.CreateElement("accidental").SetText(it.pitch.accidental)
+	}
+
+	if tuplet := it.notation.tuplet; tuplet != nil {
+		timeModification := note.CreateElement("time-modification")
+		timeModification.CreateElement("actual-notes").SetText(
+			strconv.Itoa(tuplet.actual),
+		)
+		timeModification.CreateElement("normal-notes").SetText(
+			strconv.Itoa(tuplet.normal),
+		)
+	}
+
+	if it.tieStop || it.tieStart || it.slurStart || it.slurStop {
+		notations := note.CreateElement("notations")
+
+		if it.tieStop {
+			notations.CreateElement("tied").CreateAttr("type", "stop")
+		}
+		if it.tieStart {
+			notations.CreateElement("tied").CreateAttr("type", "start")
+		}
+		if it.slurStop {
+			slur := notations.CreateElement("slur")
+			slur.CreateAttr("type", "stop")
+			slur.CreateAttr("number", "1")
+		}
+		if it.slurStart {
+			slur := notations.CreateElement("slur")
+			slur.CreateAttr("type", "start")
+			slur.CreateAttr("number", "1")
+		}
+	}
+}
+
+// renderDirection writes an item that isn't a note, rest or attribute change.
+func renderDirection(
+	parent *etree.Element, it *item, partIndex int,
+) {
+	switch it.kind {
+	case dynamicsItem:
+		direction := parent.CreateElement("direction")
+		direction.CreateAttr("placement", "below")
+		direction.CreateElement("direction-type").
+			CreateElement("dynamics").
+			CreateElement(it.dynamic)
+		direction.CreateElement("voice").SetText(strconv.Itoa(int(it.stream)))
+
+	case tempoItem:
+		// Tempo changes usually apply to the whole score, so the metronome mark is
+		// only displayed in the first part.
+		if partIndex > 0 {
+			parent.CreateElement("sound").CreateAttr("tempo", formatNumber(it.tempo))
+			return
+		}
+
+		direction := parent.CreateElement("direction")
+		direction.CreateAttr("placement", "above")
+		metronome := direction.CreateElement("direction-type").
+			CreateElement("metronome")
+		metronome.CreateElement("beat-unit").SetText("quarter")
+		metronome.CreateElement("per-minute").SetText(formatNumber(it.tempo))
+		direction.CreateElement("voice").SetText(strconv.Itoa(int(it.stream)))
+		direction.CreateElement("sound").CreateAttr("tempo", formatNumber(it.tempo))
+	}
+}
+
+func (r *renderer) moveTo(
+	parent *etree.Element, cursor float64, position float64, stream int32,
+) {
+	switch {
+	case position > cursor+epsilon:
+		forward := parent.CreateElement("forward")
+		forward.CreateElement("duration").SetText(
+			strconv.Itoa(r.duration(cursor, position)),
+		)
+		if stream > 0 {
+			forward.CreateElement("voice").SetText(strconv.Itoa(int(stream)))
+		}
+	case position < cursor-epsilon:
+		parent.CreateElement("backup").CreateElement("duration").SetText(
+			strconv.Itoa(r.duration(position, cursor)),
+		)
+	}
+}
+
+// renderPart writes the measures of a part.
+func (r *renderer) renderPart(
+	root *etree.Element, part *exportPart, partIndex int, items []*item,
+) {
+	partElement := root.CreateElement("part")
+	partElement.CreateAttr("id", part.id)
+
+	previousMeasureBeats := 0.0
+
+	for m, meas := range r.measures {
+		measureElement := partElement.CreateElement("measure")
+		measureElement.CreateAttr("number", strconv.Itoa(m+1))
+
+		measureItems := []*item{}
+		hasNotes := false
+		for _, it := range items {
+			if meas.contains(it.position) ||
+				(m == len(r.measures)-1 && it.position >= meas.end-epsilon) {
+				measureItems = append(measureItems, it)
+				if it.kind == noteItem {
+					hasNotes = true
+				}
+			}
+		}
+
+		// Attribute changes at the beginning of the measure are written together
+		// with the initial attributes.
+		var key model.KeySignature
+		var transposition *int32
+		inline := []*item{}
+		for _, it := range measureItems {
+			switch {
+			case it.kind == keyItem && it.position < meas.start+epsilon:
+				key = it.key
+			case it.kind == transposeItem && it.position < meas.start+epsilon:
+				semitones := it.transposition
+				transposition = &semitones
+			default:
+				inline = append(inline, it)
+			}
+		}
+
+		timeChanged := math.Abs(meas.beats()-previousMeasureBeats) > epsilon &&
+			!(m > 0 && m == len(r.measures)-1 &&
+				meas.beats() < previousMeasureBeats)
+
+		if m == 0 || key != nil || transposition != nil || timeChanged {
+			attributes := measureElement.CreateElement("attributes")
+
+			if m == 0 {
+				attributes.CreateElement("divisions").SetText(
+					strconv.Itoa(r.divisions),
+				)
+			}
+
+			if key != nil || m == 0 {
+				renderKey(attributes, key)
+			}
+
+			if timeChanged {
+				renderTime(attributes, meas.beats())
+			}
+
+			if m == 0 {
+				renderClef(attributes, part)
+			}
+
+			if transposition != nil {
+				renderTranspose(attributes, *transposition)
+			}
+		}
+
+		if timeChanged {
+			previousMeasureBeats = meas.beats()
+		}
+
+		streams := []int32{}
+		seen := map[int32]bool{}
+		for _, it := range inline {
+			if !seen[it.stream] {
+				seen[it.stream] = true
+				streams = append(streams, it.stream)
+			}
+		}
+		sort.Slice(streams, func(i, j int) bool { return streams[i] < streams[j] })
+
+		cursor := meas.start
+
+		if !hasNotes {
+			for _, it := range inline {
+				renderDirection(measureElement, it, partIndex)
+			}
+
+			note := measureElement.CreateElement("note")
+			note.CreateElement("rest").CreateAttr("measure", "yes")
+			note.CreateElement("duration").SetText(
+				strconv.Itoa(r.duration(meas.start, meas.end)),
+			)
+			note.CreateElement("voice").SetText("1")
+			cursor = meas.end
+		}
+
+		for _, stream := range streams {
+			if !hasNotes {
+				break
+			}
+
+			var previous *item
+			for _, it := range inline {
+				if it.stream != stream {
+					continue
+				}
+
+				chord := it.kind == noteItem && previous != nil &&
+					previous.column == it.column
+
+				if !chord {
+					r.moveTo(measureElement, cursor, it.position, stream)
+					cursor = it.position
+				}
+
+				switch it.kind {
+				case noteItem:
+					r.renderNote(measureElement, part, it, chord)
+					if !chord {
+						cursor = it.end()
+					}
+					previous = it
+				case keyItem:
+					renderKey(measureElement.CreateElement("attributes"), it.key)
+				case transposeItem:
+					renderTranspose(
+						measureElement.CreateElement("attributes"), it.transposition,
+					)
+				default:
+					renderDirection(measureElement, it, partIndex)
+				}
+			}
+		}
+
+		// The next measure starts at the end of this one, even if the last voice
+		// that we wrote ends earlier.
+		r.moveTo(measureElement, cursor, meas.end, 0)
+
+		if m == len(r.measures)-1 {
+			barline := measureElement.CreateElement("barline")
+			barline.CreateAttr("location", "right")
+			barline.CreateElement("bar-style").SetText("light-heavy")
+		}
+	}
+}
+
+// renderPartList writes the part list, which describes the instrument that
+// plays each part.
+func renderPartList(root *etree.Element, parts []*exportPart) {
+	partList := root.CreateElement("part-list")
+
+	for _, part := range parts {
+		scorePart := partList.CreateElement("score-part")
+		scorePart.CreateAttr("id", part.id)
+		scorePart.CreateElement("part-name").SetText(part.name)
+
+		if !part.instrument.IsPercussion {
+			id := fmt.Sprintf("%s-I1", part.id)
+
+			scoreInstrument := scorePart.CreateElement("score-instrument")
+			scoreInstrument.CreateAttr("id", id)
+			scoreInstrument.CreateElement("instrument-name").SetText(
+				part.instrument.NameImpl,
+			)
+
+			midiInstrument := scorePart.CreateElement("midi-instrument")
+			midiInstrument.CreateAttr("id", id)
+			midiInstrument.CreateElement("midi-program").SetText(
+				strconv.Itoa(int(part.instrument.PatchNumber) + 1),
+			)
+			continue
+		}
+
+		midiNotes := []int32{}
+		for midiNote := range part.unpitched {
+			midiNotes = append(midiNotes, midiNote)
+		}
+		sort.Slice(midiNotes, func(i, j int) bool {
+			return midiNotes[i] < midiNotes[j]
+		})
+
+		for _, midiNote := range midiNotes {
+			scoreInstrument := scorePart.CreateElement("score-instrument")
+			scoreInstrument.CreateAttr("id", instrumentID(part, midiNote))
+			scoreInstrument.CreateElement("instrument-name").SetText(
+				fmt.Sprintf("%s %d", part.instrument.NameImpl, midiNote),
+			)
+		}
+
+		for _, midiNote := range midiNotes {
+			midiInstrument := scorePart.CreateElement("midi-instrument")
+			midiInstrument.CreateAttr("id", instrumentID(part, midiNote))
+			midiInstrument.CreateElement("midi-channel").SetText("10")
+			midiInstrument.CreateElement("midi-unpitched").SetText(
+				strconv.Itoa(int(midiNote) + 1),
+			)
+		}
+	}
+}
+
+// render writes the MusicXML document for the parts that the walker collected.
+func (w *walker) render() *etree.Document {
+	measures := w.measures()
+
+	partItems := [][]*item{}
+	for _, part := range w.parts {
+		partItems = append(partItems, splitItems(part.items, measures))
+	}
+
+	r := &renderer{
+		divisions: divisions(measures, partItems),
+		measures:  measures,
+	}
+
+	doc := etree.NewDocument()
+	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
+	doc.CreateDirective(
+		`DOCTYPE score-partwise PUBLIC "-//Recordare//DTD MusicXML 3.1 Partwise//EN" ` +
+			`"http://www.musicxml.org/dtds/partwise.dtd"`,
+	)
+
+	root := doc.CreateElement("score-partwise")
+	root.CreateAttr("version", "3.1")
+
+	root.CreateElement("identification").
+		CreateElement("encoding").
+		CreateElement("software").
+		SetText("Alda")
+
+	renderPartList(root, w.parts)
+
+	for i, part := range w.parts {
+		r.renderPart(root, part, i, partItems[i])
+	}
+
+	doc.Indent(2)
+	return doc
+}
@@ -0,0 +1,190 @@
+package exporter
+
+import (
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"alda.io/client/interop/musicxml/importer"
+	"alda.io/client/model"
+	"alda.io/client/parser"
+	"github.com/beevik/etree"
+	"github.com/go-test/deep"
+)
+
+// playedNote is a note in an evaluated score, in a form that can be compared
+// across scores.
+type playedNote struct {
+	Instrument      string
+	Offset          float64
+	MidiNote        int32
+	Duration        float64
+	AudibleDuration float64
+	Volume          float64
+}
+
+func round(n float64) float64 {
+	return math.Round(n*1000) / 1000
+}
+
+// playedNotes evaluates score updates and returns the notes in the score,
+// sorted by instrument, offset, and pitch.
+func playedNotes(updates []model.ScoreUpdate) ([]playedNote, error) {
+	score := model.NewScore()
+	if err := score.Update(updates...); err != nil {
+		return nil, err
+	}
+
+	notes := []playedNote{}
+	for _, event := range score.Events {
+		noteEvent, ok := event.(model.NoteEvent)
+		if !ok {
+			continue
+		}
+
+		notes = append(notes, playedNote{
+			Instrument:      noteEvent.Part.StockInstrument.Name(),
+			Offset:          round(noteEvent.Offset),
+			MidiNote:        noteEvent.MidiNote,
+			Duration:        round(noteEvent.Duration),
+			AudibleDuration: round(noteEvent.AudibleDuration),
+			Volume:          round(noteEvent.Volume),
+		})
+	}
+
+	sort.Slice(notes, func(i, j int) bool {
+		a, b := notes[i], notes[j]
+		if a.Instrument != b.Instrument {
+			return a.Instrument < b.Instrument
+		}
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		return a.MidiNote < b.MidiNote
+	})
+
+	return notes, nil
+}
+
+// roundTripTestCase checks that a MusicXML file sounds the same after being
+// imported into Alda, exported back to MusicXML, and imported again.
+type roundTripTestCase struct {
+	label string
+	file  string
+}
+
+func executeRoundTripTestCases(t *testing.T, testCases ...roundTripTestCase) {
+	for _, testCase := range testCases {
+		b, err := os.ReadFile(testCase.file)
+		if err != nil {
+			t.Error(testCase.label)
+			t.Error(err)
+			continue
+		}
+
+		imported, err := importer.ImportMusicXML(b)
+		if err != nil {
+			t.Error(testCase.label)
+			t.Error(err)
+			continue
+		}
+
+		exported, err := ExportMusicXML(imported)
+		if err != nil {
+			t.Error(testCase.label)
+			t.Error(err)
+			continue
+		}
+
+		reimported, err := importer.ImportMusicXML(exported)
+		if err != nil {
+			t.Error(testCase.label)
+			t.Error(err)
+			continue
+		}
+
+		expected, err := playedNotes(imported)
+		if err != nil {
+			t.Error(testCase.label)
+			t.Error(err)
+			continue
+		}
+
+		actual, err := playedNotes(reimported)
+		if err != nil {
+			t.Error(testCase.label)
+			t.Error(err)
+			continue
+		}
+
+		if diff := deep.Equal(expected, actual); diff != nil {
+			t.Error(testCase.label)
+			for _, diffItem := range diff {
+				t.Errorf("%v", diffItem)
+			}
+			t.Log(string(exported))
+		}
+	}
+}
+
+// exporterTestCase checks that the MusicXML exported from some Alda code
+// contains the expected elements.
+type exporterTestCase struct {
+	label string
+	input string
+	// Paths (relative to score-partwise) mapped to the expected text of each
+	// element found at that path.
+	expected map[string][]string
+}
+
+func executeExporterTestCases(t *testing.T, testCases ...exporterTestCase) {
+	for _, testCase := range testCases {
+		ast, err := parser.Parse(
+			testCase.label, testCase.input, parser.SuppressSourceContext,
+		)
+		if err != nil {
+			t.Error(testCase.label)
+			t.Error(err)
+			continue
+		}
+
+		updates, err := ast.Updates()
+		if err != nil {
+			t.Error(testCase.label)
+			t.Error(err)
+			continue
+		}
+
+		b, err := ExportMusicXML(updates)
+		if err != nil {
+			t.Error(testCase.label)
+			t.Error(err)
+			continue
+		}
+
+		doc := etree.NewDocument()
+		if err := doc.ReadFromBytes(b); err != nil {
+			t.Error(testCase.label)
+			t.Error(err)
+			continue
+		}
+
+		root := doc.SelectElement("score-partwise")
+
+		for path, expected := range testCase.expected {
+			actual := []string{}
+			for _, element := range root.FindElements(path) {
+				actual = append(actual, strings.TrimSpace(element.Text()))
+			}
+
+			if diff := deep.Equal(expected, actual); diff != nil {
+				t.Errorf("%s: %s", testCase.label, path)
+				for _, diffItem := range diff {
+					t.Errorf("%v", diffItem)
+				}
+			}
+		}
+	}
+}
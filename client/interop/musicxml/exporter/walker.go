@@ -0,0 +1,683 @@
+package exporter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"alda.io/client/model"
+)
+
+// itemKind is the kind of thing that an item represents in a MusicXML part.
+type itemKind int
+
+const (
+	noteItem itemKind = iota
+	keyItem
+	transposeItem
+	dynamicsItem
+	tempoItem
+)
+
+// columnID identifies the notes that are written together as a chord.
+//
+// When a note is split into multiple notes (e.g. because it crosses a barline),
+// each segment gets its own column ID.
+type columnID struct {
+	column  int
+	segment int
+}
+
+// item is something to be written in a MusicXML part, at a position (in beats)
+// within a stream.
+//
+// Streams correspond to MusicXML voices.
+type item struct {
+	kind     itemKind
+	stream   int32
+	position float64
+
+	// Note and rest fields
+	beats     float64
+	column    columnID
+	rest      bool
+	pitch     pitch
+	notation  notation
+	tieStart  bool
+	tieStop   bool
+	slurStart bool
+	slurStop  bool
+
+	// Attribute and direction fields
+	key           model.KeySignature
+	transposition int32
+	dynamic       string
+	tempo         float64
+}
+
+// end returns the position where an item ends.
+func (it *item) end() float64 {
+	return it.position + it.beats
+}
+
+// exportPart is a part in the MusicXML score.
+type exportPart struct {
+	id         string
+	name       string
+	instrument model.MidiInstrument
+	items      []*item
+	// The position where the last item in each stream ends.
+	streamEnds map[int32]float64
+	// The voices that are currently writing to each stream.
+	streamOwners map[int32]*voice
+	// The voices that are being written: either the part's top-level voice, or
+	// the voices in the current voice group.
+	voices []*voice
+	// The attributes of the part as of its previous note or rest.
+	state partState
+	// For percussion parts, the MIDI note numbers that are used.
+	unpitched map[int32]bool
+	// Used to choose a clef for the part.
+	pitchSum   float64
+	pitchCount int
+}
+
+func (part *exportPart) add(it *item) {
+	part.items = append(part.items, it)
+
+	if it.end() > part.streamEnds[it.stream] {
+		part.streamEnds[it.stream] = it.end()
+	}
+}
+
+// claimStream assigns a stream to a voice that starts at the provided position,
+// preferring the provided stream number.
+//
+// A stream can't be shared by voices that are active at the same time, and it
+// can't be reused by a voice that starts before the stream's last item ends.
+// In those cases, the next available stream number is used.
+func (part *exportPart) claimStream(v *voice, preferred int32) {
+	for stream := preferred; ; stream++ {
+		owner := part.streamOwners[stream]
+		if (owner == nil || owner == v) &&
+			part.streamEnds[stream] <= v.position+epsilon {
+			part.streamOwners[stream] = v
+			v.stream = stream
+			return
+		}
+	}
+}
+
+func (part *exportPart) releaseStream(v *voice) {
+	if part.streamOwners[v.stream] == v {
+		delete(part.streamOwners, v.stream)
+	}
+}
+
+// tone is a note or rest being written. A tone can span multiple columns when
+// it is sustained while other notes in the same voice start.
+type tone struct {
+	rest    bool
+	slurred bool
+	// Whether the first note written for the tone starts or ends a slur.
+	slurStart bool
+	slurStop  bool
+	pitch     pitch
+	pieces    []piece
+	// The piece currently being written, and how much of it has been written.
+	index   int
+	offset  float64
+	started bool
+}
+
+func (t *tone) done() bool {
+	return t.index >= len(t.pieces)
+}
+
+func (t *tone) pieceRemaining() float64 {
+	return t.pieces[t.index].beats - t.offset
+}
+
+// advance marks a number of beats of the current piece as written. The
+// finished piece is returned if the piece is now fully written.
+func (t *tone) advance(beats float64) *piece {
+	t.started = true
+	t.offset += beats
+
+	if t.offset < t.pieces[t.index].beats-epsilon {
+		return nil
+	}
+
+	finished := &t.pieces[t.index]
+	t.index++
+	t.offset = 0
+	return finished
+}
+
+// voice is the state of writing a part, or a voice within a part.
+type voice struct {
+	part *exportPart
+	// The part (or voice within a part) in the score being exported.
+	modelPart *model.Part
+	stream    int32
+	position  float64
+	topLevel  bool
+	slurring  bool
+	// The notes and rests that start at the voice's current position, which are
+	// written when the voice moves forward.
+	pending []*tone
+	// Notes from previous events in the voice that are still sounding, e.g.
+	// the longer notes in a chord with notes of different lengths.
+	sustained []*tone
+}
+
+// partState is the subset of a part's attributes that are written as MusicXML
+// attributes or directions when they change.
+type partState struct {
+	key           model.KeySignature
+	transposition int32
+	tempo         float64
+	volume        float64
+}
+
+// walker follows along as a score is evaluated (see model.ScoreObserver) and
+// collects the items that make up each MusicXML part.
+//
+// The score places each note, rest and barline in time, and the walker converts
+// them into MusicXML notes, keeping track of the streams, ties and chords that
+// MusicXML requires. This way, repeats, variables, crams, markers, etc. work
+// the same way as they do when the score is played.
+type walker struct {
+	score      *model.Score
+	parts      []*exportPart
+	partsByID  map[string]*exportPart
+	voices     map[*model.Part]*voice
+	boundaries []float64
+	columns    int
+}
+
+func newWalker() *walker {
+	w := &walker{
+		score:     model.NewScore(),
+		partsByID: map[string]*exportPart{},
+		voices:    map[*model.Part]*voice{},
+	}
+
+	w.score.Observer = w
+
+	return w
+}
+
+// partName returns a name for a part, preferring the alias (if any) that the
+// score uses to refer to the part.
+func (w *walker) partName(p *model.Part, instrument model.MidiInstrument) string {
+	aliases := []string{}
+	for _, alias := range w.score.AliasesFor(p) {
+		if !strings.Contains(alias, ".") {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	if len(aliases) > 0 {
+		sort.Strings(aliases)
+		return aliases[0]
+	}
+
+	if instrument.IsPercussion {
+		return "Percussion"
+	}
+
+	return p.Name
+}
+
+// addPart starts a MusicXML part for an Alda part.
+func (w *walker) addPart(p *model.Part) *exportPart {
+	instrument, ok := p.StockInstrument.(model.MidiInstrument)
+	if !ok {
+		instrument = model.MidiInstrument{NameImpl: p.Name}
+	}
+
+	part := &exportPart{
+		instrument:   instrument,
+		streamEnds:   map[int32]float64{},
+		streamOwners: map[int32]*voice{},
+		state: partState{
+			tempo:  p.Tempo,
+			volume: model.DynamicVolumes["mf"],
+		},
+		unpitched: map[int32]bool{},
+	}
+
+	w.parts = append(w.parts, part)
+	w.partsByID[p.ID] = part
+
+	return part
+}
+
+// isTopLevel returns true if a part in the score is not a voice in a voice
+// group.
+//
+// When a voice group ends, the voice that finishes last takes the place of the
+// part in the score.
+func (w *walker) isTopLevel(p *model.Part) bool {
+	for _, scorePart := range w.score.Parts {
+		if scorePart == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// endVoiceGroup finishes the voices of a part other than `top`, which continues
+// as the part's top-level voice.
+func (w *walker) endVoiceGroup(part *exportPart, top *voice) {
+	for _, v := range part.voices {
+		if v != top {
+			w.finish(v)
+		}
+	}
+
+	part.voices = []*voice{top}
+	part.releaseStream(top)
+	top.topLevel = true
+	part.claimStream(top, 1)
+}
+
+// voiceFor returns the voice being written for a part (or a voice within a
+// part) in the score, starting a new voice if necessary.
+func (w *walker) voiceFor(p *model.Part) *voice {
+	part, ok := w.partsByID[p.ID]
+	if !ok {
+		part = w.addPart(p)
+	}
+
+	// If a voice group has ended since we last heard from the part, one of the
+	// voices in the group is now the part's top-level voice.
+	for _, v := range part.voices {
+		if !v.topLevel && w.isTopLevel(v.modelPart) {
+			w.endVoiceGroup(part, v)
+			break
+		}
+	}
+
+	if v, ok := w.voices[p]; ok {
+		return v
+	}
+
+	v := &voice{part: part, modelPart: p, position: p.CurrentBeat}
+	w.voices[p] = v
+
+	if w.isTopLevel(p) {
+		w.endVoiceGroup(part, v)
+		return v
+	}
+
+	// A voice group is starting (or continuing with a new voice), so the part's
+	// top-level voice doesn't continue.
+	voices := []*voice{}
+	for _, other := range part.voices {
+		if other.topLevel {
+			w.finish(other)
+		} else {
+			voices = append(voices, other)
+		}
+	}
+
+	part.voices = append(voices, v)
+	part.claimStream(v, 1)
+
+	return v
+}
+
+// dynamicMarking returns the dynamic marking (e.g. "mp") that sets a part's
+// volume to the provided value, if there is one.
+func dynamicMarking(volume float64) (string, bool) {
+	markings := []string{}
+	for marking := range model.DynamicVolumes {
+		markings = append(markings, marking)
+	}
+	sort.Strings(markings)
+
+	for _, marking := range markings {
+		if model.DynamicVolumes[marking] == volume {
+			return marking, true
+		}
+	}
+
+	return "", false
+}
+
+// emitChanges adds items for any attributes of a part that changed since the
+// part's previous note or rest.
+func (w *walker) emitChanges(v *voice, p *model.Part) {
+	previous := v.part.state
+	v.part.state = partState{
+		key:           p.KeySignature,
+		transposition: p.Transposition,
+		tempo:         p.Tempo,
+		volume:        p.Volume,
+	}
+
+	// Percussion notes are written as unpitched notes, so key signatures and
+	// transpositions don't apply.
+	if !v.part.instrument.IsPercussion {
+		if !keySignaturesEqual(previous.key, p.KeySignature) {
+			v.part.add(&item{
+				kind: keyItem, stream: v.stream, position: v.position,
+				key: p.KeySignature,
+			})
+		}
+
+		if previous.transposition != p.Transposition {
+			v.part.add(&item{
+				kind: transposeItem, stream: v.stream, position: v.position,
+				transposition: p.Transposition,
+			})
+		}
+	}
+
+	if previous.tempo != p.Tempo {
+		v.part.add(&item{
+			kind: tempoItem, stream: v.stream, position: v.position,
+			tempo: p.Tempo,
+		})
+	}
+
+	if previous.volume != p.Volume {
+		if marking, ok := dynamicMarking(p.Volume); ok {
+			v.part.add(&item{
+				kind: dynamicsItem, stream: v.stream, position: v.position,
+				dynamic: marking,
+			})
+		}
+	}
+}
+
+// moveTo moves a voice to a position that it didn't reach by writing notes and
+// rests, e.g. when the part jumps to a marker. If the voice has already written
+// past that position, it continues in a different stream.
+func (w *walker) moveTo(v *voice, position float64) {
+	if math.Abs(position-v.position) < epsilon {
+		return
+	}
+
+	w.flush(v)
+	v.part.releaseStream(v)
+	v.position = position
+	v.part.claimStream(v, v.stream)
+}
+
+// pitchFor returns the written pitch of a note in a part.
+func (w *walker) pitchFor(
+	identifier model.PitchIdentifier, p *model.Part, part *exportPart,
+) pitch {
+	result := writtenPitch(identifier, p)
+
+	if part.instrument.IsPercussion {
+		spelling := sharpSpellings[((result.midiNote%12)+12)%12]
+		result = pitch{
+			step:      spelling.step,
+			octave:    result.midiNote/12 - 1,
+			unpitched: true,
+			midiNote:  result.midiNote,
+		}
+		part.unpitched[result.midiNote] = true
+		return result
+	}
+
+	part.pitchSum += float64(result.midiNote)
+	part.pitchCount++
+	return result
+}
+
+// fitPieces makes sure that the pieces of a note or rest add up to the length
+// that the score gave it. If they don't (e.g. a note length that can't be
+// written), the note or rest is written as a single piece.
+func fitPieces(pieces []piece, beats float64) []piece {
+	if math.Abs(totalBeats(pieces)-beats) < epsilon {
+		return pieces
+	}
+
+	return []piece{{beats: beats, notation: notationFor(beats)}}
+}
+
+// NoteOrRestAdded implements model.ScoreObserver.NoteOrRestAdded by preparing
+// the note or rest to be written when the part moves forward.
+func (w *walker) NoteOrRestAdded(
+	p *model.Part, noteOrRest model.ScoreUpdate, beats float64,
+) {
+	v := w.voiceFor(p)
+
+	if len(v.pending) == 0 {
+		w.moveTo(v, p.CurrentBeat)
+	}
+
+	w.emitChanges(v, p)
+
+	var t *tone
+	var leadingBarline bool
+
+	switch event := noteOrRest.(type) {
+	case model.Note:
+		duration := event.Duration
+		if duration.Components == nil {
+			duration = p.Duration
+		}
+
+		t = &tone{slurred: event.Slurred}
+		t.pieces, leadingBarline = notePieces(duration, p)
+		t.pitch = w.pitchFor(event.Pitch, p, v.part)
+
+	case model.Rest:
+		duration := event.Duration
+		if duration.Components == nil {
+			duration = p.Duration
+		}
+
+		t = &tone{rest: true}
+		t.pieces, leadingBarline = restPieces(duration, p)
+
+	default:
+		return
+	}
+
+	if leadingBarline {
+		w.boundaries = append(w.boundaries, v.position)
+	}
+
+	if beats > epsilon {
+		t.pieces = fitPieces(t.pieces, beats)
+		v.pending = append(v.pending, t)
+	}
+}
+
+// PartAdvanced implements model.ScoreObserver.PartAdvanced by writing the notes
+// and rests that start at the part's previous position.
+func (w *walker) PartAdvanced(p *model.Part) {
+	v := w.voiceFor(p)
+	w.emit(v, v.pending, p.CurrentBeat-v.position)
+	v.pending = nil
+}
+
+// BarlineAdded implements model.ScoreObserver.BarlineAdded by recording a
+// measure boundary at the part's current position.
+func (w *walker) BarlineAdded(p *model.Part) {
+	w.boundaries = append(w.boundaries, p.CurrentBeat)
+}
+
+// finish writes any notes that are still sounding in a voice that won't be
+// written to anymore, and frees up its stream.
+func (w *walker) finish(v *voice) {
+	w.flush(v)
+	v.part.releaseStream(v)
+}
+
+// flush writes the remainder of any notes that are still sounding in a voice.
+func (w *walker) flush(v *voice) {
+	position := v.position
+
+	for {
+		active := []*tone{}
+		length := math.MaxFloat64
+
+		for _, t := range v.sustained {
+			if !t.done() {
+				active = append(active, t)
+				length = math.Min(length, t.pieceRemaining())
+			}
+		}
+
+		if len(active) == 0 {
+			break
+		}
+
+		w.writeColumn(v, active, position, length)
+		position += length
+	}
+
+	v.sustained = nil
+}
+
+// emit writes the tones that start at a voice's current position, along with
+// any tones that are still sounding from previous events, and moves the voice
+// forward.
+//
+// Notes in MusicXML chords must all have the same duration, so the tones are
+// written as a series of columns, with a new column wherever a tone ends.
+// Tones that continue from one column into the next are tied. Any notes that
+// are still sounding at the end are carried over to the next event.
+func (w *walker) emit(v *voice, tones []*tone, advance float64) {
+	for _, t := range tones {
+		if t.rest {
+			continue
+		}
+
+		t.slurStart = t.slurred && !v.slurring
+		t.slurStop = !t.slurred && v.slurring
+		v.slurring = t.slurred
+		break
+	}
+
+	active := append(append([]*tone{}, tones...), v.sustained...)
+	position := v.position
+	end := v.position + advance
+
+	for position < end-epsilon {
+		length := end - position
+		written := false
+
+		for _, t := range active {
+			if !t.done() {
+				length = math.Min(length, t.pieceRemaining())
+				written = true
+			}
+		}
+
+		if !written {
+			break
+		}
+
+		w.writeColumn(v, active, position, length)
+		position += length
+	}
+
+	v.sustained = nil
+	for _, t := range active {
+		if !t.rest && !t.done() {
+			v.sustained = append(v.sustained, t)
+		}
+	}
+
+	v.position = end
+}
+
+// writeColumn writes a segment of each active tone, as a chord. Rests are only
+// written when none of the tones are notes.
+func (w *walker) writeColumn(
+	v *voice, active []*tone, position float64, length float64,
+) {
+	w.columns++
+
+	pitched := false
+	for _, t := range active {
+		if !t.done() && !t.rest {
+			pitched = true
+		}
+	}
+
+	wroteRest := false
+
+	for _, t := range active {
+		if t.done() {
+			continue
+		}
+
+		current := t.pieces[t.index]
+		wholePiece := t.offset < epsilon &&
+			math.Abs(current.beats-length) < epsilon
+		tieStop := t.started
+
+		finished := t.advance(length)
+		if finished != nil && finished.barline {
+			w.boundaries = append(w.boundaries, position+length)
+		}
+
+		if t.rest && (pitched || wroteRest) {
+			continue
+		}
+
+		it := &item{
+			kind:     noteItem,
+			stream:   v.stream,
+			position: position,
+			beats:    length,
+			column:   columnID{column: w.columns},
+			rest:     t.rest,
+			pitch:    t.pitch,
+		}
+
+		if wholePiece {
+			it.notation = current.notation
+		} else {
+			it.notation = notationFor(length)
+		}
+
+		if t.rest {
+			wroteRest = true
+		} else {
+			it.tieStop = tieStop
+			it.tieStart = !t.done()
+			if !tieStop {
+				it.slurStart, it.slurStop = t.slurStart, t.slurStop
+			}
+		}
+
+		v.part.add(it)
+	}
+}
+
+// finishAll writes any notes that are still sounding at the end of the score,
+// and puts the parts in the order in which they were declared.
+//
+// Parts that don't have any notes or rests are included, too.
+func (w *walker) finishAll() {
+	for _, v := range w.voices {
+		w.flush(v)
+	}
+
+	parts := []*exportPart{}
+	for _, p := range w.score.Parts {
+		part, ok := w.partsByID[p.ID]
+		if !ok {
+			part = w.addPart(p)
+		}
+
+		part.id = fmt.Sprintf("P%d", len(parts)+1)
+		part.name = w.partName(p, part.instrument)
+		parts = append(parts, part)
+	}
+
+	w.parts = parts
+}
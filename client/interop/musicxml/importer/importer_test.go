@@ -299,13 +299,13 @@ func TestPercussion(t *testing.T) {
 			expected: `
 				midi-percussion "Triangle":
 					(key-signature "")
-					o5 g+1 | o5 a1 | r1 | o5 g+1 |
-					o5 a4 a g+ g+
+					$triangle-mute1 | $triangle-open1 | r1 | $triangle-mute1 |
+					$triangle-open4 $triangle-open $triangle-mute $triangle-mute
 	
 				midi-percussion "Wood_Blocks":
 					(key-signature "")
-					o5 e4 e f f |
-					o5 e1 | o5 e1 | o5 f1 | r1
+					$wood-block-high4 $wood-block-high $wood-block-low $wood-block-low |
+					$wood-block-high1 | $wood-block-high1 | $wood-block-low1 | r1
 			`},
 	)
 }
@@ -186,7 +186,8 @@ func (opt *optimizer) removeRedundantDurations(
 
 // translateMidiNotePitches takes all imported notes with the
 // model.MidiNoteNumber pitch identifier and translates these to
-// model.LetterAndAccidentals following standard pitched notes
+// model.DrumName where the note is in the General MIDI drum map, or otherwise
+// to model.LetterAndAccidentals following standard pitched notes
 func (opt *optimizer) translateMidiNotePitches(
 	updates []model.ScoreUpdate,
 ) []model.ScoreUpdate {
@@ -200,16 +201,22 @@ func (opt *optimizer) translateMidiNotePitches(
 		case model.Note:
 			if reflect.TypeOf(typedUpdate.Pitch) == midiNoteNumberType {
 				midiNoteNumber := typedUpdate.Pitch.(model.MidiNoteNumber)
-				laa, octave := toLetterAndAccidentalsAndOctave(midiNoteNumber)
 
-				typedUpdate.Pitch = laa
-				update = typedUpdate
+				// Drums don't depend on the octave, so no octave set is needed
+				if drum, ok := model.DrumForMidiNote(midiNoteNumber.MidiNote); ok {
+					typedUpdate.Pitch = drum
+				} else {
+					laa, octave := toLetterAndAccidentalsAndOctave(midiNoteNumber)
+					typedUpdate.Pitch = laa
 
-				if octave != opt.currentOctave {
-					octaveSetIndices = append(octaveSetIndices, i)
-					octaveSetOctaves = append(octaveSetOctaves, octave)
-					opt.currentOctave = octave
+					if octave != opt.currentOctave {
+						octaveSetIndices = append(octaveSetIndices, i)
+						octaveSetOctaves = append(octaveSetOctaves, octave)
+						opt.currentOctave = octave
+					}
 				}
+
+				update = typedUpdate
 			}
 		}
 
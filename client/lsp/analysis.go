@@ -0,0 +1,251 @@
+package lsp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"alda.io/client/model"
+	"alda.io/client/parser"
+)
+
+// sourceErrorLocation returns the most specific source context available for
+// an error, along with the underlying error message.
+//
+// This mirrors the way that AldaSourceError.Error finds the bottom-most error
+// in a chain of wrapped errors.
+func sourceErrorLocation(err error) (model.AldaSourceContext, string) {
+	var context model.AldaSourceContext
+	message := err.Error()
+
+	var sourceErr *model.AldaSourceError
+	for errors.As(err, &sourceErr) {
+		if sourceErr.Context.Line != 0 {
+			context = sourceErr.Context
+		}
+		message = sourceErr.Err.Error()
+		err = sourceErr.Err
+	}
+
+	return context, message
+}
+
+func (doc *document) diagnostic(err error) Diagnostic {
+	context, message := sourceErrorLocation(err)
+
+	return Diagnostic{
+		Range:    doc.contextRange(context),
+		Severity: SeverityError,
+		Source:   "alda",
+		Message:  message,
+	}
+}
+
+// parse parses the document, recovering from syntax errors so that as much of
+// the document as possible is available for analysis.
+func (doc *document) parse() (parser.ASTNode, error) {
+	return parser.Parse(doc.filename(), doc.text, parser.RecoverFromErrors)
+}
+
+// diagnostics returns the problems in the document.
+//
+// All syntax errors are reported. If there are no syntax errors, the score is
+// evaluated, and the first error that occurs during evaluation (e.g. an unknown
+// instrument or an invalid attribute value) is reported.
+func (doc *document) diagnostics() (diagnostics []Diagnostic) {
+	diagnostics = []Diagnostic{}
+
+	// The score is evaluated in the same process as the language server, so we
+	// don't want a bug in the evaluation of an unusual score to bring the whole
+	// server down.
+	defer func() {
+		if r := recover(); r != nil {
+			diagnostics = append(diagnostics, doc.diagnostic(
+				fmt.Errorf("internal error while analyzing the score: %v", r),
+			))
+		}
+	}()
+
+	ast, err := doc.parse()
+
+	var parseErrors parser.ParseErrors
+	switch {
+	case errors.As(err, &parseErrors):
+		for _, parseError := range parseErrors {
+			diagnostics = append(diagnostics, doc.diagnostic(parseError))
+		}
+		return diagnostics
+	case err != nil:
+		return append(diagnostics, doc.diagnostic(err))
+	}
+
+	updates, err := ast.Updates()
+	if err != nil {
+		return append(diagnostics, doc.diagnostic(err))
+	}
+
+	if err := model.NewScore().Update(updates...); err != nil {
+		return append(diagnostics, doc.diagnostic(err))
+	}
+
+	return diagnostics
+}
+
+// completions returns the completion items for a position in the document:
+//
+//   - Attribute names, right after an opening parenthesis.
+//   - Instrument names, at the beginning of a line or after a `/` (i.e. where a
+//     part declaration can be).
+func (doc *document) completions(pos Position) []CompletionItem {
+	runes := doc.line(pos.Line)
+	index := doc.index(pos)
+
+	start := index
+	for start > 0 && isWordChar(runes[start-1]) {
+		start--
+	}
+	prefix := string(runes[start:index])
+
+	// Instrument names tend to have prefixes like `midi-` that the user might
+	// not type, so we offer any name containing what the user has typed so far.
+	items := []CompletionItem{}
+	add := func(label string, kind int, detail string) {
+		if strings.Contains(label, prefix) {
+			items = append(items, CompletionItem{
+				Label: label, Kind: kind, Detail: detail,
+			})
+		}
+	}
+
+	before := strings.TrimRight(string(runes[:start]), " \t")
+
+	switch {
+	case start > 0 && runes[start-1] == '(':
+		for _, name := range model.AttributeNames() {
+			add(name, CompletionKindFunction, "attribute")
+		}
+	case before == "" || strings.HasSuffix(before, "/"):
+		for _, name := range model.InstrumentsList() {
+			add(name, CompletionKindClass, "instrument")
+		}
+	}
+
+	return items
+}
+
+// walkAST calls `f` on each node in the tree, depth-first, in source order.
+func walkAST(node parser.ASTNode, f func(parser.ASTNode)) {
+	f(node)
+
+	for _, child := range node.Children {
+		walkAST(child, f)
+	}
+}
+
+// before reports whether source context a is before source context b.
+func before(a, b model.AldaSourceContext) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+
+	return a.Column < b.Column
+}
+
+// definition returns the location where the variable or marker at a position
+// in the document is defined, or nil if there isn't one.
+//
+// For a variable, this is the most recent definition of the variable before
+// the reference (or the first definition, if there is none before it). For a
+// marker, this is the marker itself.
+func (doc *document) definition(pos Position) *Location {
+	runes := doc.line(pos.Line)
+	start, end := wordAt(runes, doc.index(pos))
+	if start == end {
+		return nil
+	}
+
+	name := string(runes[start:end])
+	isMarker := start > 0 && (runes[start-1] == '@' || runes[start-1] == '%')
+
+	reference := model.AldaSourceContext{Line: pos.Line + 1, Column: start + 1}
+
+	// Syntax errors elsewhere in the document shouldn't prevent us from finding
+	// definitions, so we use whatever could be parsed.
+	ast, _ := doc.parse()
+
+	var found *model.AldaSourceContext
+	nameLength := len(runes[start:end])
+
+	walkAST(ast, func(node parser.ASTNode) {
+		switch {
+		case isMarker && node.Type == parser.MarkerNode && node.Literal == name:
+			if found == nil {
+				context := node.SourceContext
+				found = &context
+			}
+		case !isMarker && node.Type == parser.VariableDefinitionNode:
+			nameNode := node.Children[0]
+			if nameNode.Literal != name {
+				return
+			}
+			context := nameNode.SourceContext
+			if found == nil || before(context, reference) {
+				found = &context
+			}
+		}
+	})
+
+	if found == nil {
+		return nil
+	}
+
+	line, column := found.Line-1, found.Column-1
+	if isMarker {
+		// Include the `%` prefix.
+		nameLength++
+	}
+
+	return &Location{
+		URI: doc.uri,
+		Range: Range{
+			Start: doc.position(line, column),
+			End:   doc.position(line, column+nameLength),
+		},
+	}
+}
+
+// format returns the edits that format the document, i.e. a single edit that
+// replaces the entire document with the formatted code.
+//
+// Returns an error if the document has syntax errors, or if it contains
+// comments, which the formatter does not preserve.
+func (doc *document) format(indentText string) ([]TextEdit, error) {
+	if parser.HasComments(doc.text) {
+		return nil, fmt.Errorf(
+			"formatting is not available for scores that contain comments, " +
+				"because comments would be removed",
+		)
+	}
+
+	ast, err := parser.Parse(doc.filename(), doc.text, parser.SkipIncludes)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := parser.FormatASTToCode(
+		ast, &buf, parser.ConfigureIndentText(indentText),
+	); err != nil {
+		return nil, err
+	}
+
+	if buf.String() == doc.text {
+		return []TextEdit{}, nil
+	}
+
+	return []TextEdit{{
+		Range:   Range{Start: Position{}, End: doc.end()},
+		NewText: buf.String(),
+	}}, nil
+}
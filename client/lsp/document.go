@@ -0,0 +1,144 @@
+package lsp
+
+import (
+	"net/url"
+	"strings"
+	"unicode/utf16"
+
+	"alda.io/client/model"
+)
+
+// A document is the current text of a file that is open in the editor.
+type document struct {
+	uri   string
+	text  string
+	lines [][]rune
+}
+
+func newDocument(uri string, text string) *document {
+	doc := &document{uri: uri, text: text}
+
+	for _, line := range strings.Split(text, "\n") {
+		doc.lines = append(doc.lines, []rune(strings.TrimSuffix(line, "\r")))
+	}
+
+	return doc
+}
+
+// filename returns the path of the document's file, for use in error messages,
+// or the URI itself if the document isn't a file.
+func (doc *document) filename() string {
+	u, err := url.Parse(doc.uri)
+	if err != nil || u.Scheme != "file" {
+		return doc.uri
+	}
+
+	return u.Path
+}
+
+// line returns the text of a (zero-based) line, or nil if the line doesn't
+// exist.
+func (doc *document) line(line int) []rune {
+	if line < 0 || line >= len(doc.lines) {
+		return nil
+	}
+
+	return doc.lines[line]
+}
+
+// utf16Len returns the length of a string of runes in UTF-16 code units, which
+// is how LSP measures character offsets.
+func utf16Len(runes []rune) int {
+	return len(utf16.Encode(runes))
+}
+
+// position converts a (zero-based) line and rune index into an LSP Position.
+func (doc *document) position(line int, index int) Position {
+	runes := doc.line(line)
+	if index > len(runes) {
+		index = len(runes)
+	}
+
+	return Position{Line: line, Character: utf16Len(runes[:index])}
+}
+
+// index converts an LSP Position into the index of a rune within its line.
+func (doc *document) index(pos Position) int {
+	runes := doc.line(pos.Line)
+
+	units := 0
+	for i, r := range runes {
+		if units >= pos.Character {
+			return i
+		}
+		// Invalid runes are encoded as U+FFFD, which is one unit.
+		if n := utf16.RuneLen(r); n > 0 {
+			units += n
+		} else {
+			units++
+		}
+	}
+
+	return len(runes)
+}
+
+// end returns the position at the end of the document.
+func (doc *document) end() Position {
+	last := len(doc.lines) - 1
+	return doc.position(last, len(doc.lines[last]))
+}
+
+// isWordChar reports whether a character can be part of a "word" in an Alda
+// score, e.g. an instrument name, variable name or attribute name.
+func isWordChar(r rune) bool {
+	return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') ||
+		('0' <= r && r <= '9') || strings.ContainsRune("-_+!?*.'", r)
+}
+
+// wordAt returns the start and end indexes of the word within a line that
+// contains the provided index (or ends at it).
+func wordAt(runes []rune, index int) (int, int) {
+	start, end := index, index
+
+	for start > 0 && isWordChar(runes[start-1]) {
+		start--
+	}
+
+	for end < len(runes) && isWordChar(runes[end]) {
+		end++
+	}
+
+	return start, end
+}
+
+// contextRange returns the range in the document that corresponds to an Alda
+// source context (which has one-based line and column numbers). The range
+// covers the word starting at that position, or a single character if there
+// is no word there.
+func (doc *document) contextRange(ctx model.AldaSourceContext) Range {
+	line, index := ctx.Line-1, ctx.Column-1
+	if line < 0 {
+		line, index = 0, 0
+	}
+	if line >= len(doc.lines) {
+		end := doc.end()
+		return Range{Start: end, End: end}
+	}
+	if index < 0 {
+		index = 0
+	}
+
+	runes := doc.line(line)
+	end := index
+	for end < len(runes) && runes[end] != ' ' && runes[end] != '\t' {
+		end++
+	}
+	if end == index && end < len(runes) {
+		end++
+	}
+
+	return Range{
+		Start: doc.position(line, index),
+		End:   doc.position(line, end),
+	}
+}
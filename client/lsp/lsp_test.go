@@ -0,0 +1,454 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	_ "alda.io/client/testing"
+	"github.com/go-test/deep"
+)
+
+const testURI = "file:///tmp/score.alda"
+
+// session sends messages to a language server and returns the messages that
+// the server wrote in response.
+func session(
+	t *testing.T, messages ...map[string]interface{},
+) []map[string]interface{} {
+	var in bytes.Buffer
+	for _, msg := range messages {
+		msg["jsonrpc"] = "2.0"
+		body, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	var out bytes.Buffer
+	if err := NewServer(&in, &out).Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := []map[string]interface{}{}
+	reader := bufio.NewReader(&out)
+
+	for {
+		headers := ""
+		for {
+			line, err := reader.ReadString('\n')
+			if err == io.EOF {
+				return responses
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line == "\r\n" {
+				break
+			}
+			headers += line
+		}
+
+		var length int
+		if _, err := fmt.Sscanf(headers, "Content-Length: %d", &length); err != nil {
+			t.Fatal(err)
+		}
+
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			t.Fatal(err)
+		}
+
+		var response map[string]interface{}
+		if err := json.Unmarshal(body, &response); err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, response)
+	}
+}
+
+func request(id int, method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"id": id, "method": method, "params": params}
+}
+
+func notification(method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"method": method, "params": params}
+}
+
+func open(text string) map[string]interface{} {
+	return notification("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{
+			"uri": testURI, "languageId": "alda", "version": 1, "text": text,
+		},
+	})
+}
+
+func positionParams(line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": testURI},
+		"position":     map[string]interface{}{"line": line, "character": character},
+	}
+}
+
+// withLifecycle wraps messages in the initialize/shutdown/exit lifecycle.
+func withLifecycle(messages ...map[string]interface{}) []map[string]interface{} {
+	result := []map[string]interface{}{
+		request(0, "initialize", map[string]interface{}{}),
+		notification("initialized", map[string]interface{}{}),
+	}
+	result = append(result, messages...)
+	return append(
+		result,
+		request(999, "shutdown", nil),
+		notification("exit", nil),
+	)
+}
+
+// roundTrip converts a value to the form that it would have after being
+// serialized as JSON and parsed again, for comparison with responses.
+func roundTrip(t *testing.T, v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(b, &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestLifecycle(t *testing.T) {
+	responses := session(t, withLifecycle()...)
+
+	if len(responses) != 2 {
+		t.Fatalf("expected 2 responses, got %#v", responses)
+	}
+
+	capabilities := responses[0]["result"].(map[string]interface{})["capabilities"]
+	expected := roundTrip(t, map[string]interface{}{
+		"textDocumentSync":           syncFull,
+		"definitionProvider":         true,
+		"documentFormattingProvider": true,
+		"completionProvider": map[string]interface{}{
+			"triggerCharacters": []string{"(", "/"},
+		},
+	})
+	if diff := deep.Equal(expected, capabilities); diff != nil {
+		t.Error(diff)
+	}
+
+	if result, ok := responses[1]["result"]; !ok || result != nil {
+		t.Errorf("expected a null shutdown result, got %#v", responses[1])
+	}
+}
+
+func TestRequestBeforeInitialize(t *testing.T) {
+	responses := session(
+		t,
+		request(1, "textDocument/completion", positionParams(0, 0)),
+		request(2, "initialize", map[string]interface{}{}),
+		request(3, "shutdown", nil),
+		notification("exit", nil),
+	)
+
+	rpcErr := responses[0]["error"].(map[string]interface{})
+	if rpcErr["code"] != float64(codeServerNotInitialized) {
+		t.Errorf("expected a not-initialized error, got %#v", responses[0])
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	responses := session(t, withLifecycle(
+		request(1, "textDocument/hover", positionParams(0, 0)),
+	)...)
+
+	rpcErr := responses[1]["error"].(map[string]interface{})
+	if rpcErr["code"] != float64(codeMethodNotFound) {
+		t.Errorf("expected a method-not-found error, got %#v", responses[1])
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	for _, testCase := range []struct {
+		label    string
+		text     string
+		expected []Diagnostic
+	}{
+		{
+			label:    "no problems",
+			text:     "piano: c d e",
+			expected: []Diagnostic{},
+		},
+		{
+			label: "multiple syntax errors",
+			text:  "piano: c } d\nviolin: c & d",
+			expected: []Diagnostic{
+				{
+					Range: Range{
+						Start: Position{Line: 0, Character: 9},
+						End:   Position{Line: 0, Character: 10},
+					},
+					Severity: SeverityError,
+					Source:   "alda",
+					Message:  "Unexpected end of cram expression `}` in inner events",
+				},
+				{
+					Range: Range{
+						Start: Position{Line: 1, Character: 10},
+						End:   Position{Line: 1, Character: 11},
+					},
+					Severity: SeverityError,
+					Source:   "alda",
+					Message:  "Unexpected '&' at the top level",
+				},
+			},
+		},
+		{
+			label: "unknown instrument",
+			text:  "piano: c\nkazoo-supreme: d",
+			expected: []Diagnostic{
+				{
+					Range: Range{
+						Start: Position{Line: 1, Character: 0},
+						End:   Position{Line: 1, Character: 14},
+					},
+					Severity: SeverityError,
+					Source:   "alda",
+					Message:  "unrecognized instrument: kazoo-supreme",
+				},
+			},
+		},
+	} {
+		responses := session(t, withLifecycle(open(testCase.text))...)
+
+		notification := responses[1]
+		if notification["method"] != "textDocument/publishDiagnostics" {
+			t.Fatalf("%s: expected diagnostics, got %#v", testCase.label, notification)
+		}
+
+		actual := notification["params"].(map[string]interface{})["diagnostics"]
+		if diff := deep.Equal(roundTrip(t, testCase.expected), actual); diff != nil {
+			t.Error(testCase.label)
+			for _, diffItem := range diff {
+				t.Errorf("%v", diffItem)
+			}
+		}
+	}
+}
+
+func TestDiagnosticsAfterRemovingADefinition(t *testing.T) {
+	change := notification("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": testURI, "version": 2},
+		"contentChanges": []map[string]interface{}{{"text": "piano: (test-fn)"}},
+	})
+
+	responses := session(t, withLifecycle(
+		open("(defn test-fn () (note (pitch '(c)) (duration (note-length 4))))"+
+			"\npiano: (test-fn)"),
+		change,
+	)...)
+
+	diagnostics := []interface{}{}
+	for _, response := range responses {
+		if response["method"] == "textDocument/publishDiagnostics" {
+			diagnostics = append(
+				diagnostics,
+				response["params"].(map[string]interface{})["diagnostics"],
+			)
+		}
+	}
+
+	if len(diagnostics) != 2 {
+		t.Fatalf("expected 2 diagnostics notifications, got %d", len(diagnostics))
+	}
+
+	if len(diagnostics[0].([]interface{})) != 0 {
+		t.Errorf("expected no problems with the definition: %v", diagnostics[0])
+	}
+
+	// The function was defined in the previous version of the document, so it
+	// shouldn't be available anymore.
+	if !strings.Contains(fmt.Sprint(diagnostics[1]), "unresolvable symbol: test-fn") {
+		t.Errorf("expected an unresolvable symbol: %v", diagnostics[1])
+	}
+}
+
+func labels(items []CompletionItem) []string {
+	result := []string{}
+	for _, item := range items {
+		result = append(result, item.Label)
+	}
+	return result
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCompletion(t *testing.T) {
+	for _, testCase := range []struct {
+		label      string
+		text       string
+		position   Position
+		expectSome []string
+		expectNone []string
+	}{
+		{
+			label:    "instrument at the start of a line",
+			text:     "pia",
+			position: Position{Line: 0, Character: 3},
+			expectSome: []string{
+				"midi-acoustic-grand-piano", "midi-electric-grand-piano",
+			},
+			expectNone: []string{"midi-violin", "tempo"},
+		},
+		{
+			label:      "instrument after a slash",
+			text:       "piano/vio",
+			position:   Position{Line: 0, Character: 9},
+			expectSome: []string{"midi-violin", "midi-viola"},
+			expectNone: []string{"midi-acoustic-grand-piano"},
+		},
+		{
+			label:      "attribute after a parenthesis",
+			text:       "piano: (tem",
+			position:   Position{Line: 0, Character: 11},
+			expectSome: []string{"tempo", "tempo!"},
+			expectNone: []string{"volume", "midi-acoustic-grand-piano"},
+		},
+		{
+			label:      "nothing in the middle of notes",
+			text:       "piano: c d e",
+			position:   Position{Line: 0, Character: 10},
+			expectNone: []string{"midi-acoustic-grand-piano", "tempo"},
+		},
+	} {
+		actual := labels(newDocument(testURI, testCase.text).completions(
+			testCase.position,
+		))
+
+		for _, label := range testCase.expectSome {
+			if !contains(actual, label) {
+				t.Errorf("%s: expected %q in %v", testCase.label, label, actual)
+			}
+		}
+
+		for _, label := range testCase.expectNone {
+			if contains(actual, label) {
+				t.Errorf("%s: did not expect %q in %v", testCase.label, label, actual)
+			}
+		}
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	text := strings.Join([]string{
+		"motif = c d e",
+		"piano: motif %chorus f",
+		"motif = g a b",
+		"motif @chorus",
+		"c } d",
+	}, "\n")
+
+	for _, testCase := range []struct {
+		label    string
+		position Position
+		expected *Location
+	}{
+		{
+			label:    "variable defined once before the reference",
+			position: Position{Line: 1, Character: 9},
+			expected: &Location{URI: testURI, Range: Range{
+				Start: Position{Line: 0, Character: 0},
+				End:   Position{Line: 0, Character: 5},
+			}},
+		},
+		{
+			label:    "redefined variable",
+			position: Position{Line: 3, Character: 2},
+			expected: &Location{URI: testURI, Range: Range{
+				Start: Position{Line: 2, Character: 0},
+				End:   Position{Line: 2, Character: 5},
+			}},
+		},
+		{
+			label:    "marker",
+			position: Position{Line: 3, Character: 9},
+			expected: &Location{URI: testURI, Range: Range{
+				Start: Position{Line: 1, Character: 13},
+				End:   Position{Line: 1, Character: 20},
+			}},
+		},
+		{
+			label:    "not a variable",
+			position: Position{Line: 1, Character: 21},
+			expected: nil,
+		},
+	} {
+		actual := newDocument(testURI, text).definition(testCase.position)
+		if diff := deep.Equal(testCase.expected, actual); diff != nil {
+			t.Error(testCase.label)
+			for _, diffItem := range diff {
+				t.Errorf("%v", diffItem)
+			}
+		}
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	doc := newDocument(testURI, "piano:   c   d\n\n\n e")
+
+	edits, err := doc.format("  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(edits) != 1 {
+		t.Fatalf("expected one edit, got %#v", edits)
+	}
+
+	if edits[0].Range.End != (Position{Line: 3, Character: 2}) {
+		t.Errorf("expected the edit to cover the document, got %#v", edits[0].Range)
+	}
+
+	formatted := newDocument(testURI, edits[0].NewText)
+	edits, err = formatted.format("  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 0 {
+		t.Errorf("expected formatted code to be left alone, got %#v", edits)
+	}
+
+	// Included files aren't read, so they don't need to exist.
+	include := newDocument(testURI, "(include \"missing.alda\")\npiano: c")
+	if _, err := include.format("  "); err != nil {
+		t.Errorf("expected to format a score with an include, got %v", err)
+	}
+
+	// A `#` in a string isn't a comment.
+	chords := newDocument(testURI, "piano: (chord \"F#m7\") c")
+	if _, err := chords.format("  "); err != nil {
+		t.Errorf("expected to format a sharp chord symbol, got %v", err)
+	}
+
+	for _, text := range []string{
+		"piano: c } d", "piano: c # comment", "piano: (vol 50 # comment\n)",
+	} {
+		if _, err := newDocument(testURI, text).format("  "); err == nil {
+			t.Errorf("expected an error formatting %q", text)
+		}
+	}
+}
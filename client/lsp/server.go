@@ -0,0 +1,299 @@
+// Package lsp implements a Language Server Protocol server for Alda, which
+// provides editor features like diagnostics, completion, go-to-definition and
+// formatting.
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"alda.io/client/generated"
+	log "alda.io/client/logging"
+)
+
+// A Server is an Alda language server, communicating with a single client.
+type Server struct {
+	conn        *connection
+	documents   map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer returns a server that reads messages from `r` and writes messages
+// to `w`.
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		conn:      newConnection(r, w),
+		documents: map[string]*document{},
+	}
+}
+
+// errExit is returned by a handler when the client asks the server to exit.
+var errExit = errors.New("exit")
+
+// Serve handles messages from the client until the client asks the server to
+// exit or closes the connection.
+//
+// Returns an error if the client closed the connection without first asking
+// the server to shut down.
+func (s *Server) Serve() error {
+	for {
+		msg, err := s.conn.read()
+
+		var rpcErr *responseError
+		switch {
+		case errors.As(err, &rpcErr):
+			// The message couldn't be parsed, so we don't know its ID.
+			if err := s.conn.respond(nil, nil, rpcErr); err != nil {
+				return err
+			}
+			continue
+		case err == io.EOF && s.shutdown:
+			return nil
+		case err == io.EOF:
+			return fmt.Errorf("the client closed the connection unexpectedly")
+		case err != nil:
+			return err
+		}
+
+		if err := s.handle(msg); err != nil {
+			if err == errExit {
+				if !s.shutdown {
+					return fmt.Errorf("exit requested without a shutdown request")
+				}
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// A handler handles a request and returns its result.
+type handler func(s *Server, params json.RawMessage) (interface{}, error)
+
+var requestHandlers = map[string]handler{
+	"initialize":              (*Server).initialize,
+	"shutdown":                (*Server).handleShutdown,
+	"textDocument/completion": (*Server).completion,
+	"textDocument/definition": (*Server).definition,
+	"textDocument/formatting": (*Server).formatting,
+}
+
+// A notificationHandler handles a notification, which doesn't have a result.
+type notificationHandler func(s *Server, params json.RawMessage) error
+
+var notificationHandlers = map[string]notificationHandler{
+	"exit":                   (*Server).exit,
+	"textDocument/didOpen":   (*Server).didOpen,
+	"textDocument/didChange": (*Server).didChange,
+	"textDocument/didClose":  (*Server).didClose,
+}
+
+func (s *Server) handle(msg message) error {
+	log.Debug().
+		Str("method", msg.Method).
+		Bool("request", msg.ID != nil).
+		Msg("Received LSP message.")
+
+	if msg.ID == nil {
+		handler, ok := notificationHandlers[msg.Method]
+		// Unknown notifications (e.g. `initialized` and `$/cancelRequest`) are
+		// ignored, as the specification requires.
+		if !ok || (!s.initialized && msg.Method != "exit") {
+			return nil
+		}
+
+		err := handler(s, msg.Params)
+		if err != nil && err != errExit {
+			log.Warn().Err(err).Str("method", msg.Method).Msg("LSP notification failed.")
+			return nil
+		}
+		return err
+	}
+
+	handler, ok := requestHandlers[msg.Method]
+
+	switch {
+	case !ok:
+		return s.conn.respond(msg.ID, nil, &responseError{
+			Code:    codeMethodNotFound,
+			Message: fmt.Sprintf("unsupported method: %s", msg.Method),
+		})
+	case !s.initialized && msg.Method != "initialize":
+		return s.conn.respond(msg.ID, nil, &responseError{
+			Code:    codeServerNotInitialized,
+			Message: "the server has not been initialized",
+		})
+	case s.shutdown:
+		return s.conn.respond(msg.ID, nil, &responseError{
+			Code:    codeInvalidRequest,
+			Message: "the server is shutting down",
+		})
+	}
+
+	result, err := handler(s, msg.Params)
+	if err != nil {
+		rpcErr, ok := err.(*responseError)
+		if !ok {
+			rpcErr = &responseError{Code: codeRequestFailed, Message: err.Error()}
+		}
+		return s.conn.respond(msg.ID, nil, rpcErr)
+	}
+
+	return s.conn.respond(msg.ID, result, nil)
+}
+
+// unmarshalParams parses the parameters of a message.
+func unmarshalParams(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	s.initialized = true
+
+	result := initializeResult{}
+	result.Capabilities.TextDocumentSync = syncFull
+	result.Capabilities.DefinitionProvider = true
+	result.Capabilities.DocumentFormattingProvider = true
+	result.Capabilities.CompletionProvider.TriggerCharacters = []string{"(", "/"}
+	result.ServerInfo.Name = "alda"
+	result.ServerInfo.Version = generated.ClientVersion
+
+	return result, nil
+}
+
+func (s *Server) handleShutdown(params json.RawMessage) (interface{}, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) exit(params json.RawMessage) error {
+	return errExit
+}
+
+// update replaces the text of a document and publishes its diagnostics.
+func (s *Server) update(uri string, text string) error {
+	doc := newDocument(uri, text)
+	s.documents[uri] = doc
+
+	return s.conn.notify(
+		"textDocument/publishDiagnostics",
+		publishDiagnosticsParams{URI: uri, Diagnostics: doc.diagnostics()},
+	)
+}
+
+func (s *Server) didOpen(params json.RawMessage) error {
+	var p didOpenParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return err
+	}
+
+	return s.update(p.TextDocument.URI, p.TextDocument.Text)
+}
+
+func (s *Server) didChange(params json.RawMessage) error {
+	var p didChangeParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return err
+	}
+
+	// With full document sync, each change contains the entire document, so
+	// only the last one matters.
+	if len(p.ContentChanges) == 0 {
+		return nil
+	}
+
+	return s.update(
+		p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text,
+	)
+}
+
+func (s *Server) didClose(params json.RawMessage) error {
+	var p didCloseParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return err
+	}
+
+	delete(s.documents, p.TextDocument.URI)
+
+	// Clear the diagnostics for the closed document.
+	return s.conn.notify(
+		"textDocument/publishDiagnostics",
+		publishDiagnosticsParams{
+			URI: p.TextDocument.URI, Diagnostics: []Diagnostic{},
+		},
+	)
+}
+
+// document returns an open document.
+func (s *Server) document(uri string) (*document, error) {
+	doc, ok := s.documents[uri]
+	if !ok {
+		return nil, &responseError{
+			Code:    codeInvalidParams,
+			Message: fmt.Sprintf("document not open: %s", uri),
+		}
+	}
+
+	return doc, nil
+}
+
+func (s *Server) completion(params json.RawMessage) (interface{}, error) {
+	var p textDocumentPositionParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc.completions(p.Position), nil
+}
+
+func (s *Server) definition(params json.RawMessage) (interface{}, error) {
+	var p textDocumentPositionParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	// A nil *Location would be serialized as `null`, which means "no
+	// definition", but only if we return it as an untyped nil.
+	if location := doc.definition(p.Position); location != nil {
+		return location, nil
+	}
+
+	return nil, nil
+}
+
+func (s *Server) formatting(params json.RawMessage) (interface{}, error) {
+	var p formattingParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	indentText := "\t"
+	if p.Options.InsertSpaces && p.Options.TabSize > 0 {
+		indentText = strings.Repeat(" ", p.Options.TabSize)
+	}
+
+	return doc.format(indentText)
+}
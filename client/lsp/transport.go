@@ -0,0 +1,101 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A connection reads and writes LSP messages, each of which is a JSON object
+// preceded by a `Content-Length` header.
+type connection struct {
+	reader *bufio.Reader
+	writer io.Writer
+	// Guards writes, so that messages written from different goroutines are not
+	// interleaved.
+	writeLock sync.Mutex
+}
+
+func newConnection(r io.Reader, w io.Writer) *connection {
+	return &connection{reader: bufio.NewReader(r), writer: w}
+}
+
+// read reads the next message. Returns io.EOF when the input is exhausted.
+func (c *connection) read() (message, error) {
+	headers, err := textproto.NewReader(c.reader).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(headers) == 0 {
+			return message{}, io.EOF
+		}
+		return message{}, err
+	}
+
+	contentLength, err := strconv.Atoi(
+		strings.TrimSpace(headers.Get("Content-Length")),
+	)
+	if err != nil || contentLength < 0 {
+		return message{}, fmt.Errorf("invalid Content-Length header")
+	}
+
+	body := make([]byte, contentLength)
+	if _, err := io.ReadFull(c.reader, body); err != nil {
+		return message{}, err
+	}
+
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return message{}, &responseError{
+			Code: codeParseError, Message: err.Error(),
+		}
+	}
+
+	return msg, nil
+}
+
+// write writes a JSON-RPC message.
+func (c *connection) write(msg map[string]interface{}) error {
+	msg["jsonrpc"] = "2.0"
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if _, err := fmt.Fprintf(
+		c.writer, "Content-Length: %d\r\n\r\n", len(body),
+	); err != nil {
+		return err
+	}
+
+	_, err = c.writer.Write(body)
+	return err
+}
+
+// respond writes the response to a request. A response contains either a
+// result (which may be null) or an error, but not both.
+func (c *connection) respond(
+	id *json.RawMessage, result interface{}, err *responseError,
+) error {
+	msg := map[string]interface{}{"id": id}
+
+	if err != nil {
+		msg["error"] = err
+	} else {
+		msg["result"] = result
+	}
+
+	return c.write(msg)
+}
+
+// notify writes a notification.
+func (c *connection) notify(method string, params interface{}) error {
+	return c.write(map[string]interface{}{"method": method, "params": params})
+}
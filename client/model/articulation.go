@@ -0,0 +1,127 @@
+package model
+
+import (
+	"fmt"
+	"math"
+)
+
+// An Articulation changes how a single note is played, e.g. shorter or louder
+// than usual.
+type Articulation int
+
+const (
+	// Staccato makes a note sound for half as long as usual.
+	Staccato Articulation = iota
+	// Accent makes a note louder.
+	Accent
+	// Tenuto makes a note sound for its full length, like a slur.
+	Tenuto
+	// Marcato makes a note much louder and a little shorter.
+	Marcato
+	// Fermata holds a note for twice as long as its written duration.
+	Fermata
+)
+
+// Articulations returns all of the articulations.
+func Articulations() []Articulation {
+	return []Articulation{Staccato, Accent, Tenuto, Marcato, Fermata}
+}
+
+// How articulations change the audible duration, volume and duration of notes.
+const (
+	staccatoLength  = 0.5
+	marcatoLength   = 0.75
+	accentVolume    = 0.1
+	marcatoVolume   = 0.2
+	fermataDuration = 2
+)
+
+func (articulation Articulation) String() string {
+	switch articulation {
+	case Staccato:
+		return "staccato"
+	case Accent:
+		return "accent"
+	case Tenuto:
+		return "tenuto"
+	case Marcato:
+		return "marcato"
+	case Fermata:
+		return "fermata"
+	default:
+		return fmt.Sprintf("%d (unknown)", articulation)
+	}
+}
+
+// Symbol returns the character that follows `^` to indicate the articulation in
+// Alda code, e.g. `c4^.` for a staccato quarter note.
+func (articulation Articulation) Symbol() rune {
+	switch articulation {
+	case Staccato:
+		return '.'
+	case Accent:
+		return '>'
+	case Tenuto:
+		return '_'
+	case Marcato:
+		return '^'
+	case Fermata:
+		return '~'
+	default:
+		return '?'
+	}
+}
+
+func (note Note) hasArticulation(articulation Articulation) bool {
+	for _, a := range note.Articulations {
+		if a == articulation {
+			return true
+		}
+	}
+
+	return false
+}
+
+// heldDurationMs returns the length of time that the note takes up, given its
+// written duration. This is only different when the note has a fermata.
+func (note Note) heldDurationMs(durationMs float64) float64 {
+	if note.hasArticulation(Fermata) {
+		return durationMs * fermataDuration
+	}
+
+	return durationMs
+}
+
+// audibleDurationMs returns the length of time that the note is audible, given
+// the length of time that it takes up.
+func (note Note) audibleDurationMs(
+	durationMs float64, quantization float64,
+) float64 {
+	audibleDurationMs := durationMs
+	if !note.Slurred && !note.hasArticulation(Tenuto) {
+		audibleDurationMs *= quantization
+	}
+
+	if note.hasArticulation(Staccato) {
+		audibleDurationMs *= staccatoLength
+	}
+
+	if note.hasArticulation(Marcato) {
+		audibleDurationMs *= marcatoLength
+	}
+
+	return audibleDurationMs
+}
+
+// volume returns the volume of the note, given the volume of the part.
+func (note Note) volume(volume float64) float64 {
+	if note.hasArticulation(Accent) {
+		volume += accentVolume
+	}
+
+	if note.hasArticulation(Marcato) {
+		volume += marcatoVolume
+	}
+
+	return math.Min(1, volume)
+}
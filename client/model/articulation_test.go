@@ -0,0 +1,118 @@
+package model
+
+import (
+	"testing"
+
+	_ "alda.io/client/testing"
+)
+
+func articulatedNote(articulations ...Articulation) Note {
+	return Note{
+		Pitch:         LetterAndAccidentals{NoteLetter: C},
+		Articulations: articulations,
+	}
+}
+
+func TestArticulations(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	c := noteWithLength(NoteLength{Denominator: 4})
+	mf := DynamicVolumes["mf"]
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "staccato",
+			updates: []ScoreUpdate{
+				piano, c, articulatedNote(Staccato), c,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 500, 1000),
+				expectNoteAudibleDurations(450, 225, 450),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "tenuto",
+			updates: []ScoreUpdate{
+				piano, articulatedNote(Tenuto), c,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteAudibleDurations(500, 450),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "slurred staccato",
+			updates: []ScoreUpdate{
+				piano,
+				Note{
+					Pitch:         LetterAndAccidentals{NoteLetter: C},
+					Slurred:       true,
+					Articulations: []Articulation{Staccato},
+				},
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteAudibleDurations(250),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "accent and marcato",
+			updates: []ScoreUpdate{
+				piano,
+				articulatedNote(Accent),
+				articulatedNote(Marcato),
+				c,
+				sexp(sym("vol"), num(95)),
+				articulatedNote(Accent),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteVolumes(mf+0.1, mf+0.2, mf, 1),
+				expectNoteAudibleDurations(450, 337.5, 450, 450),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "fermata",
+			updates: []ScoreUpdate{
+				piano, articulatedNote(Fermata), c,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 1000),
+				expectNoteDurations(1000, 500),
+				expectNoteAudibleDurations(900, 450),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "fermata on a chord",
+			updates: []ScoreUpdate{
+				piano,
+				Chord{
+					Events: []ScoreUpdate{
+						articulatedNote(Fermata),
+						Note{
+							Pitch:         LetterAndAccidentals{NoteLetter: E},
+							Articulations: []Articulation{Fermata},
+						},
+					},
+				},
+				c,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 0, 1000),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "fermata in a cram",
+			updates: []ScoreUpdate{
+				piano,
+				Cram{
+					Events: []ScoreUpdate{c, articulatedNote(Fermata), c},
+					Duration: Duration{
+						Components: []DurationComponent{NoteLength{Denominator: 1}},
+					},
+				},
+				c,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 500, 1500, 2000),
+			},
+		},
+	)
+}
@@ -209,7 +209,16 @@ func (gau GlobalAttributeUpdate) UpdateScore(score *Score) error {
 		}
 	}
 
-	score.GlobalAttributes.Record(offset, gau.PartUpdate)
+	update := gau.PartUpdate
+
+	// A global ramp starts at the offset where it's placed, even for parts that
+	// are in the middle of a note at that point and pick up the update later.
+	if ramp, isRamp := update.(AttributeRamp); isRamp {
+		ramp.globalOffset = offset
+		update = ramp
+	}
+
+	score.GlobalAttributes.Record(offset, update)
 
 	return nil
 }
@@ -238,6 +247,7 @@ func (ts TempoSet) JSON() *json.Container {
 }
 
 func (ts TempoSet) updatePart(part *Part, globalUpdate bool) error {
+	part.cancelRamp(RampTempo)
 	part.Tempo = ts.Tempo
 
 	// Global updates are recorded separately, and we would end up getting
@@ -266,6 +276,7 @@ func (mm MetricModulation) JSON() *json.Container {
 }
 
 func (mm MetricModulation) updatePart(part *Part, globalUpdate bool) error {
+	part.cancelRamp(RampTempo)
 	part.Tempo *= mm.Ratio
 
 	// Global updates are recorded separately, and we would end up getting
@@ -334,6 +345,7 @@ func (vs VolumeSet) JSON() *json.Container {
 }
 
 func (vs VolumeSet) updatePart(part *Part, globalUpdate bool) error {
+	part.cancelRamp(RampVolume)
 	part.Volume = vs.Volume
 
 	return nil
@@ -350,6 +362,7 @@ func (tvs TrackVolumeSet) JSON() *json.Container {
 }
 
 func (tvs TrackVolumeSet) updatePart(part *Part, globalUpdate bool) error {
+	part.cancelRamp(RampTrackVolume)
 	part.TrackVolume = tvs.TrackVolume
 
 	return nil
@@ -391,6 +404,7 @@ func (dm DynamicMarking) JSON() *json.Container {
 }
 
 func (dm DynamicMarking) updatePart(part *Part, globalUpdate bool) error {
+	part.cancelRamp(RampVolume)
 	part.Volume = DynamicVolumes[dm.Marking]
 
 	return nil
@@ -407,6 +421,7 @@ func (ps PanningSet) JSON() *json.Container {
 }
 
 func (ps PanningSet) updatePart(part *Part, globalUpdate bool) error {
+	part.cancelRamp(RampPanning)
 	part.Panning = ps.Panning
 
 	return nil
@@ -447,20 +462,62 @@ func (ds DurationSet) updatePart(part *Part, globalUpdate bool) error {
 }
 
 // KeySignatureSet sets the key signature of all active parts.
+//
+// When the key signature is set from a scale (e.g. A minor), the tonic of the
+// scale is set too. Otherwise, the tonic is implied by the key signature.
 type KeySignatureSet struct {
 	KeySignature KeySignature
+	Tonic        *LetterAndAccidentals
 }
 
 // JSON implements RepresentableAsJSON.JSON.
 func (kss KeySignatureSet) JSON() *json.Container {
-	return json.Object(
+	object := json.Object(
 		"attribute", "key-signature",
 		"value", kss.KeySignature.JSON(),
 	)
+
+	if kss.Tonic != nil {
+		object.Set(kss.Tonic.JSON(), "tonic")
+	}
+
+	return object
 }
 
 func (kss KeySignatureSet) updatePart(part *Part, globalUpdate bool) error {
 	part.KeySignature = kss.KeySignature
+	part.Tonic = kss.Tonic
+
+	return nil
+}
+
+// TimeSignatureSet sets the time signature of all active parts.
+//
+// Setting the time signature starts a new measure. If a pickup is specified,
+// the first measure is expected to be `Pickup` beats long instead of a full
+// measure.
+type TimeSignatureSet struct {
+	TimeSignature TimeSignature
+	// The length of the pickup measure (anacrusis) in beats, or 0 if there is no
+	// pickup measure.
+	Pickup float64
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (tss TimeSignatureSet) JSON() *json.Container {
+	value := tss.TimeSignature.JSON()
+	if tss.Pickup > 0 {
+		value.Set(tss.Pickup, "pickup")
+	}
+
+	return json.Object("attribute", "time-signature", "value", value)
+}
+
+func (tss TimeSignatureSet) updatePart(part *Part, globalUpdate bool) error {
+	part.TimeSignature = tss.TimeSignature
+	part.TimeSignatureValues[part.CurrentOffset] = tss.TimeSignature
+	part.beatsSinceBarline = 0
+	part.pickupBeats = tss.Pickup
 
 	return nil
 }
@@ -164,6 +164,20 @@ func expectPartLastOffset(
 	)
 }
 
+func expectPartCurrentBeat(
+	instrument string, expected float64,
+) func(s *Score) error {
+	return expectPart(instrument, func(part *Part) error {
+		if !equalish(expected, part.CurrentBeat) {
+			return fmt.Errorf(
+				"expected current beat to be %f, got %f", expected, part.CurrentBeat,
+			)
+		}
+
+		return nil
+	})
+}
+
 func expectPartDurationBeats(
 	instrument string, expected float64,
 ) func(s *Score) error {
@@ -6,6 +6,9 @@ import (
 
 // A Barline has no audible effect on a score. Its purpose is to visually
 // separate elements in an Alda source file.
+//
+// When a part has a time signature, a barline also checks that the notes since
+// the previous barline fill exactly one measure.
 type Barline struct {
 	SourceContext AldaSourceContext
 }
@@ -40,9 +43,18 @@ func (Barline) Ms(tempo float64) float64 {
 	return 0
 }
 
-// UpdateScore implements ScoreUpdate.UpdateScore by doing nothing. The purpose
-// of a barline is to visually separate elements in an Alda source file.
+// UpdateScore implements ScoreUpdate.UpdateScore by checking that the measure
+// that ends at the barline is complete, for each current part that has a time
+// signature.
 func (Barline) UpdateScore(score *Score) error {
+	for _, part := range score.CurrentParts {
+		score.observeBarline(part)
+
+		if err := part.checkMeasure(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -66,6 +66,9 @@ func (chord Chord) UpdateScore(score *Score) error {
 		for _, part := range score.CurrentParts {
 			duration := effectiveDuration(specifiedDuration, part)
 			durationMs := duration.Ms(part.Tempo) * part.TimeScale
+			if note, isNote := event.(Note); isNote {
+				durationMs = note.heldDurationMs(durationMs)
+			}
 			shortestDurationMs[part] = math.Min(shortestDurationMs[part], durationMs)
 		}
 
@@ -80,6 +83,8 @@ func (chord Chord) UpdateScore(score *Score) error {
 	for _, part := range score.CurrentParts {
 		part.LastOffset = part.CurrentOffset
 		part.CurrentOffset += shortestDurationMs[part]
+		part.countBeats(shortestDurationMs[part])
+		score.observeAdvance(part)
 	}
 
 	return nil
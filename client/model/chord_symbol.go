@@ -0,0 +1,360 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"alda.io/client/json"
+)
+
+// The notes in each quality of chord, as numbers of semitones above the root.
+var chordQualities = map[string][]int32{
+	"":      {0, 4, 7},
+	"maj":   {0, 4, 7},
+	"M":     {0, 4, 7},
+	"m":     {0, 3, 7},
+	"min":   {0, 3, 7},
+	"-":     {0, 3, 7},
+	"dim":   {0, 3, 6},
+	"o":     {0, 3, 6},
+	"°":     {0, 3, 6},
+	"aug":   {0, 4, 8},
+	"+":     {0, 4, 8},
+	"5":     {0, 7},
+	"sus2":  {0, 2, 7},
+	"sus":   {0, 5, 7},
+	"sus4":  {0, 5, 7},
+	"6":     {0, 4, 7, 9},
+	"m6":    {0, 3, 7, 9},
+	"7":     {0, 4, 7, 10},
+	"maj7":  {0, 4, 7, 11},
+	"M7":    {0, 4, 7, 11},
+	"Δ7":    {0, 4, 7, 11},
+	"m7":    {0, 3, 7, 10},
+	"min7":  {0, 3, 7, 10},
+	"-7":    {0, 3, 7, 10},
+	"mmaj7": {0, 3, 7, 11},
+	"mM7":   {0, 3, 7, 11},
+	"dim7":  {0, 3, 6, 9},
+	"o7":    {0, 3, 6, 9},
+	"°7":    {0, 3, 6, 9},
+	"m7b5":  {0, 3, 6, 10},
+	"ø":     {0, 3, 6, 10},
+	"ø7":    {0, 3, 6, 10},
+	"aug7":  {0, 4, 8, 10},
+	"+7":    {0, 4, 8, 10},
+	"7sus4": {0, 5, 7, 10},
+	"add9":  {0, 4, 7, 14},
+	"9":     {0, 4, 7, 10, 14},
+	"maj9":  {0, 4, 7, 11, 14},
+	"m9":    {0, 3, 7, 10, 14},
+	"11":    {0, 4, 7, 10, 14, 17},
+	"m11":   {0, 3, 7, 10, 14, 17},
+	"13":    {0, 4, 7, 10, 14, 21},
+	"maj13": {0, 4, 7, 11, 14, 21},
+	"m13":   {0, 3, 7, 10, 14, 21},
+}
+
+// The Roman numerals for the degrees of a scale, longest first so that e.g.
+// "IV" is matched before "I".
+var romanNumerals = []struct {
+	numeral string
+	degree  int32
+}{
+	{"VII", 7}, {"III", 3}, {"II", 2}, {"IV", 4}, {"VI", 6}, {"I", 1}, {"V", 5},
+}
+
+// A Voicing is a way of arranging the notes of a chord.
+type Voicing int
+
+const (
+	// CloseVoicing stacks the notes of a chord as closely together as possible.
+	CloseVoicing Voicing = iota
+	// Drop2Voicing drops the second-highest note of a chord by an octave.
+	Drop2Voicing
+	// SpreadVoicing raises every other note of a chord by an octave, starting
+	// from the second-lowest note.
+	SpreadVoicing
+)
+
+// NewVoicing returns the Voicing with the provided name, e.g. "drop-2".
+func NewVoicing(name string) (Voicing, error) {
+	switch name {
+	case "close":
+		return CloseVoicing, nil
+	case "drop-2":
+		return Drop2Voicing, nil
+	case "spread":
+		return SpreadVoicing, nil
+	default:
+		return 0, fmt.Errorf(
+			"invalid voicing: %q (expected close, drop-2 or spread)", name,
+		)
+	}
+}
+
+// A ChordSymbol describes a chord by its root and quality, e.g. Cmaj7 or ii7.
+type ChordSymbol struct {
+	// The root of the chord, which is either a LetterAndAccidentals or a
+	// ScaleDegree.
+	Root PitchIdentifier
+	// The notes of the chord, as numbers of semitones above the root. The bass
+	// note of a slash chord can be below the root.
+	Semitones []int32
+}
+
+// ParseChordSymbol parses a chord symbol, e.g. "Cmaj7/G".
+//
+// When `inKey` is true, the chord symbol is a Roman numeral, e.g. "ii7", whose
+// root is a degree of the scale of the part that plays it. Uppercase numerals
+// are major chords and lowercase numerals are minor chords.
+func ParseChordSymbol(symbol string, inKey bool) (ChordSymbol, error) {
+	if inKey {
+		return parseRomanNumeralChord(symbol)
+	}
+
+	root, rest, ok := parseChordRoot(symbol)
+	if !ok {
+		if _, err := parseRomanNumeralChord(symbol); err == nil {
+			return ChordSymbol{}, fmt.Errorf(
+				"invalid chord symbol: %q (use :in-key for Roman numerals)", symbol,
+			)
+		}
+
+		return ChordSymbol{}, fmt.Errorf("invalid chord symbol: %q", symbol)
+	}
+
+	quality, bass, hasBass := strings.Cut(rest, "/")
+
+	semitones, hit := chordQualities[quality]
+	if !hit {
+		return ChordSymbol{}, fmt.Errorf(
+			"invalid chord symbol: %q (unknown chord quality %q)", symbol, quality,
+		)
+	}
+
+	chord := ChordSymbol{Root: root, Semitones: append([]int32{}, semitones...)}
+
+	if hasBass {
+		bassNote, rest, ok := parseChordRoot(bass)
+		if !ok || rest != "" {
+			return ChordSymbol{}, fmt.Errorf(
+				"invalid chord symbol: %q (invalid bass note %q)", symbol, bass,
+			)
+		}
+
+		chord.Semitones = withBass(
+			chord.Semitones, pitchClass(bassNote)-pitchClass(root),
+		)
+	}
+
+	return chord, nil
+}
+
+// parseChordRoot parses the root of a chord symbol, e.g. "Bb" in "Bbm7", and
+// returns the rest of the chord symbol.
+//
+// The root always has explicit accidentals, so that it doesn't change
+// depending on the key signature.
+func parseChordRoot(symbol string) (LetterAndAccidentals, string, bool) {
+	if symbol == "" || symbol[0] < 'A' || symbol[0] > 'G' {
+		return LetterAndAccidentals{}, "", false
+	}
+
+	letter, _ := NewNoteLetter(rune(symbol[0] - 'A' + 'a'))
+	root := LetterAndAccidentals{NoteLetter: letter, Accidentals: []Accidental{}}
+
+	switch {
+	case strings.HasPrefix(symbol[1:], "#"):
+		root.Accidentals = append(root.Accidentals, Sharp)
+	case strings.HasPrefix(symbol[1:], "b"):
+		root.Accidentals = append(root.Accidentals, Flat)
+	}
+
+	return root, symbol[1+len(root.Accidentals):], true
+}
+
+func pitchClass(laa LetterAndAccidentals) int32 {
+	return laa.CalculateMidiNote(-1, KeySignature{}, 0)
+}
+
+// withBass returns the notes of a chord with the bass note of a slash chord,
+// `interval` semitones above the root, as the lowest note.
+//
+// If the bass note is in the chord, the chord is inverted so that the bass
+// note is the lowest note. Otherwise, the bass note is added below the root.
+func withBass(semitones []int32, interval int32) []int32 {
+	interval = (interval%12 + 12) % 12
+
+	for i, semitone := range semitones {
+		if semitone%12 == interval {
+			for j := 0; j < i; j++ {
+				semitones = invert(semitones)
+			}
+
+			return semitones
+		}
+	}
+
+	return append([]int32{interval - 12}, semitones...)
+}
+
+// invert moves the lowest note of a chord up by an octave.
+func invert(semitones []int32) []int32 {
+	inverted := append(append([]int32{}, semitones[1:]...), semitones[0]+12)
+	sortSemitones(inverted)
+	return inverted
+}
+
+func sortSemitones(semitones []int32) {
+	sort.Slice(semitones, func(i, j int) bool {
+		return semitones[i] < semitones[j]
+	})
+}
+
+func parseRomanNumeralChord(symbol string) (ChordSymbol, error) {
+	root := ScaleDegree{}
+	rest := symbol
+
+	switch {
+	case strings.HasPrefix(rest, "b"):
+		root.Accidentals = []Accidental{Flat}
+		rest = rest[1:]
+	case strings.HasPrefix(rest, "#"):
+		root.Accidentals = []Accidental{Sharp}
+		rest = rest[1:]
+	}
+
+	minor := false
+	for _, rn := range romanNumerals {
+		if strings.HasPrefix(rest, rn.numeral) {
+			root.Degree = rn.degree
+		} else if strings.HasPrefix(rest, strings.ToLower(rn.numeral)) {
+			root.Degree = rn.degree
+			minor = true
+		} else {
+			continue
+		}
+
+		rest = rest[len(rn.numeral):]
+		break
+	}
+
+	if root.Degree == 0 {
+		return ChordSymbol{}, fmt.Errorf(
+			"invalid chord symbol: %q (expected a Roman numeral, e.g. ii7)", symbol,
+		)
+	}
+
+	if strings.Contains(rest, "/") {
+		return ChordSymbol{}, fmt.Errorf(
+			"invalid chord symbol: %q (slash chords must use note letters)", symbol,
+		)
+	}
+
+	// A lowercase numeral makes the chord minor, unless the quality says
+	// otherwise, e.g. vii° (diminished).
+	quality := rest
+	if minor && !strings.HasPrefix(quality, "o") &&
+		!strings.HasPrefix(quality, "°") && !strings.HasPrefix(quality, "ø") &&
+		!strings.HasPrefix(quality, "dim") {
+		quality = "m" + quality
+	}
+
+	semitones, hit := chordQualities[quality]
+	if !hit {
+		return ChordSymbol{}, fmt.Errorf(
+			"invalid chord symbol: %q (unknown chord quality %q)", symbol, rest,
+		)
+	}
+
+	return ChordSymbol{Root: root, Semitones: append([]int32{}, semitones...)}, nil
+}
+
+// Voiced returns the notes of the chord, as numbers of semitones above the
+// root, after inverting the chord `inversion` times, arranging the notes
+// according to the voicing, and shifting them by `octaves` octaves.
+func (cs ChordSymbol) Voiced(
+	inversion int32, voicing Voicing, octaves int32,
+) ([]int32, error) {
+	semitones := append([]int32{}, cs.Semitones...)
+
+	if inversion < 0 || int(inversion) >= len(semitones) {
+		return nil, fmt.Errorf(
+			"invalid inversion %d for a chord with %d notes",
+			inversion, len(semitones),
+		)
+	}
+
+	for i := int32(0); i < inversion; i++ {
+		semitones = invert(semitones)
+	}
+
+	switch voicing {
+	case Drop2Voicing:
+		if len(semitones) > 1 {
+			semitones[len(semitones)-2] -= 12
+		}
+	case SpreadVoicing:
+		for i := 1; i < len(semitones); i += 2 {
+			semitones[i] += 12
+		}
+	}
+
+	for i := range semitones {
+		semitones[i] += octaves * 12
+	}
+
+	sortSemitones(semitones)
+
+	return semitones, nil
+}
+
+// Chord returns a chord made up of the provided notes (as numbers of semitones
+// above the root), each of which has the provided duration.
+func (cs ChordSymbol) Chord(semitones []int32, duration Duration) Chord {
+	chord := Chord{}
+
+	for _, semitone := range semitones {
+		chord.Events = append(chord.Events, Note{
+			Pitch:    ChordTone{Root: cs.Root, Semitones: semitone},
+			Duration: duration,
+		})
+	}
+
+	return chord
+}
+
+// A ChordTone specifies a pitch as a number of semitones above (or below) the
+// root of a chord.
+//
+// The root can be any pitch, so a ChordTone also describes a pitch that is an
+// interval away from another pitch, e.g. (interval "M3" (degree 1)).
+type ChordTone struct {
+	Root      PitchIdentifier
+	Semitones int32
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (ct ChordTone) JSON() *json.Container {
+	return json.Object("root", ct.Root.JSON(), "semitones", ct.Semitones)
+}
+
+// CalculateMidiNote implements PitchIdentifier.CalculateMidiNote by placing the
+// root of the chord in the given octave and adding the number of semitones.
+func (ct ChordTone) CalculateMidiNote(
+	octave int32, keySignature KeySignature, transposition int32,
+) int32 {
+	return ct.Root.CalculateMidiNote(octave, keySignature, transposition) +
+		ct.Semitones
+}
+
+func (ct ChordTone) midiNoteInPart(part *Part) (int32, error) {
+	root, err := part.MidiNote(ct.Root)
+	if err != nil {
+		return 0, err
+	}
+
+	return root + ct.Semitones, nil
+}
@@ -0,0 +1,216 @@
+package model
+
+import (
+	"testing"
+
+	_ "alda.io/client/testing"
+)
+
+func TestChordSymbols(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	str := func(value string) LispString { return LispString{Value: value} }
+	key := func(tonic NoteLetter, accidentals ...Accidental) AttributeUpdate {
+		return AttributeUpdate{PartUpdate: KeySignatureSet{
+			KeySignature: KeySignatureFromScale(
+				LetterAndAccidentals{NoteLetter: tonic, Accidentals: accidentals},
+				Ionian,
+			),
+		}}
+	}
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label:   "major seventh chord",
+			updates: []ScoreUpdate{piano, sexp(sym("chord"), str("Cmaj7"))},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(60, 64, 67, 71),
+				expectNoteOffsets(0, 0, 0, 0),
+			},
+		},
+		scoreUpdateTestCase{
+			label:   "root with an accidental",
+			updates: []ScoreUpdate{piano, sexp(sym("chord"), str("Bbm7"))},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(70, 73, 77, 80),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "the key signature doesn't change the root",
+			updates: []ScoreUpdate{
+				piano, key(B, Flat), sexp(sym("chord"), str("B")),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(71, 75, 78),
+			},
+		},
+		scoreUpdateTestCase{
+			label:   "slash chord with a chord tone in the bass",
+			updates: []ScoreUpdate{piano, sexp(sym("chord"), str("Cmaj7/G"))},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(67, 71, 72, 76),
+			},
+		},
+		scoreUpdateTestCase{
+			label:   "slash chord with another bass note",
+			updates: []ScoreUpdate{piano, sexp(sym("chord"), str("C/Bb"))},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(58, 60, 64, 67),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "Roman numerals in a major key",
+			updates: []ScoreUpdate{
+				piano,
+				key(G),
+				sexp(sym("chord"), str("ii7"), sym(":in-key")),
+				sexp(sym("chord"), str("V7"), sym(":in-key")),
+				sexp(sym("chord"), str("viio"), sym(":in-key")),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(
+					69, 72, 76, 79,
+					74, 78, 81, 84,
+					78, 81, 84,
+				),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "Roman numerals with accidentals",
+			updates: []ScoreUpdate{
+				piano,
+				key(F),
+				sexp(sym("chord"), str("bVII"), sym(":in-key")),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(75, 79, 82),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "Roman numerals in the relative minor key",
+			updates: []ScoreUpdate{
+				piano,
+				AttributeUpdate{PartUpdate: KeySignatureSet{
+					KeySignature: KeySignatureFromScale(
+						LetterAndAccidentals{NoteLetter: E}, Aeolian,
+					),
+				}},
+				sexp(sym("chord"), str("vi"), sym(":in-key")),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(76, 79, 83),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "inversion",
+			updates: []ScoreUpdate{
+				piano, sexp(sym("chord"), str("C"), sym(":inversion"), num(1)),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(64, 67, 72),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "drop-2 voicing",
+			updates: []ScoreUpdate{
+				piano, sexp(sym("chord"), str("Cmaj7"), sym(":voicing"), str("drop-2")),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(55, 60, 64, 71),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "spread voicing an octave down",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(
+					sym("chord"), str("C"),
+					sym(":voicing"), str("spread"),
+					sym(":octave"), num(-1),
+				),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(48, 55, 64),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "duration and transposition",
+			updates: []ScoreUpdate{
+				piano,
+				AttributeUpdate{PartUpdate: TranspositionSet{Semitones: 2}},
+				sexp(
+					sym("chord"), str("Am"),
+					sym(":duration"), sexp(sym("note-length"), num(2)),
+				),
+				noteWithLength(),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(71, 74, 78, 62),
+				expectNoteOffsets(0, 0, 0, 1000),
+			},
+		},
+	)
+}
+
+func TestChordSymbolErrors(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	str := func(value string) LispString { return LispString{Value: value} }
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label:   "unknown chord quality",
+			updates: []ScoreUpdate{piano, sexp(sym("chord"), str("Cfoo"))},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage(`unknown chord quality "foo"`),
+			},
+		},
+		scoreUpdateTestCase{
+			label:   "Roman numeral without :in-key",
+			updates: []ScoreUpdate{piano, sexp(sym("chord"), str("ii7"))},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("use :in-key for Roman numerals"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "note letter with :in-key",
+			updates: []ScoreUpdate{
+				piano, sexp(sym("chord"), str("Cmaj7"), sym(":in-key")),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("expected a Roman numeral"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "inversion out of range",
+			updates: []ScoreUpdate{
+				piano, sexp(sym("chord"), str("C"), sym(":inversion"), num(3)),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("invalid inversion 3 for a chord with 3 notes"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "unknown voicing",
+			updates: []ScoreUpdate{
+				piano, sexp(sym("chord"), str("C"), sym(":voicing"), str("wide")),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage(`invalid voicing: "wide"`),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "Roman numeral in an unconventional key",
+			updates: []ScoreUpdate{
+				piano,
+				AttributeUpdate{PartUpdate: KeySignatureSet{
+					KeySignature: KeySignature{B: {Flat}, G: {Sharp}},
+				}},
+				sexp(sym("chord"), str("I"), sym(":in-key")),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("doesn't imply a tonic"),
+			},
+		},
+	)
+}
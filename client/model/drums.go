@@ -0,0 +1,141 @@
+package model
+
+import (
+	"fmt"
+
+	"alda.io/client/help"
+	"alda.io/client/json"
+)
+
+// The percussion instruments in the General MIDI drum map, by the MIDI note
+// number that plays each one on a percussion channel.
+//
+// Reference: https://www.midi.org/specifications-old/item/gm-level-1-sound-set
+var generalMidiDrums = []struct {
+	midiNote int32
+	name     string
+	aliases  []string
+}{
+	{35, "acoustic-kick", []string{"acoustic-bass-drum"}},
+	{36, "kick", []string{"bass-drum"}},
+	{37, "side-stick", []string{"rimshot"}},
+	{38, "snare", []string{"acoustic-snare"}},
+	{39, "clap", []string{"hand-clap"}},
+	{40, "electric-snare", nil},
+	{41, "floor-tom-low", nil},
+	{42, "hh-closed", []string{"hh", "hi-hat"}},
+	{43, "floor-tom", []string{"floor-tom-high"}},
+	{44, "hh-pedal", nil},
+	{45, "tom-low", nil},
+	{46, "hh-open", nil},
+	{47, "tom-low-mid", nil},
+	{48, "tom-high-mid", nil},
+	{49, "crash", nil},
+	{50, "tom-high", nil},
+	{51, "ride", nil},
+	{52, "china", nil},
+	{53, "ride-bell", nil},
+	{54, "tambourine", nil},
+	{55, "splash", nil},
+	{56, "cowbell", nil},
+	{57, "crash-alt", nil},
+	{58, "vibraslap", nil},
+	{59, "ride-alt", nil},
+	{60, "bongo-high", nil},
+	{61, "bongo-low", nil},
+	{62, "conga-mute", nil},
+	{63, "conga-high", nil},
+	{64, "conga-low", nil},
+	{65, "timbale-high", nil},
+	{66, "timbale-low", nil},
+	{67, "agogo-high", nil},
+	{68, "agogo-low", nil},
+	{69, "cabasa", nil},
+	{70, "maracas", nil},
+	{71, "whistle-short", nil},
+	{72, "whistle-long", nil},
+	{73, "guiro-short", nil},
+	{74, "guiro-long", nil},
+	{75, "claves", nil},
+	{76, "wood-block-high", nil},
+	{77, "wood-block-low", nil},
+	{78, "cuica-mute", nil},
+	{79, "cuica-open", nil},
+	{80, "triangle-mute", nil},
+	{81, "triangle-open", nil},
+}
+
+// drumMidiNotes maps each drum name and alias to a MIDI note number.
+var drumMidiNotes = map[string]int32{}
+
+// drumNames maps each MIDI note number in the drum map to the drum's name.
+var drumNames = map[int32]string{}
+
+func init() {
+	for _, drum := range generalMidiDrums {
+		drumMidiNotes[drum.name] = drum.midiNote
+		drumNames[drum.midiNote] = drum.name
+		for _, alias := range drum.aliases {
+			drumMidiNotes[alias] = drum.midiNote
+		}
+	}
+}
+
+// IsDrumName returns true if `name` is the name (or an alias) of a percussion
+// instrument in the General MIDI drum map.
+func IsDrumName(name string) bool {
+	_, hit := drumMidiNotes[name]
+	return hit
+}
+
+// DrumForMidiNote returns the drum that a MIDI note number plays on a
+// percussion channel, if there is one.
+func DrumForMidiNote(midiNote int32) (DrumName, bool) {
+	name, hit := drumNames[midiNote]
+	return DrumName{Name: name}, hit
+}
+
+// DrumName specifies the pitch of a note in a percussion part as the name of a
+// percussion instrument in the General MIDI drum map, e.g. "snare".
+type DrumName struct {
+	Name string
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (dn DrumName) JSON() *json.Container {
+	return json.Object("drum", dn.Name)
+}
+
+// CalculateMidiNote implements PitchIdentifier.CalculateMidiNote by looking up
+// the drum in the General MIDI drum map. The octave, key signature and
+// transposition don't apply to drums.
+//
+// Returns -1 (an invalid MIDI note) if the drum name is unknown.
+func (dn DrumName) CalculateMidiNote(
+	octave int32, keySignature KeySignature, transposition int32,
+) int32 {
+	midiNote, hit := drumMidiNotes[dn.Name]
+	if !hit {
+		return -1
+	}
+
+	return midiNote
+}
+
+func (dn DrumName) midiNoteInPart(part *Part) (int32, error) {
+	if !IsDrumName(dn.Name) {
+		return 0, fmt.Errorf("unknown drum: %s", dn.Name)
+	}
+
+	// A drum's MIDI note would play a pitch instead in other parts.
+	//
+	// TODO: Update this type assertion if/when we add non-MIDI instruments.
+	if !part.StockInstrument.(MidiInstrument).IsPercussion {
+		return 0, help.UserFacingErrorf(
+			`Can't play drum %s in part "%s", which isn't a percussion part.`,
+			dn.Name, part.Name,
+		)
+	}
+
+	return drumMidiNotes[dn.Name], nil
+}
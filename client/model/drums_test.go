@@ -0,0 +1,85 @@
+package model
+
+import (
+	"testing"
+
+	_ "alda.io/client/testing"
+)
+
+func TestDrumNames(t *testing.T) {
+	percussion := PartDeclaration{Names: []string{"midi-percussion"}}
+	drum := func(name string) Note { return Note{Pitch: DrumName{Name: name}} }
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "drums play their General MIDI notes",
+			updates: []ScoreUpdate{
+				percussion,
+				drum("kick"), drum("snare"), drum("hh-closed"), drum("ride"),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(36, 38, 42, 51),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "aliases",
+			updates: []ScoreUpdate{
+				percussion, drum("bass-drum"), drum("hh"), drum("rimshot"),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(36, 42, 37),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "octave, key and transposition don't apply to drums",
+			updates: []ScoreUpdate{
+				percussion,
+				AttributeUpdate{PartUpdate: OctaveSet{OctaveNumber: 2}},
+				AttributeUpdate{PartUpdate: TranspositionSet{Semitones: 5}},
+				drum("kick"),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(36),
+			},
+		},
+	)
+}
+
+func TestDrumNameErrors(t *testing.T) {
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "drum in a part that isn't a percussion part",
+			updates: []ScoreUpdate{
+				PartDeclaration{Names: []string{"piano"}},
+				Note{Pitch: DrumName{Name: "snare"}},
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage(
+					`Can't play drum snare in part "piano", which isn't a percussion part.`,
+				),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "unknown drum",
+			updates: []ScoreUpdate{
+				PartDeclaration{Names: []string{"midi-percussion"}},
+				Note{Pitch: DrumName{Name: "bongo"}},
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("unknown drum: bongo"),
+			},
+		},
+	)
+}
+
+func TestDrumForMidiNote(t *testing.T) {
+	if drum, ok := DrumForMidiNote(38); !ok || drum.Name != "snare" {
+		t.Errorf("expected MIDI note 38 to be a snare, got %v", drum)
+	}
+
+	if _, ok := DrumForMidiNote(100); ok {
+		t.Errorf("expected MIDI note 100 not to be a drum")
+	}
+}
@@ -33,7 +33,12 @@ func (es EventSequence) JSON() *json.Container {
 // UpdateScore implements ScoreUpdate.UpdateScore by updating the score with
 // each event in the sequence, in order.
 func (es EventSequence) UpdateScore(score *Score) error {
-	return score.Update(es.Events...)
+	// An event sequence can be the captured value of a variable reference (see
+	// VariableReference.VariableValue), so we include it in the chain of source
+	// contexts of the resulting events.
+	return score.withSourceContext(es.SourceContext, func() error {
+		return score.Update(es.Events...)
+	})
 }
 
 // DurationMs implements ScoreUpdate.DurationMs by returning the total duration
@@ -0,0 +1,350 @@
+package model
+
+import (
+	encjson "encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"alda.io/client/json"
+)
+
+// Swing delays every other note of a subdivision (e.g. every other eighth note)
+// so that the first note of each pair is longer than the second.
+type Swing struct {
+	// The length of the first note of each pair relative to the second, e.g. 2
+	// for "triplet" swing. A ratio of 1 means no swing.
+	Ratio float64
+	// The note length that is swung, e.g. an eighth note.
+	Subdivision Duration
+}
+
+// IsSet returns true if the swing has any effect on the timing of notes.
+func (swing Swing) IsSet() bool {
+	return swing.Ratio > 0 && swing.Ratio != 1
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (swing Swing) JSON() *json.Container {
+	return json.Object(
+		"ratio", swing.Ratio,
+		"subdivision", swing.Subdivision.JSON(),
+	)
+}
+
+// shift returns the number of beats by which swing moves a point in time that
+// is `beat` beats into the part's grid.
+//
+// Each pair of subdivisions is warped so that its midpoint falls `Ratio` times
+// as far from the start of the pair as from the end. The start and end of each
+// pair stay where they are.
+func (swing Swing) shift(beat float64) float64 {
+	pair := 2 * swing.Subdivision.Beats()
+	half := pair / 2
+	position := math.Mod(beat, pair)
+	swungMidpoint := pair * swing.Ratio / (1 + swing.Ratio)
+
+	if position <= half {
+		return position/half*swungMidpoint - position
+	}
+
+	return swungMidpoint + (position-half)/half*(pair-swungMidpoint) - position
+}
+
+// SwingSet sets the swing of all active parts.
+type SwingSet struct {
+	Swing Swing
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (ss SwingSet) JSON() *json.Container {
+	return json.Object("attribute", "swing", "value", ss.Swing.JSON())
+}
+
+func (ss SwingSet) updatePart(part *Part, globalUpdate bool) error {
+	part.Swing = ss.Swing
+
+	return nil
+}
+
+// A GrooveTemplate is a named pattern of small timing and volume adjustments,
+// one per position of a grid (e.g. sixteenth notes), that repeats throughout a
+// part.
+type GrooveTemplate struct {
+	Name string
+	// The note length of each position in the grid.
+	Subdivision Duration
+	// For each position, how far to move a note that starts there, as a fraction
+	// of the subdivision. Negative values move the note earlier.
+	TimingOffsets []float64
+	// For each position, an amount (on a 0-1 scale) to add to the volume of a
+	// note that starts there.
+	VolumeOffsets []float64
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (groove GrooveTemplate) JSON() *json.Container {
+	return json.Object(
+		"name", groove.Name,
+		"subdivision", groove.Subdivision.JSON(),
+		"timing-offsets", groove.TimingOffsets,
+		"volume-offsets", groove.VolumeOffsets,
+	)
+}
+
+// The maximum distance in beats between a note and a position of a groove
+// template's grid for the note to be considered to be on that position.
+const grooveGridTolerance = 0.001
+
+// offsets returns the timing offset (in beats) and volume offset for a note
+// that starts `beat` beats into the part's grid. Notes that don't start on a
+// position of the grid (e.g. triplets in a sixteenth note groove) are left
+// alone.
+func (groove GrooveTemplate) offsets(beat float64) (float64, float64) {
+	subdivision := groove.Subdivision.Beats()
+	slot := math.Round(beat / subdivision)
+
+	if math.Abs(beat-slot*subdivision) > grooveGridTolerance {
+		return 0, 0
+	}
+
+	positions := len(groove.TimingOffsets)
+	if len(groove.VolumeOffsets) > positions {
+		positions = len(groove.VolumeOffsets)
+	}
+
+	position := int(slot) % positions
+
+	timing, volume := 0.0, 0.0
+	if position < len(groove.TimingOffsets) {
+		timing = groove.TimingOffsets[position] * subdivision
+	}
+	if position < len(groove.VolumeOffsets) {
+		volume = groove.VolumeOffsets[position]
+	}
+
+	return timing, volume
+}
+
+// The format of a groove template in a groove file, e.g.:
+//
+//	{
+//	  "mpc-16": {
+//	    "subdivision": 16,
+//	    "timing": [0, 12, 0, 18],
+//	    "velocity": [10, -15, 0, -10]
+//	  }
+//	}
+//
+// Timing offsets are percentages of the subdivision, and velocity offsets are
+// on the same 0-100 scale as `(volume ...)`. If one list is shorter than the
+// other, the missing offsets are 0.
+type grooveTemplateFile map[string]struct {
+	Subdivision int32     `json:"subdivision"`
+	Timing      []float64 `json:"timing"`
+	Velocity    []float64 `json:"velocity"`
+}
+
+// ReadGrooveTemplates reads the groove templates defined in a JSON file.
+func ReadGrooveTemplates(filename string) ([]GrooveTemplate, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var file grooveTemplateFile
+	if err := encjson.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid groove file %s: %s", filename, err)
+	}
+
+	templates := []GrooveTemplate{}
+
+	for name, template := range file {
+		invalid := func(format string, args ...interface{}) error {
+			return fmt.Errorf(
+				"invalid groove %q in %s: %s",
+				name, filename, fmt.Sprintf(format, args...),
+			)
+		}
+
+		if template.Subdivision < 1 {
+			return nil, invalid("expected a positive subdivision, e.g. 16")
+		}
+
+		if len(template.Timing) == 0 && len(template.Velocity) == 0 {
+			return nil, invalid("expected timing and/or velocity offsets")
+		}
+
+		groove := GrooveTemplate{
+			Name: name,
+			Subdivision: Duration{
+				Components: []DurationComponent{
+					NoteLength{Denominator: float64(template.Subdivision)},
+				},
+			},
+		}
+
+		for _, timing := range template.Timing {
+			if timing <= -100 || timing >= 100 {
+				return nil, invalid("timing offset not between -100 and 100: %f", timing)
+			}
+			groove.TimingOffsets = append(groove.TimingOffsets, timing/100)
+		}
+
+		for _, velocity := range template.Velocity {
+			if velocity < -100 || velocity > 100 {
+				return nil, invalid("velocity offset not between -100 and 100: %f", velocity)
+			}
+			groove.VolumeOffsets = append(groove.VolumeOffsets, velocity/100)
+		}
+
+		templates = append(templates, groove)
+	}
+
+	// Sort by name so that the order doesn't depend on map iteration.
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+
+	return templates, nil
+}
+
+// GrooveTemplates defines groove templates that have been read from a file, so
+// that parts can use them by name.
+type GrooveTemplates struct {
+	SourceContext AldaSourceContext
+	Filename      string
+	Templates     []GrooveTemplate
+}
+
+// GetSourceContext implements HasSourceContext.GetSourceContext.
+func (gt GrooveTemplates) GetSourceContext() AldaSourceContext {
+	return gt.SourceContext
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (gt GrooveTemplates) JSON() *json.Container {
+	templates := json.Array()
+	for _, template := range gt.Templates {
+		templates.ArrayAppend(template.JSON())
+	}
+
+	return json.Object(
+		"type", "groove-templates",
+		"value", json.Object("filename", gt.Filename, "templates", templates),
+	)
+}
+
+// UpdateScore implements ScoreUpdate.UpdateScore by defining the groove
+// templates in the score. A template replaces any existing template with the
+// same name.
+func (gt GrooveTemplates) UpdateScore(score *Score) error {
+	for _, template := range gt.Templates {
+		score.Grooves[template.Name] = template
+	}
+
+	return nil
+}
+
+// DurationMs implements ScoreUpdate.DurationMs by returning 0, since defining
+// groove templates doesn't take any time.
+func (GrooveTemplates) DurationMs(part *Part) float64 {
+	return 0
+}
+
+// VariableValue implements ScoreUpdate.VariableValue.
+func (gt GrooveTemplates) VariableValue(score *Score) (ScoreUpdate, error) {
+	return gt, nil
+}
+
+// GrooveSet sets the groove template of all active parts. An empty name means
+// no groove.
+type GrooveSet struct {
+	Name string
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (gs GrooveSet) JSON() *json.Container {
+	return json.Object("attribute", "groove", "value", gs.Name)
+}
+
+func (gs GrooveSet) updatePart(part *Part, globalUpdate bool) error {
+	if gs.Name == "" {
+		part.Groove = nil
+		return nil
+	}
+
+	groove, hit := part.score.Grooves[gs.Name]
+	if !hit {
+		return fmt.Errorf("undefined groove: %q", gs.Name)
+	}
+
+	part.Groove = &groove
+
+	return nil
+}
+
+// gridBeat returns the position of the part's current offset on its
+// swing/groove grid, in beats.
+//
+// The grid starts over at the beginning of each measure, so that e.g. the first
+// position of a groove template falls on the downbeat, no matter where the
+// swing or groove was set. Without a time signature, the grid starts at the
+// beginning of the score.
+func (part *Part) gridBeat() float64 {
+	if !part.TimeSignature.IsSet() {
+		return part.CurrentBeat
+	}
+
+	measure := part.TimeSignature.MeasureBeats()
+
+	// A pickup measure ends on the downbeat of the first full measure.
+	beat := part.beatsSinceBarline
+	if part.pickupBeats > 0 {
+		beat += measure - part.pickupBeats
+	}
+
+	position := math.Mod(beat, measure)
+	if measure-position < barCheckTolerance {
+		return 0
+	}
+
+	return position
+}
+
+// applySwingAndGroove adjusts the timing and volume of a note that starts at
+// the part's current offset, according to the part's swing and groove.
+//
+// Only the note itself is affected. The part's current offset, and therefore
+// the placement of subsequent events, stays the same.
+func (part *Part) applySwingAndGroove(note *NoteEvent) {
+	if !part.Swing.IsSet() && part.Groove == nil {
+		return
+	}
+
+	msPerBeat := 60000 / part.Tempo
+	startBeat := part.gridBeat()
+	endBeat := startBeat + note.Duration/msPerBeat
+
+	// Swing moves the start and the end of the note separately, so that the
+	// first note of a swung pair gets longer and the second note gets shorter.
+	startShift, endShift := 0.0, 0.0
+	if part.Swing.IsSet() {
+		startShift = part.Swing.shift(startBeat)
+		endShift = part.Swing.shift(endBeat)
+	}
+
+	// A groove moves the whole note.
+	if part.Groove != nil {
+		timing, volume := part.Groove.offsets(startBeat)
+		startShift += timing
+		endShift += timing
+		note.Volume = math.Max(0, math.Min(1, note.Volume+volume))
+	}
+
+	duration := note.Duration + (endShift-startShift)*msPerBeat
+	note.AudibleDuration *= duration / note.Duration
+	note.Duration = duration
+	note.Offset = math.Max(0, note.Offset+startShift*msPerBeat)
+}
@@ -0,0 +1,327 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "alda.io/client/testing"
+)
+
+// Writes a groove file to a temporary directory and returns its path.
+func grooveFile(t *testing.T, contents string) string {
+	filename := filepath.Join(t.TempDir(), "grooves.json")
+
+	if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+func TestSwing(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	eighth := noteWithLength(NoteLength{Denominator: 8})
+	quarter := noteWithLength(NoteLength{Denominator: 4})
+	sixteenth := noteWithLength(NoteLength{Denominator: 16})
+	str := func(value string) LispString { return LispString{Value: value} }
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "swung eighth notes",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("swing"), num(2)),
+				eighth, eighth, eighth, eighth,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 1000.0/3, 500, 500+1000.0/3),
+				expectNoteDurations(1000.0/3, 500.0/3, 1000.0/3, 500.0/3),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "notes on the beat aren't swung",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("swing"), num(2)),
+				quarter, quarter, quarter,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 500, 1000),
+				expectNoteDurations(500, 500, 500),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "swung sixteenth notes",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("swing"), num(3), str("16")),
+				sixteenth, sixteenth, quarter,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 187.5, 250),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "swing 1 is straight",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("swing"), num(2)),
+				sexp(sym("swing"), num(1)),
+				eighth, eighth,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 250),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "swing in a cram",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("swing"), num(2)),
+				Cram{
+					Events: []ScoreUpdate{
+						noteWithLength(), noteWithLength(),
+						noteWithLength(), noteWithLength(),
+					},
+					Duration: Duration{
+						Components: []DurationComponent{NoteLength{Denominator: 2}},
+					},
+				},
+				quarter,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 1000.0/3, 500, 500+1000.0/3, 1000),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "swing set off the beat is aligned to the beat",
+			updates: []ScoreUpdate{
+				piano,
+				eighth,
+				sexp(sym("swing"), num(2)),
+				eighth, eighth, eighth,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 1000.0/3, 500, 500+1000.0/3),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "swing in voices",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("swing"), num(2)),
+				VoiceMarker{VoiceNumber: 1},
+				eighth, eighth,
+				VoiceMarker{VoiceNumber: 2},
+				quarter, eighth, eighth,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 1000.0/3, 0, 500, 500+1000.0/3),
+			},
+		},
+	)
+}
+
+func TestGrooveTemplates(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	sixteenth := noteWithLength(NoteLength{Denominator: 16})
+	triplet := noteWithLength(NoteLength{Denominator: 12})
+	str := func(value string) LispString { return LispString{Value: value} }
+
+	grooves := grooveFile(t, `{
+	  "push-pull": {
+	    "subdivision": 16,
+	    "timing": [0, 20],
+	    "velocity": [10, -10]
+	  },
+	  "accents": {"subdivision": 8, "velocity": [20]},
+	  "downbeats": {"subdivision": 4, "velocity": [20, 0, 0]}
+	}`)
+	quarter := noteWithLength(NoteLength{Denominator: 4})
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "groove template",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("load-grooves"), str(grooves)),
+				sexp(sym("groove"), str("push-pull")),
+				sexp(sym("vol"), num(50)),
+				sixteenth, sixteenth, sixteenth, sixteenth, sixteenth,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 150, 250, 400, 500),
+				expectNoteDurations(125, 125, 125, 125, 125),
+				expectNoteVolumes(0.6, 0.4, 0.6, 0.4, 0.6),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "notes off the grid aren't affected",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("load-grooves"), str(grooves)),
+				sexp(sym("groove"), str("push-pull")),
+				sexp(sym("vol"), num(50)),
+				triplet, triplet, triplet, triplet,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 500.0/3, 1000.0/3, 500),
+				expectNoteVolumes(0.6, 0.5, 0.5, 0.6),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "groove nil",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("load-grooves"), str(grooves)),
+				sexp(sym("vol"), num(90)),
+				sexp(sym("groove"), str("accents")),
+				sixteenth,
+				sexp(sym("groove"), sym("nil")),
+				sixteenth,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteVolumes(1, 0.9),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "swing and groove together",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("load-grooves"), str(grooves)),
+				sexp(sym("groove!"), str("accents")),
+				sexp(sym("swing!"), num(2)),
+				sexp(sym("vol"), num(50)),
+				noteWithLength(NoteLength{Denominator: 8}),
+				noteWithLength(NoteLength{Denominator: 8}),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 1000.0/3),
+				expectNoteVolumes(0.7, 0.7),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "groove aligned to the measure",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("load-grooves"), str(grooves)),
+				sexp(sym("time-signature"), num(4), num(4)),
+				sexp(sym("vol"), num(50)),
+				quarter,
+				sexp(sym("groove"), str("downbeats")),
+				quarter, quarter, quarter,
+				Barline{},
+				quarter,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteVolumes(0.5, 0.5, 0.5, 0.7, 0.7),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "groove after jumping to a marker in the middle of a measure",
+			updates: []ScoreUpdate{
+				sexp(sym("load-grooves"), str(grooves)),
+				piano,
+				sexp(sym("time-signature"), num(3), num(4)),
+				sexp(sym("vol"), num(50)),
+				quarter,
+				Marker{Name: "verse"},
+				quarter, quarter,
+				PartDeclaration{Names: []string{"violin"}},
+				sexp(sym("time-signature"), num(3), num(4)),
+				sexp(sym("groove"), str("downbeats")),
+				sexp(sym("vol"), num(50)),
+				AtMarker{Name: "verse"},
+				quarter, quarter,
+				Barline{},
+				quarter,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteVolumes(0.5, 0.5, 0.5, 0.5, 0.5, 0.7),
+			},
+		},
+	)
+}
+
+func TestLoadGroovesRelativeToTheScoreFile(t *testing.T) {
+	grooves := grooveFile(t, `{"accents": {"subdivision": 8, "velocity": [20]}}`)
+	scoreFile := filepath.Join(filepath.Dir(grooves), "score.alda")
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "relative path",
+			updates: []ScoreUpdate{
+				sexp(
+					sym("load-grooves"),
+					LispString{
+						Value:         "grooves.json",
+						SourceContext: AldaSourceContext{Filename: scoreFile},
+					},
+				),
+			},
+			expectations: []scoreUpdateExpectation{
+				func(s *Score) error {
+					if _, ok := s.Grooves["accents"]; !ok {
+						return fmt.Errorf("expected the groove to be defined")
+					}
+
+					return nil
+				},
+			},
+		},
+	)
+}
+
+func TestGrooveTemplateErrors(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	str := func(value string) LispString { return LispString{Value: value} }
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label:   "undefined groove",
+			updates: []ScoreUpdate{piano, sexp(sym("groove"), str("nope"))},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage(`undefined groove: "nope"`),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "missing groove file",
+			updates: []ScoreUpdate{
+				sexp(sym("load-grooves"), str(filepath.Join(t.TempDir(), "nope.json"))),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("no such file or directory"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "invalid groove",
+			updates: []ScoreUpdate{
+				sexp(
+					sym("load-grooves"),
+					str(grooveFile(t, `{"bad": {"subdivision": 0, "timing": [0]}}`)),
+				),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("expected a positive subdivision"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "timing offset out of range",
+			updates: []ScoreUpdate{
+				sexp(
+					sym("load-grooves"),
+					str(grooveFile(t, `{"bad": {"subdivision": 16, "timing": [150]}}`)),
+				),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("timing offset not between -100 and 100"),
+			},
+		},
+	)
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+
+	"alda.io/client/json"
+)
+
+// Humanization adds small random variations to the timing, length and volume
+// of each note, to make a part sound less mechanical.
+type Humanization struct {
+	// The maximum number of milliseconds by which a note starts early or late.
+	Timing float64
+	// The maximum number of milliseconds by which a note is shortened or
+	// lengthened.
+	Duration float64
+	// The maximum amount (on a 0-1 scale) by which a note is quieter or louder.
+	Volume float64
+}
+
+// IsSet returns true if the humanization has any effect on notes.
+func (h Humanization) IsSet() bool {
+	return h.Timing > 0 || h.Duration > 0 || h.Volume > 0
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (h Humanization) JSON() *json.Container {
+	return json.Object(
+		"timing", h.Timing,
+		"duration", h.Duration,
+		"volume", h.Volume,
+	)
+}
+
+// HumanizationSet sets the humanization of all active parts.
+type HumanizationSet struct {
+	Humanization Humanization
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (hs HumanizationSet) JSON() *json.Container {
+	return json.Object("attribute", "humanize", "value", hs.Humanization.JSON())
+}
+
+func (hs HumanizationSet) updatePart(part *Part, globalUpdate bool) error {
+	part.Humanization = hs.Humanization
+	return nil
+}
+
+// partRandom returns the random number generator that is used to humanize the
+// notes of a part.
+//
+// Each part has its own generator, so that the variations in one part don't
+// depend on how many notes there are in the other parts. The generator is
+// seeded from the score's seed and the part's ID, which means that a score is
+// humanized the same way every time it is built (e.g. by `alda play` and
+// `alda export`) unless the seed changes.
+//
+// The generator belongs to the part ID rather than the part itself, so that it
+// is shared by all of the voices of the part.
+func (score *Score) partRandom(part *Part) *rand.Rand {
+	if random, hit := score.partRandoms[part.ID]; hit {
+		return random
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(part.ID))
+
+	random := rand.New(rand.NewSource(score.seed ^ int64(hash.Sum64())))
+	score.partRandoms[part.ID] = random
+
+	return random
+}
+
+// humanize applies the part's humanization to a note.
+func (part *Part) humanize(note *NoteEvent) {
+	if !part.Humanization.IsSet() {
+		return
+	}
+
+	random := part.score.partRandom(part)
+
+	// Returns a random number between -bound and bound.
+	//
+	// We always draw the same number of random values for each note, so that
+	// e.g. turning off the volume variation doesn't change the timing.
+	jitter := func(bound float64) float64 {
+		return (random.Float64()*2 - 1) * bound
+	}
+
+	timing := jitter(part.Humanization.Timing)
+	duration := jitter(part.Humanization.Duration)
+	volume := jitter(part.Humanization.Volume)
+
+	note.Offset = math.Max(0, note.Offset+timing)
+	// Keep the note audible, no matter how short it is.
+	note.AudibleDuration = math.Max(1, note.AudibleDuration+duration)
+	note.Volume = math.Max(0, math.Min(1, note.Volume+volume))
+}
@@ -0,0 +1,200 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	_ "alda.io/client/testing"
+)
+
+// Returns the offset, audible duration and volume of each note in the score
+// that belongs to the part named `partName`.
+func humanizedValues(score *Score, partName string) [][3]float64 {
+	values := [][3]float64{}
+
+	for _, event := range score.Events {
+		note := event.(NoteEvent)
+		if note.Part.Name == partName {
+			values = append(
+				values, [3]float64{note.Offset, note.AudibleDuration, note.Volume},
+			)
+		}
+	}
+
+	return values
+}
+
+func buildScore(t *testing.T, updates ...ScoreUpdate) *Score {
+	score := NewScore()
+	if err := score.Update(updates...); err != nil {
+		t.Fatal(err)
+	}
+
+	return score
+}
+
+func repeatUpdate(update ScoreUpdate, times int) []ScoreUpdate {
+	updates := []ScoreUpdate{}
+	for i := 0; i < times; i++ {
+		updates = append(updates, update)
+	}
+
+	return updates
+}
+
+func TestHumanize(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	c := noteWithLength(NoteLength{Denominator: 4})
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "humanized notes vary within bounds",
+			updates: append(
+				[]ScoreUpdate{
+					piano,
+					sexp(
+						sym("humanize"),
+						sym(":timing"), num(10),
+						sym(":velocity"), num(5),
+						sym(":duration"), num(20),
+					),
+				},
+				repeatUpdate(c, 50)...,
+			),
+			expectations: []scoreUpdateExpectation{
+				func(s *Score) error {
+					varied := false
+
+					for i, values := range humanizedValues(s, "piano") {
+						offset, audibleDuration, volume := values[0], values[1], values[2]
+
+						if math.Abs(offset-float64(i)*500) > 10 ||
+							math.Abs(audibleDuration-450) > 20 ||
+							math.Abs(volume-DynamicVolumes["mf"]) > 0.05+1e-9 {
+							return fmt.Errorf("note #%d out of bounds: %v", i+1, values)
+						}
+
+						varied = varied || offset != float64(i)*500
+					}
+
+					if !varied {
+						return fmt.Errorf("expected the notes to vary")
+					}
+
+					return nil
+				},
+			},
+		},
+		scoreUpdateTestCase{
+			label: "(humanize) turns humanization off",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("humanize"), sym(":timing"), num(10)),
+				sexp(sym("humanize")),
+				c, c, c,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 500, 1000),
+			},
+		},
+	)
+}
+
+func TestHumanizeIsReproducible(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	violin := PartDeclaration{Names: []string{"violin"}}
+	c := noteWithLength(NoteLength{Denominator: 8})
+	humanize := sexp(
+		sym("humanize!"), sym(":timing"), num(20), sym(":velocity"), num(10),
+	)
+
+	pianoFirst := append(
+		append([]ScoreUpdate{humanize, piano}, repeatUpdate(c, 8)...),
+		append([]ScoreUpdate{violin}, repeatUpdate(c, 8)...)...,
+	)
+
+	expected := humanizedValues(buildScore(t, pianoFirst...), "piano")
+
+	if actual := humanizedValues(
+		buildScore(t, pianoFirst...), "piano",
+	); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected the same score to be humanized the same way")
+	}
+
+	// The violin notes come first this time, but the piano is still the first
+	// part, so its notes are humanized the same way.
+	violinFirst := append(
+		append([]ScoreUpdate{humanize, piano, violin}, repeatUpdate(c, 8)...),
+		append([]ScoreUpdate{piano}, repeatUpdate(c, 8)...)...,
+	)
+
+	if actual := humanizedValues(
+		buildScore(t, violinFirst...), "piano",
+	); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected a part's humanization not to depend on other parts")
+	}
+
+	reseeded := append([]ScoreUpdate{RandomSeedSet{Seed: 42}}, pianoFirst...)
+
+	if actual := humanizedValues(
+		buildScore(t, reseeded...), "piano",
+	); reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected a different seed to humanize the score differently")
+	}
+}
+
+func TestHumanizeErrors(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label:   "missing value",
+			updates: []ScoreUpdate{piano, sexp(sym("humanize"), sym(":timing"))},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("expected pairs of keywords and values"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "unknown keyword",
+			updates: []ScoreUpdate{
+				piano, sexp(sym("humanize"), sym(":pitch"), num(1)),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage(
+					"unexpected keyword :pitch (expected one of :timing, :velocity, :duration)",
+				),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "not a keyword",
+			updates: []ScoreUpdate{
+				piano, sexp(sym("humanize"), num(10), num(5)),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("expected a keyword (e.g. :timing), got number"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "negative timing",
+			updates: []ScoreUpdate{
+				piano, sexp(sym("humanize"), sym(":timing"), num(-5)),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("expected a non-negative number of ms after :timing"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "velocity out of range",
+			updates: []ScoreUpdate{
+				piano, sexp(sym("humanize"), sym(":velocity"), num(101)),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("value not between 0 and 100"),
+			},
+		},
+	)
+}
@@ -231,3 +231,49 @@ func KeySignatureFromScale(
 
 	return keySignature
 }
+
+// semitoneShift returns the number of semitones by which the key signature
+// raises (or lowers, if negative) each note letter.
+func (k KeySignature) semitoneShift(letter NoteLetter) int32 {
+	shift := int32(0)
+
+	for _, accidental := range k[letter] {
+		switch accidental {
+		case Flat:
+			shift--
+		case Sharp:
+			shift++
+		}
+	}
+
+	return shift
+}
+
+// majorTonic returns the note letter of the major key that has this key
+// signature, e.g. G for a key signature with one sharp (F#). Any accidentals
+// on the tonic come from the key signature itself, e.g. F# in the key of F#
+// major.
+//
+// Returns false if this isn't the key signature of a major key (or, by
+// extension, its relative minor key or any other mode).
+func (k KeySignature) majorTonic() (NoteLetter, bool) {
+	for fifths := -7; fifths <= 7; fifths++ {
+		candidate := KeySignatureFromCircleOfFifths(fifths)
+
+		matches := true
+		for _, letter := range []NoteLetter{A, B, C, D, E, F, G} {
+			if k.semitoneShift(letter) != candidate.semitoneShift(letter) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			// Each fifth up the circle moves the tonic up 4 note letters, e.g. from C
+			// to G.
+			return NoteLetter(((int(C)+4*fifths)%7 + 7) % 7), true
+		}
+	}
+
+	return 0, false
+}
@@ -2,7 +2,11 @@ package model
 
 import (
 	"fmt"
+	"math"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -331,6 +335,10 @@ Got:
 
 var specialForms = map[string]LispForm{
 	"quote": LispSpecialFormQuote{},
+	"defn":  LispSpecialFormDefn{},
+	"fn":    LispSpecialFormFn{},
+	"let":   LispSpecialFormLet{},
+	"if":    LispSpecialFormIf{},
 }
 
 var environment = map[string]LispForm{}
@@ -342,6 +350,7 @@ type attributeFunctionSignature struct {
 
 func def(name string, value LispForm) {
 	environment[name] = value
+	builtins[name] = true
 }
 
 func defn(name string, signatures ...FunctionSignature) {
@@ -351,8 +360,8 @@ func defn(name string, signatures ...FunctionSignature) {
 	// runtime. `panic` makes sense here because we want the whole system to fall
 	// over if any of the built-in functions we've defined are invalid.
 	//
-	// If/when we implement `defn` in user-space, we should handle the validation
-	// error instead.
+	// The `defn` special form, which score authors use to define their own
+	// functions, handles the validation error instead. (See evalDefn.)
 	if err := fn.Validate(); err != nil {
 		panic(err)
 	}
@@ -360,6 +369,18 @@ func defn(name string, signatures ...FunctionSignature) {
 	def(name, fn)
 }
 
+// attributeNames is the list of names of the attributes defined via
+// `defattribute`, including aliases and the global (`!`) variants.
+var attributeNames = []string{}
+
+// AttributeNames returns the names of the attributes that can be set in an Alda
+// score, e.g. `tempo`, `volume` and `tempo!`, in alphabetical order.
+func AttributeNames() []string {
+	names := append([]string{}, attributeNames...)
+	sort.Strings(names)
+	return names
+}
+
 func defattribute(names []string, signatures ...attributeFunctionSignature) {
 	type defattributeImpl struct {
 		name        string
@@ -411,6 +432,7 @@ func defattribute(names []string, signatures ...attributeFunctionSignature) {
 			}
 
 			defn(impl.name, functionSignatures...)
+			attributeNames = append(attributeNames, impl.name)
 		}
 	}
 }
@@ -491,6 +513,65 @@ func percentage(form LispForm) (float64, error) {
 	return number.Value / 100, nil
 }
 
+// maxRangeLength is the maximum number of elements that `range` will return,
+// which prevents a typo from exhausting the available memory.
+const maxRangeLength = 100000
+
+// numberRange returns a list of numbers from `start` (inclusive) to `end`
+// (exclusive), incrementing by `step`.
+func numberRange(start float64, end LispNumber, step LispNumber) (LispForm, error) {
+	if step.Value == 0 {
+		return nil, &AldaSourceError{
+			Context: step.SourceContext,
+			Err:     fmt.Errorf("range step must not be 0"),
+		}
+	}
+
+	result := LispList{Elements: []LispForm{}}
+
+	for n := start; (step.Value > 0 && n < end.Value) ||
+		(step.Value < 0 && n > end.Value); n += step.Value {
+		if len(result.Elements) == maxRangeLength {
+			return nil, &AldaSourceError{
+				Context: end.SourceContext,
+				Err: fmt.Errorf(
+					"range is longer than the maximum of %d elements", maxRangeLength,
+				),
+			}
+		}
+
+		result.Elements = append(result.Elements, LispNumber{Value: n})
+	}
+
+	return result, nil
+}
+
+// randomInteger returns a random integer between `lower` (inclusive) and
+// `upper` (exclusive).
+func randomInteger(lower LispNumber, upper LispNumber) (LispForm, error) {
+	for _, number := range []LispNumber{lower, upper} {
+		if number.Value != math.Trunc(number.Value) {
+			return nil, &AldaSourceError{
+				Context: number.SourceContext,
+				Err:     fmt.Errorf("expected integer, got %f", number.Value),
+			}
+		}
+	}
+
+	if upper.Value <= lower.Value {
+		return nil, &AldaSourceError{
+			Context: upper.SourceContext,
+			Err: fmt.Errorf(
+				"expected upper bound greater than %d, got %d",
+				int64(lower.Value), int64(upper.Value),
+			),
+		}
+	}
+
+	n := randomSource().Int63n(int64(upper.Value) - int64(lower.Value))
+	return LispNumber{Value: lower.Value + float64(n)}, nil
+}
+
 func isDigit(c rune) bool {
 	return '0' <= c && c <= '9'
 }
@@ -602,6 +683,173 @@ func letterAndAccidentals(str string) (NoteLetter, []Accidental, error) {
 	return letter, accidentals, nil
 }
 
+func timeSignatureFromNumbers(
+	numeratorForm LispForm, denominatorForm LispForm,
+) (TimeSignature, error) {
+	numerator, err := integerInRange(numeratorForm, 1, 128)
+	if err != nil {
+		return TimeSignature{}, err
+	}
+
+	denominator, err := integerInRange(denominatorForm, 1, 128)
+	if err != nil {
+		return TimeSignature{}, err
+	}
+
+	return TimeSignature{Numerator: numerator, Denominator: denominator}, nil
+}
+
+func timeSignatureFromString(form LispForm) (TimeSignature, error) {
+	stringLiteral := form.(LispString)
+
+	validityError := &AldaSourceError{
+		Context: stringLiteral.SourceContext,
+		Err: fmt.Errorf(
+			"invalid time signature: %q (expected e.g. \"3/4\")",
+			stringLiteral.Value,
+		),
+	}
+
+	strs := strings.Split(strings.TrimSpace(stringLiteral.Value), "/")
+	if len(strs) != 2 {
+		return TimeSignature{}, validityError
+	}
+
+	numerator, err := strconv.Atoi(strs[0])
+	if err != nil || numerator < 1 || numerator > 128 {
+		return TimeSignature{}, validityError
+	}
+
+	denominator, err := strconv.Atoi(strs[1])
+	if err != nil || denominator < 1 || denominator > 128 {
+		return TimeSignature{}, validityError
+	}
+
+	return TimeSignature{
+		Numerator: int32(numerator), Denominator: int32(denominator),
+	}, nil
+}
+
+// Returns the length in beats of a pickup measure, which is expressed as a
+// duration, e.g. "8" for an eighth note pickup.
+func pickupBeats(form LispForm, timeSig TimeSignature) (float64, error) {
+	pickup, err := duration(form)
+	if err != nil {
+		return 0, err
+	}
+
+	beats := pickup.Beats()
+	if beats <= 0 || beats >= timeSig.MeasureBeats() {
+		return 0, &AldaSourceError{
+			Context: form.(LispString).SourceContext,
+			Err: fmt.Errorf(
+				"expected a pickup shorter than a measure of %s, got %q",
+				timeSig.String(),
+				form.(LispString).Value,
+			),
+		}
+	}
+
+	return beats, nil
+}
+
+var barsRegex = regexp.MustCompile(`^(\d+) bars?$`)
+
+// Returns an AttributeRamp that ramps `attribute` to `target` over the length
+// expressed by `form`, which is either a duration (e.g. "2" or "1~1") or a
+// number of measures (e.g. "4 bars").
+func rampTo(
+	attribute RampAttribute, target float64, form LispForm,
+) (AttributeRamp, error) {
+	stringLiteral := form.(LispString)
+
+	if match := barsRegex.FindStringSubmatch(stringLiteral.Value); match != nil {
+		bars, err := strconv.Atoi(match[1])
+		if err != nil || bars < 1 {
+			return AttributeRamp{}, &AldaSourceError{
+				Context: stringLiteral.SourceContext,
+				Err:     fmt.Errorf("invalid number of bars: %q", stringLiteral.Value),
+			}
+		}
+
+		return AttributeRamp{
+			Attribute: attribute, Target: target, Bars: int32(bars),
+		}, nil
+	}
+
+	duration, err := duration(form)
+	if err != nil {
+		return AttributeRamp{}, err
+	}
+
+	return AttributeRamp{
+		Attribute: attribute, Target: target, Duration: duration,
+	}, nil
+}
+
+// Returns the values of keyword arguments, e.g. `:timing 8 :velocity 5`, by
+// keyword (without the colon). Only the keywords in `allowed` are accepted.
+func keywordArguments(
+	args []LispForm, allowed ...string,
+) (map[string]LispForm, error) {
+	if len(args)%2 != 0 {
+		return nil, fmt.Errorf(
+			"expected pairs of keywords and values, got %d argument(s)", len(args),
+		)
+	}
+
+	values := map[string]LispForm{}
+
+	for i := 0; i < len(args); i += 2 {
+		keyword, ok := args[i].(LispSymbol)
+		if !ok || !keyword.IsKeyword() {
+			return nil, fmt.Errorf(
+				"expected a keyword (e.g. :%s), got %s",
+				allowed[0], args[i].TypeString(),
+			)
+		}
+
+		name := strings.TrimPrefix(keyword.Name, ":")
+
+		known := false
+		for _, allowedName := range allowed {
+			known = known || name == allowedName
+		}
+
+		if !known {
+			return nil, &AldaSourceError{
+				Context: keyword.SourceContext,
+				Err: fmt.Errorf(
+					"unexpected keyword %s (expected one of :%s)",
+					keyword.Name, strings.Join(allowed, ", :"),
+				),
+			}
+		}
+
+		values[name] = args[i+1]
+	}
+
+	return values, nil
+}
+
+// Returns true if the keyword `flag` (e.g. `:in-key`), which doesn't take a
+// value, is one of the arguments, along with the rest of the arguments.
+func keywordFlag(args []LispForm, flag string) (bool, []LispForm) {
+	rest := []LispForm{}
+	found := false
+
+	for _, arg := range args {
+		if symbol, ok := arg.(LispSymbol); ok && symbol.Name == ":"+flag {
+			found = true
+			continue
+		}
+
+		rest = append(rest, arg)
+	}
+
+	return found, rest
+}
+
 func keySignatureFromString(form LispForm) (KeySignature, error) {
 	stringLiteral := form.(LispString)
 
@@ -659,7 +907,10 @@ func scaleType(forms []LispForm) (ScaleType, error) {
 	}
 }
 
-func keySignatureFromScaleName(forms []LispForm) (KeySignature, error) {
+// Returns the key signature of a scale, e.g. (a minor), along with its tonic.
+func keySignatureFromScaleName(
+	forms []LispForm,
+) (KeySignature, LetterAndAccidentals, error) {
 	validityError := fmt.Errorf("invalid scale name: %#v", forms)
 
 	letter := NoteLetter(0)
@@ -667,17 +918,17 @@ func keySignatureFromScaleName(forms []LispForm) (KeySignature, error) {
 	case LispSymbol:
 		chars := []rune(form.(LispSymbol).Name)
 		if len(chars) > 1 {
-			return KeySignature{}, validityError
+			return KeySignature{}, LetterAndAccidentals{}, validityError
 		}
 
 		ltr, err := NewNoteLetter(chars[0])
 		if err != nil {
-			return KeySignature{}, err
+			return KeySignature{}, LetterAndAccidentals{}, err
 		}
 
 		letter = ltr
 	default:
-		return KeySignature{}, validityError
+		return KeySignature{}, LetterAndAccidentals{}, validityError
 	}
 
 	tonic := LetterAndAccidentals{NoteLetter: letter}
@@ -689,7 +940,7 @@ func keySignatureFromScaleName(forms []LispForm) (KeySignature, error) {
 		case LispSymbol:
 			if accidental, err := NewAccidental(form.Name); err == nil {
 				if passedAccidentals {
-					return KeySignature{}, validityError
+					return KeySignature{}, tonic, validityError
 				}
 
 				tonic.Accidentals = append(tonic.Accidentals, accidental)
@@ -699,16 +950,16 @@ func keySignatureFromScaleName(forms []LispForm) (KeySignature, error) {
 			passedAccidentals = true
 			remainingForms = append(remainingForms, form)
 		default:
-			return KeySignature{}, validityError
+			return KeySignature{}, tonic, validityError
 		}
 	}
 
 	scaleType, err := scaleType(remainingForms)
 	if err != nil {
-		return KeySignature{}, err
+		return KeySignature{}, tonic, err
 	}
 
-	return KeySignatureFromScale(tonic, scaleType), nil
+	return KeySignatureFromScale(tonic, scaleType), tonic, nil
 }
 
 func keySignatureFromAccidentals(forms []LispForm) (KeySignature, error) {
@@ -767,7 +1018,10 @@ func keySignatureFromAccidentals(forms []LispForm) (KeySignature, error) {
 	return keySig, nil
 }
 
-func keySignatureFromList(form LispForm) (KeySignature, error) {
+// Returns a key signature given as a list, e.g. '(a minor) or
+// '(b (flat) e (flat)). When the list is a scale name, the key's tonic is set
+// too.
+func keySignatureFromList(form LispForm) (KeySignatureSet, error) {
 	list := form.(LispList)
 
 	sourceError := func(err error) error {
@@ -781,24 +1035,24 @@ func keySignatureFromList(form LispForm) (KeySignature, error) {
 	validityError := sourceError(fmt.Errorf("invalid key signature: %#v", forms))
 
 	if len(forms) < 2 {
-		return KeySignature{}, validityError
+		return KeySignatureSet{}, validityError
 	}
 
 	switch forms[1].(type) {
 	case LispSymbol:
-		keySig, err := keySignatureFromScaleName(forms)
+		keySig, tonic, err := keySignatureFromScaleName(forms)
 		if err != nil {
-			return KeySignature{}, sourceError(err)
+			return KeySignatureSet{}, sourceError(err)
 		}
-		return keySig, nil
+		return KeySignatureSet{KeySignature: keySig, Tonic: &tonic}, nil
 	case LispList:
 		keySig, err := keySignatureFromAccidentals(forms)
 		if err != nil {
-			return KeySignature{}, sourceError(err)
+			return KeySignatureSet{}, sourceError(err)
 		}
-		return keySig, nil
+		return KeySignatureSet{KeySignature: keySig}, nil
 	default:
-		return KeySignature{}, validityError
+		return KeySignatureSet{}, validityError
 	}
 }
 
@@ -888,6 +1142,24 @@ func init() {
 		},
 	)
 
+	// Gradually changes the tempo to a target tempo over a duration, e.g.
+	// (accel 160 "4 bars").
+	defattribute([]string{
+		"tempo-ramp", "accel", "accelerando", "rit", "ritardando", "rall",
+		"rallentando",
+	},
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispNumber{}, LispString{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				bpm, err := positiveNumber(args[0])
+				if err != nil {
+					return nil, err
+				}
+				return rampTo(RampTempo, bpm, args[1])
+			},
+		},
+	)
+
 	// Express tempo in terms of metric modulation, where the new note takes the
 	// same amount of time (one beat) as the old note.
 	//
@@ -1005,6 +1277,38 @@ func init() {
 		},
 	)
 
+	// Gradually changes the volume to a target volume over a duration, e.g.
+	// (cresc 90 "2") or (dim "p" "1~1").
+	defattribute([]string{
+		"volume-ramp", "cresc", "crescendo", "dim", "diminuendo", "decresc",
+		"decrescendo",
+	},
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispNumber{}, LispString{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				percentage, err := percentage(args[0])
+				if err != nil {
+					return nil, err
+				}
+				return rampTo(RampVolume, percentage, args[1])
+			},
+		},
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispString{}, LispString{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				marking := args[0].(LispString)
+				volume, ok := DynamicVolumes[marking.Value]
+				if !ok {
+					return nil, &AldaSourceError{
+						Context: marking.SourceContext,
+						Err:     fmt.Errorf("invalid dynamic marking: %q", marking.Value),
+					}
+				}
+				return rampTo(RampVolume, volume, args[1])
+			},
+		},
+	)
+
 	// More general volume for the track as a whole. Although this can be changed
 	// just as often as volume, to do so is not idiomatic. For MIDI purposes, this
 	// corresponds to the volume of a channel."
@@ -1021,6 +1325,19 @@ func init() {
 		},
 	)
 
+	defattribute([]string{"track-volume-ramp", "track-vol-ramp"},
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispNumber{}, LispString{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				percentage, err := percentage(args[0])
+				if err != nil {
+					return nil, err
+				}
+				return rampTo(RampTrackVolume, percentage, args[1])
+			},
+		},
+	)
+
 	// Dynamic markings corresponding to a volume set
 	var dynamicImplementation = func(marking string) func(args ...LispForm) (PartUpdate, error) {
 		return func(args ...LispForm) (PartUpdate, error) {
@@ -1051,6 +1368,21 @@ func init() {
 		},
 	)
 
+	// Gradually changes the panning to a target panning over a duration, e.g.
+	// (pan-ramp 100 "1") to sweep from the current panning to hard right.
+	defattribute([]string{"panning-ramp", "pan-ramp"},
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispNumber{}, LispString{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				percentage, err := percentage(args[0])
+				if err != nil {
+					return nil, err
+				}
+				return rampTo(RampPanning, percentage, args[1])
+			},
+		},
+	)
+
 	// Default note duration in beats.
 	defattribute([]string{"set-duration"},
 		attributeFunctionSignature{
@@ -1127,118 +1459,608 @@ func init() {
 		attributeFunctionSignature{
 			argumentTypes: []LispForm{LispList{}},
 			implementation: func(args ...LispForm) (PartUpdate, error) {
-				keySig, err := keySignatureFromList(args[0])
-				if err != nil {
-					return nil, err
-				}
-				return KeySignatureSet{KeySignature: keySig}, nil
+				return keySignatureFromList(args[0])
 			},
 		},
 	)
 
-	// The number of semitones to transpose. A negative number means transpose
-	// down, a positive number means transpose up.
-	defattribute([]string{"transposition", "transpose"},
+	// The tonic of the part's key, which determines the pitch of each scale
+	// degree, e.g. (tonic "e") or (tonic "f+"). Setting the key signature
+	// replaces the tonic with the one implied by the key signature.
+	defattribute([]string{"tonic"},
 		attributeFunctionSignature{
-			argumentTypes: []LispForm{LispNumber{}},
+			argumentTypes: []LispForm{LispString{}},
 			implementation: func(args ...LispForm) (PartUpdate, error) {
-				semitones, err := integer(args[0])
+				str := args[0].(LispString)
+
+				// A note letter without accidentals is in the key, e.g. (tonic "f") is
+				// F# in the key of G major.
+				if chars := []rune(str.Value); len(chars) == 1 {
+					letter, err := NewNoteLetter(chars[0])
+					if err != nil {
+						return nil, &AldaSourceError{Context: str.SourceContext, Err: err}
+					}
+
+					return TonicSet{Tonic: LetterAndAccidentals{NoteLetter: letter}}, nil
+				}
+
+				letter, accidentals, err := letterAndAccidentals(str.Value)
 				if err != nil {
-					return nil, err
+					return nil, &AldaSourceError{Context: str.SourceContext, Err: err}
 				}
-				return TranspositionSet{Semitones: semitones}, nil
+
+				return TonicSet{Tonic: LetterAndAccidentals{
+					NoteLetter: letter, Accidentals: accidentals,
+				}}, nil
 			},
 		},
 	)
 
-	// The reference pitch, expressed as the frequency of A4 in Hz.
-	defattribute([]string{"reference-pitch", "tuning-constant"},
+	// e.g. (time-signature 3 4) or (time-signature "3/4")
+	//
+	// An optional duration specifies the length of a pickup measure, e.g.
+	// (time-signature 3 4 "4") for a pickup of one quarter note.
+	defattribute([]string{"time-signature", "time-sig"},
 		attributeFunctionSignature{
-			argumentTypes: []LispForm{LispNumber{}},
+			argumentTypes: []LispForm{LispNumber{}, LispNumber{}},
 			implementation: func(args ...LispForm) (PartUpdate, error) {
-				frequency, err := positiveNumber(args[0])
+				timeSig, err := timeSignatureFromNumbers(args[0], args[1])
 				if err != nil {
 					return nil, err
 				}
-				return ReferencePitchSet{Frequency: frequency}, nil
+				return TimeSignatureSet{TimeSignature: timeSig}, nil
 			},
 		},
-	)
-
-	// The MIDI channel to use. See `MidiChannelSet`.
-	defattribute([]string{"midi-channel"},
 		attributeFunctionSignature{
-			argumentTypes: []LispForm{LispNumber{}},
+			argumentTypes: []LispForm{LispNumber{}, LispNumber{}, LispString{}},
 			implementation: func(args ...LispForm) (PartUpdate, error) {
-				channelNumber, err := integerInRange(args[0], 0, 15)
+				timeSig, err := timeSignatureFromNumbers(args[0], args[1])
 				if err != nil {
 					return nil, err
 				}
-				return MidiChannelSet{ChannelNumber: channelNumber}, nil
+				pickup, err := pickupBeats(args[2], timeSig)
+				if err != nil {
+					return nil, err
+				}
+				return TimeSignatureSet{TimeSignature: timeSig, Pickup: pickup}, nil
 			},
 		},
-	)
-
-	defn("list",
-		FunctionSignature{
-			ArgumentTypes: []LispForm{LispVariadic{LispAny{}}},
-			Implementation: func(args ...LispForm) (LispForm, error) {
-				return LispList{Elements: args}, nil
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispString{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				timeSig, err := timeSignatureFromString(args[0])
+				if err != nil {
+					return nil, err
+				}
+				return TimeSignatureSet{TimeSignature: timeSig}, nil
 			},
 		},
-	)
-
-	defn("ms",
-		FunctionSignature{
-			ArgumentTypes: []LispForm{LispNumber{}},
-			Implementation: func(args ...LispForm) (LispForm, error) {
-				quantity, err := positiveNumber(args[0])
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispString{}, LispString{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				timeSig, err := timeSignatureFromString(args[0])
 				if err != nil {
 					return nil, err
 				}
-				return LispDuration{NoteLengthMs{Quantity: quantity}}, nil
+				pickup, err := pickupBeats(args[1], timeSig)
+				if err != nil {
+					return nil, err
+				}
+				return TimeSignatureSet{TimeSignature: timeSig, Pickup: pickup}, nil
 			},
 		},
 	)
 
-	defn("note-length",
-		FunctionSignature{
-			ArgumentTypes: []LispForm{LispNumber{}},
-			Implementation: func(args ...LispForm) (LispForm, error) {
-				denominator, err := positiveNumber(args[0])
+	// e.g. (swing 2) for "triplet" swing eighth notes, or (swing 3 "16") for
+	// sixteenth notes swung 3:1. (swing 1) means no swing.
+	defattribute([]string{"swing"},
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispNumber{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				ratio, err := positiveNumber(args[0])
 				if err != nil {
 					return nil, err
 				}
-
-				noteLength := NoteLength{Denominator: denominator}
-				return LispDuration{DurationComponent: noteLength}, nil
+				return SwingSet{
+					Swing: Swing{
+						Ratio: ratio,
+						Subdivision: Duration{
+							Components: []DurationComponent{NoteLength{Denominator: 8}},
+						},
+					},
+				}, nil
 			},
 		},
-		FunctionSignature{
-			ArgumentTypes: []LispForm{LispString{}},
-			Implementation: func(args ...LispForm) (LispForm, error) {
-				noteLength, err := noteLength(args[0].(LispString).Value)
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispNumber{}, LispString{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				ratio, err := positiveNumber(args[0])
 				if err != nil {
 					return nil, err
 				}
-
-				return LispDuration{DurationComponent: noteLength}, nil
-			},
-		},
-	)
-
-	defn("duration",
-		FunctionSignature{
-			ArgumentTypes: []LispForm{LispVariadic{LispDuration{}}},
-			Implementation: func(args ...LispForm) (LispForm, error) {
-				duration := Duration{}
-
-				for _, arg := range args {
-					component := arg.(LispDuration).DurationComponent
-					duration.Components = append(duration.Components, component)
+				subdivision, err := duration(args[1])
+				if err != nil {
+					return nil, err
 				}
-
-				return LispDuration{DurationComponent: duration}, nil
+				return SwingSet{
+					Swing: Swing{Ratio: ratio, Subdivision: subdivision},
+				}, nil
+			},
+		},
+	)
+
+	// e.g. (groove "mpc-16"), after defining the groove via (load-grooves ...).
+	// (groove nil) means no groove.
+	defattribute([]string{"groove"},
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispString{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				return GrooveSet{Name: args[0].(LispString).Value}, nil
+			},
+		},
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispNil{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				return GrooveSet{}, nil
+			},
+		},
+	)
+
+	// e.g. (humanize :timing 8 :velocity 5) makes each note start up to 8 ms
+	// early or late, and makes it up to 5 (out of 100) quieter or louder.
+	// :duration lengthens or shortens each note by up to a number of ms.
+	// (humanize) turns humanization off.
+	defattribute([]string{"humanize"},
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispVariadic{LispAny{}}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				values, err := keywordArguments(
+					args, "timing", "velocity", "duration",
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				humanization := Humanization{}
+
+				for keyword, value := range values {
+					number, ok := value.(LispNumber)
+					if !ok {
+						return nil, fmt.Errorf(
+							"expected a number after :%s, got %s",
+							keyword, value.TypeString(),
+						)
+					}
+
+					if keyword == "velocity" {
+						if humanization.Volume, err = percentage(number); err != nil {
+							return nil, err
+						}
+						continue
+					}
+
+					if number.Value < 0 {
+						return nil, &AldaSourceError{
+							Context: number.SourceContext,
+							Err: fmt.Errorf(
+								"expected a non-negative number of ms after :%s, got %f",
+								keyword, number.Value,
+							),
+						}
+					}
+
+					if keyword == "timing" {
+						humanization.Timing = number.Value
+					} else {
+						humanization.Duration = number.Value
+					}
+				}
+
+				return HumanizationSet{Humanization: humanization}, nil
+			},
+		},
+	)
+
+	// The number of semitones to transpose. A negative number means transpose
+	// down, a positive number means transpose up.
+	defattribute([]string{"transposition", "transpose"},
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispNumber{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				semitones, err := integer(args[0])
+				if err != nil {
+					return nil, err
+				}
+				return TranspositionSet{Semitones: semitones}, nil
+			},
+		},
+	)
+
+	// The reference pitch, expressed as the frequency of A4 in Hz.
+	defattribute([]string{"reference-pitch", "tuning-constant"},
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispNumber{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				frequency, err := positiveNumber(args[0])
+				if err != nil {
+					return nil, err
+				}
+				return ReferencePitchSet{Frequency: frequency}, nil
+			},
+		},
+	)
+
+	// e.g. (tuning "just.scl") or (tuning "maqam.scl" "maqam.kbm"), where the
+	// files are a Scala scale and (optionally) keyboard mapping. Paths are
+	// relative to the score file. (tuning nil) means 12-tone equal temperament.
+	defattribute([]string{"tuning"},
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispString{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				return tuningFromFiles(args[0].(LispString), nil)
+			},
+		},
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispString{}, LispString{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				mappingFile := args[1].(LispString)
+				return tuningFromFiles(args[0].(LispString), &mappingFile)
+			},
+		},
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispNil{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				return TuningSet{}, nil
+			},
+		},
+	)
+
+	// The MIDI channel to use. See `MidiChannelSet`.
+	defattribute([]string{"midi-channel"},
+		attributeFunctionSignature{
+			argumentTypes: []LispForm{LispNumber{}},
+			implementation: func(args ...LispForm) (PartUpdate, error) {
+				channelNumber, err := integerInRange(args[0], 0, 15)
+				if err != nil {
+					return nil, err
+				}
+				return MidiChannelSet{ChannelNumber: channelNumber}, nil
+			},
+		},
+	)
+
+	defn("list",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispVariadic{LispAny{}}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				return LispList{Elements: args}, nil
+			},
+		},
+	)
+
+	def("nil", LispNil{})
+	def("true", LispBoolean{Value: true})
+	def("false", LispBoolean{Value: false})
+
+	defn("+",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispVariadic{LispNumber{}}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				sum := 0.0
+				for _, arg := range args {
+					sum += arg.(LispNumber).Value
+				}
+				return LispNumber{Value: sum}, nil
+			},
+		},
+	)
+
+	defn("-",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispNumber{}, LispVariadic{LispNumber{}}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				difference := args[0].(LispNumber).Value
+
+				// With a single argument, `-` negates the number.
+				if len(args) == 1 {
+					return LispNumber{Value: -difference}, nil
+				}
+
+				for _, arg := range args[1:] {
+					difference -= arg.(LispNumber).Value
+				}
+				return LispNumber{Value: difference}, nil
+			},
+		},
+	)
+
+	defn("*",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispVariadic{LispNumber{}}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				product := 1.0
+				for _, arg := range args {
+					product *= arg.(LispNumber).Value
+				}
+				return LispNumber{Value: product}, nil
+			},
+		},
+	)
+
+	defn("/",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispNumber{}, LispVariadic{LispNumber{}}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				divisors := args[1:]
+				quotient := args[0].(LispNumber).Value
+
+				// With a single argument, `/` returns the reciprocal of the number.
+				if len(args) == 1 {
+					divisors = args
+					quotient = 1
+				}
+
+				for _, arg := range divisors {
+					divisor := arg.(LispNumber)
+					if divisor.Value == 0 {
+						return nil, &AldaSourceError{
+							Context: divisor.SourceContext,
+							Err:     fmt.Errorf("division by zero"),
+						}
+					}
+					quotient /= divisor.Value
+				}
+				return LispNumber{Value: quotient}, nil
+			},
+		},
+	)
+
+	defn("=",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispAny{}, LispVariadic{LispAny{}}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				// Source context isn't included in the JSON representation of a value,
+				// so comparing JSON representations compares values regardless of
+				// where they came from.
+				expected := args[0].JSON().String()
+				for _, arg := range args[1:] {
+					if arg.JSON().String() != expected {
+						return LispBoolean{Value: false}, nil
+					}
+				}
+				return LispBoolean{Value: true}, nil
+			},
+		},
+	)
+
+	for _, _comparison := range []struct {
+		name    string
+		compare func(float64, float64) bool
+	}{
+		{"<", func(a, b float64) bool { return a < b }},
+		{">", func(a, b float64) bool { return a > b }},
+		{"<=", func(a, b float64) bool { return a <= b }},
+		{">=", func(a, b float64) bool { return a >= b }},
+	} {
+		// We have to do this in order to close over `comparison` in the function
+		// below. This is because closures don't work properly in Go.
+		//
+		// ref: https://www.calhoun.io/gotchas-and-common-mistakes-with-closures-in-go/
+		comparison := _comparison
+
+		defn(comparison.name,
+			FunctionSignature{
+				ArgumentTypes: []LispForm{LispNumber{}, LispVariadic{LispNumber{}}},
+				Implementation: func(args ...LispForm) (LispForm, error) {
+					for i := 1; i < len(args); i++ {
+						if !comparison.compare(
+							args[i-1].(LispNumber).Value, args[i].(LispNumber).Value,
+						) {
+							return LispBoolean{Value: false}, nil
+						}
+					}
+					return LispBoolean{Value: true}, nil
+				},
+			},
+		)
+	}
+
+	defn("not",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispAny{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				return LispBoolean{Value: !truthy(args[0])}, nil
+			},
+		},
+	)
+
+	defn("range",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispNumber{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				return numberRange(0, args[0].(LispNumber), LispNumber{Value: 1})
+			},
+		},
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispNumber{}, LispNumber{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				return numberRange(
+					args[0].(LispNumber).Value, args[1].(LispNumber), LispNumber{Value: 1},
+				)
+			},
+		},
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispNumber{}, LispNumber{}, LispNumber{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				return numberRange(
+					args[0].(LispNumber).Value, args[1].(LispNumber), args[2].(LispNumber),
+				)
+			},
+		},
+	)
+
+	defn("map",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispFunction{}, LispList{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				f := args[0].(LispFunction)
+				result := LispList{}
+
+				for _, element := range args[1].(LispList).Elements {
+					value, err := f.Operate([]LispForm{element})
+					if err != nil {
+						return nil, err
+					}
+					result.Elements = append(result.Elements, value)
+				}
+
+				return result, nil
+			},
+		},
+	)
+
+	defn("concat",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispVariadic{LispList{}}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				result := LispList{Elements: []LispForm{}}
+				for _, arg := range args {
+					result.Elements = append(result.Elements, arg.(LispList).Elements...)
+				}
+				return result, nil
+			},
+		},
+	)
+
+	defn("seed",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispNumber{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				number := args[0].(LispNumber)
+				if number.Value != math.Trunc(number.Value) {
+					return nil, &AldaSourceError{
+						Context: number.SourceContext,
+						Err:     fmt.Errorf("expected integer, got %f", number.Value),
+					}
+				}
+
+				return LispScoreUpdate{
+					ScoreUpdate: RandomSeedSet{
+						SourceContext: number.SourceContext,
+						Seed:          int64(number.Value),
+					},
+				}, nil
+			},
+		},
+	)
+
+	defn("random",
+		// (random) returns a random number between 0 (inclusive) and 1 (exclusive).
+		FunctionSignature{
+			ArgumentTypes: []LispForm{},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				return LispNumber{Value: randomSource().Float64()}, nil
+			},
+		},
+		// (random n) returns a random integer between 0 (inclusive) and n
+		// (exclusive).
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispNumber{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				return randomInteger(LispNumber{Value: 0}, args[0].(LispNumber))
+			},
+		},
+		// (random lower upper) returns a random integer between lower (inclusive)
+		// and upper (exclusive).
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispNumber{}, LispNumber{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				return randomInteger(args[0].(LispNumber), args[1].(LispNumber))
+			},
+		},
+	)
+
+	defn("choose",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispList{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				list := args[0].(LispList)
+				if len(list.Elements) == 0 {
+					return nil, &AldaSourceError{
+						Context: list.SourceContext,
+						Err:     fmt.Errorf("can't choose from an empty list"),
+					}
+				}
+
+				return list.Elements[randomSource().Intn(len(list.Elements))], nil
+			},
+		},
+	)
+
+	defn("shuffle",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispList{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				elements := append([]LispForm{}, args[0].(LispList).Elements...)
+				randomSource().Shuffle(len(elements), func(i, j int) {
+					elements[i], elements[j] = elements[j], elements[i]
+				})
+				return LispList{Elements: elements}, nil
+			},
+		},
+	)
+
+	defn("ms",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispNumber{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				quantity, err := positiveNumber(args[0])
+				if err != nil {
+					return nil, err
+				}
+				return LispDuration{NoteLengthMs{Quantity: quantity}}, nil
+			},
+		},
+	)
+
+	defn("note-length",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispNumber{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				denominator, err := positiveNumber(args[0])
+				if err != nil {
+					return nil, err
+				}
+
+				noteLength := NoteLength{Denominator: denominator}
+				return LispDuration{DurationComponent: noteLength}, nil
+			},
+		},
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispString{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				noteLength, err := noteLength(args[0].(LispString).Value)
+				if err != nil {
+					return nil, err
+				}
+
+				return LispDuration{DurationComponent: noteLength}, nil
+			},
+		},
+	)
+
+	defn("duration",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispVariadic{LispDuration{}}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				duration := Duration{}
+
+				for _, arg := range args {
+					component := arg.(LispDuration).DurationComponent
+					duration.Components = append(duration.Components, component)
+				}
+
+				return LispDuration{DurationComponent: duration}, nil
 			},
 		},
 	)
@@ -1313,6 +2135,40 @@ func init() {
 		},
 	)
 
+	// A degree of the scale of the part's key, e.g. (degree 5) for the dominant.
+	defn("degree",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispNumber{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				degree, err := integerInRange(args[0], 1, 64)
+				if err != nil {
+					return nil, err
+				}
+				return LispPitch{ScaleDegree{Degree: degree}}, nil
+			},
+		},
+	)
+
+	// A pitch that is an interval above (or below) another pitch, e.g.
+	// (interval "M3" (degree 1)) or (interval "-P5" (pitch '(c))).
+	defn("interval",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispString{}, LispPitch{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				name := args[0].(LispString)
+
+				semitones, err := IntervalSemitones(name.Value)
+				if err != nil {
+					return nil, &AldaSourceError{Context: name.SourceContext, Err: err}
+				}
+
+				return LispPitch{ChordTone{
+					Root: args[1].(LispPitch).PitchIdentifier, Semitones: semitones,
+				}}, nil
+			},
+		},
+	)
+
 	defn("note",
 		FunctionSignature{
 			ArgumentTypes: []LispForm{LispPitch{}},
@@ -1375,6 +2231,227 @@ func init() {
 		},
 	)
 
+	// Returns a chord, given a chord symbol like "Cmaj7/G", or a Roman numeral
+	// like "ii7" followed by :in-key. The keyword arguments :inversion, :voicing
+	// ("close", "drop-2" or "spread"), :octave and :duration change the way that
+	// the chord is played.
+	defn("chord",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispString{}, LispVariadic{LispAny{}}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				symbol := args[0].(LispString)
+				inKey, rest := keywordFlag(args[1:], "in-key")
+
+				values, err := keywordArguments(
+					rest, "inversion", "voicing", "octave", "duration",
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				chordSymbol, err := ParseChordSymbol(symbol.Value, inKey)
+				if err != nil {
+					return nil, &AldaSourceError{Context: symbol.SourceContext, Err: err}
+				}
+
+				inversion, octaves, voicing := int32(0), int32(0), CloseVoicing
+				duration := Duration{}
+
+				for keyword, value := range values {
+					switch keyword {
+					case "inversion", "octave":
+						if _, ok := value.(LispNumber); !ok {
+							return nil, fmt.Errorf(
+								"expected a number after :%s, got %s",
+								keyword, value.TypeString(),
+							)
+						}
+
+						if keyword == "inversion" {
+							inversion, err = integer(value)
+						} else {
+							octaves, err = integer(value)
+						}
+					case "voicing":
+						name, ok := value.(LispString)
+						if !ok {
+							return nil, fmt.Errorf(
+								"expected a string after :voicing, got %s", value.TypeString(),
+							)
+						}
+						voicing, err = NewVoicing(name.Value)
+					case "duration":
+						component, ok := value.(LispDuration)
+						if !ok {
+							return nil, fmt.Errorf(
+								"expected a duration after :duration, got %s",
+								value.TypeString(),
+							)
+						}
+						duration.Components = []DurationComponent{
+							component.DurationComponent,
+						}
+					}
+
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				semitones, err := chordSymbol.Voiced(inversion, voicing, octaves)
+				if err != nil {
+					return nil, err
+				}
+
+				chord := chordSymbol.Chord(semitones, duration)
+				chord.SourceContext = symbol.SourceContext
+
+				return LispScoreUpdate{ScoreUpdate: chord}, nil
+			},
+		},
+	)
+
+	// Defines the groove templates in a JSON file. See grooveTemplateFile.
+	//
+	// Like an `include`, a relative path is relative to the directory of the
+	// score file, or the working directory if the input didn't come from a file.
+	defn("load-grooves",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispString{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				filename := args[0].(LispString)
+
+				path := filename.Value
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(
+						filepath.Dir(filename.SourceContext.Filename), path,
+					)
+				}
+
+				templates, err := ReadGrooveTemplates(path)
+				if err != nil {
+					return nil, &AldaSourceError{
+						Context: filename.SourceContext,
+						Err:     err,
+					}
+				}
+
+				return LispScoreUpdate{
+					ScoreUpdate: GrooveTemplates{
+						Filename: path, Templates: templates,
+					},
+				}, nil
+			},
+		},
+	)
+
+	controlChange := FunctionSignature{
+		ArgumentTypes: []LispForm{LispNumber{}, LispNumber{}},
+		Implementation: func(args ...LispForm) (LispForm, error) {
+			controller, err := integerInRange(args[0], 0, 127)
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := integerInRange(args[1], 0, 127)
+			if err != nil {
+				return nil, err
+			}
+
+			cc := ControlChange{Controller: controller, Value: value}
+			return LispScoreUpdate{ScoreUpdate: cc}, nil
+		},
+	}
+
+	defn("control-change", controlChange)
+	defn("cc", controlChange)
+
+	defn("sustain-pedal",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispBoolean{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				cc := ControlChange{Controller: SustainPedalController}
+				if args[0].(LispBoolean).Value {
+					cc.Value = 127
+				}
+				return LispScoreUpdate{ScoreUpdate: cc}, nil
+			},
+		},
+	)
+
+	// NB: The player uses the expression controller to set each part's track
+	// volume, so an expression value only lasts until the track volume changes.
+	for _, _controller := range []struct {
+		name   string
+		number int32
+	}{
+		{"modulation", ModulationController},
+		{"expression", ExpressionController},
+	} {
+		// See the comment above about closures in Go.
+		controller := _controller
+
+		defn(controller.name,
+			FunctionSignature{
+				ArgumentTypes: []LispForm{LispNumber{}},
+				Implementation: func(args ...LispForm) (LispForm, error) {
+					value, err := percentage(args[0])
+					if err != nil {
+						return nil, err
+					}
+
+					cc := ControlChange{
+						Controller: controller.number,
+						Value:      int32(math.Round(value * 127)),
+					}
+					return LispScoreUpdate{ScoreUpdate: cc}, nil
+				},
+			},
+		)
+	}
+
+	// The amount of pitch bend is expressed as a number from -100 (fully down) to
+	// 100 (fully up).
+	defn("pitch-bend",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispNumber{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				number := args[0].(LispNumber)
+
+				if number.Value < -100 || number.Value > 100 {
+					return nil, &AldaSourceError{
+						Context: number.SourceContext,
+						Err: fmt.Errorf(
+							"value not between -100 and 100: %f", number.Value,
+						),
+					}
+				}
+
+				value := int32(math.Round(number.Value / 100 * -float64(minPitchBend)))
+				if value > maxPitchBend {
+					value = maxPitchBend
+				}
+
+				return LispScoreUpdate{ScoreUpdate: PitchBend{Value: value}}, nil
+			},
+		},
+	)
+
+	defn("aftertouch",
+		FunctionSignature{
+			ArgumentTypes: []LispForm{LispNumber{}},
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				value, err := percentage(args[0])
+				if err != nil {
+					return nil, err
+				}
+
+				aftertouch := Aftertouch{Value: int32(math.Round(value * 127))}
+				return LispScoreUpdate{ScoreUpdate: aftertouch}, nil
+			},
+		},
+	)
+
 	// TODO: Support more advanced part declarations, e.g. multiple instruments
 	// in a group (`trumpet/piano`) and aliases (`trumpet/piano 'trumpiano'`).
 	// This will require a more nuanced API design for the Lisp function
@@ -1486,16 +2563,34 @@ func (sym LispSymbol) String() string {
 	return "'" + sym.Name
 }
 
+// IsKeyword returns true if the symbol starts with a colon, e.g. `:timing`.
+//
+// Like keywords in Clojure, keywords are used to name the arguments of a
+// function, e.g. (humanize :timing 8 :velocity 5).
+func (sym LispSymbol) IsKeyword() bool {
+	return len(sym.Name) > 1 && strings.HasPrefix(sym.Name, ":")
+}
+
 // Eval implements LispForm.Eval by resolving the symbol and returning the
-// corresponding value.
+// corresponding value. A keyword evaluates to itself.
 //
 // Returns an error if the symbol cannot be resolved.
 func (sym LispSymbol) Eval() (LispForm, error) {
+	if sym.IsKeyword() {
+		return sym, nil
+	}
+
 	specialForm, hit := specialForms[sym.Name]
 	if hit {
 		return specialForm, nil
 	}
 
+	if lispScore != nil {
+		if value, hit := lispScore.definitions[sym.Name]; hit {
+			return value, nil
+		}
+	}
+
 	value, hit := environment[sym.Name]
 	if hit {
 		return value, nil
@@ -1533,6 +2628,32 @@ func (n LispNumber) Eval() (LispForm, error) {
 	return n, nil
 }
 
+// LispBoolean is a boolean value, i.e. true or false.
+type LispBoolean struct {
+	SourceContext AldaSourceContext
+	Value         bool
+}
+
+// GetSourceContext implements HasSourceContext.GetSourceContext.
+func (b LispBoolean) GetSourceContext() AldaSourceContext {
+	return b.SourceContext
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (b LispBoolean) JSON() *json.Container {
+	return json.Object("type", "boolean", "value", b.Value)
+}
+
+// TypeString implements LispForm.TypeString.
+func (LispBoolean) TypeString() string {
+	return "boolean"
+}
+
+// Eval implements LispForm.Eval by returning the boolean.
+func (b LispBoolean) Eval() (LispForm, error) {
+	return b, nil
+}
+
 // LispString is a string value.
 type LispString struct {
 	SourceContext AldaSourceContext
@@ -1706,6 +2827,14 @@ func (l LispList) Eval() (LispForm, error) {
 		}
 
 		return l.Elements[1], nil
+	case LispSpecialFormDefn:
+		return evalDefn(l)
+	case LispSpecialFormFn:
+		return evalFn(l)
+	case LispSpecialFormLet:
+		return evalLet(l)
+	case LispSpecialFormIf:
+		return evalIf(l)
 	}
 
 	arguments := []LispForm{}
@@ -1735,6 +2864,16 @@ func unpackScoreUpdate(form LispForm) ScoreUpdate {
 	switch form := form.(type) {
 	case LispScoreUpdate:
 		return form.ScoreUpdate
+	case LispNil:
+		return form
+	case LispList:
+		// A list of score updates (e.g. the result of `map`) is treated as a
+		// sequence of events.
+		events := []ScoreUpdate{}
+		for _, element := range form.Elements {
+			events = append(events, unpackScoreUpdate(element))
+		}
+		return EventSequence{SourceContext: form.SourceContext, Events: events}
 	default:
 		log.Warn().
 			Interface("form", form).
@@ -1751,7 +2890,12 @@ func (l LispList) UpdateScore(score *Score) error {
 		return err
 	}
 
-	return score.Update(unpackScoreUpdate(result))
+	// The events that the S-expression evaluates to (e.g. the notes of a chord
+	// symbol) aren't written anywhere in the source code, so they are traced
+	// back to the S-expression.
+	return score.withSourceContext(l.SourceContext, func() error {
+		return score.Update(unpackScoreUpdate(result))
+	})
 }
 
 // DurationMs implements ScoreUpdate.DurationMs by evaluating the S-expression
@@ -0,0 +1,540 @@
+package model
+
+import (
+	"fmt"
+
+	"alda.io/client/json"
+)
+
+// This file contains the special forms that allow score authors to define
+// their own functions and local bindings:
+//
+//   (defn name (params...) body...)
+//   (fn (params...) body...)
+//   (let (name value name value ...) body...)
+//   (if condition then else)
+//
+// alda-lisp forms don't have access to an environment when they are
+// evaluated, so parameters and `let` bindings are implemented by substituting
+// the bound values into the body before it is evaluated. This gives us lexical
+// scope: a function created inside of a `let` or another function "closes
+// over" the values that were bound at the time.
+
+// Functions defined via `defn` belong to the score that is being updated when
+// they are defined (see enterLispContext), so they don't carry over into other
+// scores. Symbols are resolved against the score's definitions before the
+// built-in functions.
+
+// maxCallDepth is the maximum number of nested user-defined function calls,
+// which prevents runaway recursion from crashing the process.
+const maxCallDepth = 1000
+
+// builtins is the set of names of the functions that are defined as part of
+// the runtime. Score authors can't redefine them via `defn`.
+var builtins = map[string]bool{}
+
+// LispSpecialFormDefn is the special form `defn`.
+type LispSpecialFormDefn struct{}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (LispSpecialFormDefn) JSON() *json.Container {
+	return json.Object("type", "special-form", "value", "defn")
+}
+
+// TypeString implements LispForm.TypeString.
+func (LispSpecialFormDefn) TypeString() string {
+	return "special-form"
+}
+
+// GetSourceContext implements HasSourceContext.GetSourceContext.
+func (LispSpecialFormDefn) GetSourceContext() AldaSourceContext {
+	// See the comment in LispSpecialFormQuote.GetSourceContext.
+	return AldaSourceContext{}
+}
+
+// Eval implements LispForm.Eval by returning the special form.
+func (d LispSpecialFormDefn) Eval() (LispForm, error) {
+	return d, nil
+}
+
+// LispSpecialFormFn is the special form `fn`.
+type LispSpecialFormFn struct{}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (LispSpecialFormFn) JSON() *json.Container {
+	return json.Object("type", "special-form", "value", "fn")
+}
+
+// TypeString implements LispForm.TypeString.
+func (LispSpecialFormFn) TypeString() string {
+	return "special-form"
+}
+
+// GetSourceContext implements HasSourceContext.GetSourceContext.
+func (LispSpecialFormFn) GetSourceContext() AldaSourceContext {
+	// See the comment in LispSpecialFormQuote.GetSourceContext.
+	return AldaSourceContext{}
+}
+
+// Eval implements LispForm.Eval by returning the special form.
+func (f LispSpecialFormFn) Eval() (LispForm, error) {
+	return f, nil
+}
+
+// LispSpecialFormLet is the special form `let`.
+type LispSpecialFormLet struct{}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (LispSpecialFormLet) JSON() *json.Container {
+	return json.Object("type", "special-form", "value", "let")
+}
+
+// TypeString implements LispForm.TypeString.
+func (LispSpecialFormLet) TypeString() string {
+	return "special-form"
+}
+
+// GetSourceContext implements HasSourceContext.GetSourceContext.
+func (LispSpecialFormLet) GetSourceContext() AldaSourceContext {
+	// See the comment in LispSpecialFormQuote.GetSourceContext.
+	return AldaSourceContext{}
+}
+
+// Eval implements LispForm.Eval by returning the special form.
+func (l LispSpecialFormLet) Eval() (LispForm, error) {
+	return l, nil
+}
+
+// LispSpecialFormIf is the special form `if`.
+type LispSpecialFormIf struct{}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (LispSpecialFormIf) JSON() *json.Container {
+	return json.Object("type", "special-form", "value", "if")
+}
+
+// TypeString implements LispForm.TypeString.
+func (LispSpecialFormIf) TypeString() string {
+	return "special-form"
+}
+
+// GetSourceContext implements HasSourceContext.GetSourceContext.
+func (LispSpecialFormIf) GetSourceContext() AldaSourceContext {
+	// See the comment in LispSpecialFormQuote.GetSourceContext.
+	return AldaSourceContext{}
+}
+
+// Eval implements LispForm.Eval by returning the special form.
+func (i LispSpecialFormIf) Eval() (LispForm, error) {
+	return i, nil
+}
+
+// formSourceContext returns the source context of a form if it has one, or
+// the fallback context otherwise.
+func formSourceContext(
+	form LispForm, fallback AldaSourceContext,
+) AldaSourceContext {
+	if form, ok := form.(HasSourceContext); ok {
+		if context := form.GetSourceContext(); context.Line != 0 {
+			return context
+		}
+	}
+
+	return fallback
+}
+
+// truthy returns false if a value is nil or false, and true otherwise.
+func truthy(form LispForm) bool {
+	switch form := form.(type) {
+	case LispNil:
+		return false
+	case LispBoolean:
+		return form.Value
+	default:
+		return true
+	}
+}
+
+// withoutBindings returns a copy of `bindings` without the provided names,
+// which are shadowed by new bindings.
+func withoutBindings(
+	bindings map[string]LispForm, names ...string,
+) map[string]LispForm {
+	result := map[string]LispForm{}
+	for name, value := range bindings {
+		result[name] = value
+	}
+
+	for _, name := range names {
+		delete(result, name)
+	}
+
+	return result
+}
+
+// symbolNames returns the names of the symbols in a list, ignoring any other
+// forms. (Invalid parameter lists are reported when the form is evaluated.)
+func symbolNames(form LispForm) []string {
+	names := []string{}
+
+	if list, ok := form.(LispList); ok {
+		for _, element := range list.Elements {
+			if symbol, ok := element.(LispSymbol); ok {
+				names = append(names, symbol.Name)
+			}
+		}
+	}
+
+	return names
+}
+
+// substitute returns a copy of `form` where each symbol that is bound in
+// `bindings` is replaced by its (quoted) value.
+//
+// Quoted forms are left alone, as are symbols that are shadowed by the
+// parameters of a nested function or the bindings of a nested `let`.
+func substitute(form LispForm, bindings map[string]LispForm) LispForm {
+	if len(bindings) == 0 {
+		return form
+	}
+
+	switch form := form.(type) {
+	case LispSymbol:
+		if value, hit := bindings[form.Name]; hit {
+			// The value is quoted so that evaluating it again (e.g. a list or a
+			// symbol) returns the value itself.
+			return LispQuotedForm{SourceContext: form.SourceContext, Form: value}
+		}
+		return form
+	case LispList:
+		return substituteList(form, bindings)
+	default:
+		return form
+	}
+}
+
+func substituteList(list LispList, bindings map[string]LispForm) LispList {
+	elements := list.Elements
+	result := LispList{SourceContext: list.SourceContext}
+
+	substituteAll := func(forms []LispForm, bindings map[string]LispForm) {
+		for _, form := range forms {
+			result.Elements = append(result.Elements, substitute(form, bindings))
+		}
+	}
+
+	operator := ""
+	if len(elements) > 0 {
+		if symbol, ok := elements[0].(LispSymbol); ok {
+			if _, shadowed := bindings[symbol.Name]; !shadowed {
+				operator = symbol.Name
+			}
+		}
+	}
+
+	switch {
+	case operator == "quote":
+		return list
+	case operator == "fn" && len(elements) > 1:
+		result.Elements = append(result.Elements, elements[:2]...)
+		substituteAll(
+			elements[2:], withoutBindings(bindings, symbolNames(elements[1])...),
+		)
+	case operator == "defn" && len(elements) > 2:
+		result.Elements = append(result.Elements, elements[:3]...)
+		substituteAll(
+			elements[3:], withoutBindings(bindings, symbolNames(elements[2])...),
+		)
+	case operator == "let" && len(elements) > 1:
+		bindingList, ok := elements[1].(LispList)
+		if !ok {
+			substituteAll(elements, bindings)
+			break
+		}
+
+		// Each binding can refer to the ones before it, so a binding shadows
+		// outer bindings for the remaining bindings and the body.
+		innerBindings := bindings
+		newBindingList := LispList{SourceContext: bindingList.SourceContext}
+		for i, form := range bindingList.Elements {
+			if i%2 == 0 {
+				newBindingList.Elements = append(newBindingList.Elements, form)
+				continue
+			}
+
+			newBindingList.Elements = append(
+				newBindingList.Elements, substitute(form, innerBindings),
+			)
+			if symbol, ok := bindingList.Elements[i-1].(LispSymbol); ok {
+				innerBindings = withoutBindings(innerBindings, symbol.Name)
+			}
+		}
+
+		result.Elements = append(result.Elements, elements[0], newBindingList)
+		substituteAll(elements[2:], innerBindings)
+	default:
+		substituteAll(elements, bindings)
+	}
+
+	return result
+}
+
+// evalBody evaluates a sequence of forms after substituting the provided
+// bindings, and returns the value of the last one (or nil, if there are none).
+func evalBody(
+	body []LispForm, bindings map[string]LispForm,
+) (LispForm, error) {
+	var result LispForm = LispNil{}
+
+	for _, form := range body {
+		value, err := substitute(form, bindings).Eval()
+		if err != nil {
+			return nil, err
+		}
+		result = value
+	}
+
+	return result, nil
+}
+
+// userFunction returns a function with a single signature, which binds the
+// provided arguments to the parameters and evaluates the body.
+//
+// The last parameter can be preceded by `&`, in which case any remaining
+// arguments are bound to it as a list.
+func userFunction(
+	name string, params LispForm, body []LispForm, context AldaSourceContext,
+) (LispFunction, error) {
+	paramList, ok := params.(LispList)
+	if !ok {
+		return LispFunction{}, &AldaSourceError{
+			Context: formSourceContext(params, context),
+			Err: fmt.Errorf(
+				"expected a list of parameters, got %s", params.TypeString(),
+			),
+		}
+	}
+
+	names := []string{}
+	argumentTypes := []LispForm{}
+	variadic := false
+
+	for i, param := range paramList.Elements {
+		symbol, ok := param.(LispSymbol)
+		if !ok {
+			return LispFunction{}, &AldaSourceError{
+				Context: formSourceContext(param, context),
+				Err: fmt.Errorf(
+					"expected parameter to be a symbol, got %s", param.TypeString(),
+				),
+			}
+		}
+
+		if symbol.Name == "&" {
+			if i != len(paramList.Elements)-2 {
+				return LispFunction{}, &AldaSourceError{
+					Context: formSourceContext(param, context),
+					Err:     fmt.Errorf("expected exactly one parameter after `&`"),
+				}
+			}
+			variadic = true
+			continue
+		}
+
+		for _, name := range names {
+			if name == symbol.Name {
+				return LispFunction{}, &AldaSourceError{
+					Context: formSourceContext(param, context),
+					Err:     fmt.Errorf("duplicate parameter: %s", symbol.Name),
+				}
+			}
+		}
+
+		names = append(names, symbol.Name)
+
+		if variadic {
+			argumentTypes = append(argumentTypes, LispVariadic{LispAny{}})
+		} else {
+			argumentTypes = append(argumentTypes, LispAny{})
+		}
+	}
+
+	fn := LispFunction{
+		Name: name,
+		Signatures: []FunctionSignature{{
+			ArgumentTypes: argumentTypes,
+			Implementation: func(args ...LispForm) (LispForm, error) {
+				bindings := map[string]LispForm{}
+
+				for i, name := range names {
+					if variadic && i == len(names)-1 {
+						bindings[name] = LispList{
+							Elements: append([]LispForm{}, args[i:]...),
+						}
+						break
+					}
+					bindings[name] = args[i]
+				}
+
+				if score := lispScore; score != nil {
+					if score.callDepth >= maxCallDepth {
+						return nil, fmt.Errorf(
+							"maximum function call depth (%d) exceeded in `%s`",
+							maxCallDepth, name,
+						)
+					}
+
+					score.callDepth++
+					defer func() { score.callDepth-- }()
+				}
+
+				return evalBody(body, bindings)
+			},
+		}},
+	}
+
+	if err := fn.Validate(); err != nil {
+		return LispFunction{}, &AldaSourceError{Context: context, Err: err}
+	}
+
+	return fn, nil
+}
+
+// evalDefn evaluates `(defn name (params...) body...)` by defining a function
+// that can be called for the rest of the score.
+func evalDefn(l LispList) (LispForm, error) {
+	if len(l.Elements) < 3 {
+		return nil, &AldaSourceError{
+			Context: l.SourceContext,
+			Err: fmt.Errorf(
+				"expected a name and a list of parameters after `defn`",
+			),
+		}
+	}
+
+	symbol, ok := l.Elements[1].(LispSymbol)
+	if !ok {
+		return nil, &AldaSourceError{
+			Context: formSourceContext(l.Elements[1], l.SourceContext),
+			Err: fmt.Errorf(
+				"expected function name to be a symbol, got %s",
+				l.Elements[1].TypeString(),
+			),
+		}
+	}
+
+	_, isSpecialForm := specialForms[symbol.Name]
+	if isSpecialForm || builtins[symbol.Name] {
+		return nil, &AldaSourceError{
+			Context: symbol.SourceContext,
+			Err:     fmt.Errorf("cannot redefine built-in `%s`", symbol.Name),
+		}
+	}
+
+	if lispScore == nil {
+		return nil, &AldaSourceError{
+			Context: l.SourceContext,
+			Err:     fmt.Errorf("`defn` can only be used in a score"),
+		}
+	}
+
+	fn, err := userFunction(
+		symbol.Name, l.Elements[2], l.Elements[3:], l.SourceContext,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	lispScore.definitions[symbol.Name] = fn
+
+	return LispNil{SourceContext: l.SourceContext}, nil
+}
+
+// evalFn evaluates `(fn (params...) body...)` by returning an anonymous
+// function.
+func evalFn(l LispList) (LispForm, error) {
+	if len(l.Elements) < 2 {
+		return nil, &AldaSourceError{
+			Context: l.SourceContext,
+			Err:     fmt.Errorf("expected a list of parameters after `fn`"),
+		}
+	}
+
+	return userFunction("fn", l.Elements[1], l.Elements[2:], l.SourceContext)
+}
+
+// evalLet evaluates `(let (name value name value ...) body...)` by binding
+// each name to its value and evaluating the body. Each value can refer to the
+// names bound before it.
+func evalLet(l LispList) (LispForm, error) {
+	if len(l.Elements) < 2 {
+		return nil, &AldaSourceError{
+			Context: l.SourceContext,
+			Err:     fmt.Errorf("expected a list of bindings after `let`"),
+		}
+	}
+
+	bindingList, ok := l.Elements[1].(LispList)
+	if !ok || len(bindingList.Elements)%2 != 0 {
+		return nil, &AldaSourceError{
+			Context: formSourceContext(l.Elements[1], l.SourceContext),
+			Err: fmt.Errorf(
+				"expected `let` bindings to be a list of names and values",
+			),
+		}
+	}
+
+	bindings := map[string]LispForm{}
+
+	for i := 0; i < len(bindingList.Elements); i += 2 {
+		symbol, ok := bindingList.Elements[i].(LispSymbol)
+		if !ok {
+			return nil, &AldaSourceError{
+				Context: formSourceContext(bindingList.Elements[i], l.SourceContext),
+				Err: fmt.Errorf(
+					"expected binding name to be a symbol, got %s",
+					bindingList.Elements[i].TypeString(),
+				),
+			}
+		}
+
+		value, err := substitute(bindingList.Elements[i+1], bindings).Eval()
+		if err != nil {
+			return nil, err
+		}
+
+		bindings[symbol.Name] = value
+	}
+
+	return evalBody(l.Elements[2:], bindings)
+}
+
+// evalIf evaluates `(if condition then else)` by evaluating `then` if the
+// condition is truthy, or `else` otherwise. The `else` form is optional and
+// defaults to nil.
+func evalIf(l LispList) (LispForm, error) {
+	arguments := l.Elements[1:]
+	if len(arguments) < 2 || len(arguments) > 3 {
+		return nil, &AldaSourceError{
+			Context: l.SourceContext,
+			Err: fmt.Errorf(
+				"expected 2 or 3 arguments to if, got %d", len(arguments),
+			),
+		}
+	}
+
+	condition, err := arguments[0].Eval()
+	if err != nil {
+		return nil, err
+	}
+
+	if truthy(condition) {
+		return arguments[1].Eval()
+	}
+
+	if len(arguments) == 3 {
+		return arguments[2].Eval()
+	}
+
+	return LispNil{SourceContext: l.SourceContext}, nil
+}
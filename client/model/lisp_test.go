@@ -0,0 +1,400 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	_ "alda.io/client/testing"
+)
+
+func sexp(elements ...LispForm) LispList {
+	return LispList{Elements: elements}
+}
+
+func sym(name string) LispSymbol {
+	return LispSymbol{Name: name}
+}
+
+func num(value float64) LispNumber {
+	return LispNumber{Value: value}
+}
+
+func quoted(form LispForm) LispQuotedForm {
+	return LispQuotedForm{Form: form}
+}
+
+// (note (pitch (list letter)))
+func noteForm(letter LispForm) LispList {
+	return sexp(sym("note"), sexp(sym("pitch"), sexp(sym("list"), letter)))
+}
+
+// (note (midi-note n))
+func midiNoteForm(n LispForm) LispList {
+	return sexp(sym("note"), sexp(sym("midi-note"), n))
+}
+
+func expectErrorMessage(substring string) func(error) error {
+	return func(err error) error {
+		if !strings.Contains(err.Error(), substring) {
+			return fmt.Errorf("expected error to contain %q, got %q", substring, err)
+		}
+
+		return nil
+	}
+}
+
+func expectErrorLine(line int) func(error) error {
+	return func(err error) error {
+		var sourceErr *AldaSourceError
+		for errors.As(err, &sourceErr) {
+			if sourceErr.Context.Line == line {
+				return nil
+			}
+			err = sourceErr.Err
+		}
+
+		return fmt.Errorf("expected error to have context on line %d: %v", line, err)
+	}
+}
+
+func TestUserFunctions(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "defn and call",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("defn"), sym("test-play"), sexp(sym("letter")),
+					noteForm(sym("letter"))),
+				sexp(sym("test-play"), quoted(sym("e"))),
+			},
+			expectations: []scoreUpdateExpectation{expectMidiNoteNumbers(64)},
+		},
+		scoreUpdateTestCase{
+			label: "map over a quoted list",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("defn"), sym("test-arpeggiate"), sexp(sym("letter")),
+					noteForm(sym("letter"))),
+				sexp(sym("map"), sym("test-arpeggiate"),
+					quoted(sexp(sym("c"), sym("e"), sym("g")))),
+			},
+			expectations: []scoreUpdateExpectation{expectMidiNoteNumbers(60, 64, 67)},
+		},
+		scoreUpdateTestCase{
+			label: "let and arithmetic",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("let"),
+					sexp(
+						sym("n"), sexp(sym("+"), num(1), num(2)),
+						sym("m"), sexp(sym("*"), sym("n"), num(2)),
+					),
+					sexp(sym("transpose"), sexp(sym("-"), sym("m"), num(1)))),
+				noteForm(quoted(sym("c"))),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectPartTransposition("piano", 5),
+				expectMidiNoteNumbers(65),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "if",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("if"), sexp(sym("<"), num(1), num(2)),
+					noteForm(quoted(sym("c"))), noteForm(quoted(sym("d")))),
+				sexp(sym("if"), sexp(sym("not"), sym("true")),
+					noteForm(quoted(sym("c"))), noteForm(quoted(sym("d")))),
+				sexp(sym("if"), sym("nil"), noteForm(quoted(sym("e")))),
+			},
+			expectations: []scoreUpdateExpectation{expectMidiNoteNumbers(60, 62)},
+		},
+		scoreUpdateTestCase{
+			label: "range, concat and a closure",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("let"), sexp(sym("root"), num(60)),
+					sexp(sym("concat"),
+						sexp(sym("list"), midiNoteForm(num(72))),
+						sexp(sym("map"),
+							sexp(sym("fn"), sexp(sym("n")),
+								midiNoteForm(sexp(sym("+"), sym("root"), sym("n")))),
+							sexp(sym("range"), num(0), num(6), num(2))))),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(72, 60, 62, 64),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "variadic parameters",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("defn"), sym("test-arpeggiate"), sexp(sym("letter")),
+					noteForm(sym("letter"))),
+				sexp(sym("defn"), sym("test-variadic"),
+					sexp(sym("first"), sym("&"), sym("more")),
+					sexp(sym("map"), sym("test-arpeggiate"),
+						sexp(sym("concat"), sexp(sym("list"), sym("first")), sym("more")))),
+				sexp(sym("test-variadic"), quoted(sym("d")), quoted(sym("f"))),
+			},
+			expectations: []scoreUpdateExpectation{expectMidiNoteNumbers(62, 65)},
+		},
+		scoreUpdateTestCase{
+			label: "recursion",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("defn"), sym("test-countdown"), sexp(sym("n")),
+					sexp(sym("if"), sexp(sym("<="), sym("n"), num(0)),
+						sym("nil"),
+						sexp(sym("list"),
+							midiNoteForm(sexp(sym("+"), num(60), sym("n"))),
+							sexp(sym("test-countdown"), sexp(sym("-"), sym("n"), num(1)))))),
+				sexp(sym("test-countdown"), num(3)),
+			},
+			expectations: []scoreUpdateExpectation{expectMidiNoteNumbers(63, 62, 61)},
+		},
+		scoreUpdateTestCase{
+			label: "shadowed bindings",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("let"), sexp(sym("x"), num(60)),
+					sexp(sym("map"),
+						sexp(sym("fn"), sexp(sym("x")), midiNoteForm(sym("x"))),
+						quoted(sexp(num(61), num(62)))),
+					// `quote` is left alone.
+					sexp(sym("if"),
+						sexp(sym("="), sexp(sym("quote"), sym("x")), quoted(sym("x"))),
+						midiNoteForm(sym("x")))),
+			},
+			expectations: []scoreUpdateExpectation{expectMidiNoteNumbers(60)},
+		},
+	)
+}
+
+func TestUserFunctionsBelongToTheScore(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+
+	score := NewScore()
+	if err := score.Update(
+		piano,
+		sexp(sym("defn"), sym("test-scoped"), sexp(), midiNoteForm(num(60))),
+		sexp(sym("test-scoped")),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	err := NewScore().Update(piano, sexp(sym("test-scoped")))
+	if err == nil || !strings.Contains(err.Error(), "unresolvable symbol") {
+		t.Errorf(
+			"expected a function defined in another score to be unresolvable, "+
+				"got error: %v",
+			err,
+		)
+	}
+}
+
+func TestUserFunctionErrors(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "wrong number of arguments",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("defn"), sym("test-one-arg"), sexp(sym("x")), sym("x")),
+				sexp(sym("test-one-arg"), num(1), num(2)),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("do not match the signature of `test-one-arg`"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "error in a function body",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("defn"), sym("test-divide"), sexp(sym("x")),
+					sexp(sym("/"), num(1), LispNumber{
+						SourceContext: AldaSourceContext{Line: 3, Column: 7},
+					})),
+				sexp(sym("test-divide"), num(1)),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("division by zero"),
+				expectErrorLine(3),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "redefining a built-in",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("defn"), LispSymbol{
+					SourceContext: AldaSourceContext{Line: 2, Column: 7},
+					Name:          "tempo",
+				}, sexp(), num(1)),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("cannot redefine built-in `tempo`"),
+				expectErrorLine(2),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "invalid parameter",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("defn"), sym("test-invalid"), sexp(num(1)), num(1)),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("expected parameter to be a symbol, got number"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "odd number of let bindings",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("let"), sexp(sym("x")), sym("x")),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("list of names and values"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "runaway recursion",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("defn"), sym("test-forever"), sexp(sym("n")),
+					sexp(sym("test-forever"), sym("n"))),
+				sexp(sym("test-forever"), num(1)),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("maximum function call depth"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "range with a step of 0",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("range"), num(0), num(4), num(0)),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("range step must not be 0"),
+			},
+		},
+	)
+}
+
+func midiNoteNumbers(score *Score) []int32 {
+	result := []int32{}
+	for _, event := range score.Events {
+		result = append(result, event.(NoteEvent).MidiNote)
+	}
+	return result
+}
+
+func TestRandomness(t *testing.T) {
+	generativeUpdates := func(seed float64) []ScoreUpdate {
+		return []ScoreUpdate{
+			PartDeclaration{Names: []string{"piano"}},
+			sexp(sym("seed"), num(seed)),
+			sexp(sym("map"),
+				sexp(sym("fn"), sexp(sym("n")),
+					midiNoteForm(sexp(sym("+"), num(60), sym("n")))),
+				sexp(sym("shuffle"), sexp(sym("range"), num(12)))),
+			midiNoteForm(sexp(sym("random"), num(40), num(50))),
+			midiNoteForm(sexp(sym("choose"), quoted(sexp(num(70), num(80))))),
+		}
+	}
+
+	build := func(updates []ScoreUpdate) []int32 {
+		score := NewScore()
+		if err := score.Update(updates...); err != nil {
+			t.Fatal(err)
+		}
+		return midiNoteNumbers(score)
+	}
+
+	first := build(generativeUpdates(42))
+	if len(first) != 14 {
+		t.Fatalf("expected 14 notes, got %v", first)
+	}
+
+	if second := build(generativeUpdates(42)); fmt.Sprint(first) != fmt.Sprint(second) {
+		t.Errorf("expected the same seed to produce %v, got %v", first, second)
+	}
+
+	if other := build(generativeUpdates(43)); fmt.Sprint(first) == fmt.Sprint(other) {
+		t.Errorf("expected a different seed to produce different notes: %v", other)
+	}
+
+	seen := map[int32]bool{}
+	for _, note := range first[:12] {
+		seen[note] = true
+	}
+	if len(seen) != 12 {
+		t.Errorf("expected shuffle to return each element once, got %v", first[:12])
+	}
+
+	if note := first[12]; note < 40 || note >= 50 {
+		t.Errorf("expected random note in [40, 50), got %d", note)
+	}
+
+	if note := first[13]; note != 70 && note != 80 {
+		t.Errorf("expected 70 or 80 to be chosen, got %d", note)
+	}
+
+	// Seeding the score directly is equivalent to using `(seed n)`.
+	score := NewScore()
+	score.SetRandomSeed(42)
+	updates := generativeUpdates(42)
+	// Leave out `(seed 42)`.
+	updates = append(updates[:1], updates[2:]...)
+	if err := score.Update(updates...); err != nil {
+		t.Fatal(err)
+	}
+	if actual := midiNoteNumbers(score); fmt.Sprint(first[:12]) != fmt.Sprint(actual[:12]) {
+		t.Errorf("expected SetRandomSeed to produce %v, got %v", first[:12], actual[:12])
+	}
+}
+
+func TestRandomnessErrors(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "choose from an empty list",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("choose"), quoted(sexp())),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("can't choose from an empty list"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "random with an empty range",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("random"), num(5), num(5)),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("expected upper bound greater than 5, got 5"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "non-integer seed",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("seed"), num(1.5)),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("expected integer"),
+			},
+		},
+	)
+}
@@ -6,6 +6,17 @@ import (
 	"alda.io/client/json"
 )
 
+// markerBeats is the position of a marker in beats, in terms of the part(s)
+// where the marker was placed.
+type markerBeats struct {
+	// The part's CurrentBeat.
+	beat float64
+	// The number of beats into the part's current measure.
+	beatsSinceBarline float64
+	// The remaining length of the part's pickup measure, if it was in one.
+	pickupBeats float64
+}
+
 // A Marker gives a name to a point in time in a score.
 type Marker struct {
 	SourceContext AldaSourceContext
@@ -49,6 +60,12 @@ func (marker Marker) UpdateScore(score *Score) error {
 	}
 
 	score.Markers[marker.Name] = offset
+	part := score.CurrentParts[0]
+	score.markerBeats[marker.Name] = markerBeats{
+		beat:              part.CurrentBeat,
+		beatsSinceBarline: part.beatsSinceBarline,
+		pickupBeats:       part.pickupBeats,
+	}
 
 	return nil
 }
@@ -99,9 +116,17 @@ func (atMarker AtMarker) UpdateScore(score *Score) error {
 		return fmt.Errorf("Marker undefined: %s", atMarker.Name)
 	}
 
+	beats := score.markerBeats[atMarker.Name]
+
 	for _, part := range score.CurrentParts {
 		part.LastOffset = part.CurrentOffset
 		part.CurrentOffset = offset
+		// The part picks up at the same point in the measure as the part where the
+		// marker was placed, so that bar checks and the swing/groove grid line up
+		// with the other parts.
+		part.CurrentBeat = beats.beat
+		part.beatsSinceBarline = beats.beatsSinceBarline
+		part.pickupBeats = beats.pickupBeats
 	}
 
 	return nil
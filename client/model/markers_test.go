@@ -91,6 +91,7 @@ func TestMarkers(t *testing.T) {
 				expectMarker("test-marker", 4000),
 				expectPartLastOffset("bassoon", 4000),
 				expectPartCurrentOffset("bassoon", 4500),
+				expectPartCurrentBeat("bassoon", 9),
 				expectNoteOffsets(4000),
 			},
 		},
@@ -0,0 +1,294 @@
+package model
+
+import (
+	"alda.io/client/json"
+)
+
+// Control change (CC) numbers for the controllers that have dedicated
+// functions in alda-lisp.
+//
+// ref: https://www.midi.org/specifications-old/item/table-3-control-change-messages-data-bytes-2
+const (
+	ModulationController   int32 = 1
+	ExpressionController   int32 = 11
+	SustainPedalController int32 = 64
+)
+
+// The range of MIDI pitch bend values. 0 means no pitch bend.
+const (
+	minPitchBend int32 = -8192
+	maxPitchBend int32 = 8191
+)
+
+// addMidiControlEvents adds an event produced by `event` to the score for each
+// current part, at the part's current offset.
+//
+// Like a note, a MIDI control event is tied to the MIDI channel of its part, so
+// we assign the part a MIDI channel if it doesn't already have one.
+func addMidiControlEvents(
+	score *Score, event func(part *Part, midiChannel int32) ScoreEvent,
+) error {
+	if err := score.ApplyGlobalAttributes(); err != nil {
+		return err
+	}
+
+	for _, part := range score.CurrentParts {
+		midiChannel, err := score.assignMidiChannel(part, 0)
+		if err != nil {
+			return err
+		}
+
+		part.MidiChannel = midiChannel
+		part.origin.MidiChannel = midiChannel
+
+		score.Events = append(score.Events, event(part, midiChannel))
+	}
+
+	return nil
+}
+
+// A ControlChange sets the value of a MIDI controller (e.g. the sustain pedal)
+// on the channels of the current parts.
+type ControlChange struct {
+	SourceContext AldaSourceContext
+	// The control change (CC) number, 0-127.
+	Controller int32
+	// The value of the controller, 0-127.
+	Value int32
+}
+
+// GetSourceContext implements HasSourceContext.GetSourceContext.
+func (cc ControlChange) GetSourceContext() AldaSourceContext {
+	return cc.SourceContext
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (cc ControlChange) JSON() *json.Container {
+	return json.Object(
+		"type", "control-change",
+		"value", json.Object("controller", cc.Controller, "value", cc.Value),
+	)
+}
+
+// UpdateScore implements ScoreUpdate.UpdateScore by adding a
+// ControlChangeEvent to the score for each current part.
+func (cc ControlChange) UpdateScore(score *Score) error {
+	return addMidiControlEvents(
+		score,
+		func(part *Part, midiChannel int32) ScoreEvent {
+			return ControlChangeEvent{
+				Part:           part.origin,
+				MidiChannel:    midiChannel,
+				Offset:         part.CurrentOffset,
+				Controller:     cc.Controller,
+				Value:          cc.Value,
+				SourceContexts: score.sourceContexts(cc.SourceContext),
+			}
+		},
+	)
+}
+
+// DurationMs implements ScoreUpdate.DurationMs by returning 0, since a control
+// change is instantaneous.
+func (ControlChange) DurationMs(part *Part) float64 {
+	return 0
+}
+
+// VariableValue implements ScoreUpdate.VariableValue.
+func (cc ControlChange) VariableValue(score *Score) (ScoreUpdate, error) {
+	return cc, nil
+}
+
+// A ControlChangeEvent is a ControlChange expressed in absolute terms, i.e. on a
+// particular MIDI channel at a particular offset.
+type ControlChangeEvent struct {
+	Part           *Part
+	MidiChannel    int32
+	Offset         float64
+	Controller     int32
+	Value          int32
+	SourceContexts []AldaSourceContext
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (cc ControlChangeEvent) JSON() *json.Container {
+	return json.Object(
+		"part", cc.Part.ID,
+		"midi-channel", cc.MidiChannel,
+		"offset", cc.Offset,
+		"controller", cc.Controller,
+		"value", cc.Value,
+		"source-context", sourceContextsJSON(cc.SourceContexts),
+	)
+}
+
+// EventOffset implements ScoreEvent.EventOffset by returning the offset of the
+// control change.
+func (cc ControlChangeEvent) EventOffset() float64 {
+	return cc.Offset
+}
+
+// EventSourceContexts implements ScoreEvent.EventSourceContexts.
+func (cc ControlChangeEvent) EventSourceContexts() []AldaSourceContext {
+	return cc.SourceContexts
+}
+
+// A PitchBend bends the pitch of the notes on the channels of the current parts
+// up or down, until the next PitchBend.
+type PitchBend struct {
+	SourceContext AldaSourceContext
+	// The amount of pitch bend, from -8192 (fully down) to 8191 (fully up). 0
+	// means no pitch bend.
+	//
+	// How far a full pitch bend bends the pitch is up to the synthesizer, but it
+	// is usually 2 semitones.
+	Value int32
+}
+
+// GetSourceContext implements HasSourceContext.GetSourceContext.
+func (pb PitchBend) GetSourceContext() AldaSourceContext {
+	return pb.SourceContext
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (pb PitchBend) JSON() *json.Container {
+	return json.Object("type", "pitch-bend", "value", pb.Value)
+}
+
+// UpdateScore implements ScoreUpdate.UpdateScore by adding a PitchBendEvent to
+// the score for each current part.
+func (pb PitchBend) UpdateScore(score *Score) error {
+	return addMidiControlEvents(
+		score,
+		func(part *Part, midiChannel int32) ScoreEvent {
+			return PitchBendEvent{
+				Part:           part.origin,
+				MidiChannel:    midiChannel,
+				Offset:         part.CurrentOffset,
+				Value:          pb.Value,
+				SourceContexts: score.sourceContexts(pb.SourceContext),
+			}
+		},
+	)
+}
+
+// DurationMs implements ScoreUpdate.DurationMs by returning 0, since a pitch
+// bend is instantaneous.
+func (PitchBend) DurationMs(part *Part) float64 {
+	return 0
+}
+
+// VariableValue implements ScoreUpdate.VariableValue.
+func (pb PitchBend) VariableValue(score *Score) (ScoreUpdate, error) {
+	return pb, nil
+}
+
+// A PitchBendEvent is a PitchBend expressed in absolute terms, i.e. on a
+// particular MIDI channel at a particular offset.
+type PitchBendEvent struct {
+	Part           *Part
+	MidiChannel    int32
+	Offset         float64
+	Value          int32
+	SourceContexts []AldaSourceContext
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (pb PitchBendEvent) JSON() *json.Container {
+	return json.Object(
+		"part", pb.Part.ID,
+		"midi-channel", pb.MidiChannel,
+		"offset", pb.Offset,
+		"value", pb.Value,
+		"source-context", sourceContextsJSON(pb.SourceContexts),
+	)
+}
+
+// EventOffset implements ScoreEvent.EventOffset by returning the offset of the
+// pitch bend.
+func (pb PitchBendEvent) EventOffset() float64 {
+	return pb.Offset
+}
+
+// EventSourceContexts implements ScoreEvent.EventSourceContexts.
+func (pb PitchBendEvent) EventSourceContexts() []AldaSourceContext {
+	return pb.SourceContexts
+}
+
+// An Aftertouch sets the channel pressure (i.e. how hard the keys are being
+// pressed after they're struck) on the channels of the current parts.
+type Aftertouch struct {
+	SourceContext AldaSourceContext
+	// The amount of pressure, 0-127.
+	Value int32
+}
+
+// GetSourceContext implements HasSourceContext.GetSourceContext.
+func (at Aftertouch) GetSourceContext() AldaSourceContext {
+	return at.SourceContext
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (at Aftertouch) JSON() *json.Container {
+	return json.Object("type", "aftertouch", "value", at.Value)
+}
+
+// UpdateScore implements ScoreUpdate.UpdateScore by adding an AftertouchEvent
+// to the score for each current part.
+func (at Aftertouch) UpdateScore(score *Score) error {
+	return addMidiControlEvents(
+		score,
+		func(part *Part, midiChannel int32) ScoreEvent {
+			return AftertouchEvent{
+				Part:           part.origin,
+				MidiChannel:    midiChannel,
+				Offset:         part.CurrentOffset,
+				Value:          at.Value,
+				SourceContexts: score.sourceContexts(at.SourceContext),
+			}
+		},
+	)
+}
+
+// DurationMs implements ScoreUpdate.DurationMs by returning 0, since a change
+// in channel pressure is instantaneous.
+func (Aftertouch) DurationMs(part *Part) float64 {
+	return 0
+}
+
+// VariableValue implements ScoreUpdate.VariableValue.
+func (at Aftertouch) VariableValue(score *Score) (ScoreUpdate, error) {
+	return at, nil
+}
+
+// An AftertouchEvent is an Aftertouch expressed in absolute terms, i.e. on a
+// particular MIDI channel at a particular offset.
+type AftertouchEvent struct {
+	Part           *Part
+	MidiChannel    int32
+	Offset         float64
+	Value          int32
+	SourceContexts []AldaSourceContext
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (at AftertouchEvent) JSON() *json.Container {
+	return json.Object(
+		"part", at.Part.ID,
+		"midi-channel", at.MidiChannel,
+		"offset", at.Offset,
+		"value", at.Value,
+		"source-context", sourceContextsJSON(at.SourceContexts),
+	)
+}
+
+// EventOffset implements ScoreEvent.EventOffset by returning the offset of the
+// aftertouch.
+func (at AftertouchEvent) EventOffset() float64 {
+	return at.Offset
+}
+
+// EventSourceContexts implements ScoreEvent.EventSourceContexts.
+func (at AftertouchEvent) EventSourceContexts() []AldaSourceContext {
+	return at.SourceContexts
+}
@@ -0,0 +1,200 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	_ "alda.io/client/testing"
+)
+
+// Returns an expectation that the score's events are the events returned by
+// `expected`, which is given the score so that it can refer to its parts.
+func expectEvents(expected func(s *Score) []ScoreEvent) func(*Score) error {
+	return func(s *Score) error {
+		expectedEvents := expected(s)
+
+		if len(s.Events) != len(expectedEvents) {
+			return fmt.Errorf(
+				"expected %d events, got %d", len(expectedEvents), len(s.Events),
+			)
+		}
+
+		for i, event := range s.Events {
+			if !reflect.DeepEqual(event, expectedEvents[i]) {
+				return fmt.Errorf(
+					"expected event #%d to be %#v, but it was %#v",
+					i+1, expectedEvents[i], event,
+				)
+			}
+		}
+
+		return nil
+	}
+}
+
+func TestMidiControlEvents(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	quarterNote := Note{
+		Pitch: LetterAndAccidentals{NoteLetter: C},
+		Duration: Duration{
+			Components: []DurationComponent{NoteLength{Denominator: 4}},
+		},
+	}
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "control change",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("control-change"), num(91), num(100)),
+				quarterNote,
+				sexp(sym("cc"), num(0), num(1)),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectEvents(func(s *Score) []ScoreEvent {
+					part := s.Parts[0]
+					return []ScoreEvent{
+						ControlChangeEvent{
+							Part: part, MidiChannel: 0, Offset: 0,
+							Controller: 91, Value: 100,
+						},
+						s.Events[1],
+						ControlChangeEvent{
+							Part: part, MidiChannel: 0, Offset: 500,
+							Controller: 0, Value: 1,
+						},
+					}
+				}),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "sustain pedal, modulation and expression",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("sustain-pedal"), sym("true")),
+				sexp(sym("modulation"), num(50)),
+				sexp(sym("expression"), num(100)),
+				quarterNote,
+				sexp(sym("sustain-pedal"), sym("false")),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectEvents(func(s *Score) []ScoreEvent {
+					part := s.Parts[0]
+					return []ScoreEvent{
+						ControlChangeEvent{
+							Part: part, Controller: SustainPedalController, Value: 127,
+						},
+						ControlChangeEvent{
+							Part: part, Controller: ModulationController, Value: 64,
+						},
+						ControlChangeEvent{
+							Part: part, Controller: ExpressionController, Value: 127,
+						},
+						s.Events[3],
+						ControlChangeEvent{
+							Part: part, Offset: 500,
+							Controller: SustainPedalController, Value: 0,
+						},
+					}
+				}),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "pitch bend",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("pitch-bend"), num(100)),
+				sexp(sym("pitch-bend"), num(-100)),
+				sexp(sym("pitch-bend"), num(50)),
+				sexp(sym("pitch-bend"), num(0)),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectEvents(func(s *Score) []ScoreEvent {
+					part := s.Parts[0]
+					return []ScoreEvent{
+						PitchBendEvent{Part: part, Value: 8191},
+						PitchBendEvent{Part: part, Value: -8192},
+						PitchBendEvent{Part: part, Value: 4096},
+						PitchBendEvent{Part: part, Value: 0},
+					}
+				}),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "aftertouch",
+			updates: []ScoreUpdate{
+				piano,
+				quarterNote,
+				sexp(sym("aftertouch"), num(100)),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectEvents(func(s *Score) []ScoreEvent {
+					return []ScoreEvent{
+						s.Events[0],
+						AftertouchEvent{Part: s.Parts[0], Offset: 500, Value: 127},
+					}
+				}),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "control events use the part's MIDI channel",
+			updates: []ScoreUpdate{
+				PartDeclaration{Names: []string{"percussion"}},
+				sexp(sym("cc"), num(1), num(2)),
+				piano,
+				sexp(sym("midi-channel"), num(5)),
+				sexp(sym("aftertouch"), num(0)),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectEvents(func(s *Score) []ScoreEvent {
+					return []ScoreEvent{
+						ControlChangeEvent{
+							Part: s.Parts[0], MidiChannel: 9, Controller: 1, Value: 2,
+						},
+						AftertouchEvent{Part: s.Parts[1], MidiChannel: 5},
+					}
+				}),
+			},
+		},
+	)
+}
+
+func TestMidiControlEventValidation(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "controller number out of range",
+			updates: []ScoreUpdate{
+				piano, sexp(sym("control-change"), num(128), num(0)),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("expected integer in range 0-127, got 128"),
+			},
+		},
+		scoreUpdateTestCase{
+			label:   "control change value out of range",
+			updates: []ScoreUpdate{piano, sexp(sym("cc"), num(64), num(-1))},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("expected integer in range 0-127, got -1"),
+			},
+		},
+		scoreUpdateTestCase{
+			label:   "pitch bend out of range",
+			updates: []ScoreUpdate{piano, sexp(sym("pitch-bend"), num(101))},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("value not between -100 and 100"),
+			},
+		},
+		scoreUpdateTestCase{
+			label:   "sustain pedal requires a boolean",
+			updates: []ScoreUpdate{piano, sexp(sym("sustain-pedal"), num(1))},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("sustain-pedal"),
+			},
+		},
+	)
+}
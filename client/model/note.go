@@ -14,6 +14,12 @@ type Note struct {
 	// When a note is slurred, it means there is minimal space between that note
 	// and the next.
 	Slurred bool
+	// Changes to the way that this note (and only this note) is played, e.g.
+	// staccato. See articulation.go.
+	Articulations []Articulation
+	// Raises (or lowers, when negative) the pitch of the note by a number of
+	// cents, i.e. hundredths of a semitone.
+	Cents float64
 }
 
 // GetSourceContext implements HasSourceContext.GetSourceContext.
@@ -29,25 +35,46 @@ func (note Note) JSON() *json.Container {
 		value.Set(note.Duration.JSON(), "duration")
 	}
 
+	if note.Cents != 0 {
+		value.Set(note.Cents, "cents")
+	}
+
 	if note.Slurred {
 		value.Set(true, "slurred?")
 	}
 
+	if len(note.Articulations) > 0 {
+		articulations := json.Array()
+		for _, articulation := range note.Articulations {
+			articulations.ArrayAppend(articulation.String())
+		}
+		value.Set(articulations, "articulations")
+	}
+
 	return json.Object("type", "note", "value", value)
 }
 
 // A NoteEvent is a Note expressed in absolute terms with the goal of performing
 // the note e.g. on a MIDI sequencer/synthesizer.
 type NoteEvent struct {
-	Part            *Part
-	MidiChannel     int32
-	MidiNote        int32
+	Part        *Part
+	MidiChannel int32
+	// The MIDI note number of the note as written, in 12-tone equal temperament.
+	MidiNote int32
+	// The pitch that the note sounds at, as a fractional MIDI note number, e.g.
+	// 60.5 is a quarter tone above middle C. This is different from MidiNote
+	// when the note has cents, or the part has a tuning or a reference pitch
+	// other than A440.
+	Pitch           float64
 	Offset          float64
 	Duration        float64
 	AudibleDuration float64
 	Volume          float64
 	TrackVolume     float64
 	Panning         float64
+	// The chain of places in the source code that led to the note, ending with
+	// the note itself. See ScoreEvent.EventSourceContexts.
+	SourceContexts []AldaSourceContext
 }
 
 // JSON implements RepresentableAsJSON.JSON.
@@ -56,12 +83,14 @@ func (note NoteEvent) JSON() *json.Container {
 		"part", note.Part.ID,
 		"midi-channel", note.MidiChannel,
 		"midi-note", note.MidiNote,
+		"pitch", note.Pitch,
 		"offset", note.Offset,
 		"duration", note.Duration,
 		"audible-duration", note.AudibleDuration,
 		"volume", note.Volume,
 		"track-volume", note.TrackVolume,
 		"panning", note.Panning,
+		"source-context", sourceContextsJSON(note.SourceContexts),
 	)
 }
 
@@ -71,6 +100,11 @@ func (note NoteEvent) EventOffset() float64 {
 	return note.Offset
 }
 
+// EventSourceContexts implements ScoreEvent.EventSourceContexts.
+func (note NoteEvent) EventSourceContexts() []AldaSourceContext {
+	return note.SourceContexts
+}
+
 func effectiveDuration(specifiedDuration Duration, part *Part) Duration {
 	// If no duration is specified, use the part's default duration.
 	if specifiedDuration.Components == nil {
@@ -113,25 +147,41 @@ func addNoteOrRest(score *Score, noteOrRest ScoreUpdate) error {
 	}
 
 	for _, part := range score.CurrentParts {
+		part.applyRamps(part.CurrentOffset)
+
 		duration := effectiveDuration(specifiedDuration, part)
 		durationMs := duration.Ms(part.Tempo) * part.TimeScale
 
 		switch noteOrRest := noteOrRest.(type) {
 		case Note:
-			audibleDurationMs := durationMs
-			if !noteOrRest.Slurred {
-				audibleDurationMs *= part.Quantization
-			}
+			durationMs = noteOrRest.heldDurationMs(durationMs)
+			audibleDurationMs := noteOrRest.audibleDurationMs(
+				durationMs, part.Quantization,
+			)
 
 			if audibleDurationMs > 0 {
-				midiNote := noteOrRest.Pitch.CalculateMidiNote(
-					part.Octave, part.KeySignature, part.Transposition,
-				)
+				midiNote, err := part.MidiNote(noteOrRest.Pitch)
+				if err != nil {
+					return err
+				}
 
 				if midiNote < 0 || midiNote > 127 {
 					return help.UserFacingErrorf("MIDI note out of the 0-127 range. Input note: %d", midiNote)
 				}
 
+				pitch, err := part.pitch(midiNote, noteOrRest.Cents)
+				if err != nil {
+					return err
+				}
+
+				if pitch <= -0.5 || pitch >= 127.5 {
+					return help.UserFacingErrorf(
+						"Pitch out of the MIDI note range (0-127). Input note: %d, "+
+							"pitch: %.2f",
+						midiNote, pitch,
+					)
+				}
+
 				midiChannel, err := score.assignMidiChannel(part, audibleDurationMs)
 				if err != nil {
 					return err
@@ -144,14 +194,19 @@ func addNoteOrRest(score *Score, noteOrRest ScoreUpdate) error {
 					Part:            part.origin,
 					MidiChannel:     midiChannel,
 					MidiNote:        midiNote,
+					Pitch:           pitch,
 					Offset:          part.CurrentOffset,
 					Duration:        durationMs,
 					AudibleDuration: audibleDurationMs,
-					Volume:          part.Volume,
+					Volume:          noteOrRest.volume(part.Volume),
 					TrackVolume:     part.TrackVolume,
 					Panning:         part.Panning,
+					SourceContexts:  score.sourceContexts(noteOrRest.SourceContext),
 				}
 
+				part.applySwingAndGroove(&noteEvent)
+				part.humanize(&noteEvent)
+
 				log.Debug().
 					Int32("MidiNote", noteEvent.MidiNote).
 					Float64("Offset", noteEvent.Offset).
@@ -170,9 +225,19 @@ func addNoteOrRest(score *Score, noteOrRest ScoreUpdate) error {
 			}
 		}
 
+		score.observeNoteOrRest(
+			part, noteOrRest, part.msToBeats(duration.Ms(part.Tempo)*part.TimeScale),
+		)
+
 		if !score.chordMode {
 			part.LastOffset = part.CurrentOffset
 			part.CurrentOffset += durationMs
+
+			if err := part.countNoteBeats(specifiedDuration); err != nil {
+				return err
+			}
+
+			score.observeAdvance(part)
 		}
 
 		updateDefaultDuration(part, duration)
@@ -195,7 +260,9 @@ func (note Note) UpdateScore(score *Score) error {
 // Also updates the part's default duration, so that it can be correctly
 // considered when tallying the duration of subsequent events.
 func (note Note) DurationMs(part *Part) float64 {
-	durationMs := effectiveDuration(note.Duration, part).Ms(part.Tempo)
+	durationMs := note.heldDurationMs(
+		effectiveDuration(note.Duration, part).Ms(part.Tempo),
+	)
 	updateDefaultDuration(part, note.Duration)
 	return durationMs
 }
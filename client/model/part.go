@@ -46,10 +46,14 @@ type Part struct {
 	ReferencePitch  float64
 	CurrentOffset   float64
 	LastOffset      float64
-	Octave          int32
-	Volume          float64
-	TrackVolume     float64
-	Panning         float64
+	// The part's current offset in quarter note beats, counting the written
+	// length of each note and rest. Unlike CurrentOffset, this doesn't depend on
+	// the tempo.
+	CurrentBeat float64
+	Octave      int32
+	Volume      float64
+	TrackVolume float64
+	Panning     float64
 	// The MIDI channel number (0-15) that this part is currently assigned to.
 	// Each time a note occurs for this part, the specified channel will be
 	// preferred if there are no other parts using that channel at that point in
@@ -69,6 +73,23 @@ type Part struct {
 	// A map of offset to the tempo value that should be applied at that offset.
 	// See *Part.RecordTempoValue.
 	TempoValues map[float64]float64
+	// When set, barlines check that the notes since the previous barline fill
+	// exactly one measure. See *Part.checkMeasure.
+	TimeSignature TimeSignature
+	// A map of offset to the time signature that takes effect at that offset.
+	TimeSignatureValues map[float64]TimeSignature
+	// Swing and Groove adjust the timing and volume of individual notes. See
+	// groove.go.
+	Swing  Swing
+	Groove *GrooveTemplate
+	// Random variations in the timing and volume of notes. See humanize.go.
+	Humanization Humanization
+	// The tonic of the part's key, when it's set explicitly rather than implied
+	// by the key signature. See ScaleDegree.
+	Tonic *LetterAndAccidentals
+	// When set, determines the pitch of each note instead of 12-tone equal
+	// temperament. See tuning.go.
+	Tuning *Tuning
 	// Used in order to track the case where a part overrides a global attribute
 	// change with a local attribute change just for that part, at the exact same
 	// offset.
@@ -78,6 +99,15 @@ type Part struct {
 	//
 	// See repetitions.go.
 	currentRepetition int32
+	// The number of beats since the previous barline, or since the time
+	// signature was set. See time_signature.go.
+	beatsSinceBarline float64
+	// When positive, the length (in beats) of the pickup measure, i.e. the
+	// expected number of beats before the next barline.
+	pickupBeats float64
+	// The attributes that are currently ramping (e.g. a crescendo) from one value
+	// to another. See ramp.go.
+	ramps map[RampAttribute]attributeRamp
 	// A snapshot copy of the part at the point in time when a voice group starts.
 	// This is used as a template for each new voice.
 	voiceTemplate *Part
@@ -105,6 +135,26 @@ func (part *Part) JSON() *json.Container {
 		tempoValues.Set(tempo, fmt.Sprintf("%f", offset))
 	}
 
+	timeSignatureValues := json.Object()
+	for offset, timeSignature := range part.TimeSignatureValues {
+		timeSignatureValues.Set(timeSignature.JSON(), fmt.Sprintf("%f", offset))
+	}
+
+	groove := ""
+	if part.Groove != nil {
+		groove = part.Groove.Name
+	}
+
+	var tonic interface{}
+	if part.Tonic != nil {
+		tonic = part.Tonic.JSON()
+	}
+
+	var tuning interface{}
+	if part.Tuning != nil {
+		tuning = part.Tuning.JSON()
+	}
+
 	return json.Object(
 		"id", part.ID,
 		"name", part.Name,
@@ -112,9 +162,17 @@ func (part *Part) JSON() *json.Container {
 		"tempo-role", part.TempoRole.String(),
 		"tempo", part.Tempo,
 		"key-signature", part.KeySignature.JSON(),
+		"tonic", tonic,
+		"time-signature", part.TimeSignature.JSON(),
+		"time-signature-values", timeSignatureValues,
+		"swing", part.Swing.JSON(),
+		"groove", groove,
+		"humanization", part.Humanization.JSON(),
 		"transposition", part.Transposition,
 		"reference-pitch", part.ReferencePitch,
+		"tuning", tuning,
 		"current-offset", part.CurrentOffset,
+		"current-beat", part.CurrentBeat,
 		"last-offset", part.LastOffset,
 		"octave", part.Octave,
 		"volume", part.Volume,
@@ -140,6 +198,12 @@ func (part *Part) Clone() *Part {
 
 	// Instead, we manually copy the fields here.
 	clone.currentRepetition = part.currentRepetition
+	clone.beatsSinceBarline = part.beatsSinceBarline
+	clone.pickupBeats = part.pickupBeats
+	clone.ramps = map[RampAttribute]attributeRamp{}
+	for attribute, ramp := range part.ramps {
+		clone.ramps[attribute] = ramp
+	}
 	clone.origin = part.origin
 	clone.voiceTemplate = part.voiceTemplate
 	clone.voices = part.voices
@@ -166,6 +230,8 @@ func (score *Score) NewPart(name string) (*Part, error) {
 		Octave:                 4,
 		Tempo:                  120,
 		TempoValues:            map[float64]float64{},
+		TimeSignatureValues:    map[float64]TimeSignature{},
+		ramps:                  map[RampAttribute]attributeRamp{},
 		Volume:                 DynamicVolumes["mf"],
 		TrackVolume:            100.0 / 127,
 		Panning:                0.5,
@@ -2,6 +2,7 @@ package model
 
 import (
 	"fmt"
+	"strings"
 
 	"alda.io/client/json"
 )
@@ -138,6 +139,27 @@ type PitchIdentifier interface {
 	) int32
 }
 
+// A partPitch is a PitchIdentifier whose pitch depends on more about the part
+// playing the note than the arguments of CalculateMidiNote, e.g. the tonic of
+// the part's key, or whether the part is a percussion part.
+type partPitch interface {
+	// midiNoteInPart returns the MIDI note number of a note in the context of the
+	// part playing it, or an error if the pitch can't be determined in that
+	// context.
+	midiNoteInPart(part *Part) (int32, error)
+}
+
+// MidiNote returns the MIDI note number of a pitch in the context of the part.
+func (part *Part) MidiNote(pitch PitchIdentifier) (int32, error) {
+	if pitch, ok := pitch.(partPitch); ok {
+		return pitch.midiNoteInPart(part)
+	}
+
+	return pitch.CalculateMidiNote(
+		part.Octave, part.KeySignature, part.Transposition,
+	), nil
+}
+
 // LetterAndAccidentals specifies a pitch as a note letter and (optional)
 // accidentals.
 type LetterAndAccidentals struct {
@@ -203,3 +225,27 @@ func (mnn MidiNoteNumber) CalculateMidiNote(
 ) int32 {
 	return mnn.MidiNote + transposition
 }
+
+// The number of semitones in each named interval, e.g. "M3" (a major third).
+var namedIntervals = map[string]int32{
+	"P1": 0, "m2": 1, "M2": 2, "m3": 3, "M3": 4, "P4": 5, "A4": 6, "d5": 6,
+	"P5": 7, "A5": 8, "m6": 8, "M6": 9, "m7": 10, "M7": 11, "P8": 12, "m9": 13,
+	"M9": 14, "m10": 15, "M10": 16, "P11": 17, "P12": 19, "M13": 21, "P15": 24,
+}
+
+// IntervalSemitones returns the number of semitones in a named interval, e.g.
+// 4 for "M3" (a major third). A leading "-" means that the interval is
+// descending, e.g. -7 for "-P5".
+func IntervalSemitones(name string) (int32, error) {
+	direction := int32(1)
+	if strings.HasPrefix(name, "-") {
+		direction = -1
+	}
+
+	semitones, hit := namedIntervals[strings.TrimPrefix(name, "-")]
+	if !hit {
+		return 0, fmt.Errorf("invalid interval: %q (expected e.g. M3 or -P5)", name)
+	}
+
+	return direction * semitones, nil
+}
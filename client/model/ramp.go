@@ -0,0 +1,194 @@
+package model
+
+import (
+	"fmt"
+
+	"alda.io/client/json"
+)
+
+// A RampAttribute is an attribute that can change gradually over time via an
+// AttributeRamp.
+type RampAttribute int
+
+const (
+	// RampVolume ramps the volume, e.g. a crescendo or diminuendo.
+	RampVolume RampAttribute = iota
+	// RampTrackVolume ramps the track volume.
+	RampTrackVolume
+	// RampPanning ramps the panning, e.g. a sweep from left to right.
+	RampPanning
+	// RampTempo ramps the tempo, e.g. an accelerando or ritardando.
+	RampTempo
+)
+
+func (attribute RampAttribute) String() string {
+	switch attribute {
+	case RampVolume:
+		return "volume"
+	case RampTrackVolume:
+		return "track-volume"
+	case RampPanning:
+		return "panning"
+	case RampTempo:
+		return "tempo"
+	default:
+		return fmt.Sprintf("%d (unknown)", attribute)
+	}
+}
+
+func (attribute RampAttribute) value(part *Part) float64 {
+	switch attribute {
+	case RampVolume:
+		return part.Volume
+	case RampTrackVolume:
+		return part.TrackVolume
+	case RampPanning:
+		return part.Panning
+	default:
+		return part.Tempo
+	}
+}
+
+func (attribute RampAttribute) setValue(
+	part *Part, value float64, offset float64,
+) {
+	switch attribute {
+	case RampVolume:
+		part.Volume = value
+	case RampTrackVolume:
+		part.TrackVolume = value
+	case RampPanning:
+		part.Panning = value
+	default:
+		part.Tempo = value
+		// Unlike a global TempoSet, we record the tempo even when the ramp is
+		// global, because the global attribute itinerary only knows where the ramp
+		// starts, not the tempo values along the way. See *Score.TempoItinerary.
+		part.TempoValues[offset] = value
+	}
+}
+
+// An attributeRamp is an AttributeRamp that is in progress for a part, in
+// absolute terms.
+type attributeRamp struct {
+	startOffset float64
+	endOffset   float64
+	startValue  float64
+	targetValue float64
+}
+
+// valueAt returns the value of the attribute at `offset`, interpolating
+// linearly between the start value and the target value.
+func (ramp attributeRamp) valueAt(offset float64) float64 {
+	if offset <= ramp.startOffset {
+		return ramp.startValue
+	}
+
+	if offset >= ramp.endOffset {
+		return ramp.targetValue
+	}
+
+	progress := (offset - ramp.startOffset) / (ramp.endOffset - ramp.startOffset)
+	return ramp.startValue + (ramp.targetValue-ramp.startValue)*progress
+}
+
+// applyRamps sets the values of the part's ramping attributes to their values
+// at `offset`. Ramps that are complete by that point are discarded.
+//
+// This is done right before each note, so that the note has the correct volume,
+// panning, etc., and so that the note's duration reflects the current tempo.
+func (part *Part) applyRamps(offset float64) {
+	for attribute, ramp := range part.ramps {
+		attribute.setValue(part, ramp.valueAt(offset), offset)
+
+		if offset >= ramp.endOffset {
+			delete(part.ramps, attribute)
+		}
+	}
+}
+
+// cancelRamp stops ramping an attribute. This happens when the attribute is set
+// to a specific value, e.g. `(vol 50)` in the middle of a crescendo.
+func (part *Part) cancelRamp(attribute RampAttribute) {
+	delete(part.ramps, attribute)
+}
+
+// AttributeRamp gradually changes the value of an attribute of all active parts
+// from its current value to a target value, over a duration.
+type AttributeRamp struct {
+	Attribute RampAttribute
+	Target    float64
+	// The length of the ramp, as a note duration.
+	Duration Duration
+	// When positive, the length of the ramp is a number of measures of the part's
+	// time signature instead of `Duration`.
+	Bars int32
+	// The offset where a global ramp was placed. See
+	// GlobalAttributeUpdate.UpdateScore.
+	globalOffset float64
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (ar AttributeRamp) JSON() *json.Container {
+	value := json.Object("ramp-to", ar.Target)
+
+	if ar.Bars > 0 {
+		value.Set(ar.Bars, "bars")
+	} else {
+		value.Set(ar.Duration.JSON(), "duration")
+	}
+
+	return json.Object("attribute", ar.Attribute.String(), "value", value)
+}
+
+// Returns the length of the ramp in beats.
+func (ar AttributeRamp) beats(part *Part) (float64, error) {
+	if ar.Bars <= 0 {
+		return ar.Duration.Beats(), nil
+	}
+
+	if !part.TimeSignature.IsSet() {
+		return 0, fmt.Errorf(
+			"can't ramp %s over %d bar(s) because %s has no time signature",
+			ar.Attribute, ar.Bars, part.Name,
+		)
+	}
+
+	return float64(ar.Bars) * part.TimeSignature.MeasureBeats(), nil
+}
+
+func (ar AttributeRamp) updatePart(part *Part, globalUpdate bool) error {
+	startOffset := part.CurrentOffset
+	if globalUpdate {
+		startOffset = ar.globalOffset
+	}
+
+	beats, err := ar.beats(part)
+	if err != nil {
+		return err
+	}
+
+	// Start from wherever any ramps in progress are at this point.
+	part.applyRamps(startOffset)
+
+	startValue := ar.Attribute.value(part)
+
+	// Normally, the tempo stays the same throughout the ramp, so the ramp takes
+	// as long as its duration at the current tempo.
+	//
+	// When the tempo itself is ramping, it changes linearly over time, so the
+	// average tempo is halfway between the start and target tempos.
+	tempo := part.Tempo
+	if ar.Attribute == RampTempo {
+		tempo = (startValue + ar.Target) / 2
+	}
+
+	part.ramps[ar.Attribute] = attributeRamp{
+		startOffset: startOffset,
+		endOffset:   startOffset + beats*60000/tempo*part.TimeScale,
+		startValue:  startValue,
+		targetValue: ar.Target,
+	}
+
+	return nil
+}
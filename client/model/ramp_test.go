@@ -0,0 +1,231 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	_ "alda.io/client/testing"
+)
+
+func expectNoteVolumes(expectedVolumes ...float64) func(*Score) error {
+	return expectNoteFloatValues(
+		"volume",
+		func(note NoteEvent) float64 { return note.Volume },
+		expectedVolumes,
+	)
+}
+
+func expectTempoItinerary(expected map[float64]float64) func(*Score) error {
+	sortedOffsets := func(itinerary map[float64]float64) []float64 {
+		offsets := []float64{}
+		for offset := range itinerary {
+			offsets = append(offsets, offset)
+		}
+		sort.Float64s(offsets)
+		return offsets
+	}
+
+	return func(s *Score) error {
+		actual := s.TempoItinerary()
+		expectedOffsets, actualOffsets := sortedOffsets(expected), sortedOffsets(actual)
+
+		if len(actualOffsets) != len(expectedOffsets) {
+			return fmt.Errorf("expected tempo itinerary %v, got %v", expected, actual)
+		}
+
+		for i, offset := range expectedOffsets {
+			if !equalish(offset, actualOffsets[i]) ||
+				!equalish(expected[offset], actual[actualOffsets[i]]) {
+				return fmt.Errorf(
+					"expected tempo itinerary %v, got %v", expected, actual,
+				)
+			}
+		}
+
+		return nil
+	}
+}
+
+func TestAttributeRamps(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	violin := PartDeclaration{Names: []string{"violin"}}
+	// A note with the part's default duration (a quarter note, unless otherwise
+	// specified).
+	c := noteWithLength()
+	half := noteWithLength(NoteLength{Denominator: 2})
+	whole := noteWithLength(NoteLength{Denominator: 1})
+	quarter := noteWithLength(NoteLength{Denominator: 4})
+	str := func(value string) LispString { return LispString{Value: value} }
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "crescendo",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("vol"), num(0)),
+				sexp(sym("cresc"), num(100), str("1")),
+				c, c, c, c, c,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteVolumes(0, 0.25, 0.5, 0.75, 1),
+				expectPartVolume("piano", 1),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "diminuendo to a dynamic marking",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("vol"), num(100)),
+				sexp(sym("dim"), str("pp"), str("2")),
+				c, c, c,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteVolumes(
+					1, (1+DynamicVolumes["pp"])/2, DynamicVolumes["pp"],
+				),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "a volume change cancels a crescendo",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("vol"), num(0)),
+				sexp(sym("cresc"), num(100), str("1")),
+				c,
+				sexp(sym("vol"), num(50)),
+				c, c, c, c,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteVolumes(0, 0.5, 0.5, 0.5, 0.5),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "panning and track volume ramps",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("pan"), num(0)),
+				sexp(sym("pan-ramp"), num(100), str("2")),
+				sexp(sym("track-vol"), num(100)),
+				sexp(sym("track-volume-ramp"), num(0), str("4~4")),
+				c, c, c,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteFloatValues(
+					"panning",
+					func(note NoteEvent) float64 { return note.Panning },
+					[]float64{0, 0.5, 1},
+				),
+				expectNoteFloatValues(
+					"track volume",
+					func(note NoteEvent) float64 { return note.TrackVolume },
+					[]float64{1, 0.5, 0},
+				),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "ramp over a number of bars",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("time-signature"), num(3), num(4)),
+				sexp(sym("vol"), num(0)),
+				sexp(sym("cresc"), num(60), str("2 bars")),
+				half, quarter, quarter, half, quarter,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteVolumes(0, 0.2, 0.3, 0.4, 0.6),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "accelerando",
+			updates: []ScoreUpdate{
+				piano,
+				// The average tempo is 150 bpm, so the ramp lasts 1600 ms.
+				sexp(sym("accel"), num(180), str("1")),
+				c, c,
+				sexp(sym("tempo"), num(60)),
+				c,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteOffsets(0, 500, 500+60000/138.75),
+				expectTempoItinerary(map[float64]float64{
+					0: 120, 500: 138.75, 500 + 60000/138.75: 60,
+				}),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "ritardando ends at the target tempo",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("tempo"), num(100)),
+				// The average tempo is 75 bpm, so the ramp lasts 800 ms.
+				sexp(sym("rit"), num(50), str("4")),
+				c, c, c,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectPartTempo("piano", 50),
+				expectTempoItinerary(
+					map[float64]float64{0: 100, 600: 62.5, 1560: 50},
+				),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "global crescendo",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("vol"), num(0)),
+				half,
+				sexp(sym("cresc!"), num(100), str("1")),
+				whole, whole,
+				violin,
+				sexp(sym("vol"), num(0)),
+				whole, quarter, quarter, quarter,
+			},
+			expectations: []scoreUpdateExpectation{
+				expectNoteVolumes(0, 0, 1, 0, 0.5, 0.75, 1),
+			},
+		},
+	)
+}
+
+func TestAttributeRampErrors(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	str := func(value string) LispString { return LispString{Value: value} }
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "ramp over bars without a time signature",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("cresc"), num(100), str("4 bars")),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage(
+					"can't ramp volume over 4 bar(s) because piano has no time signature",
+				),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "invalid ramp length",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("pan-ramp"), num(100), str("four bars")),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("invalid note length"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "invalid dynamic marking",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("dim"), str("ppq"), str("1")),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("invalid dynamic marking"),
+			},
+		},
+	)
+}
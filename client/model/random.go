@@ -0,0 +1,104 @@
+package model
+
+import (
+	"math/rand"
+	"time"
+
+	"alda.io/client/json"
+)
+
+// Each score has its own random number generator, which is used by the
+// alda-lisp randomness functions (`random`, `choose`, `shuffle`).
+//
+// alda-lisp functions don't have access to the score when they are evaluated,
+// so while a score is being updated, the score is made available to the lisp
+// environment as `lispScore`. This is also how functions defined via `defn`
+// belong to the score that defines them. (See lisp_special_forms.go.)
+//
+// Seeding the generator (via `(seed n)` in the score or Score.SetRandomSeed)
+// makes the generative output of a score reproducible.
+
+// lispScore is the score that is currently being updated, or nil if no score
+// is being updated.
+var lispScore *Score
+
+// fallbackRandom is used when a lisp form is evaluated outside of the context
+// of a score update.
+var fallbackRandom = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func randomSource() *rand.Rand {
+	if lispScore != nil {
+		return lispScore.random
+	}
+
+	return fallbackRandom
+}
+
+// enterLispContext makes the score's random number generator and function
+// definitions available to alda-lisp, and returns a function that restores the
+// previous ones.
+func (score *Score) enterLispContext() func() {
+	previousScore := lispScore
+	lispScore = score
+
+	return func() { lispScore = previousScore }
+}
+
+// EvalLisp evaluates a form in the context of the score, i.e. using the score's
+// random number generator and function definitions.
+//
+// This is only needed when evaluating a form outside of Score.Update.
+func (score *Score) EvalLisp(form LispForm) (LispForm, error) {
+	defer score.enterLispContext()()
+
+	return form.Eval()
+}
+
+// SetRandomSeed seeds the score's random number generator, so that the
+// randomness functions in alda-lisp produce the same values each time that the
+// score is built.
+//
+// The seed also determines how notes are humanized from this point on. See
+// humanize.go.
+func (score *Score) SetRandomSeed(seed int64) {
+	score.random.Seed(seed)
+	score.seed = seed
+	score.partRandoms = map[string]*rand.Rand{}
+}
+
+// A RandomSeedSet seeds the random number generator of a score.
+type RandomSeedSet struct {
+	SourceContext AldaSourceContext
+	Seed          int64
+}
+
+// GetSourceContext implements HasSourceContext.GetSourceContext.
+func (rss RandomSeedSet) GetSourceContext() AldaSourceContext {
+	return rss.SourceContext
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (rss RandomSeedSet) JSON() *json.Container {
+	return json.Object(
+		"type", "random-seed",
+		"value", json.Object("seed", rss.Seed),
+	)
+}
+
+// UpdateScore implements ScoreUpdate.UpdateScore by seeding the score's random
+// number generator.
+func (rss RandomSeedSet) UpdateScore(score *Score) error {
+	score.SetRandomSeed(rss.Seed)
+	return nil
+}
+
+// DurationMs implements ScoreUpdate.DurationMs by returning 0, since seeding
+// the random number generator is conceptually instantaneous.
+func (RandomSeedSet) DurationMs(part *Part) float64 {
+	return 0
+}
+
+// VariableValue implements ScoreUpdate.VariableValue.
+func (rss RandomSeedSet) VariableValue(score *Score) (ScoreUpdate, error) {
+	return rss, nil
+}
@@ -42,7 +42,9 @@ func (repeat Repeat) UpdateScore(score *Score) error {
 			part.currentRepetition = repetition
 		}
 
-		if err := score.Update(repeat.Event); err != nil {
+		if err := score.withSourceContext(repeat.SourceContext, func() error {
+			return score.Update(repeat.Event)
+		}); err != nil {
 			return err
 		}
 	}
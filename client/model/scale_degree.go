@@ -0,0 +1,134 @@
+package model
+
+import (
+	"alda.io/client/help"
+	"alda.io/client/json"
+)
+
+// A ScaleDegree specifies a pitch as a degree of the scale of the part's key,
+// counting from the tonic (1), and (optional) accidentals, e.g. ^3- is a minor
+// third above the tonic in a major key.
+//
+// Degrees above 7 continue up the scale into the next octave, e.g. 8 is the
+// tonic an octave higher.
+//
+// A ScaleDegree is also the root of a Roman numeral chord symbol, e.g. 2 for
+// the chord ii7.
+type ScaleDegree struct {
+	Degree      int32
+	Accidentals []Accidental
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (sd ScaleDegree) JSON() *json.Container {
+	accidentals := json.Array()
+	for _, accidental := range sd.Accidentals {
+		accidentals.ArrayAppend(accidental.String())
+	}
+
+	return json.Object("degree", sd.Degree, "accidentals", accidentals)
+}
+
+// midiNote returns the MIDI note number of the scale degree, given the tonic
+// of the key. The tonic is placed in the given octave, and the scale goes up
+// from there.
+func (sd ScaleDegree) midiNote(
+	tonic LetterAndAccidentals,
+	octave int32,
+	keySignature KeySignature,
+	transposition int32,
+) int32 {
+	steps := sd.Degree - 1
+
+	pitch := tonic
+	if steps%7 != 0 {
+		pitch = LetterAndAccidentals{
+			NoteLetter: NoteLetter((int32(tonic.NoteLetter) + steps%7) % 7),
+		}
+	}
+
+	midiNote := pitch.CalculateMidiNote(octave, keySignature, 0) + (steps/7)*12
+
+	// Octaves start at C, so the letters that come before the tonic's letter
+	// (counting from C) are in the next octave up.
+	if NoteLetterIntervals[pitch.NoteLetter] <
+		NoteLetterIntervals[tonic.NoteLetter] {
+		midiNote += 12
+	}
+
+	for _, accidental := range sd.Accidentals {
+		switch accidental {
+		case Flat:
+			midiNote--
+		case Sharp:
+			midiNote++
+		}
+	}
+
+	return midiNote + transposition
+}
+
+// CalculateMidiNote implements PitchIdentifier.CalculateMidiNote by counting
+// up the scale of the major key that has the given key signature.
+//
+// Notes played by a part use the part's tonic instead (see *Part.MidiNote),
+// which also supports minor keys and modes.
+//
+// Returns -1 (an invalid MIDI note) if the key signature isn't that of a major
+// key.
+func (sd ScaleDegree) CalculateMidiNote(
+	octave int32, keySignature KeySignature, transposition int32,
+) int32 {
+	tonic, ok := keySignature.majorTonic()
+	if !ok {
+		return -1
+	}
+
+	return sd.midiNote(
+		LetterAndAccidentals{NoteLetter: tonic}, octave, keySignature, transposition,
+	)
+}
+
+func (sd ScaleDegree) midiNoteInPart(part *Part) (int32, error) {
+	tonic, ok := part.tonic()
+	if !ok {
+		return 0, help.UserFacingErrorf(
+			"Can't find scale degree %d in part \"%s\", because its key "+
+				"signature (%s) doesn't imply a tonic. Use the tonic attribute to "+
+				"set one.",
+			sd.Degree, part.Name, part.KeySignature.String(),
+		)
+	}
+
+	return sd.midiNote(
+		tonic, part.Octave, part.KeySignature, part.Transposition,
+	), nil
+}
+
+// tonic returns the tonic of the part's key, which is either set explicitly or
+// implied by the key signature.
+func (part *Part) tonic() (LetterAndAccidentals, bool) {
+	if part.Tonic != nil {
+		return *part.Tonic, true
+	}
+
+	letter, ok := part.KeySignature.majorTonic()
+	return LetterAndAccidentals{NoteLetter: letter}, ok
+}
+
+// TonicSet sets the tonic of all active parts, which determines the pitch of
+// each scale degree.
+type TonicSet struct {
+	Tonic LetterAndAccidentals
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (ts TonicSet) JSON() *json.Container {
+	return json.Object("attribute", "tonic", "value", ts.Tonic.JSON())
+}
+
+func (ts TonicSet) updatePart(part *Part, globalUpdate bool) error {
+	tonic := ts.Tonic
+	part.Tonic = &tonic
+	return nil
+}
@@ -0,0 +1,180 @@
+package model
+
+import (
+	"testing"
+
+	_ "alda.io/client/testing"
+)
+
+func TestScaleDegrees(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	str := func(value string) LispString { return LispString{Value: value} }
+	degree := func(degree int32, accidentals ...Accidental) Note {
+		return Note{Pitch: ScaleDegree{Degree: degree, Accidentals: accidentals}}
+	}
+	keySig := func(tonic string, mode string) LispList {
+		return sexp(
+			sym("key-signature"),
+			sexp(sym("quote"), sexp(sym(tonic), sym(mode))),
+		)
+	}
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "C major by default",
+			updates: []ScoreUpdate{
+				piano, degree(1), degree(3), degree(5), degree(7),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(60, 64, 67, 71),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "G major",
+			updates: []ScoreUpdate{
+				piano, keySig("g", "major"),
+				degree(1), degree(4), degree(7), degree(8),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(67, 72, 78, 79),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "minor keys start from their own tonic",
+			updates: []ScoreUpdate{
+				piano, keySig("a", "minor"), degree(1), degree(3), degree(5),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(69, 72, 76),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "accidentals",
+			updates: []ScoreUpdate{
+				piano, degree(3, Flat), degree(4, Sharp),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(63, 66),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "tonic attribute",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("tonic"), str("e")),
+				degree(1), degree(2), degree(3),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(64, 65, 67),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "changing the key changes the scale degrees",
+			updates: []ScoreUpdate{
+				piano,
+				degree(5),
+				keySig("d", "major"),
+				degree(5),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(67, 69),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "octave and transposition",
+			updates: []ScoreUpdate{
+				piano,
+				AttributeUpdate{PartUpdate: OctaveSet{OctaveNumber: 3}},
+				AttributeUpdate{PartUpdate: TranspositionSet{Semitones: 2}},
+				degree(1), degree(5),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(50, 57),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "degree function",
+			updates: []ScoreUpdate{
+				piano, sexp(sym("note"), sexp(sym("degree"), num(9))),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(74),
+			},
+		},
+	)
+}
+
+func TestIntervals(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	str := func(value string) LispString { return LispString{Value: value} }
+	interval := func(name string, from LispList) LispList {
+		return sexp(sym("note"), sexp(sym("interval"), str(name), from))
+	}
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "intervals above and below a scale degree",
+			updates: []ScoreUpdate{
+				piano,
+				AttributeUpdate{PartUpdate: KeySignatureSet{
+					KeySignature: KeySignatureFromScale(
+						LetterAndAccidentals{NoteLetter: F}, Ionian,
+					),
+				}},
+				interval("M3", sexp(sym("degree"), num(1))),
+				interval("-P5", sexp(sym("degree"), num(1))),
+				interval("P8", sexp(sym("degree"), num(2))),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(69, 58, 79),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "interval above a note",
+			updates: []ScoreUpdate{
+				piano,
+				interval("m7", sexp(sym("pitch"), sexp(sym("quote"), sexp(sym("d"))))),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(72),
+			},
+		},
+	)
+}
+
+func TestScaleDegreeErrors(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	str := func(value string) LispString { return LispString{Value: value} }
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "invalid interval",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(
+					sym("note"),
+					sexp(sym("interval"), str("X3"), sexp(sym("degree"), num(1))),
+				),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage(`invalid interval: "X3"`),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "key signature that doesn't imply a tonic",
+			updates: []ScoreUpdate{
+				piano,
+				AttributeUpdate{PartUpdate: KeySignatureSet{
+					KeySignature: KeySignature{F: {Sharp}, B: {Flat}},
+				}},
+				Note{Pitch: ScaleDegree{Degree: 1}},
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("doesn't imply a tonic"),
+			},
+		},
+	)
+}
@@ -1,8 +1,10 @@
 package model
 
 import (
+	"math/rand"
 	"regexp"
 	"strconv"
+	"time"
 
 	"alda.io/client/color"
 	"alda.io/client/help"
@@ -41,6 +43,32 @@ type ScoreEvent interface {
 	// EventOffset returns the offset of the event, represented as a number of
 	// milliseconds after the beginning of the score.
 	EventOffset() float64
+
+	// EventSourceContexts returns the chain of places in the Alda source code
+	// that led to the event, e.g. a variable reference, then the note inside of
+	// the variable definition.
+	EventSourceContexts() []AldaSourceContext
+}
+
+// The ScoreObserver interface is implemented by things that follow along as a
+// score is updated, e.g. in order to notate the score.
+//
+// An observer is notified of each note, rest and barline after the score has
+// placed it in time, so that it doesn't need to duplicate the logic that does
+// that. When a method is called, the part's attributes (octave, key signature,
+// CurrentBeat, etc.) are the ones in effect at that point in the part.
+type ScoreObserver interface {
+	// NoteOrRestAdded is called for each part that a note or rest is added to,
+	// before the part moves forward. `beats` is the written length of the note
+	// or rest, in quarter note beats.
+	NoteOrRestAdded(part *Part, noteOrRest ScoreUpdate, beats float64)
+
+	// PartAdvanced is called when a part moves forward after a note, a rest, or
+	// all of the notes in a chord have been added.
+	PartAdvanced(part *Part)
+
+	// BarlineAdded is called for each current part when there is a barline.
+	BarlineAdded(part *Part)
 }
 
 // A Score is a data structure representing a musical score.
@@ -55,11 +83,33 @@ type Score struct {
 	GlobalAttributes *GlobalAttributes
 	Markers          map[string]float64
 	Variables        map[string][]ScoreUpdate
+	Grooves          map[string]GrooveTemplate
 	midiChannelUsage midiChannelUsage
 	partCounter      int
+	// Used by the randomness functions in alda-lisp. See random.go.
+	random *rand.Rand
+	// The seed of `random`, if it has been seeded explicitly. Also used to seed
+	// the random number generators in `partRandoms`.
+	seed int64
+	// A random number generator for each part ID, used to humanize notes. See
+	// humanize.go.
+	partRandoms map[string]*rand.Rand
 	// When true, notes/rests added to the score are placed at the same offset.
 	// Otherwise, they are appended sequentially.
 	chordMode bool
+	// The source contexts of the expansions (variable references, repeats, etc.)
+	// that are in progress. See withSourceContext.
+	sourceContextStack []AldaSourceContext
+	// The functions defined in the score via `defn`, and the number of nested
+	// calls to them that are in progress. See lisp_special_forms.go.
+	definitions map[string]LispForm
+	callDepth   int
+	// Where each marker was placed, in beats, so that parts that jump to a marker
+	// can continue counting beats from there.
+	markerBeats map[string]markerBeats
+	// When set, it is notified of the notes, rests and barlines that are added to
+	// the score.
+	Observer ScoreObserver
 }
 
 // JSON implements RepresentableAsJSON.JSON.
@@ -117,8 +167,13 @@ func NewScore() *Score {
 		Aliases:          map[string][]*Part{},
 		GlobalAttributes: NewGlobalAttributes(),
 		Markers:          map[string]float64{},
+		markerBeats:      map[string]markerBeats{},
+		definitions:      map[string]LispForm{},
 		Variables:        map[string][]ScoreUpdate{},
+		Grooves:          map[string]GrooveTemplate{},
 		midiChannelUsage: [16][]*Part{},
+		random:           rand.New(rand.NewSource(time.Now().UnixNano())),
+		partRandoms:      map[string]*rand.Rand{},
 	}
 }
 
@@ -127,6 +182,8 @@ func NewScore() *Score {
 //
 // Returns nil if no error occurs.
 func (score *Score) Update(updates ...ScoreUpdate) error {
+	defer score.enterLispContext()()
+
 	for _, update := range updates {
 		if err := update.UpdateScore(score); err != nil {
 			return &AldaSourceError{Context: update.GetSourceContext(), Err: err}
@@ -136,6 +193,26 @@ func (score *Score) Update(updates ...ScoreUpdate) error {
 	return nil
 }
 
+func (score *Score) observeNoteOrRest(
+	part *Part, noteOrRest ScoreUpdate, beats float64,
+) {
+	if score.Observer != nil {
+		score.Observer.NoteOrRestAdded(part, noteOrRest, beats)
+	}
+}
+
+func (score *Score) observeAdvance(part *Part) {
+	if score.Observer != nil {
+		score.Observer.PartAdvanced(part)
+	}
+}
+
+func (score *Score) observeBarline(part *Part) {
+	if score.Observer != nil {
+		score.Observer.BarlineAdded(part)
+	}
+}
+
 // Tracks returns a map of Part instances to track numbers for the purposes of
 // transmitting score data.
 //
@@ -256,6 +333,12 @@ func (score *Score) TempoItinerary() map[float64]float64 {
 				itinerary[offset] = update.Tempo
 			case MetricModulation:
 				itinerary[offset] = lastGlobalTempo * update.Ratio
+			case AttributeRamp:
+				// The tempo values along the way are recorded by each part as the ramp
+				// progresses. See ramp.go.
+				if update.Attribute == RampTempo {
+					lastGlobalTempo = update.Target
+				}
 			}
 		}
 	}
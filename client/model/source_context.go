@@ -3,6 +3,9 @@ package model
 import (
 	"errors"
 	"fmt"
+
+	"alda.io/client/json"
+	log "alda.io/client/logging"
 )
 
 // AldaSourceContext provides some context about the origin of an error in an
@@ -13,6 +16,77 @@ type AldaSourceContext struct {
 	Column   int
 }
 
+// JSON implements RepresentableAsJSON.JSON.
+func (context AldaSourceContext) JSON() *json.Container {
+	contextJSON := json.Object("line", context.Line, "column", context.Column)
+
+	if context.Filename != "" {
+		contextJSON.Set(context.Filename, "filename")
+	}
+
+	return contextJSON
+}
+
+// sourceContextsJSON returns a JSON array representing a chain of source
+// contexts.
+func sourceContextsJSON(contexts []AldaSourceContext) *json.Container {
+	contextsJSON := json.Array()
+	for _, context := range contexts {
+		contextsJSON.ArrayAppend(context.JSON())
+	}
+
+	return contextsJSON
+}
+
+// ScoreUpdatesJSON returns a JSON array representing a sequence of score
+// updates, including the source context of each update.
+//
+// The source context of an update is represented as a chain with a single
+// element, for consistency with the source contexts of score events. (See
+// ScoreEvent.EventSourceContexts.)
+//
+// The updates are evaluated in order within the context of a new score, and
+// each update also includes the score events that it produced. The source
+// context chain of each score event leads from the update through any
+// variable references, repeats, etc. that it expanded.
+//
+// An update that can't be evaluated (e.g. because it references an undefined
+// variable) is still included, with no score events.
+func ScoreUpdatesJSON(updates []ScoreUpdate) *json.Container {
+	score := NewScore()
+	updatesJSON := json.Array()
+
+	for _, update := range updates {
+		eventCountBefore := len(score.Events)
+
+		err := score.Update(update)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to evaluate score update.")
+		}
+
+		updateJSON := update.JSON()
+
+		var contexts []AldaSourceContext
+		if context := update.GetSourceContext(); context.Line != 0 {
+			contexts = append(contexts, context)
+		}
+
+		updateJSON.Set(sourceContextsJSON(contexts), "source-context")
+
+		eventsJSON := json.Array()
+		if err == nil {
+			for _, event := range score.Events[eventCountBefore:] {
+				eventsJSON.ArrayAppend(event.JSON())
+			}
+		}
+
+		updateJSON.Set(eventsJSON, "score-events")
+		updatesJSON.ArrayAppend(updateJSON)
+	}
+
+	return updatesJSON
+}
+
 // HasSourceContext is an interface implemented by types that can be linked to a
 // particular place (e.g. line and column) in an Alda source file.
 type HasSourceContext interface {
@@ -95,3 +169,43 @@ func (ase *AldaSourceError) Error() string {
 		bottom.Err.Error(),
 	)
 }
+
+// withSourceContext updates the score via `update`, with `context` pushed onto
+// the score's stack of source contexts for the duration of the update.
+//
+// We do this whenever we expand something that refers to events written
+// elsewhere in the source code, e.g. a variable reference or a repeat, so that
+// each event added to the score can be traced back through the chain of
+// expansions that produced it. (See sourceContexts.)
+func (score *Score) withSourceContext(
+	context AldaSourceContext, update func() error,
+) error {
+	stackSize := len(score.sourceContextStack)
+
+	// Nested expansions often start at the same place in the source code, e.g.
+	// the event sequence in `[c d]*2` and the repeat itself, in which case we
+	// only record the source context once.
+	if context.Line == 0 ||
+		(stackSize > 0 && score.sourceContextStack[stackSize-1] == context) {
+		return update()
+	}
+
+	score.sourceContextStack = append(score.sourceContextStack, context)
+	defer func() { score.sourceContextStack = score.sourceContextStack[:stackSize] }()
+
+	return update()
+}
+
+// sourceContexts returns the chain of source contexts that led to an event
+// written at `context`, starting with the outermost expansion and ending with
+// `context` itself.
+func (score *Score) sourceContexts(context AldaSourceContext) []AldaSourceContext {
+	contexts := append([]AldaSourceContext(nil), score.sourceContextStack...)
+
+	if context.Line != 0 &&
+		(len(contexts) == 0 || contexts[len(contexts)-1] != context) {
+		contexts = append(contexts, context)
+	}
+
+	return contexts
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"fmt"
+	"testing"
+
+	_ "alda.io/client/testing"
+	"github.com/go-test/deep"
+)
+
+func at(line int, column int) AldaSourceContext {
+	return AldaSourceContext{Line: line, Column: column}
+}
+
+func expectSourceContexts(
+	expectedContexts ...[]AldaSourceContext,
+) func(*Score) error {
+	return func(s *Score) error {
+		if len(s.Events) != len(expectedContexts) {
+			return fmt.Errorf(
+				"expected %d events, got %d", len(expectedContexts), len(s.Events),
+			)
+		}
+
+		for i, expected := range expectedContexts {
+			actual := s.Events[i].EventSourceContexts()
+			if diff := deep.Equal(expected, actual); diff != nil {
+				return fmt.Errorf(
+					"event #%d has unexpected source contexts: %v", i+1, diff,
+				)
+			}
+		}
+
+		return nil
+	}
+}
+
+func TestEventSourceContexts(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	note := func(context AldaSourceContext, letter NoteLetter) Note {
+		return Note{
+			SourceContext: context,
+			Pitch:         LetterAndAccidentals{NoteLetter: letter},
+		}
+	}
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label:   "notes",
+			updates: []ScoreUpdate{piano, note(at(1, 8), C), note(at(1, 10), D)},
+			expectations: []scoreUpdateExpectation{
+				expectSourceContexts(
+					[]AldaSourceContext{at(1, 8)},
+					[]AldaSourceContext{at(1, 10)},
+				),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "variable reference",
+			updates: []ScoreUpdate{
+				VariableDefinition{
+					VariableName: "motif",
+					Events:       []ScoreUpdate{note(at(1, 9), C)},
+				},
+				piano,
+				VariableReference{SourceContext: at(2, 8), VariableName: "motif"},
+			},
+			expectations: []scoreUpdateExpectation{
+				expectSourceContexts([]AldaSourceContext{at(2, 8), at(1, 9)}),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "variable reference in a variable definition",
+			updates: []ScoreUpdate{
+				VariableDefinition{
+					VariableName: "inner",
+					Events:       []ScoreUpdate{note(at(1, 9), C)},
+				},
+				VariableDefinition{
+					VariableName: "outer",
+					Events: []ScoreUpdate{
+						VariableReference{SourceContext: at(2, 9), VariableName: "inner"},
+					},
+				},
+				piano,
+				VariableReference{SourceContext: at(3, 8), VariableName: "outer"},
+			},
+			expectations: []scoreUpdateExpectation{
+				expectSourceContexts(
+					[]AldaSourceContext{at(3, 8), at(2, 9), at(1, 9)},
+				),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "repeat",
+			updates: []ScoreUpdate{
+				piano,
+				Repeat{
+					SourceContext: at(1, 8),
+					Event: EventSequence{
+						SourceContext: at(1, 8),
+						Events:        []ScoreUpdate{note(at(1, 9), C)},
+					},
+					Times: 2,
+				},
+				note(at(1, 14), D),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectSourceContexts(
+					[]AldaSourceContext{at(1, 8), at(1, 9)},
+					[]AldaSourceContext{at(1, 8), at(1, 9)},
+					[]AldaSourceContext{at(1, 14)},
+				),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "S-expression",
+			updates: []ScoreUpdate{
+				piano,
+				LispList{
+					SourceContext: at(1, 8),
+					Elements: []LispForm{
+						sym("chord"), LispString{Value: "C5"},
+					},
+				},
+				LispList{
+					SourceContext: at(1, 21),
+					Elements: []LispForm{
+						sym("sustain-pedal"), LispBoolean{Value: true},
+					},
+				},
+			},
+			expectations: []scoreUpdateExpectation{
+				expectSourceContexts(
+					[]AldaSourceContext{at(1, 8)},
+					[]AldaSourceContext{at(1, 8)},
+					[]AldaSourceContext{at(1, 21)},
+				),
+			},
+		},
+	)
+}
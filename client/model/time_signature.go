@@ -0,0 +1,141 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"alda.io/client/json"
+)
+
+// A TimeSignature is a meter in Western standard musical notation, e.g. 3/4.
+//
+// The zero value means that no time signature has been set.
+type TimeSignature struct {
+	// The number of beats in a measure, e.g. 3 in 3/4.
+	Numerator int32
+	// The note value that represents one beat, e.g. 4 (a quarter note) in 3/4.
+	Denominator int32
+}
+
+// IsSet returns true if the time signature is not the zero value.
+func (ts TimeSignature) IsSet() bool {
+	return ts != TimeSignature{}
+}
+
+func (ts TimeSignature) String() string {
+	return fmt.Sprintf("%d/%d", ts.Numerator, ts.Denominator)
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+//
+// A time signature that has not been set is represented as null.
+func (ts TimeSignature) JSON() *json.Container {
+	if !ts.IsSet() {
+		return json.ToJSON(nil)
+	}
+
+	return json.Object(
+		"numerator", ts.Numerator,
+		"denominator", ts.Denominator,
+	)
+}
+
+// MeasureBeats returns the length of a measure in beats, where a beat is a
+// quarter note, e.g. a measure of 6/8 is 3 beats long.
+func (ts TimeSignature) MeasureBeats() float64 {
+	return float64(ts.Numerator) * 4 / float64(ts.Denominator)
+}
+
+// The tolerance (in beats) used when checking that a measure is complete. This
+// accounts for floating point error, e.g. when adding up the beats of triplets.
+const barCheckTolerance = 0.001
+
+func formatBeats(beats float64) string {
+	return strconv.FormatFloat(math.Round(beats*1000)/1000, 'f', -1, 64)
+}
+
+// msToBeats converts a duration in milliseconds into a number of quarter note
+// beats at the part's current tempo.
+func (part *Part) msToBeats(durationMs float64) float64 {
+	return durationMs * part.Tempo / 60000
+}
+
+// countBeats records that the part's current offset has advanced by
+// `durationMs`, for the purpose of checking that measures are complete and
+// placing notes on the part's swing/groove grid. The part's CurrentBeat moves
+// forward by the same number of beats.
+func (part *Part) countBeats(durationMs float64) {
+	beats := part.msToBeats(durationMs)
+	part.CurrentBeat += beats
+	part.beatsSinceBarline += beats
+}
+
+// countNoteBeats records that the part's current offset has advanced by the
+// duration of a note or rest, for the purpose of checking that measures are
+// complete.
+//
+// A note that is tied across a barline (e.g. `c2~|2`) includes the barline in
+// its duration, in which case the part of the note before the barline completes
+// the previous measure.
+func (part *Part) countNoteBeats(specifiedDuration Duration) error {
+	duration := effectiveDuration(specifiedDuration, part)
+
+	// Barlines in the part's default duration (i.e. the duration of a previous
+	// note) have already been checked.
+	checkBarlines := specifiedDuration.Components != nil
+
+	for _, component := range duration.Components {
+		if _, isBarline := component.(Barline); isBarline {
+			if checkBarlines {
+				if err := part.checkMeasure(); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		part.countBeats(component.Ms(part.Tempo) * part.TimeScale)
+	}
+
+	return nil
+}
+
+// checkMeasure checks that the beats since the previous barline fill exactly
+// one measure in the part's time signature (or the pickup measure, if there is
+// one), and then starts counting the beats of the next measure.
+//
+// Returns an error if the measure is incomplete or too long. Measures aren't
+// checked if the part has no time signature, or if no beats have passed since
+// the previous barline. The latter happens when a barline directly follows
+// another one (e.g. `c1 || d1`), the start of the part, or a time signature
+// change.
+func (part *Part) checkMeasure() error {
+	beats := part.beatsSinceBarline
+	part.beatsSinceBarline = 0
+
+	if !part.TimeSignature.IsSet() || beats < barCheckTolerance {
+		return nil
+	}
+
+	expectedBeats := part.TimeSignature.MeasureBeats()
+	measure := "a measure of " + part.TimeSignature.String()
+	if part.pickupBeats > 0 {
+		expectedBeats = part.pickupBeats
+		measure = "the pickup measure"
+		part.pickupBeats = 0
+	}
+
+	if math.Abs(beats-expectedBeats) > barCheckTolerance {
+		return fmt.Errorf(
+			"bar check failed for %s: expected %s beat(s) in %s, but found %s",
+			part.Name,
+			formatBeats(expectedBeats),
+			measure,
+			formatBeats(beats),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,315 @@
+package model
+
+import (
+	"testing"
+
+	_ "alda.io/client/testing"
+)
+
+// Returns a C note with a duration made up of the provided components, e.g.
+// noteWithLength(NoteLength{Denominator: 4}).
+func noteWithLength(components ...DurationComponent) Note {
+	return Note{
+		Pitch:    LetterAndAccidentals{NoteLetter: C},
+		Duration: Duration{Components: components},
+	}
+}
+
+func timeSignatureSet(numerator int32, denominator int32) AttributeUpdate {
+	return AttributeUpdate{
+		PartUpdate: TimeSignatureSet{
+			TimeSignature: TimeSignature{
+				Numerator: numerator, Denominator: denominator,
+			},
+		},
+	}
+}
+
+func expectPartTimeSignature(
+	instrument string, expected TimeSignature,
+) func(s *Score) error {
+	return expectPartValueDeepEquals(
+		instrument,
+		"time signature",
+		func(part *Part) interface{} { return part.TimeSignature },
+		expected,
+	)
+}
+
+func TestTimeSignatures(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	quarter := NoteLength{Denominator: 4}
+	half := NoteLength{Denominator: 2}
+	eighth := NoteLength{Denominator: 8}
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "barlines are not checked without a time signature",
+			updates: []ScoreUpdate{
+				piano,
+				noteWithLength(quarter),
+				Barline{},
+				noteWithLength(half),
+				noteWithLength(half),
+				noteWithLength(half),
+				Barline{},
+			},
+			expectations: []scoreUpdateExpectation{
+				expectPartTimeSignature("piano", TimeSignature{}),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "complete measures of 3/4",
+			updates: []ScoreUpdate{
+				piano,
+				timeSignatureSet(3, 4),
+				noteWithLength(quarter),
+				noteWithLength(quarter),
+				noteWithLength(quarter),
+				Barline{},
+				noteWithLength(half),
+				noteWithLength(eighth),
+				noteWithLength(eighth),
+				Barline{},
+			},
+			expectations: []scoreUpdateExpectation{
+				expectPartTimeSignature("piano", TimeSignature{3, 4}),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "complete measures of 6/8 with a tempo change",
+			updates: []ScoreUpdate{
+				piano,
+				timeSignatureSet(6, 8),
+				noteWithLength(NoteLength{Denominator: 4, Dots: 1}),
+				AttributeUpdate{PartUpdate: TempoSet{Tempo: 90}},
+				noteWithLength(NoteLength{Denominator: 4, Dots: 1}),
+				Barline{},
+			},
+		},
+		scoreUpdateTestCase{
+			label: "triplets and a chord fill a measure of 2/4",
+			updates: []ScoreUpdate{
+				piano,
+				timeSignatureSet(2, 4),
+				noteWithLength(NoteLength{Denominator: 12}),
+				noteWithLength(NoteLength{Denominator: 12}),
+				noteWithLength(NoteLength{Denominator: 12}),
+				Chord{
+					Events: []ScoreUpdate{
+						noteWithLength(quarter),
+						noteWithLength(half),
+					},
+				},
+				Barline{},
+			},
+		},
+		scoreUpdateTestCase{
+			label: "a note tied across a barline",
+			updates: []ScoreUpdate{
+				piano,
+				timeSignatureSet(4, 4),
+				noteWithLength(half),
+				noteWithLength(quarter),
+				noteWithLength(quarter, Barline{}, half),
+				noteWithLength(half),
+				Barline{},
+			},
+		},
+		scoreUpdateTestCase{
+			label: "a cram fills a measure of 2/4",
+			updates: []ScoreUpdate{
+				piano,
+				timeSignatureSet(2, 4),
+				Cram{
+					Duration: Duration{Components: []DurationComponent{half}},
+					Events: []ScoreUpdate{
+						noteWithLength(quarter),
+						noteWithLength(quarter),
+						noteWithLength(quarter),
+					},
+				},
+				Barline{},
+			},
+		},
+		scoreUpdateTestCase{
+			label: "pickup measure",
+			updates: []ScoreUpdate{
+				piano,
+				AttributeUpdate{
+					PartUpdate: TimeSignatureSet{
+						TimeSignature: TimeSignature{3, 4},
+						Pickup:        1,
+					},
+				},
+				noteWithLength(quarter),
+				Barline{},
+				noteWithLength(half, NoteLength{Denominator: 4}),
+				Barline{},
+			},
+		},
+		scoreUpdateTestCase{
+			label: "changing the time signature starts a new measure",
+			updates: []ScoreUpdate{
+				piano,
+				timeSignatureSet(3, 4),
+				noteWithLength(half, quarter),
+				Barline{},
+				timeSignatureSet(2, 4),
+				noteWithLength(half),
+				Barline{},
+			},
+			expectations: []scoreUpdateExpectation{
+				expectPartTimeSignature("piano", TimeSignature{2, 4}),
+				expectPartValueDeepEquals(
+					"piano",
+					"time signature values",
+					func(part *Part) interface{} { return part.TimeSignatureValues },
+					map[float64]TimeSignature{0: {3, 4}, 1500: {2, 4}},
+				),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "time-signature S-expression",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("time-signature"), num(5), num(4)),
+				sexp(sym("time-sig"), LispString{Value: "7/8"}),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectPartTimeSignature("piano", TimeSignature{7, 8}),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "time-signature S-expression with a pickup",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(
+					sym("time-signature"), num(4), num(4), LispString{Value: "8"},
+				),
+				noteWithLength(eighth),
+				Barline{},
+				noteWithLength(NoteLength{Denominator: 1}),
+				Barline{},
+			},
+		},
+		scoreUpdateTestCase{
+			label: "consecutive barlines",
+			updates: []ScoreUpdate{
+				piano,
+				timeSignatureSet(4, 4),
+				noteWithLength(NoteLength{Denominator: 1}, Barline{}, Barline{}),
+				Barline{},
+				noteWithLength(NoteLength{Denominator: 1}),
+				Barline{},
+			},
+		},
+		scoreUpdateTestCase{
+			label: "barline right after a time signature change",
+			updates: []ScoreUpdate{
+				piano,
+				Barline{},
+				timeSignatureSet(3, 4),
+				Barline{},
+				noteWithLength(half, quarter),
+				Barline{},
+				timeSignatureSet(2, 4),
+				Barline{},
+				noteWithLength(half),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "barline before a pickup measure",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(
+					sym("time-signature"), num(4), num(4), LispString{Value: "8"},
+				),
+				Barline{},
+				noteWithLength(eighth),
+				Barline{},
+				noteWithLength(NoteLength{Denominator: 1}),
+				Barline{},
+			},
+		},
+	)
+}
+
+func TestTimeSignatureErrors(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	quarter := NoteLength{Denominator: 4}
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "incomplete measure",
+			updates: []ScoreUpdate{
+				piano,
+				timeSignatureSet(3, 4),
+				noteWithLength(quarter),
+				noteWithLength(quarter),
+				Barline{SourceContext: AldaSourceContext{Line: 3, Column: 7}},
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage(
+					"expected 3 beat(s) in a measure of 3/4, but found 2",
+				),
+				expectErrorLine(3),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "measure too long",
+			updates: []ScoreUpdate{
+				piano,
+				timeSignatureSet(2, 4),
+				noteWithLength(quarter),
+				noteWithLength(NoteLength{Denominator: 4, Dots: 1}),
+				Barline{SourceContext: AldaSourceContext{Line: 2}},
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("but found 2.5"),
+				expectErrorLine(2),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "pickup measure of the wrong length",
+			updates: []ScoreUpdate{
+				piano,
+				AttributeUpdate{
+					PartUpdate: TimeSignatureSet{
+						TimeSignature: TimeSignature{4, 4},
+						Pickup:        1,
+					},
+				},
+				noteWithLength(NoteLength{Denominator: 2}),
+				Barline{},
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("expected 1 beat(s) in the pickup measure"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "invalid time signature string",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("time-signature"), LispString{Value: "3-4"}),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("invalid time signature"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "pickup as long as a measure",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(
+					sym("time-signature"), num(2), num(4), LispString{Value: "2"},
+				),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("expected a pickup shorter than a measure of 2/4"),
+			},
+		},
+	)
+}
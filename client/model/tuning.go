@@ -0,0 +1,439 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"alda.io/client/help"
+	"alda.io/client/json"
+)
+
+// A ScalaScale is a scale read from a Scala scale (.scl) file.
+//
+// Reference: https://www.huygens-fokker.org/scala/scl_format.html
+type ScalaScale struct {
+	Description string
+	// The pitch of each degree of the scale above the first, in cents above the
+	// first. The last degree is the interval at which the scale repeats,
+	// usually an octave (1200 cents).
+	Cents []float64
+}
+
+// A KeyboardMapping maps MIDI note numbers (keys) to the degrees of a scale. It
+// is read from a Scala keyboard mapping (.kbm) file.
+//
+// Reference: https://www.huygens-fokker.org/scala/help.htm#mappings
+type KeyboardMapping struct {
+	// The scale degree that each key maps to, starting from MiddleNote, or -1 if
+	// the key isn't mapped. The pattern repeats every len(Degrees) keys.
+	//
+	// When empty, consecutive keys map to consecutive scale degrees.
+	Degrees []int
+	// The key on which the first degree of the scale is played.
+	MiddleNote int32
+	// The key that is tuned to ReferenceFrequency.
+	ReferenceNote int32
+	// The frequency of ReferenceNote, in Hz.
+	ReferenceFrequency float64
+	// The scale degree at which the mapping repeats, usually the last degree of
+	// the scale. When 0, the last degree of the scale is used.
+	OctaveDegree int
+}
+
+// A Tuning determines the pitch of each key when it's played by a part, instead
+// of 12-tone equal temperament.
+type Tuning struct {
+	Scale ScalaScale
+	// When nil, consecutive keys play consecutive scale degrees, starting with
+	// the first degree on middle C, which is tuned relative to the part's
+	// reference pitch as it would be in 12-tone equal temperament.
+	Mapping *KeyboardMapping
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (tuning Tuning) JSON() *json.Container {
+	cents := json.Array()
+	for _, c := range tuning.Scale.Cents {
+		cents.ArrayAppend(c)
+	}
+
+	value := json.Object(
+		"description", tuning.Scale.Description,
+		"cents", cents,
+	)
+
+	if tuning.Mapping != nil {
+		degrees := json.Array()
+		for _, degree := range tuning.Mapping.Degrees {
+			degrees.ArrayAppend(degree)
+		}
+
+		value.Set(json.Object(
+			"degrees", degrees,
+			"middle-note", tuning.Mapping.MiddleNote,
+			"reference-note", tuning.Mapping.ReferenceNote,
+			"reference-frequency", tuning.Mapping.ReferenceFrequency,
+			"octave-degree", tuning.Mapping.OctaveDegree,
+		), "mapping")
+	}
+
+	return value
+}
+
+// scalaLines returns the lines of a Scala file, leaving out comments (lines
+// that start with `!`).
+func scalaLines(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lines := []string{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "!") {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+// scalaField returns the first whitespace-separated field of a line in a Scala
+// file. Anything after that is ignored.
+func scalaField(line string) string {
+	if fields := strings.Fields(line); len(fields) > 0 {
+		return fields[0]
+	}
+
+	return ""
+}
+
+// parseScalaPitch parses a pitch in a Scala scale file, which is either a
+// number of cents (e.g. 701.955) or a ratio (e.g. 3/2 or 2).
+func parseScalaPitch(pitch string) (float64, error) {
+	if strings.Contains(pitch, ".") {
+		return strconv.ParseFloat(pitch, 64)
+	}
+
+	numerator, denominator, hasDenominator := strings.Cut(pitch, "/")
+	if !hasDenominator {
+		denominator = "1"
+	}
+
+	n, err := strconv.ParseUint(numerator, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	d, err := strconv.ParseUint(denominator, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if n == 0 || d == 0 {
+		return 0, fmt.Errorf("ratio must be positive: %s", pitch)
+	}
+
+	return 1200 * math.Log2(float64(n)/float64(d)), nil
+}
+
+// ReadScalaScale reads a scale from a Scala scale (.scl) file.
+func ReadScalaScale(filename string) (ScalaScale, error) {
+	lines, err := scalaLines(filename)
+	if err != nil {
+		return ScalaScale{}, err
+	}
+
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf(
+			"invalid Scala scale file %s: %s", filename, fmt.Sprintf(format, args...),
+		)
+	}
+
+	if len(lines) < 2 {
+		return ScalaScale{}, invalid("expected a description and number of notes")
+	}
+
+	scale := ScalaScale{Description: lines[0]}
+
+	notes, err := strconv.Atoi(scalaField(lines[1]))
+	if err != nil || notes < 1 {
+		return ScalaScale{}, invalid("invalid number of notes: %q", lines[1])
+	}
+
+	if len(lines)-2 < notes {
+		return ScalaScale{}, invalid(
+			"expected %d notes, but found %d", notes, len(lines)-2,
+		)
+	}
+
+	for _, line := range lines[2 : 2+notes] {
+		cents, err := parseScalaPitch(scalaField(line))
+		if err != nil {
+			return ScalaScale{}, invalid("invalid pitch %q: %s", line, err)
+		}
+
+		scale.Cents = append(scale.Cents, cents)
+	}
+
+	return scale, nil
+}
+
+// ReadKeyboardMapping reads a keyboard mapping from a Scala keyboard mapping
+// (.kbm) file.
+func ReadKeyboardMapping(filename string) (KeyboardMapping, error) {
+	allLines, err := scalaLines(filename)
+	if err != nil {
+		return KeyboardMapping{}, err
+	}
+
+	lines := []string{}
+	for _, line := range allLines {
+		if line != "" {
+			lines = append(lines, scalaField(line))
+		}
+	}
+
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf(
+			"invalid Scala keyboard mapping file %s: %s",
+			filename, fmt.Sprintf(format, args...),
+		)
+	}
+
+	if len(lines) < 7 {
+		return KeyboardMapping{}, invalid(
+			"expected the map size, first note, last note, middle note, " +
+				"reference note, reference frequency and octave degree",
+		)
+	}
+
+	integers := make([]int, 7)
+	for i, line := range lines[:7] {
+		if i == 5 {
+			continue
+		}
+
+		integers[i], err = strconv.Atoi(line)
+		if err != nil {
+			return KeyboardMapping{}, invalid("expected a number: %q", line)
+		}
+	}
+
+	size := integers[0]
+	if size < 0 {
+		return KeyboardMapping{}, invalid("invalid map size: %d", size)
+	}
+
+	frequency, err := strconv.ParseFloat(lines[5], 64)
+	if err != nil || frequency <= 0 {
+		return KeyboardMapping{}, invalid("invalid reference frequency: %q", lines[5])
+	}
+
+	mapping := KeyboardMapping{
+		MiddleNote:         int32(integers[3]),
+		ReferenceNote:      int32(integers[4]),
+		ReferenceFrequency: frequency,
+		OctaveDegree:       integers[6],
+	}
+
+	for i := 0; i < size; i++ {
+		// Keys at the end of the mapping can be left out, in which case they
+		// aren't mapped.
+		if 7+i >= len(lines) || lines[7+i] == "x" {
+			mapping.Degrees = append(mapping.Degrees, -1)
+			continue
+		}
+
+		degree, err := strconv.Atoi(lines[7+i])
+		if err != nil || degree < 0 {
+			return KeyboardMapping{}, invalid("invalid scale degree: %q", lines[7+i])
+		}
+
+		mapping.Degrees = append(mapping.Degrees, degree)
+	}
+
+	return mapping, nil
+}
+
+// floorDiv divides `a` by `b`, rounding down, and returns the quotient and the
+// (non-negative) remainder.
+func floorDiv(a int, b int) (int, int) {
+	quotient := a / b
+	if a%b < 0 {
+		quotient--
+	}
+
+	return quotient, a - quotient*b
+}
+
+// degreeCents returns the pitch of a scale degree in cents above the first
+// degree. Degrees beyond the last one continue into the next repetition of the
+// scale.
+func (scale ScalaScale) degreeCents(degree int) float64 {
+	repetitions, degree := floorDiv(degree, len(scale.Cents))
+
+	cents := float64(repetitions) * scale.Cents[len(scale.Cents)-1]
+	if degree > 0 {
+		cents += scale.Cents[degree-1]
+	}
+
+	return cents
+}
+
+// keyCents returns the pitch of a key in cents above the middle note.
+func (tuning Tuning) keyCents(key int32, mapping KeyboardMapping) (float64, error) {
+	offset := int(key - mapping.MiddleNote)
+
+	if len(mapping.Degrees) == 0 {
+		return tuning.Scale.degreeCents(offset), nil
+	}
+
+	repetitions, index := floorDiv(offset, len(mapping.Degrees))
+
+	degree := mapping.Degrees[index]
+	if degree < 0 {
+		return 0, help.UserFacingErrorf(
+			"MIDI note %d isn't mapped to a scale degree by the tuning's keyboard "+
+				"mapping.",
+			key,
+		)
+	}
+
+	octaveDegree := mapping.OctaveDegree
+	if octaveDegree == 0 {
+		octaveDegree = len(tuning.Scale.Cents)
+	}
+
+	return float64(repetitions)*tuning.Scale.degreeCents(octaveDegree) +
+		tuning.Scale.degreeCents(degree), nil
+}
+
+// pitch returns the pitch of a key in the tuning, as a fractional MIDI note
+// number. `referencePitch` (the frequency of A4) is used when the tuning has
+// no keyboard mapping.
+func (tuning Tuning) pitch(key int32, referencePitch float64) (float64, error) {
+	mapping := KeyboardMapping{
+		MiddleNote:         60,
+		ReferenceNote:      60,
+		ReferenceFrequency: referencePitch * math.Pow(2, -9.0/12),
+	}
+
+	if tuning.Mapping != nil {
+		mapping = *tuning.Mapping
+	}
+
+	cents, err := tuning.keyCents(key, mapping)
+	if err != nil {
+		return 0, err
+	}
+
+	referenceCents, err := tuning.keyCents(mapping.ReferenceNote, mapping)
+	if err != nil {
+		return 0, err
+	}
+
+	frequency := mapping.ReferenceFrequency *
+		math.Pow(2, (cents-referenceCents)/1200)
+
+	return 69 + 12*math.Log2(frequency/440), nil
+}
+
+// pitch returns the pitch of a key (a MIDI note number) when it's played by the
+// part, as a fractional MIDI note number, taking into account the part's
+// tuning and reference pitch. `cents` raises or lowers the pitch.
+//
+// In percussion parts, each key is a different instrument, so the pitch is
+// always the key.
+func (part *Part) pitch(key int32, cents float64) (float64, error) {
+	// TODO: Update this type assertion if/when we add non-MIDI instruments.
+	if part.StockInstrument.(MidiInstrument).IsPercussion {
+		return float64(key), nil
+	}
+
+	if part.Tuning == nil {
+		return float64(key) + 12*math.Log2(part.ReferencePitch/440) + cents/100,
+			nil
+	}
+
+	pitch, err := part.Tuning.pitch(key, part.ReferencePitch)
+	if err != nil {
+		return 0, err
+	}
+
+	return pitch + cents/100, nil
+}
+
+// TuningSet sets the tuning of all active parts. A nil Tuning means 12-tone
+// equal temperament.
+type TuningSet struct {
+	Tuning *Tuning
+}
+
+// JSON implements RepresentableAsJSON.JSON.
+func (ts TuningSet) JSON() *json.Container {
+	var value interface{}
+	if ts.Tuning != nil {
+		value = ts.Tuning.JSON()
+	}
+
+	return json.Object("attribute", "tuning", "value", value)
+}
+
+func (ts TuningSet) updatePart(part *Part, globalUpdate bool) error {
+	part.Tuning = ts.Tuning
+	return nil
+}
+
+// tuningFromFiles reads a Scala scale file and (optionally) keyboard mapping
+// file, and returns a TuningSet with the resulting tuning.
+func tuningFromFiles(
+	scaleFile LispString, mappingFile *LispString,
+) (TuningSet, error) {
+	scale, err := ReadScalaScale(
+		relativePath(scaleFile.Value, scaleFile.SourceContext),
+	)
+	if err != nil {
+		return TuningSet{}, &AldaSourceError{
+			Context: scaleFile.SourceContext,
+			Err:     err,
+		}
+	}
+
+	tuning := &Tuning{Scale: scale}
+
+	if mappingFile != nil {
+		mapping, err := ReadKeyboardMapping(
+			relativePath(mappingFile.Value, mappingFile.SourceContext),
+		)
+		if err != nil {
+			return TuningSet{}, &AldaSourceError{
+				Context: mappingFile.SourceContext,
+				Err:     err,
+			}
+		}
+
+		tuning.Mapping = &mapping
+	}
+
+	return TuningSet{Tuning: tuning}, nil
+}
+
+// relativePath returns `path`, relative to the directory of the file where the
+// source context is, unless it's an absolute path.
+func relativePath(path string, context AldaSourceContext) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(filepath.Dir(context.Filename), path)
+}
@@ -0,0 +1,219 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "alda.io/client/testing"
+)
+
+// Writes a Scala file to a temporary directory and returns its path.
+func scalaFile(t *testing.T, name string, contents string) string {
+	filename := filepath.Join(t.TempDir(), name)
+
+	if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+func expectPitches(expectedPitches ...float64) func(*Score) error {
+	return func(s *Score) error {
+		if len(s.Events) != len(expectedPitches) {
+			return fmt.Errorf(
+				"expected %d events, got %d", len(expectedPitches), len(s.Events),
+			)
+		}
+
+		for i, expectedPitch := range expectedPitches {
+			actualPitch := s.Events[i].(NoteEvent).Pitch
+			if !equalish(expectedPitch, actualPitch) {
+				return fmt.Errorf(
+					"expected note #%d to have pitch %f, but it was %f",
+					i+1, expectedPitch, actualPitch,
+				)
+			}
+		}
+
+		return nil
+	}
+}
+
+const justIntonation = `! just.scl
+!
+Just intonation
+ 12
+!
+ 16/15
+ 9/8
+ 6/5
+ 5/4
+ 4/3
+ 45/32
+ 3/2
+ 8/5
+ 5/3
+ 9/5
+ 15/8
+ 2/1
+`
+
+func TestTuning(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	str := func(value string) LispString { return LispString{Value: value} }
+	note := func(letter NoteLetter, cents float64) Note {
+		return Note{Pitch: LetterAndAccidentals{NoteLetter: letter}, Cents: cents}
+	}
+
+	just := scalaFile(t, "just.scl", justIntonation)
+	a440 := scalaFile(t, "a440.kbm", `! a440.kbm
+12
+0
+127
+60
+69
+440.0
+12
+0
+1
+2
+3
+4
+5
+6
+7
+8
+9
+10
+11
+`)
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label:   "12-tone equal temperament",
+			updates: []ScoreUpdate{piano, note(C, 0), note(A, 0)},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(60, 69),
+				expectPitches(60, 69),
+			},
+		},
+		scoreUpdateTestCase{
+			label:   "cents",
+			updates: []ScoreUpdate{piano, note(C, 50), note(E, -14)},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(60, 64),
+				expectPitches(60.5, 63.86),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "reference pitch",
+			updates: []ScoreUpdate{
+				piano,
+				AttributeUpdate{PartUpdate: ReferencePitchSet{Frequency: 415}},
+				note(A, 0),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(69),
+				expectPitches(67.987),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "Scala scale",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("tuning"), str(just)),
+				note(C, 0), note(E, 0), note(G, 0), note(A, 10),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectMidiNoteNumbers(60, 64, 67, 69),
+				expectPitches(60, 63.863, 67.020, 68.944),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "Scala scale and keyboard mapping",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("tuning"), str(just), str(a440)),
+				note(C, 0), note(A, 0),
+				AttributeUpdate{PartUpdate: OctaveSet{OctaveNumber: 5}},
+				note(C, 0),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectPitches(60.156, 69, 72.156),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "back to equal temperament",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("tuning"), str(just)),
+				note(E, 0),
+				sexp(sym("tuning"), LispNil{}),
+				note(E, 0),
+			},
+			expectations: []scoreUpdateExpectation{
+				expectPitches(63.863, 64),
+			},
+		},
+	)
+}
+
+func TestTuningErrors(t *testing.T) {
+	piano := PartDeclaration{Names: []string{"piano"}}
+	str := func(value string) LispString { return LispString{Value: value} }
+
+	just := scalaFile(t, "just.scl", justIntonation)
+	tooFewNotes := scalaFile(t, "short.scl", "Short\n3\n3/2\n2/1\n")
+	unmapped := scalaFile(t, "unmapped.kbm", "2\n0\n127\n60\n60\n261.6\n12\n0\nx\n")
+
+	executeScoreUpdateTestCases(
+		t,
+		scoreUpdateTestCase{
+			label: "missing file",
+			updates: []ScoreUpdate{
+				piano, sexp(sym("tuning"), str(filepath.Join(t.TempDir(), "nope.scl"))),
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("no such file"),
+			},
+		},
+		scoreUpdateTestCase{
+			label:   "invalid scale file",
+			updates: []ScoreUpdate{piano, sexp(sym("tuning"), str(tooFewNotes))},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("expected 3 notes, but found 2"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "unmapped key",
+			updates: []ScoreUpdate{
+				piano,
+				sexp(sym("tuning"), str(just), str(unmapped)),
+				Note{Pitch: LetterAndAccidentals{NoteLetter: C}},
+				Note{
+					Pitch: LetterAndAccidentals{
+						NoteLetter: C, Accidentals: []Accidental{Sharp},
+					},
+				},
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("MIDI note 61 isn't mapped"),
+			},
+		},
+		scoreUpdateTestCase{
+			label: "pitch out of range",
+			updates: []ScoreUpdate{
+				piano,
+				AttributeUpdate{PartUpdate: OctaveSet{OctaveNumber: -1}},
+				Note{Pitch: LetterAndAccidentals{NoteLetter: C}, Cents: -60},
+			},
+			errorExpectations: []scoreUpdateErrorExpectation{
+				expectErrorMessage("Pitch out of the MIDI note range"),
+			},
+		},
+	)
+}
@@ -132,13 +132,15 @@ func (vr VariableReference) UpdateScore(score *Score) error {
 		Interface("events", events).
 		Msg("Dereferenced variable.")
 
-	for _, event := range events {
-		if err := event.UpdateScore(score); err != nil {
-			return err
+	return score.withSourceContext(vr.SourceContext, func() error {
+		for _, event := range events {
+			if err := event.UpdateScore(score); err != nil {
+				return err
+			}
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
 
 // DurationMs implements ScoreUpdate.DurationMs by looking up the sequence of
@@ -170,5 +172,5 @@ func (vr VariableReference) VariableValue(score *Score) (ScoreUpdate, error) {
 		return nil, err
 	}
 
-	return EventSequence{Events: events}, nil
+	return EventSequence{SourceContext: vr.SourceContext, Events: events}, nil
 }
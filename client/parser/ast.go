@@ -64,6 +64,11 @@ const (
 	VoiceGroupEndMarkerNode
 	VoiceGroupNode
 	VoiceNumberNode
+	ArticulationNode
+	DrumNameNode
+	ScaleDegreeNode
+	IncludeNode
+	CentsNode
 )
 
 type ASTNode struct {
@@ -175,6 +180,16 @@ func (nt ASTNodeType) String() string {
 		return "VoiceGroupNode"
 	case VoiceNumberNode:
 		return "VoiceNumberNode"
+	case ArticulationNode:
+		return "ArticulationNode"
+	case DrumNameNode:
+		return "DrumNameNode"
+	case ScaleDegreeNode:
+		return "ScaleDegreeNode"
+	case IncludeNode:
+		return "IncludeNode"
+	case CentsNode:
+		return "CentsNode"
 	default:
 		return fmt.Sprintf("%d (String not implemented)", nt)
 	}
@@ -367,6 +382,83 @@ func duration(node ASTNode) (model.Duration, error) {
 	return duration, nil
 }
 
+// noteAccidentals returns the accidentals of a NoteAccidentalsNode.
+func noteAccidentals(node ASTNode) ([]model.Accidental, error) {
+	accidentalsNode, err := node.expectNodeType(NoteAccidentalsNode)
+	if err != nil {
+		return nil, err
+	}
+
+	accidentals := []model.Accidental{}
+
+	for _, child := range accidentalsNode.Children {
+		switch child.Type {
+		default:
+			return nil, errUnexpectedNodeChild(accidentalsNode.Type, child.Type)
+		case FlatNode:
+			accidentals = append(accidentals, model.Flat)
+		case NaturalNode:
+			accidentals = append(accidentals, model.Natural)
+		case SharpNode:
+			accidentals = append(accidentals, model.Sharp)
+		}
+	}
+
+	return accidentals, nil
+}
+
+// notePitch returns the pitch specified by the first child of a NoteNode,
+// which is a NoteLetterAndAccidentalsNode, a DrumNameNode or a
+// ScaleDegreeNode.
+func notePitch(node ASTNode) (model.PitchIdentifier, error) {
+	switch node.Type {
+	case DrumNameNode:
+		return model.DrumName{Name: node.Literal.(string)}, nil
+
+	case ScaleDegreeNode:
+		degree := model.ScaleDegree{Degree: node.Literal.(int32)}
+
+		if len(node.Children) > 0 {
+			accidentals, err := noteAccidentals(node.Children[0])
+			if err != nil {
+				return nil, err
+			}
+
+			degree.Accidentals = accidentals
+		}
+
+		return degree, nil
+	}
+
+	laaNode, err := node.expectNodeType(NoteLetterAndAccidentalsNode)
+	if err != nil {
+		return nil, err
+	}
+
+	letterNode, err := laaNode.Children[0].expectNodeType(NoteLetterNode)
+	if err != nil {
+		return nil, err
+	}
+
+	noteLetter, err := model.NewNoteLetter(letterNode.Literal.(rune))
+	if err != nil {
+		return nil, err
+	}
+
+	laa := model.LetterAndAccidentals{NoteLetter: noteLetter}
+
+	if len(laaNode.Children) > 1 {
+		accidentals, err := noteAccidentals(laaNode.Children[1])
+		if err != nil {
+			return nil, err
+		}
+
+		laa.Accidentals = accidentals
+	}
+
+	return laa, nil
+}
+
 func (node ASTNode) Updates() ([]model.ScoreUpdate, error) {
 	concatChildUpdates := func(node ASTNode) ([]model.ScoreUpdate, error) {
 		updates := []model.ScoreUpdate{}
@@ -475,6 +567,28 @@ func (node ASTNode) Updates() ([]model.ScoreUpdate, error) {
 
 		return concatChildUpdates(events)
 
+	case IncludeNode:
+		if err := node.expectNChildren(2); err != nil {
+			return nil, err
+		}
+
+		included, err := node.Children[1].expectNodeType(RootNode)
+		if err != nil {
+			return nil, err
+		}
+
+		updates, err := included.Updates()
+		if err != nil {
+			return nil, err
+		}
+
+		// The namespace of an `import`, or "" for an `include`.
+		if namespace := node.Literal.(string); namespace != "" {
+			return imported(updates, namespace), nil
+		}
+
+		return updates, nil
+
 	case LispListNode:
 		var lispForm func(ASTNode) (model.LispForm, error)
 		lispForm = func(node ASTNode) (model.LispForm, error) {
@@ -552,48 +666,14 @@ func (node ASTNode) Updates() ([]model.ScoreUpdate, error) {
 			return nil, err
 		}
 
-		laaNode, err := node.Children[0].expectNodeType(NoteLetterAndAccidentalsNode)
+		pitch, err := notePitch(node.Children[0])
 		if err != nil {
 			return nil, err
 		}
 
-		letterNode, err := laaNode.Children[0].expectNodeType(NoteLetterNode)
-		if err != nil {
-			return nil, err
-		}
-
-		noteLetter, err := model.NewNoteLetter(letterNode.Literal.(rune))
-		if err != nil {
-			return nil, err
-		}
-
-		laa := model.LetterAndAccidentals{NoteLetter: noteLetter}
-
-		if len(laaNode.Children) > 1 {
-			accidentalsNode, err := laaNode.Children[1].expectNodeType(
-				NoteAccidentalsNode,
-			)
-			if err != nil {
-				return nil, err
-			}
-
-			for _, child := range accidentalsNode.Children {
-				switch child.Type {
-				default:
-					return nil, errUnexpectedNodeChild(accidentalsNode.Type, child.Type)
-				case FlatNode:
-					laa.Accidentals = append(laa.Accidentals, model.Flat)
-				case NaturalNode:
-					laa.Accidentals = append(laa.Accidentals, model.Natural)
-				case SharpNode:
-					laa.Accidentals = append(laa.Accidentals, model.Sharp)
-				}
-			}
-		}
-
 		note := model.Note{
 			SourceContext: node.SourceContext,
-			Pitch:         laa,
+			Pitch:         pitch,
 		}
 
 		if len(node.Children) > 1 {
@@ -601,6 +681,8 @@ func (node ASTNode) Updates() ([]model.ScoreUpdate, error) {
 				switch child.Type {
 				default:
 					return nil, errUnexpectedNodeChild(node.Type, child.Type)
+				case CentsNode:
+					note.Cents = child.Literal.(float64)
 				case DurationNode:
 					dur, err := duration(child)
 					if err != nil {
@@ -609,6 +691,10 @@ func (node ASTNode) Updates() ([]model.ScoreUpdate, error) {
 					note.Duration = dur
 				case TieNode:
 					note.Slurred = true
+				case ArticulationNode:
+					note.Articulations = append(
+						note.Articulations, child.Literal.(model.Articulation),
+					)
 				}
 			}
 		}
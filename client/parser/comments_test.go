@@ -43,3 +43,22 @@ func TestComments(t *testing.T) {
 		},
 	)
 }
+
+func TestHasComments(t *testing.T) {
+	for _, testCase := range []struct {
+		input    string
+		expected bool
+	}{
+		{"piano: c d e", false},
+		{"piano: c # d e", true},
+		{"piano: (vol 50 # loud\n) c", true},
+		{"piano: (chord \"F#m7\") c", false},
+		{"piano: c } # a syntax error doesn't hide the comment", true},
+	} {
+		if actual := HasComments(testCase.input); actual != testCase.expected {
+			t.Errorf(
+				"expected HasComments(%q) to be %v", testCase.input, testCase.expected,
+			)
+		}
+	}
+}
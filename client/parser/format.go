@@ -6,6 +6,8 @@ import (
 	"io"
 	"strconv"
 	"strings"
+
+	"alda.io/client/model"
 )
 
 type varDefState int
@@ -219,6 +221,91 @@ func (f *formatter) formatWithDuration(
 	return nil
 }
 
+// formatAccidentals returns the Alda code for the accidentals in a
+// NoteAccidentalsNode, e.g. `+` for a sharp.
+func formatAccidentals(node ASTNode) (string, error) {
+	accidentals, err := node.expectNodeType(NoteAccidentalsNode)
+	if err != nil {
+		return "", err
+	}
+
+	text := strings.Builder{}
+
+	for _, child := range accidentals.Children {
+		switch child.Type {
+		default:
+			return "", fmt.Errorf(
+				"unexpected NoteAccidentalsNode %#v during formatting", child,
+			)
+		case FlatNode:
+			text.WriteString("-")
+		case NaturalNode:
+			text.WriteString("_")
+		case SharpNode:
+			text.WriteString("+")
+		}
+	}
+
+	return text.String(), nil
+}
+
+// formatCents returns the Alda code for a number of cents that raises or
+// lowers the pitch of a note, e.g. `+25&`.
+func formatCents(cents float64) string {
+	text := strconv.FormatFloat(cents, 'f', -1, 64)
+	if cents > 0 {
+		text = "+" + text
+	}
+
+	return text + "&"
+}
+
+// formatPitch returns the Alda code for the pitch of a note, which is a note
+// letter and accidentals (e.g. `c+`), a drum name (e.g. `$snare`) or a scale
+// degree (e.g. `^3-`).
+func formatPitch(node ASTNode) (string, error) {
+	pitchText := strings.Builder{}
+	var accidentalsNodes []ASTNode
+
+	switch node.Type {
+	case DrumNameNode:
+		return fmt.Sprintf("$%s", node.Literal.(string)), nil
+
+	case ScaleDegreeNode:
+		pitchText.WriteString(fmt.Sprintf("^%d", node.Literal.(int32)))
+		accidentalsNodes = node.Children
+
+	default:
+		laa, err := node.expectNodeType(NoteLetterAndAccidentalsNode)
+		if err != nil {
+			return "", err
+		}
+
+		if err := laa.expectChildren(); err != nil {
+			return "", err
+		}
+
+		letter, err := laa.Children[0].expectNodeType(NoteLetterNode)
+		if err != nil {
+			return "", err
+		}
+
+		pitchText.WriteRune(letter.Literal.(rune))
+		accidentalsNodes = laa.Children[1:]
+	}
+
+	for _, accidentals := range accidentalsNodes {
+		text, err := formatAccidentals(accidentals)
+		if err != nil {
+			return "", err
+		}
+
+		pitchText.WriteString(text)
+	}
+
+	return pitchText.String(), nil
+}
+
 // formatInnerEvents handles formatting of inner events within parts.
 func (f *formatter) formatInnerEvents(nodes ...ASTNode) error {
 	for _, node := range nodes {
@@ -316,6 +403,13 @@ func (f *formatter) formatInnerEvents(nodes ...ASTNode) error {
 			f.unindent()
 			f.write("]")
 
+		case IncludeNode:
+			// Only the `include` or `import` S-expression is part of the input.
+			err := f.formatInnerEvents(node.Children[0])
+			if err != nil {
+				return err
+			}
+
 		case LispListNode:
 			var lispString func(ASTNode) (string, error)
 			lispString = func(lisp ASTNode) (string, error) {
@@ -385,70 +479,45 @@ func (f *formatter) formatInnerEvents(nodes ...ASTNode) error {
 			f.write(fmt.Sprintf("%%%s", node.Literal.(string)))
 
 		case NoteNode:
-			if err := node.expectNChildren(1, 2, 3); err != nil {
-				return err
-			}
-
-			laa, err := node.Children[0].expectNodeType(
-				NoteLetterAndAccidentalsNode,
-			)
-			if err != nil {
-				return err
-			}
-
-			if err := laa.expectChildren(); err != nil {
+			// A note has a pitch, optionally followed by a duration, any number of
+			// articulations and a slur.
+			if err := node.expectChildren(); err != nil {
 				return err
 			}
 
-			letter, err := laa.Children[0].expectNodeType(NoteLetterNode)
+			pitchText, err := formatPitch(node.Children[0])
 			if err != nil {
 				return err
 			}
 
-			pitchText := strings.Builder{}
-			pitchText.WriteRune(letter.Literal.(rune))
-
-			if len(laa.Children) > 1 {
-				accidentals, err := laa.Children[1].expectNodeType(
-					NoteAccidentalsNode,
-				)
-				if err != nil {
-					return err
-				}
-
-				for _, child := range accidentals.Children {
-					switch child.Type {
-					default:
-						return fmt.Errorf(
-							"unexpected NoteAccidentalsNode %#v during formatting",
-							child,
-						)
-					case FlatNode:
-						pitchText.WriteString("-")
-					case NaturalNode:
-						pitchText.WriteString("_")
-					case SharpNode:
-						pitchText.WriteString("+")
-					}
-				}
-			}
-
+			// Articulations are always written before the slur.
+			postText := strings.Builder{}
 			slurText := ""
-			for _, child := range node.Children[1:] {
-				if child.Type == TieNode {
+			var durationNode *ASTNode
+			for i, child := range node.Children[1:] {
+				switch child.Type {
+				case CentsNode:
+					pitchText += formatCents(child.Literal.(float64))
+				case DurationNode:
+					durationNode = &node.Children[1+i]
+				case ArticulationNode:
+					postText.WriteRune('^')
+					postText.WriteRune(child.Literal.(model.Articulation).Symbol())
+				case TieNode:
 					slurText = "~"
 				}
 			}
+			postText.WriteString(slurText)
 
-			if len(node.Children) > 1 && node.Children[1].Type == DurationNode {
+			if durationNode != nil {
 				err = f.formatWithDuration(
-					pitchText.String(), node.Children[1], slurText,
+					pitchText, *durationNode, postText.String(),
 				)
 				if err != nil {
 					return err
 				}
 			} else {
-				f.write(fmt.Sprintf("%s%s", pitchText.String(), slurText))
+				f.write(fmt.Sprintf("%s%s", pitchText, postText.String()))
 			}
 
 		case OctaveDownNode:
@@ -60,6 +60,94 @@ func withDuration(node ASTNode, duration model.Duration) (ASTNode, error) {
 	return node, nil
 }
 
+// mapPartUpdate maps a model.PartUpdate to ASTNode.
+func mapPartUpdate(partUpdate model.PartUpdate) (ASTNode, error) {
+	switch pu := partUpdate.(type) {
+
+	// The following have direct ASTNode representations
+	case model.OctaveSet:
+		return ASTNode{Type: OctaveSetNode, Literal: pu.OctaveNumber}, nil
+
+	case model.OctaveUp:
+		return ASTNode{Type: OctaveUpNode}, nil
+
+	case model.OctaveDown:
+		return ASTNode{Type: OctaveDownNode}, nil
+
+	// Most part updates must be formatted via lisp.
+	// We handle the subset that can be generated via MusicXML and MIDI import.
+	// TODO: handle generating all possible part updates into lisp.
+	case model.DynamicMarking:
+		return ASTNode{Type: LispListNode, Children: []ASTNode{{
+			Type:    LispSymbolNode,
+			Literal: pu.Marking,
+		}}}, nil
+
+	case model.KeySignatureSet:
+		// Note: we arbitrarily select one of multiple lisp names.
+		// This is ok for now, but would make generated ASTs different from
+		// parsed ones (different LispSymbolNode Literal) if ASTNode.Updates
+		// ever directly outputs evaluated lisp.
+		return ASTNode{Type: LispListNode, Children: []ASTNode{
+			{
+				Type:    LispSymbolNode,
+				Literal: "key-signature",
+			},
+			{
+				Type:    LispStringNode,
+				Literal: pu.KeySignature.String(),
+			},
+		}}, nil
+
+	case model.TempoSet:
+		return ASTNode{Type: LispListNode, Children: []ASTNode{
+			{
+				Type:    LispSymbolNode,
+				Literal: "tempo",
+			},
+			{
+				Type:    LispNumberNode,
+				Literal: pu.Tempo,
+			},
+		}}, nil
+
+	case model.TranspositionSet:
+		return ASTNode{Type: LispListNode, Children: []ASTNode{
+			{
+				Type:    LispSymbolNode,
+				Literal: "transpose",
+			},
+			{
+				Type:    LispNumberNode,
+				Literal: pu.Semitones,
+			},
+		}}, nil
+
+	default:
+		return ASTNode{}, fmt.Errorf(
+			"unexpected PartUpdate type during AST generation: %#v", pu,
+		)
+
+	}
+}
+
+// accidentalsNode maps accidentals to a NoteAccidentalsNode.
+func accidentalsNode(accidentals []model.Accidental) ASTNode {
+	var acc []ASTNode
+	for _, accidental := range accidentals {
+		switch accidental {
+		case model.Flat:
+			acc = append(acc, ASTNode{Type: FlatNode})
+		case model.Natural:
+			acc = append(acc, ASTNode{Type: NaturalNode})
+		case model.Sharp:
+			acc = append(acc, ASTNode{Type: SharpNode})
+		}
+	}
+
+	return ASTNode{Type: NoteAccidentalsNode, Children: acc}
+}
+
 // mapIsolatedUpdate maps a single isolated model.ScoreUpdate to ASTNode.
 // Holistic updates that require "re-construction" are handled upstream:
 //  1. Parts in mapTopLevel.
@@ -71,62 +159,26 @@ func mapIsolatedUpdate(scoreUpdate model.ScoreUpdate) (ASTNode, error) {
 		return ASTNode{Type: AtMarkerNode, Literal: update.Name}, nil
 
 	case model.AttributeUpdate:
-		switch pu := update.PartUpdate.(type) {
+		return mapPartUpdate(update.PartUpdate)
 
-		// The following have direct ASTNode representations
-		case model.OctaveSet:
-			return ASTNode{Type: OctaveSetNode, Literal: pu.OctaveNumber}, nil
-
-		case model.OctaveUp:
-			return ASTNode{Type: OctaveUpNode}, nil
-
-		case model.OctaveDown:
-			return ASTNode{Type: OctaveDownNode}, nil
-
-		// Most part updates must be formatted via lisp.
-		// We handle the subset that can be generated via MusicXML import.
-		// TODO: handle generating all possible part updates into lisp.
-		case model.DynamicMarking:
-			return ASTNode{Type: LispListNode, Children: []ASTNode{{
-				Type:    LispSymbolNode,
-				Literal: pu.Marking,
-			}}}, nil
-
-		case model.KeySignatureSet:
-			// Note: we arbitrarily select one of multiple lisp names.
-			// This is ok for now, but would make generated ASTs different from
-			// parsed ones (different LispSymbolNode Literal) if ASTNode.Updates
-			// ever directly outputs evaluated lisp.
-			return ASTNode{Type: LispListNode, Children: []ASTNode{
-				{
-					Type:    LispSymbolNode,
-					Literal: "key-signature",
-				},
-				{
-					Type:    LispStringNode,
-					Literal: pu.KeySignature.String(),
-				},
-			}}, nil
-
-		case model.TranspositionSet:
-			return ASTNode{Type: LispListNode, Children: []ASTNode{
-				{
-					Type:    LispSymbolNode,
-					Literal: "transpose",
-				},
-				{
-					Type:    LispNumberNode,
-					Literal: pu.Semitones,
-				},
-			}}, nil
+	case model.GlobalAttributeUpdate:
+		node, err := mapPartUpdate(update.PartUpdate)
+		if err != nil {
+			return ASTNode{}, err
+		}
 
-		default:
+		// Global attribute updates can only be expressed via lisp, e.g.
+		// (tempo! 120), where the name of the function ends with "!".
+		if node.Type != LispListNode {
 			return ASTNode{}, fmt.Errorf(
-				"unexpected PartUpdate type during AST generation: %#v", pu,
+				"unexpected global PartUpdate type during AST generation: %#v",
+				update.PartUpdate,
 			)
-
 		}
 
+		node.Children[0].Literal = node.Children[0].Literal.(string) + "!"
+		return node, nil
+
 	case model.Barline:
 		return ASTNode{Type: BarlineNode}, nil
 
@@ -220,25 +272,26 @@ func mapIsolatedUpdate(scoreUpdate model.ScoreUpdate) (ASTNode, error) {
 			})
 
 			if len(pitch.Accidentals) > 0 {
-				var acc []ASTNode
-				for _, accidental := range pitch.Accidentals {
-					switch accidental {
-					case model.Flat:
-						acc = append(acc, ASTNode{Type: FlatNode})
-					case model.Natural:
-						acc = append(acc, ASTNode{Type: NaturalNode})
-					case model.Sharp:
-						acc = append(acc, ASTNode{Type: SharpNode})
-					}
-				}
-				laa.Children = append(laa.Children, ASTNode{
-					Type:     NoteAccidentalsNode,
-					Children: acc,
-				})
+				laa.Children = append(laa.Children, accidentalsNode(pitch.Accidentals))
 			}
 
 			note.Children = append(note.Children, laa)
 
+		case model.ScaleDegree:
+			degree := ASTNode{Type: ScaleDegreeNode, Literal: pitch.Degree}
+
+			if len(pitch.Accidentals) > 0 {
+				degree.Children = []ASTNode{accidentalsNode(pitch.Accidentals)}
+			}
+
+			note.Children = append(note.Children, degree)
+
+		case model.DrumName:
+			note.Children = append(note.Children, ASTNode{
+				Type:    DrumNameNode,
+				Literal: pitch.Name,
+			})
+
 		default:
 			// model.MidiNoteNumber - never parsed from Alda code, only used:
 			// 	1. with model.LispPitch (unnecessary to handle here)
@@ -249,11 +302,25 @@ func mapIsolatedUpdate(scoreUpdate model.ScoreUpdate) (ASTNode, error) {
 
 		}
 
+		if update.Cents != 0 {
+			note.Children = append(note.Children, ASTNode{
+				Type:    CentsNode,
+				Literal: update.Cents,
+			})
+		}
+
 		note, err := withDuration(note, update.Duration)
 		if err != nil {
 			return ASTNode{}, err
 		}
 
+		for _, articulation := range update.Articulations {
+			note.Children = append(note.Children, ASTNode{
+				Type:    ArticulationNode,
+				Literal: articulation,
+			})
+		}
+
 		if update.Slurred {
 			note.Children = append(note.Children, ASTNode{Type: TieNode})
 		}
@@ -313,7 +380,6 @@ func mapIsolatedUpdate(scoreUpdate model.ScoreUpdate) (ASTNode, error) {
 
 	default:
 		// PartDeclaration, VoiceMarker, VoiceGroupEndMarker - handled upstream
-		// GlobalAttributeUpdate - only generated within Lisp
 		// LispNil - should never exist here
 		return ASTNode{}, fmt.Errorf(
 			"unexpected ScoreUpdate type during AST gen: %#v", update,
@@ -0,0 +1,341 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+
+	"alda.io/client/help"
+	"alda.io/client/model"
+)
+
+// An include is an `include` or `import` S-expression at the top level of a
+// score, which brings in the contents of another file.
+//
+// `(include "path.alda")` adds all of the events in the file to the score, as
+// if they were written in place of the `include`.
+//
+// `(import "lib.alda" :as lib)` only adds the variables defined in the file,
+// each of which is namespaced, e.g. `lib/motif`. When `:as` is omitted, the
+// namespace is the name of the file without its extension.
+type include struct {
+	sourceContext model.AldaSourceContext
+	path          string
+	// The namespace of the imported variables, or "" for an `include`.
+	namespace string
+}
+
+// parseInclude returns the include described by a Lisp list, and false if the
+// list is not an `include` or `import` S-expression.
+func parseInclude(list model.LispList) (include, bool, error) {
+	if len(list.Elements) == 0 {
+		return include{}, false, nil
+	}
+
+	operator, ok := list.Elements[0].(model.LispSymbol)
+	if !ok || (operator.Name != "include" && operator.Name != "import") {
+		return include{}, false, nil
+	}
+
+	sourceError := func(format string, args ...interface{}) error {
+		return &model.AldaSourceError{
+			Context: list.SourceContext,
+			Err:     help.UserFacingErrorf(format, args...),
+		}
+	}
+
+	args := list.Elements[1:]
+
+	if len(args) == 0 {
+		return include{}, true, sourceError(
+			"%s expects the path to a file, e.g. (%s \"lib.alda\")",
+			operator.Name, operator.Name,
+		)
+	}
+
+	path, ok := args[0].(model.LispString)
+	if !ok {
+		return include{}, true, sourceError(
+			"%s expects the path to a file, but got %s",
+			operator.Name, args[0].JSON().String(),
+		)
+	}
+
+	inc := include{sourceContext: list.SourceContext, path: path.Value}
+
+	if operator.Name == "include" {
+		if len(args) > 1 {
+			return include{}, true, sourceError(
+				"include expects only the path to a file",
+			)
+		}
+
+		return inc, true, nil
+	}
+
+	inc.namespace = strings.TrimSuffix(
+		filepath.Base(path.Value), filepath.Ext(path.Value),
+	)
+
+	switch {
+	case len(args) == 1:
+	case len(args) == 3 && isKeyword(args[1], ":as"):
+		switch namespace := args[2].(type) {
+		case model.LispSymbol:
+			inc.namespace = namespace.Name
+		case model.LispString:
+			inc.namespace = namespace.Value
+		default:
+			return include{}, true, sourceError(
+				"import expects a namespace after :as, but got %s",
+				args[2].JSON().String(),
+			)
+		}
+	default:
+		return include{}, true, sourceError(
+			"import expects the path to a file, optionally followed by " +
+				"`:as namespace`",
+		)
+	}
+
+	if !isValidVariableName(inc.namespace) {
+		return include{}, true, sourceError(
+			"Invalid namespace: %q. A namespace must start with two letters, "+
+				"e.g. (import \"%s\" :as lib)",
+			inc.namespace, path.Value,
+		)
+	}
+
+	return inc, true, nil
+}
+
+func isKeyword(form model.LispForm, keyword string) bool {
+	symbol, ok := form.(model.LispSymbol)
+	return ok && symbol.Name == keyword
+}
+
+// isValidVariableName returns true if `name` is scanned as a Name token, i.e.
+// it can be used as the name of a variable.
+func isValidVariableName(name string) bool {
+	chars := []rune(name)
+
+	if len(chars) < 2 || !isLetter(chars[0]) || !isLetter(chars[1]) {
+		return false
+	}
+
+	for _, c := range chars {
+		if !isValidNameChar(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// absolutePath returns the absolute path of the included file. A relative path is
+// relative to the directory of the file that includes it, or the working
+// directory if the input didn't come from a file.
+func (inc include) absolutePath() (string, error) {
+	path := inc.path
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(inc.sourceContext.Filename), path)
+	}
+
+	return filepath.Abs(path)
+}
+
+// parse parses the included file, along with any files that it includes.
+//
+// `includers` is the chain of files that led to this include, which is used to
+// detect cycles, e.g. a file that includes itself.
+func (inc include) parse(includers []string) (ASTNode, error) {
+	path, err := inc.absolutePath()
+	if err != nil {
+		return ASTNode{}, err
+	}
+
+	if inc.sourceContext.Filename != "" {
+		includer, err := filepath.Abs(inc.sourceContext.Filename)
+		if err != nil {
+			return ASTNode{}, err
+		}
+
+		includers = append(append([]string{}, includers...), includer)
+	}
+
+	for i, includer := range includers {
+		if includer == path {
+			cycle := append(append([]string{}, includers[i:]...), path)
+
+			return ASTNode{}, &model.AldaSourceError{
+				Context: inc.sourceContext,
+				Err: help.UserFacingErrorf(
+					"Include cycle detected: %s", strings.Join(cycle, " -> "),
+				),
+			}
+		}
+	}
+
+	ast, err := ParseFile(path, includedBy(includers))
+	if err != nil {
+		// Errors in the included file refer to the included file, so they are
+		// presented as-is.
+		if _, ok := err.(*model.AldaSourceError); ok {
+			return ASTNode{}, err
+		}
+
+		return ASTNode{}, &model.AldaSourceError{
+			Context: inc.sourceContext, Err: err,
+		}
+	}
+
+	return ast, nil
+}
+
+// imported returns the score updates that an `import` with the given namespace
+// brings in from the updates of the imported file, i.e. its variable
+// definitions, namespaced.
+func imported(
+	updates []model.ScoreUpdate, namespace string,
+) []model.ScoreUpdate {
+	definitions := []model.ScoreUpdate{}
+	names := map[string]bool{}
+
+	for _, update := range updates {
+		if definition, ok := update.(model.VariableDefinition); ok {
+			definitions = append(definitions, definition)
+			names[definition.VariableName] = true
+		}
+	}
+
+	for i, definition := range definitions {
+		definitions[i] = namespaced(definition, namespace, names)
+	}
+
+	return definitions
+}
+
+// namespaced returns a version of `update` where the names of the variables in
+// `names` are prefixed with the namespace, e.g. `motif` becomes `lib/motif`.
+func namespaced(
+	update model.ScoreUpdate, namespace string, names map[string]bool,
+) model.ScoreUpdate {
+	qualify := func(name string) string {
+		if names[name] {
+			return namespace + "/" + name
+		}
+
+		return name
+	}
+
+	all := func(events []model.ScoreUpdate) []model.ScoreUpdate {
+		result := make([]model.ScoreUpdate, len(events))
+		for i, event := range events {
+			result[i] = namespaced(event, namespace, names)
+		}
+
+		return result
+	}
+
+	switch update := update.(type) {
+	case model.VariableDefinition:
+		update.VariableName = qualify(update.VariableName)
+		update.Events = all(update.Events)
+		return update
+	case model.VariableReference:
+		update.VariableName = qualify(update.VariableName)
+		return update
+	case model.Chord:
+		update.Events = all(update.Events)
+		return update
+	case model.Cram:
+		update.Events = all(update.Events)
+		return update
+	case model.EventSequence:
+		update.Events = all(update.Events)
+		return update
+	case model.Repeat:
+		update.Event = namespaced(update.Event, namespace, names)
+		return update
+	case model.OnRepetitions:
+		update.Event = namespaced(update.Event, namespace, names)
+		return update
+	}
+
+	return update
+}
+
+// resolveIncludes parses the files brought in by each top-level `include` and
+// `import` S-expression in the AST, replacing the S-expression with an
+// IncludeNode whose children are the S-expression and the AST of the included
+// file.
+//
+// The included files are parsed along with the score, so that evaluating the
+// AST doesn't involve reading any files.
+func (p *parser) resolveIncludes(root *ASTNode) error {
+	for i := range root.Children {
+		for j := range root.Children[i].Children {
+			events := &root.Children[i].Children[j]
+			if events.Type != EventSequenceNode {
+				continue
+			}
+
+			for k, node := range events.Children {
+				if node.Type != LispListNode {
+					continue
+				}
+
+				includeNode, ok, err := p.resolveInclude(node)
+				if err != nil {
+					if p.recoverFromErrors {
+						p.errors.add(err)
+						continue
+					}
+
+					return err
+				}
+
+				if ok {
+					events.Children[k] = includeNode
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveInclude returns the IncludeNode for a LispListNode, and false if the
+// list is not an `include` or `import` S-expression.
+func (p *parser) resolveInclude(node ASTNode) (ASTNode, bool, error) {
+	updates, err := node.Updates()
+	if err != nil {
+		return ASTNode{}, false, err
+	}
+
+	if len(updates) != 1 {
+		return ASTNode{}, false, nil
+	}
+
+	list, ok := updates[0].(model.LispList)
+	if !ok {
+		return ASTNode{}, false, nil
+	}
+
+	inc, ok, err := parseInclude(list)
+	if !ok || err != nil {
+		return ASTNode{}, ok, err
+	}
+
+	included, err := inc.parse(p.includers)
+	if err != nil {
+		return ASTNode{}, true, err
+	}
+
+	return ASTNode{
+		Type:          IncludeNode,
+		Literal:       inc.namespace,
+		Children:      []ASTNode{node, included},
+		SourceContext: node.SourceContext,
+	}, true, nil
+}
@@ -0,0 +1,298 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"alda.io/client/model"
+	_ "alda.io/client/testing"
+	"github.com/go-test/deep"
+)
+
+// Writes each file to a temporary directory and returns the directory.
+func scoreFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+
+	for name, contents := range files {
+		filename := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+// Parses a file and returns the MIDI note numbers of the notes in the
+// resulting score.
+func fileMidiNotes(filename string) ([]int32, error) {
+	ast, err := ParseFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	updates, err := ast.Updates()
+	if err != nil {
+		return nil, err
+	}
+
+	score := model.NewScore()
+	if err := score.Update(updates...); err != nil {
+		return nil, err
+	}
+
+	midiNotes := []int32{}
+	for _, event := range score.Events {
+		if note, ok := event.(model.NoteEvent); ok {
+			midiNotes = append(midiNotes, note.MidiNote)
+		}
+	}
+
+	return midiNotes, nil
+}
+
+func TestIncludes(t *testing.T) {
+	for _, testCase := range []struct {
+		label           string
+		files           map[string]string
+		expectMidiNotes []int32
+	}{
+		{
+			label: "include",
+			files: map[string]string{
+				"main.alda":  "(include \"setup.alda\")\ne",
+				"setup.alda": "piano: (tempo 90) c d",
+			},
+			expectMidiNotes: []int32{60, 62, 64},
+		},
+		{
+			label: "variables from an included file aren't namespaced",
+			files: map[string]string{
+				"main.alda":   "(include \"motifs.alda\")\npiano: motif",
+				"motifs.alda": "motif = c e",
+			},
+			expectMidiNotes: []int32{60, 64},
+		},
+		{
+			label: "import with a namespace",
+			files: map[string]string{
+				"main.alda": "(import \"lib.alda\" :as lib)\n" +
+					"motif = g\npiano: lib/riff lib/motif motif",
+				"lib.alda": "motif = c d\nriff = motif e\npiano: f",
+			},
+			expectMidiNotes: []int32{60, 62, 64, 60, 62, 67},
+		},
+		{
+			label: "import without a namespace",
+			files: map[string]string{
+				"main.alda":      "(import \"drums/kit.alda\")\npiano: kit/beat",
+				"drums/kit.alda": "beat = c/e",
+			},
+			expectMidiNotes: []int32{60, 64},
+		},
+		{
+			label: "paths are relative to the including file",
+			files: map[string]string{
+				"main.alda": "(include \"parts/strings.alda\")",
+				"parts/strings.alda": "(import \"../lib/motifs.alda\")\n" +
+					"cello: motifs/motif",
+				"lib/motifs.alda": "motif = o3 c",
+			},
+			expectMidiNotes: []int32{48},
+		},
+		{
+			label: "nested imports",
+			files: map[string]string{
+				"main.alda":  "(import \"outer.alda\")\npiano: outer/both",
+				"outer.alda": "(import \"inner.alda\")\nboth = inner/note d",
+				"inner.alda": "note = c",
+			},
+			expectMidiNotes: []int32{60, 62},
+		},
+		{
+			label: "the same file included twice",
+			files: map[string]string{
+				"main.alda": "(include \"a.alda\")\n(include \"b.alda\")\n" +
+					"piano: motif",
+				"a.alda":      "(include \"motifs.alda\")",
+				"b.alda":      "(include \"motifs.alda\")",
+				"motifs.alda": "motif = c",
+			},
+			expectMidiNotes: []int32{60},
+		},
+	} {
+		dir := scoreFiles(t, testCase.files)
+
+		midiNotes, err := fileMidiNotes(filepath.Join(dir, "main.alda"))
+		if err != nil {
+			t.Errorf("%s: %v", testCase.label, err)
+			continue
+		}
+
+		if diff := deep.Equal(testCase.expectMidiNotes, midiNotes); diff != nil {
+			t.Error(testCase.label)
+			for _, diffItem := range diff {
+				t.Errorf("%v", diffItem)
+			}
+		}
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	for _, testCase := range []struct {
+		label       string
+		files       map[string]string
+		expectError string
+	}{
+		{
+			label: "file that includes itself",
+			files: map[string]string{
+				"main.alda": "(include \"main.alda\")",
+			},
+			expectError: "main.alda:1:1 Include cycle detected",
+		},
+		{
+			label: "include cycle",
+			files: map[string]string{
+				"main.alda": "(include \"a.alda\")",
+				"a.alda":    "(import \"b.alda\" :as lib)",
+				"b.alda":    "c\n(include \"a.alda\")",
+			},
+			expectError: "b.alda:2:1 Include cycle detected",
+		},
+		{
+			label: "missing file",
+			files: map[string]string{
+				"main.alda": "piano: c\n(include \"nope.alda\")",
+			},
+			expectError: "main.alda:2:1 Failed to open",
+		},
+		{
+			label: "syntax error in an included file",
+			files: map[string]string{
+				"main.alda": "(include \"lib.alda\")",
+				"lib.alda":  "piano: c & d",
+			},
+			expectError: "lib.alda:1:10 Unexpected",
+		},
+		{
+			label: "include with extra arguments",
+			files: map[string]string{
+				"main.alda": "(include \"lib.alda\" :as lib)",
+			},
+			expectError: "include expects only the path to a file",
+		},
+		{
+			label: "import with an invalid namespace",
+			files: map[string]string{
+				"main.alda": "(import \"lib.alda\" :as x)",
+			},
+			expectError: "Invalid namespace: \"x\"",
+		},
+	} {
+		dir := scoreFiles(t, testCase.files)
+
+		_, err := fileMidiNotes(filepath.Join(dir, "main.alda"))
+		if err == nil {
+			t.Errorf("%s: expected an error", testCase.label)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), testCase.expectError) {
+			t.Errorf(
+				"%s: expected an error containing %q, got %q",
+				testCase.label, testCase.expectError, err.Error(),
+			)
+		}
+	}
+}
+
+func TestIncludesAreParsedWithTheScore(t *testing.T) {
+	dir := scoreFiles(t, map[string]string{
+		"main.alda":  "(include \"setup.alda\")\npiano: e",
+		"setup.alda": "piano: c d",
+	})
+
+	ast, err := ParseFile(filepath.Join(dir, "main.alda"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Evaluating the AST doesn't read the included file again.
+	if err := os.Remove(filepath.Join(dir, "setup.alda")); err != nil {
+		t.Fatal(err)
+	}
+
+	updates, err := ast.Updates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	score := model.NewScore()
+	if err := score.Update(updates...); err != nil {
+		t.Fatal(err)
+	}
+
+	midiNotes := []int32{}
+	for _, event := range score.Events {
+		if note, ok := event.(model.NoteEvent); ok {
+			midiNotes = append(midiNotes, note.MidiNote)
+		}
+	}
+
+	if diff := deep.Equal([]int32{60, 62, 64}, midiNotes); diff != nil {
+		t.Errorf("unexpected MIDI notes: %v", diff)
+	}
+
+	_, err = ParseFile(
+		filepath.Join(dir, "main.alda"), RecoverFromErrors,
+	)
+	if _, ok := err.(ParseErrors); !ok ||
+		!strings.Contains(err.Error(), "main.alda:1:1 Failed to open") {
+		t.Errorf("expected the missing file to be a parse error, got %v", err)
+	}
+}
+
+func TestFormatIncludes(t *testing.T) {
+	dir := scoreFiles(t, map[string]string{
+		"main.alda":  "(include \"setup.alda\")\npiano: c",
+		"setup.alda": "violin: g",
+	})
+
+	format := func(opts ...ParseOption) string {
+		ast, err := ParseFile(filepath.Join(dir, "main.alda"), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out := strings.Builder{}
+		if err := FormatASTToCode(ast, &out); err != nil {
+			t.Fatal(err)
+		}
+
+		return out.String()
+	}
+
+	// The contents of the included file are not formatted along with the score.
+	if out := format(); !strings.Contains(out, "(include \"setup.alda\")") ||
+		strings.Contains(out, "violin") {
+		t.Errorf("expected only the include to be formatted, got %q", out)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "setup.alda")); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := format(SkipIncludes); !strings.Contains(
+		out, "(include \"setup.alda\")",
+	) {
+		t.Errorf("expected the include to be formatted, got %q", out)
+	}
+}
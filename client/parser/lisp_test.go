@@ -55,6 +55,31 @@ func TestLisp(t *testing.T) {
 				lispList(lispSymbol("key-signature"), lispString("f+ c+ g+")),
 			},
 		},
+		parseTestCase{
+			label: "keyword arguments",
+			given: "(humanize :timing 8 :velocity 5)",
+			expectUpdates: []model.ScoreUpdate{
+				lispList(
+					lispSymbol("humanize"),
+					lispSymbol(":timing"), lispNumber(8),
+					lispSymbol(":velocity"), lispNumber(5),
+				),
+			},
+		},
+		parseTestCase{
+			label: "subtraction",
+			given: "(- 5 3)",
+			expectUpdates: []model.ScoreUpdate{
+				lispList(lispSymbol("-"), lispNumber(5), lispNumber(3)),
+			},
+		},
+		parseTestCase{
+			label: "negative number",
+			given: "(transpose -2)",
+			expectUpdates: []model.ScoreUpdate{
+				lispList(lispSymbol("transpose"), lispNumber(-2)),
+			},
+		},
 		parseTestCase{
 			label: "global attribute change",
 			given: "(tempo! 200)",
@@ -101,3 +101,319 @@ func TestNotes(t *testing.T) {
 		},
 	)
 }
+
+func TestArticulations(t *testing.T) {
+	quarter := model.Duration{
+		Components: []model.DurationComponent{model.NoteLength{Denominator: 4}},
+	}
+
+	executeParseTestCases(
+		t,
+		parseTestCase{
+			label: "staccato note",
+			given: "c4^.",
+			expectUpdates: []model.ScoreUpdate{
+				model.Note{
+					Pitch:         model.LetterAndAccidentals{NoteLetter: model.C},
+					Duration:      quarter,
+					Articulations: []model.Articulation{model.Staccato},
+				},
+			},
+		},
+		parseTestCase{
+			label: "accented note with implicit duration",
+			given: "f+^>",
+			expectUpdates: []model.ScoreUpdate{
+				model.Note{
+					Pitch: model.LetterAndAccidentals{
+						NoteLetter:  model.F,
+						Accidentals: []model.Accidental{model.Sharp},
+					},
+					Articulations: []model.Articulation{model.Accent},
+				},
+			},
+		},
+		parseTestCase{
+			label: "multiple articulations",
+			given: "e8.^_^~",
+			expectUpdates: []model.ScoreUpdate{
+				model.Note{
+					Pitch: model.LetterAndAccidentals{NoteLetter: model.E},
+					Duration: model.Duration{
+						Components: []model.DurationComponent{
+							model.NoteLength{Denominator: 8, Dots: 1},
+						},
+					},
+					Articulations: []model.Articulation{model.Tenuto, model.Fermata},
+				},
+			},
+		},
+		parseTestCase{
+			label: "articulation before a slur",
+			given: "c4^^~ d",
+			expectUpdates: []model.ScoreUpdate{
+				model.Note{
+					Pitch:         model.LetterAndAccidentals{NoteLetter: model.C},
+					Duration:      quarter,
+					Slurred:       true,
+					Articulations: []model.Articulation{model.Marcato},
+				},
+				model.Note{Pitch: model.LetterAndAccidentals{NoteLetter: model.D}},
+			},
+		},
+		parseTestCase{
+			label: "articulation after a slur",
+			given: "c4~^^ d",
+			expectUpdates: []model.ScoreUpdate{
+				model.Note{
+					Pitch:         model.LetterAndAccidentals{NoteLetter: model.C},
+					Duration:      quarter,
+					Slurred:       true,
+					Articulations: []model.Articulation{model.Marcato},
+				},
+				model.Note{Pitch: model.LetterAndAccidentals{NoteLetter: model.D}},
+			},
+		},
+		parseTestCase{
+			label: "articulation in a chord",
+			given: "c/e/g^>",
+			expectUpdates: []model.ScoreUpdate{
+				model.Chord{
+					Events: []model.ScoreUpdate{
+						model.Note{Pitch: model.LetterAndAccidentals{NoteLetter: model.C}},
+						model.Note{Pitch: model.LetterAndAccidentals{NoteLetter: model.E}},
+						model.Note{
+							Pitch:         model.LetterAndAccidentals{NoteLetter: model.G},
+							Articulations: []model.Articulation{model.Accent},
+						},
+					},
+				},
+			},
+		},
+	)
+}
+
+func TestCents(t *testing.T) {
+	piano := model.PartDeclaration{Names: []string{"piano"}}
+	eighth := model.Duration{
+		Components: []model.DurationComponent{model.NoteLength{Denominator: 8}},
+	}
+	quarter := model.Duration{
+		Components: []model.DurationComponent{model.NoteLength{Denominator: 4}},
+	}
+
+	executeParseTestCases(
+		t,
+		parseTestCase{
+			label: "notes with cents",
+			given: "piano: c+25& e-14&8 f+-12.5& ^3+50&~",
+			expectUpdates: []model.ScoreUpdate{
+				piano,
+				model.Note{
+					Pitch: model.LetterAndAccidentals{NoteLetter: model.C},
+					Cents: 25,
+				},
+				model.Note{
+					Pitch:    model.LetterAndAccidentals{NoteLetter: model.E},
+					Cents:    -14,
+					Duration: eighth,
+				},
+				model.Note{
+					Pitch: model.LetterAndAccidentals{
+						NoteLetter: model.F, Accidentals: []model.Accidental{model.Sharp},
+					},
+					Cents: -12.5,
+				},
+				model.Note{
+					Pitch:   model.ScaleDegree{Degree: 3},
+					Cents:   50,
+					Slurred: true,
+				},
+			},
+		},
+		parseTestCase{
+			label: "sharps and flats followed by durations",
+			given: "piano: c+8 d-4 c+8&",
+			expectUpdates: []model.ScoreUpdate{
+				piano,
+				model.Note{
+					Pitch: model.LetterAndAccidentals{
+						NoteLetter: model.C, Accidentals: []model.Accidental{model.Sharp},
+					},
+					Duration: eighth,
+				},
+				model.Note{
+					Pitch: model.LetterAndAccidentals{
+						NoteLetter: model.D, Accidentals: []model.Accidental{model.Flat},
+					},
+					Duration: quarter,
+				},
+				model.Note{
+					Pitch: model.LetterAndAccidentals{NoteLetter: model.C},
+					Cents: 8,
+				},
+			},
+		},
+		parseTestCase{
+			// This is the syntax that we don't use for cents, because it would
+			// change the meaning of existing scores like this one.
+			label: "sharps and flats followed by durations and notes",
+			given: "piano: c+8c d-4d",
+			expectUpdates: []model.ScoreUpdate{
+				piano,
+				model.Note{
+					Pitch: model.LetterAndAccidentals{
+						NoteLetter: model.C, Accidentals: []model.Accidental{model.Sharp},
+					},
+					Duration: eighth,
+				},
+				model.Note{Pitch: model.LetterAndAccidentals{NoteLetter: model.C}},
+				model.Note{
+					Pitch: model.LetterAndAccidentals{
+						NoteLetter: model.D, Accidentals: []model.Accidental{model.Flat},
+					},
+					Duration: quarter,
+				},
+				model.Note{Pitch: model.LetterAndAccidentals{NoteLetter: model.D}},
+			},
+		},
+		parseTestCase{
+			label: "chord with cents",
+			given: "piano: c/e-14&/g+2&",
+			expectUpdates: []model.ScoreUpdate{
+				piano,
+				model.Chord{
+					Events: []model.ScoreUpdate{
+						model.Note{Pitch: model.LetterAndAccidentals{NoteLetter: model.C}},
+						model.Note{
+							Pitch: model.LetterAndAccidentals{NoteLetter: model.E},
+							Cents: -14,
+						},
+						model.Note{
+							Pitch: model.LetterAndAccidentals{NoteLetter: model.G},
+							Cents: 2,
+						},
+					},
+				},
+			},
+		},
+	)
+}
+
+func TestScaleDegrees(t *testing.T) {
+	piano := model.PartDeclaration{Names: []string{"piano"}}
+
+	executeParseTestCases(
+		t,
+		parseTestCase{
+			label: "scale degrees",
+			given: "piano: ^1 ^3-8 ^4+ ^5^.",
+			expectUpdates: []model.ScoreUpdate{
+				piano,
+				model.Note{Pitch: model.ScaleDegree{Degree: 1}},
+				model.Note{
+					Pitch: model.ScaleDegree{
+						Degree: 3, Accidentals: []model.Accidental{model.Flat},
+					},
+					Duration: model.Duration{
+						Components: []model.DurationComponent{
+							model.NoteLength{Denominator: 8},
+						},
+					},
+				},
+				model.Note{
+					Pitch: model.ScaleDegree{
+						Degree: 4, Accidentals: []model.Accidental{model.Sharp},
+					},
+				},
+				model.Note{
+					Pitch:         model.ScaleDegree{Degree: 5},
+					Articulations: []model.Articulation{model.Staccato},
+				},
+			},
+		},
+		parseTestCase{
+			label: "scale degree chord",
+			given: "piano: ^1/^3/^5",
+			expectUpdates: []model.ScoreUpdate{
+				piano,
+				model.Chord{
+					Events: []model.ScoreUpdate{
+						model.Note{Pitch: model.ScaleDegree{Degree: 1}},
+						model.Note{Pitch: model.ScaleDegree{Degree: 3}},
+						model.Note{Pitch: model.ScaleDegree{Degree: 5}},
+					},
+				},
+			},
+		},
+	)
+}
+
+func TestDrumNames(t *testing.T) {
+	percussion := model.PartDeclaration{Names: []string{"midi-percussion"}}
+
+	executeParseTestCases(
+		t,
+		parseTestCase{
+			label: "drum names",
+			given: "midi-percussion: $kick8 $hh-closed $snare4.",
+			expectUpdates: []model.ScoreUpdate{
+				percussion,
+				model.Note{
+					Pitch: model.DrumName{Name: "kick"},
+					Duration: model.Duration{
+						Components: []model.DurationComponent{
+							model.NoteLength{Denominator: 8},
+						},
+					},
+				},
+				model.Note{Pitch: model.DrumName{Name: "hh-closed"}},
+				model.Note{
+					Pitch: model.DrumName{Name: "snare"},
+					Duration: model.Duration{
+						Components: []model.DurationComponent{
+							model.NoteLength{Denominator: 4, Dots: 1},
+						},
+					},
+				},
+			},
+		},
+		parseTestCase{
+			label: "drum chord",
+			given: "midi-percussion: $kick/$hh r/$snare",
+			expectUpdates: []model.ScoreUpdate{
+				percussion,
+				model.Chord{
+					Events: []model.ScoreUpdate{
+						model.Note{Pitch: model.DrumName{Name: "kick"}},
+						model.Note{Pitch: model.DrumName{Name: "hh"}},
+					},
+				},
+				model.Chord{
+					Events: []model.ScoreUpdate{
+						model.Rest{},
+						model.Note{Pitch: model.DrumName{Name: "snare"}},
+					},
+				},
+			},
+		},
+		parseTestCase{
+			label: "drums with articulations and slurs",
+			given: "midi-percussion: $crash1~^> $ride-bell~",
+			expectUpdates: []model.ScoreUpdate{
+				percussion,
+				model.Note{
+					Pitch: model.DrumName{Name: "crash"},
+					Duration: model.Duration{
+						Components: []model.DurationComponent{
+							model.NoteLength{Denominator: 1},
+						},
+					},
+					Slurred:       true,
+					Articulations: []model.Articulation{model.Accent},
+				},
+				model.Note{Pitch: model.DrumName{Name: "ride-bell"}, Slurred: true},
+			},
+		},
+	)
+}
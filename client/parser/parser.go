@@ -20,16 +20,41 @@ type parser struct {
 	// useful for testing, e.g. for checking the equality of a list of expected
 	// tokens, agnostic of source context like line and column numbers.
 	suppressSourceContext bool
+	// When true, the parser keeps going after a syntax error and collects the
+	// errors in `errors`. See RecoverFromErrors.
+	recoverFromErrors bool
+	errors            ParseErrors
+	// When true, `include` and `import` S-expressions are left as they are,
+	// instead of parsing the files that they refer to. See SkipIncludes.
+	skipIncludes bool
+	// The chain of files that included the input, which is used to detect
+	// include cycles.
+	includers []string
 }
 
-// A parseOption is a function that customizes a parser instance.
-type parseOption func(*parser)
+// A ParseOption is a function that customizes a parser instance.
+type ParseOption func(*parser)
 
 // SuppressSourceContext customizes a parser to ignore source context
 func SuppressSourceContext(parser *parser) {
 	parser.suppressSourceContext = true
 }
 
+// SkipIncludes customizes a parser to leave `include` and `import`
+// S-expressions unresolved, which is useful when only the syntax of the input
+// matters, e.g. when formatting it.
+func SkipIncludes(parser *parser) {
+	parser.skipIncludes = true
+}
+
+// includedBy customizes a parser to parse a file that was included by the
+// chain of files `includers`.
+func includedBy(includers []string) ParseOption {
+	return func(parser *parser) {
+		parser.includers = includers
+	}
+}
+
 func (p *parser) sourceContext(token Token) model.AldaSourceContext {
 	if p.suppressSourceContext {
 		return model.AldaSourceContext{}
@@ -38,7 +63,7 @@ func (p *parser) sourceContext(token Token) model.AldaSourceContext {
 	return token.sourceContext
 }
 
-func newParser(filename string, tokens []Token, opts ...parseOption) *parser {
+func newParser(filename string, tokens []Token, opts ...ParseOption) *parser {
 	parser := &parser{
 		filename: filename,
 		input:    tokens,
@@ -264,17 +289,30 @@ func (p *parser) partDeclaration() (ASTNode, error) {
 	return partDecl, nil
 }
 
+// looksLikePartDeclaration returns true if the next tokens are one or more
+// names separated by `/`, followed by an alias or a colon, e.g. `piano/violin:`.
+//
+// Names separated by `/` without an alias or a colon are a reference to a
+// namespaced variable, e.g. `lib/motif`.
 func (p *parser) looksLikePartDeclaration() bool {
-	current := p.peek().tokenType
-
-	if current == EOF {
+	if !p.check(Name) {
 		return false
 	}
 
-	next := p.next().tokenType
+	i := p.current + 1
+
+	for p.input[i].tokenType == Separator {
+		// A trailing `/` is an error in a part declaration.
+		if p.input[i+1].tokenType != Name {
+			return true
+		}
+
+		i += 2
+	}
+
+	next := p.input[i].tokenType
 
-	return current == Name &&
-		(next == Alias || next == Separator || next == Colon)
+	return next == Alias || next == Colon
 }
 
 func (p *parser) partEvents() (ASTNode, error) {
@@ -286,8 +324,14 @@ func (p *parser) partEvents() (ASTNode, error) {
 
 	// Keep consuming events until we reach either a part declaration or EOF.
 	for !p.check(EOF) && !p.looksLikePartDeclaration() {
+		start := p.current
+
 		event, err := p.innerEvent()
 		if err != nil {
+			if p.recover(err, start) {
+				continue
+			}
+
 			return ASTNode{}, err
 		}
 
@@ -412,11 +456,18 @@ func (p *parser) singleOrRepeated(node ASTNode) ASTNode {
 func (p *parser) variableReference() (ASTNode, error) {
 	// NB: This assumes the initial Name token was already consumed.
 	nameToken := p.previous()
+	name := nameToken.text
+
+	// A variable imported from another file is namespaced, e.g. `lib/motif`.
+	for p.check(Separator) && p.next().tokenType == Name {
+		p.advance()
+		name += "/" + p.advance().text
+	}
 
 	reference := ASTNode{
 		Type:          VariableReferenceNode,
 		SourceContext: p.sourceContext(nameToken),
-		Literal:       nameToken.text,
+		Literal:       name,
 	}
 
 	return p.singleOrRepeated(reference), nil
@@ -563,22 +614,9 @@ func (p *parser) duration() ASTNode {
 	}
 }
 
-func (p *parser) note() (ASTNode, error) {
-	// NB: This assumes the initial NoteLetter token was already consumed.
-	noteLetterToken := p.previous()
-
-	laaNode := ASTNode{
-		Type:          NoteLetterAndAccidentalsNode,
-		SourceContext: p.sourceContext(noteLetterToken),
-		Children: []ASTNode{
-			{
-				Type:          NoteLetterNode,
-				SourceContext: p.sourceContext(noteLetterToken),
-				Literal:       noteLetterToken.literal,
-			},
-		},
-	}
-
+// accidentals parses any number of accidentals, e.g. the `+` in `c+`, and
+// returns a NoteAccidentalsNode if there were any.
+func (p *parser) accidentals() (ASTNode, bool) {
 	accidentalNodes := []ASTNode{}
 
 AccidentalsLoop:
@@ -603,25 +641,91 @@ AccidentalsLoop:
 		}
 	}
 
-	if len(accidentalNodes) > 0 {
-		laaNode.Children = append(laaNode.Children, ASTNode{
-			Type:          NoteAccidentalsNode,
-			SourceContext: accidentalNodes[0].SourceContext,
-			Children:      accidentalNodes,
-		})
+	if len(accidentalNodes) == 0 {
+		return ASTNode{}, false
+	}
+
+	return ASTNode{
+		Type:          NoteAccidentalsNode,
+		SourceContext: accidentalNodes[0].SourceContext,
+		Children:      accidentalNodes,
+	}, true
+}
+
+func (p *parser) letterAndAccidentals() ASTNode {
+	// NB: This assumes the initial NoteLetter token was already consumed.
+	noteLetterToken := p.previous()
+
+	laaNode := ASTNode{
+		Type:          NoteLetterAndAccidentalsNode,
+		SourceContext: p.sourceContext(noteLetterToken),
+		Children: []ASTNode{
+			{
+				Type:          NoteLetterNode,
+				SourceContext: p.sourceContext(noteLetterToken),
+				Literal:       noteLetterToken.literal,
+			},
+		},
+	}
+
+	if accidentals, matched := p.accidentals(); matched {
+		laaNode.Children = append(laaNode.Children, accidentals)
+	}
+
+	return laaNode
+}
+
+func (p *parser) note() (ASTNode, error) {
+	// NB: This assumes the initial NoteLetter/DrumName/ScaleDegree token was
+	// already consumed.
+	pitchToken := p.previous()
+
+	var pitchNode ASTNode
+	switch pitchToken.tokenType {
+	case DrumName:
+		pitchNode = ASTNode{
+			Type:          DrumNameNode,
+			SourceContext: p.sourceContext(pitchToken),
+			Literal:       pitchToken.literal,
+		}
+	case ScaleDegree:
+		pitchNode = ASTNode{
+			Type:          ScaleDegreeNode,
+			SourceContext: p.sourceContext(pitchToken),
+			Literal:       pitchToken.literal,
+		}
+
+		if accidentals, matched := p.accidentals(); matched {
+			pitchNode.Children = []ASTNode{accidentals}
+		}
+	default:
+		pitchNode = p.letterAndAccidentals()
 	}
 
 	noteNode := ASTNode{
 		Type:          NoteNode,
-		SourceContext: p.sourceContext(noteLetterToken),
-		Children:      []ASTNode{laaNode},
+		SourceContext: p.sourceContext(pitchToken),
+		Children:      []ASTNode{pitchNode},
+	}
+
+	if token, matched := p.match(Cents); matched {
+		noteNode.Children = append(noteNode.Children, ASTNode{
+			Type:          CentsNode,
+			SourceContext: p.sourceContext(token),
+			Literal:       token.literal,
+		})
 	}
 
 	if _, matched := p.matchDurationComponent(); matched {
 		noteNode.Children = append(noteNode.Children, p.duration())
 	}
 
+	// Articulations can come before or after the slur, e.g. `c4^.~` or `c4~^.`.
+	// Either way, the articulation nodes come before the tie node.
+	noteNode.Children = append(noteNode.Children, p.articulations()...)
+
 	if tie, matched := p.match(Tie); matched {
+		noteNode.Children = append(noteNode.Children, p.articulations()...)
 		noteNode.Children = append(noteNode.Children, ASTNode{
 			Type:          TieNode,
 			SourceContext: p.sourceContext(tie),
@@ -631,6 +735,23 @@ AccidentalsLoop:
 	return noteNode, nil
 }
 
+func (p *parser) articulations() []ASTNode {
+	articulationNodes := []ASTNode{}
+
+	for {
+		token, matched := p.match(Articulation)
+		if !matched {
+			return articulationNodes
+		}
+
+		articulationNodes = append(articulationNodes, ASTNode{
+			Type:          ArticulationNode,
+			SourceContext: p.sourceContext(token),
+			Literal:       token.literal,
+		})
+	}
+}
+
 func (p *parser) rest() ASTNode {
 	// NB: This assumes the initial RestLetter token was already consumed.
 	token := p.previous()
@@ -648,9 +769,10 @@ func (p *parser) rest() ASTNode {
 }
 
 func (p *parser) noteOrRest() (ASTNode, error) {
-	// NB: This assumes the initial NoteLetter/RestLetter was already consumed.
+	// NB: This assumes the initial NoteLetter/DrumName/ScaleDegree/RestLetter
+	// was already consumed.
 	switch letter := p.previous(); letter.tokenType {
-	case NoteLetter:
+	case NoteLetter, DrumName, ScaleDegree:
 		return p.note()
 	case RestLetter:
 		return p.rest(), nil
@@ -694,7 +816,8 @@ func (p *parser) nodesBetweenNotesInChord() ([]ASTNode, error) {
 // Parses a note or chord. A chord contains multiple chords and rests, not to
 // mention attribute changes, so any of those will be parsed too in the process.
 func (p *parser) noteRestOrChord() (ASTNode, error) {
-	// NB: This assumes the initial NoteLetter/RestLetter was already consumed.
+	// NB: This assumes the initial NoteLetter/DrumName/ScaleDegree/RestLetter
+	// was already consumed.
 
 	// The cumulative list of nodes. Depending on whether this is a chord, the
 	// nodes will either be emitted as part of the chord, or emitted individually.
@@ -771,7 +894,7 @@ func (p *parser) noteRestOrChord() (ASTNode, error) {
 
 		allNodes = append(allNodes, nodes...)
 
-		if _, matched := p.match(NoteLetter, RestLetter); !matched {
+		if _, matched := p.match(NoteLetter, DrumName, ScaleDegree, RestLetter); !matched {
 			return ASTNode{}, p.unexpectedTokenError(p.peek(), "in chord")
 		}
 	}
@@ -1026,7 +1149,7 @@ func (p *parser) innerEvent() (ASTNode, error) {
 		}, nil
 	}
 
-	if _, matched := p.match(NoteLetter, RestLetter); matched {
+	if _, matched := p.match(NoteLetter, DrumName, ScaleDegree, RestLetter); matched {
 		return p.noteRestOrChord()
 	}
 
@@ -1082,8 +1205,14 @@ func (p *parser) parseAST() (ASTNode, error) {
 
 	for t := p.peek(); t.tokenType != EOF; t = p.peek() {
 		// fmt.Printf("t: %s\n", t.String())
+		start := p.current
+
 		node, err := p.topLevel()
 		if err != nil {
+			if p.recover(err, start) {
+				continue
+			}
+
 			return ASTNode{}, err
 		}
 
@@ -1094,8 +1223,12 @@ func (p *parser) parseAST() (ASTNode, error) {
 }
 
 // Parse a string of input into a root ASTNode.
+//
+// By default, parsing stops at the first syntax error. With the
+// RecoverFromErrors option, all of the syntax errors are returned together as
+// a ParseErrors, along with the partial AST.
 func Parse(
-	filepath string, input string, opts ...parseOption,
+	filepath string, input string, opts ...ParseOption,
 ) (ASTNode, error) {
 	defer func(start time.Time) {
 		if r := recover(); r != nil {
@@ -1108,23 +1241,44 @@ func Parse(
 			Msg("Parsed input.")
 	}(time.Now())
 
-	tokens, err := Scan(filepath, input)
+	p := newParser(filepath, nil, opts...)
+
+	s := newScanner(filepath, input)
+	s.recoverFromErrors = p.recoverFromErrors
+
+	tokens, err := s.scan()
+	if err != nil && !p.recoverFromErrors {
+		return ASTNode{}, err
+	}
+
+	p.input = tokens
+
+	ast, err := p.parseAST()
 	if err != nil {
 		return ASTNode{}, err
 	}
 
-	p := newParser(filepath, tokens, opts...)
+	if !p.skipIncludes {
+		if err := p.resolveIncludes(&ast); err != nil {
+			return ASTNode{}, err
+		}
+	}
+
+	if parseErrors := append(s.errors, p.errors...); len(parseErrors) > 0 {
+		parseErrors.sort()
+		return ast, parseErrors
+	}
 
-	return p.parseAST()
+	return ast, nil
 }
 
 // ParseString reads and parses a string of input.
-func ParseString(input string) (ASTNode, error) {
-	return Parse("", input)
+func ParseString(input string, opts ...ParseOption) (ASTNode, error) {
+	return Parse("", input, opts...)
 }
 
 // ParseFile reads a file and parses the input.
-func ParseFile(filepath string) (ASTNode, error) {
+func ParseFile(filepath string, opts ...ParseOption) (ASTNode, error) {
 	contents, err := os.ReadFile(filepath)
 
 	if errors.Is(err, os.ErrNotExist) {
@@ -1140,5 +1294,5 @@ Please check that you haven't misspelled the file name, etc.`,
 		return ASTNode{}, err
 	}
 
-	return Parse(filepath, string(contents))
+	return Parse(filepath, string(contents), opts...)
 }
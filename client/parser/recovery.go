@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"alda.io/client/model"
+)
+
+// ParseErrors is a list of errors encountered while parsing with
+// RecoverFromErrors, in the order in which they appear in the source.
+type ParseErrors []*model.AldaSourceError
+
+// Error returns all of the errors, one per line.
+func (pe ParseErrors) Error() string {
+	messages := []string{}
+	for _, err := range pe {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// add records an error. Errors without source context are wrapped in an
+// AldaSourceError without context.
+func (pe *ParseErrors) add(err error) {
+	var sourceErr *model.AldaSourceError
+	if !errors.As(err, &sourceErr) {
+		sourceErr = &model.AldaSourceError{Err: err}
+	}
+
+	*pe = append(*pe, sourceErr)
+}
+
+// sort sorts the errors by their position in the source.
+func (pe ParseErrors) sort() {
+	sort.SliceStable(pe, func(i, j int) bool {
+		a, b := pe[i].Context, pe[j].Context
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// RecoverFromErrors customizes a parser to keep going after a syntax error
+// instead of stopping at the first one.
+//
+// After an error, the parser skips ahead to the next part declaration, barline
+// or line, and carries on from there. When there are errors, Parse returns the
+// partial AST that it was able to parse, along with a ParseErrors containing
+// all of the errors.
+func RecoverFromErrors(parser *parser) {
+	parser.recoverFromErrors = true
+}
+
+// recover records an error that occurred while parsing something that started
+// at the token with index `start`, then skips ahead to a point where parsing
+// can resume.
+//
+// Returns false (and does nothing) if the parser isn't recovering from errors.
+func (p *parser) recover(err error, start int) bool {
+	if !p.recoverFromErrors {
+		return false
+	}
+
+	p.errors.add(err)
+
+	// Make sure that we always make progress, so that we don't encounter the
+	// same error over and over again.
+	if p.current == start {
+		p.advance()
+	}
+
+	line := p.previous().sourceContext.Line
+	var sourceErr *model.AldaSourceError
+	if errors.As(err, &sourceErr) && sourceErr.Context.Line > line {
+		line = sourceErr.Context.Line
+	}
+
+	p.synchronize(line)
+
+	return true
+}
+
+// synchronize skips tokens until reaching a token at which parsing can resume:
+// a part declaration, a barline, or the first token on a line after `line`.
+func (p *parser) synchronize(line int) {
+	for !p.check(EOF) {
+		if p.check(Barline) || p.looksLikePartDeclaration() ||
+			p.peek().sourceContext.Line > line {
+			return
+		}
+
+		p.advance()
+	}
+}
@@ -0,0 +1,160 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"alda.io/client/model"
+	_ "alda.io/client/testing"
+	"github.com/go-test/deep"
+)
+
+type recoveryTestCase struct {
+	label string
+	given string
+	// Each error, formatted as "line:column message".
+	expectErrors  []string
+	expectUpdates []model.ScoreUpdate
+}
+
+func executeRecoveryTestCases(t *testing.T, testCases ...recoveryTestCase) {
+	for _, testCase := range testCases {
+		ast, err := Parse(
+			testCase.label, testCase.given, SuppressSourceContext, RecoverFromErrors,
+		)
+
+		actualErrors := []string{}
+		var parseErrors ParseErrors
+		switch {
+		case err == nil:
+		case errors.As(err, &parseErrors):
+			for _, parseError := range parseErrors {
+				actualErrors = append(actualErrors, fmt.Sprintf(
+					"%d:%d %s",
+					parseError.Context.Line,
+					parseError.Context.Column,
+					parseError.Err.Error(),
+				))
+			}
+		default:
+			t.Errorf("%s: expected ParseErrors, got %#v", testCase.label, err)
+			continue
+		}
+
+		if diff := deep.Equal(testCase.expectErrors, actualErrors); diff != nil {
+			t.Error(testCase.label)
+			for _, diffItem := range diff {
+				t.Errorf("%v", diffItem)
+			}
+		}
+
+		updates, err := ast.Updates()
+		if err != nil {
+			t.Error(testCase.label)
+			t.Error(err)
+			continue
+		}
+
+		if diff := deep.Equal(testCase.expectUpdates, updates); diff != nil {
+			t.Error(testCase.label)
+			for _, diffItem := range diff {
+				t.Errorf("%v", diffItem)
+			}
+		}
+	}
+}
+
+func note(letter model.NoteLetter) model.Note {
+	return model.Note{Pitch: model.LetterAndAccidentals{NoteLetter: letter}}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	executeRecoveryTestCases(
+		t,
+		recoveryTestCase{
+			label:        "no errors",
+			given:        "piano: c d e",
+			expectErrors: []string{},
+			expectUpdates: []model.ScoreUpdate{
+				model.PartDeclaration{Names: []string{"piano"}},
+				note(model.C), note(model.D), note(model.E),
+			},
+		},
+		recoveryTestCase{
+			label: "errors on several lines",
+			given: "piano: c d e\nf } g a\nviolin: c & d\ne f",
+			expectErrors: []string{
+				"2:3 Unexpected end of cram expression `}` in inner events",
+				"3:11 Unexpected '&' at the top level",
+			},
+			expectUpdates: []model.ScoreUpdate{
+				model.PartDeclaration{Names: []string{"piano"}},
+				note(model.C), note(model.D), note(model.E), note(model.F),
+				model.PartDeclaration{Names: []string{"violin"}},
+				note(model.C), note(model.E), note(model.F),
+			},
+		},
+		recoveryTestCase{
+			label: "unknown drum",
+			given: "midi-percussion: $kick $bongo\n$snare",
+			expectErrors: []string{
+				"1:24 Unknown drum: bongo",
+			},
+			expectUpdates: []model.ScoreUpdate{
+				model.PartDeclaration{Names: []string{"midi-percussion"}},
+				model.Note{Pitch: model.DrumName{Name: "kick"}},
+				model.Note{Pitch: model.DrumName{Name: "snare"}},
+			},
+		},
+		recoveryTestCase{
+			label: "resynchronizing at a barline",
+			given: "piano: c/ | d e",
+			expectErrors: []string{
+				"1:11 Unexpected barline `|` in chord",
+			},
+			expectUpdates: []model.ScoreUpdate{
+				model.PartDeclaration{Names: []string{"piano"}},
+				model.Barline{}, note(model.D), note(model.E),
+			},
+		},
+		recoveryTestCase{
+			label: "resynchronizing at a part declaration",
+			given: "piano: c } d violin: e",
+			expectErrors: []string{
+				"1:10 Unexpected end of cram expression `}` in inner events",
+			},
+			expectUpdates: []model.ScoreUpdate{
+				model.PartDeclaration{Names: []string{"piano"}},
+				note(model.C),
+				model.PartDeclaration{Names: []string{"violin"}},
+				note(model.E),
+			},
+		},
+		recoveryTestCase{
+			label: "error in a part declaration",
+			given: "piano/: c d\nviolin: e",
+			expectErrors: []string{
+				"1:7 Unexpected colon `:` in part declaration",
+			},
+			expectUpdates: []model.ScoreUpdate{
+				model.PartDeclaration{Names: []string{"violin"}},
+				note(model.E),
+			},
+		},
+	)
+}
+
+func TestErrorRecoveryDisabled(t *testing.T) {
+	_, err := Parse("test", "piano: c/ g\nviolin: c & d", SuppressSourceContext)
+
+	var sourceErr *model.AldaSourceError
+	if !errors.As(err, &sourceErr) {
+		t.Fatalf("expected an AldaSourceError, got %#v", err)
+	}
+
+	var parseErrors ParseErrors
+	if errors.As(err, &parseErrors) {
+		t.Error("expected parsing to stop at the first error")
+	}
+}
@@ -94,6 +94,10 @@ const (
 	Tie
 	Repeat
 	VoiceMarker
+	Articulation
+	DrumName
+	ScaleDegree
+	Cents
 )
 
 // A Token is a result of lexical analysis done by the scanner.
@@ -176,6 +180,14 @@ func (tt TokenType) String() string {
 		return "tie"
 	case VoiceMarker:
 		return "voice marker"
+	case Articulation:
+		return "articulation"
+	case DrumName:
+		return "drum name"
+	case ScaleDegree:
+		return "scale degree"
+	case Cents:
+		return "cents"
 	default:
 		return fmt.Sprintf("%d (String not implemented)", tt)
 	}
@@ -203,6 +215,12 @@ type scanner struct {
 	startLine   int
 	startColumn int
 	sexpLevel   int
+	// When true, the scanner keeps going after an error, skipping the rest of
+	// the line on which the error occurred, and collects the errors in `errors`.
+	recoverFromErrors bool
+	errors            ParseErrors
+	// The number of comments skipped so far. Comments don't produce tokens.
+	comments int
 }
 
 func newScanner(filename string, input string) *scanner {
@@ -289,6 +307,8 @@ func (s *scanner) addToken(tokenType TokenType, literal interface{}) {
 }
 
 func (s *scanner) skipComment() {
+	s.comments++
+
 	for s.peek() != '\n' && !s.reachedEOF() {
 		s.advance()
 	}
@@ -345,6 +365,53 @@ func (s *scanner) parseFloatFrom(startIndex int) float64 {
 	return number
 }
 
+// parseCents scans a number of cents that raises or lowers a pitch, e.g. `+25&`
+// or `-12.5&`, and returns false if the input isn't a number of cents, in which
+// case nothing is consumed.
+//
+// The `&` sets cents apart from a sharp or flat followed by a note length, e.g.
+// `c+8` is a C sharp eighth note. `&` can't appear anywhere else at the top
+// level, so no other input is scanned differently because of cents.
+//
+// NB: This assumes the initial `+` or `-` was already consumed.
+func (s *scanner) parseCents(sign rune) bool {
+	i := s.current
+	digits := func() {
+		for i < len(s.input) && isDigit(s.input[i]) {
+			i++
+		}
+	}
+
+	digits()
+	if i == s.current {
+		return false
+	}
+
+	if i+1 < len(s.input) && s.input[i] == '.' && isDigit(s.input[i+1]) {
+		i++
+		digits()
+	}
+
+	if i >= len(s.input) || s.input[i] != '&' {
+		return false
+	}
+
+	for s.current < i {
+		s.advance()
+	}
+
+	cents := s.parseFloatFrom(s.start + 1)
+	if sign == '-' {
+		cents = -cents
+	}
+
+	// Consume the `&`.
+	s.advance()
+
+	s.addToken(Cents, cents)
+	return true
+}
+
 type noteLength struct {
 	denominator float64
 	dots        int32
@@ -608,6 +675,66 @@ func (s *scanner) parseAtMarker() error {
 	return s.parsePrefixedName(AtMarker, "in marker name")
 }
 
+// The `^` sigil has two uses:
+//
+//   - A scale degree, e.g. `^5`, starts a new note. The degree is always a
+//     single digit from 1 to 7, so that a note length can follow it, e.g. `^54`
+//     is a quarter note on the fifth degree, not degree 54.
+//   - An articulation, e.g. `c4^.`, applies to the note before it. The symbol
+//     after the `^` is one of model.Articulations, none of which are digits.
+//
+// So the character after the `^` is enough to tell the two apart.
+func (s *scanner) parseArticulationOrScaleDegree() error {
+	// NB: This assumes the initial ^ was already consumed.
+	c := s.peek()
+
+	if '1' <= c && c <= '7' {
+		s.advance()
+		s.addToken(ScaleDegree, int32(c-'0'))
+		return nil
+	}
+
+	for _, articulation := range model.Articulations() {
+		if c == articulation.Symbol() {
+			s.advance()
+			s.addToken(Articulation, articulation)
+			return nil
+		}
+	}
+
+	return s.unexpectedCharError(
+		c, "in articulation or scale degree", s.line, s.column,
+	)
+}
+
+func (s *scanner) parseDrumName() error {
+	// NB: This assumes the initial $ was already consumed.
+
+	if c := s.peek(); !isLetter(c) {
+		return s.unexpectedCharError(c, "in drum name", s.line, s.column)
+	}
+
+	// Drum names are made up of words separated by hyphens, e.g. `hh-closed`. A
+	// hyphen that isn't followed by a letter is left alone, so that it can be
+	// parsed as something else.
+	for isLetter(s.peek()) || (s.peek() == '-' && isLetter(s.peekNext())) {
+		s.advance()
+	}
+
+	// Trim the initial $
+	name := string(s.input[s.start+1 : s.current])
+
+	if !model.IsDrumName(name) {
+		return s.errorAtPosition(
+			s.startLine, s.startColumn, fmt.Sprintf("Unknown drum: %s", name),
+		)
+	}
+
+	s.addToken(DrumName, name)
+
+	return nil
+}
+
 func isNoteLetter(c rune) bool {
 	return 'a' <= c && c <= 'g'
 }
@@ -631,7 +758,7 @@ func followsNoteLetter(c rune) bool {
 
 	switch c {
 	case '#', ' ', '\r', '\n', '+', '-', '_', '/', '~', '*', '\'', '}', ']', '<',
-		'>':
+		'>', '^':
 		return true
 	}
 
@@ -686,7 +813,9 @@ func (s *scanner) scanToken() error {
 			err = s.parseString()
 		default:
 			switch {
-			case c == '-' || isDigit(c):
+			// A `-` is the start of a negative number only if a digit follows it.
+			// Otherwise, it's a symbol, e.g. the subtraction function `-`.
+			case (c == '-' && isDigit(s.peek())) || isDigit(c):
 				s.parseNumber()
 			case isValidSymbolChar(c):
 				s.parseSymbol()
@@ -709,9 +838,13 @@ func (s *scanner) scanToken() error {
 	case ']':
 		s.addToken(EventSeqClose, nil)
 	case '-':
-		s.addToken(Flat, nil)
+		if !s.parseCents(c) {
+			s.addToken(Flat, nil)
+		}
 	case '+':
-		s.addToken(Sharp, nil)
+		if !s.parseCents(c) {
+			s.addToken(Sharp, nil)
+		}
 	case '_':
 		s.addToken(Natural, nil)
 	case '/':
@@ -738,6 +871,10 @@ func (s *scanner) scanToken() error {
 		err = s.parseMarker()
 	case '@':
 		err = s.parseAtMarker()
+	case '^':
+		err = s.parseArticulationOrScaleDegree()
+	case '$':
+		err = s.parseDrumName()
 	default:
 		switch {
 		case isDigit(c):
@@ -766,12 +903,27 @@ func (s *scanner) scanToken() error {
 	return err
 }
 
-// Scan an input string and return a list of tokens.
+// skipLine skips the rest of the current line, keeping track of any
+// parentheses along the way so that we know whether the next line starts
+// inside of an S-expression.
+func (s *scanner) skipLine() {
+	for !s.reachedEOF() && s.peek() != '\n' {
+		switch s.advance() {
+		case '(':
+			s.sexpLevel++
+		case ')':
+			if s.sexpLevel > 0 {
+				s.sexpLevel--
+			}
+		}
+	}
+}
+
+// scan scans the scanner's input and returns the list of tokens.
 //
-// The `filename` argument is included in the error message in the event of a
-// parse error.
-func Scan(filename string, input string) ([]Token, error) {
-	s := newScanner(filename, input)
+// When the scanner is recovering from errors, the tokens that could be scanned
+// are returned along with a ParseErrors describing every error encountered.
+func (s *scanner) scan() ([]Token, error) {
 	for !s.reachedEOF() {
 		// We are at the beginning of the next lexeme.
 		s.start = s.current
@@ -786,7 +938,12 @@ func Scan(filename string, input string) ([]Token, error) {
 		// 	Msg("Scanning token.")
 		// Scan the next token.
 		if err := s.scanToken(); err != nil {
-			return nil, err
+			if !s.recoverFromErrors {
+				return nil, err
+			}
+
+			s.errors.add(err)
+			s.skipLine()
 		}
 	}
 
@@ -801,9 +958,34 @@ func Scan(filename string, input string) ([]Token, error) {
 		},
 	})
 
+	if len(s.errors) > 0 {
+		return s.tokens, s.errors
+	}
+
 	return s.tokens, nil
 }
 
+// Scan an input string and return a list of tokens.
+//
+// The `filename` argument is included in the error message in the event of a
+// parse error.
+func Scan(filename string, input string) ([]Token, error) {
+	return newScanner(filename, input).scan()
+}
+
+// HasComments returns true if the input contains any comments. A `#` that
+// doesn't start a comment, e.g. in a string like "F#m7", doesn't count.
+//
+// Comments are skipped over when scanning, so this is useful for knowing
+// whether anything would be lost by formatting the input.
+func HasComments(input string) bool {
+	s := newScanner("", input)
+	s.recoverFromErrors = true
+	s.scan()
+
+	return s.comments > 0
+}
+
 // ScanFile reads a file, scans it, and returns a list of tokens.
 func ScanFile(filepath string) ([]Token, error) {
 	contents, err := os.ReadFile(filepath)
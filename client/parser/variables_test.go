@@ -226,5 +226,19 @@ func TestVariables(t *testing.T) {
 				variableDefinition("foo", variableReference("bar")),
 			},
 		},
+		parseTestCase{
+			label: "namespaced variable references",
+			given: "piano/violin: lib/motif c lib/riff*2\ncello: lib/motif",
+			expectUpdates: []model.ScoreUpdate{
+				model.PartDeclaration{Names: []string{"piano", "violin"}},
+				variableReference("lib/motif"),
+				model.Note{Pitch: model.LetterAndAccidentals{NoteLetter: model.C}},
+				model.Repeat{Event: variableReference("lib/riff"), Times: 2},
+				model.PartDeclaration{Names: []string{"cello"}},
+				variableReference("lib/motif"),
+			},
+			// The variables are defined in an imported file.
+			scoreApplyOptOut: true,
+		},
 	)
 }
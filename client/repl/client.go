@@ -229,10 +229,50 @@ file into the REPL server.`,
 					return err
 				}
 
+				// The server resolves the paths of included files relative to this
+				// file.
+				filename, err := filepath.Abs(client.inputFilepath)
+				if err != nil {
+					return err
+				}
+
 				_, err = client.sendRequest(
 					map[string]interface{}{
-						"op":   "load",
-						"code": string(contents)},
+						"op":       "load",
+						"code":     string(contents),
+						"filename": filename},
+				)
+				if err != nil {
+					return err
+				}
+
+				return nil
+			},
+		},
+
+		"loop": {
+			helpSummary: "Loops a pattern indefinitely.",
+			helpDetails: `Usage:
+
+  :loop beat
+
+The pattern must first be defined via :pattern. While the pattern is looping,
+you can redefine it via :pattern, and the loop will play the new version
+starting with its next iteration.
+
+Use :stop-loop to stop the loop.`,
+			run: func(client *Client, argsString string) error {
+				args, err := shlex.Split(argsString)
+				if err != nil {
+					return err
+				}
+
+				if len(args) != 1 {
+					return invalidArgsError(args)
+				}
+
+				_, err = client.sendRequest(
+					map[string]interface{}{"op": "loop", "name": args[0]},
 				)
 				if err != nil {
 					return err
@@ -276,6 +316,86 @@ file into the REPL server.`,
 			},
 		},
 
+		"pattern": {
+			helpSummary: "Defines (or redefines) a named pattern that can be looped.",
+			helpDetails: `Usage:
+
+  :pattern beat percussion: o2 c8 c e c c c e c
+  :pattern bass bass: o2 c4. c8 r2
+
+A pattern consists of Alda code for a single part. Patterns are separate from
+the current score, but they can refer to its variables.
+
+If the pattern is looping (see :loop), the new version of the pattern will be
+played starting with the next iteration of the loop. The instrument of a
+looping pattern can't be changed until the loop is stopped.`,
+			run: func(client *Client, argsString string) error {
+				// We don't split the arguments with shlex here because the pattern's
+				// code is passed through as-is.
+				fields := strings.Fields(argsString)
+				if len(fields) < 2 {
+					return invalidArgsError(fields)
+				}
+
+				name := fields[0]
+				code := strings.TrimSpace(
+					strings.TrimPrefix(strings.TrimSpace(argsString), name),
+				)
+
+				_, err := client.sendRequest(
+					map[string]interface{}{
+						"op":   "pattern",
+						"name": name,
+						"code": code,
+					},
+				)
+				if err != nil {
+					return err
+				}
+
+				return nil
+			},
+		},
+
+		"patterns": {
+			helpSummary: "Displays the patterns that have been defined.",
+			run: func(client *Client, argsString string) error {
+				res, err := client.sendRequest(
+					map[string]interface{}{"op": "patterns"},
+				)
+				if err != nil {
+					return err
+				}
+
+				// See the comment about lists of strings in the :instruments command.
+				patterns, ok := res["patterns"].([]interface{})
+				if !ok {
+					return fmt.Errorf(
+						"the response from the REPL server did not contain the list of " +
+							"patterns",
+					)
+				}
+
+				looping := map[interface{}]bool{}
+				switch names := res["looping"].(type) {
+				case []interface{}:
+					for _, name := range names {
+						looping[name] = true
+					}
+				}
+
+				for _, pattern := range patterns {
+					if looping[pattern] {
+						fmt.Printf("%s (looping)\n", pattern)
+					} else {
+						fmt.Println(pattern)
+					}
+				}
+
+				return nil
+			},
+		},
+
 		"play": {
 			helpSummary: "Plays the current score.",
 			helpDetails: `Can take optional ` + "`from`" + ` and ` + "`to`" +
@@ -480,6 +600,34 @@ arguments will save the updated score to the same file.`,
 			},
 		},
 
+		"stop-loop": {
+			helpSummary: "Stops looping a pattern.",
+			helpDetails: `Usage:
+
+  :stop-loop beat
+
+The loop stops once the current iteration of the pattern is complete.`,
+			run: func(client *Client, argsString string) error {
+				args, err := shlex.Split(argsString)
+				if err != nil {
+					return err
+				}
+
+				if len(args) != 1 {
+					return invalidArgsError(args)
+				}
+
+				_, err = client.sendRequest(
+					map[string]interface{}{"op": "stop-loop", "name": args[0]},
+				)
+				if err != nil {
+					return err
+				}
+
+				return nil
+			},
+		},
+
 		"version": {
 			helpSummary: "Displays the version numbers of the Alda server and client.",
 			run: func(client *Client, argsString string) error {
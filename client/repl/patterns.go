@@ -0,0 +1,266 @@
+package repl
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	log "alda.io/client/logging"
+	"alda.io/client/model"
+	"alda.io/client/parser"
+	"alda.io/client/transmitter"
+)
+
+// Patterns are named snippets of Alda code that the player can loop
+// indefinitely. Redefining a pattern while it's looping changes what the loop
+// plays, starting with the next iteration of the loop. This is what makes live
+// coding in the REPL possible.
+//
+// Each pattern is evaluated as a separate score consisting of a single part,
+// independently of the REPL session's score.
+
+// Each pattern loop is scheduled on its own track, because a looping pattern
+// occupies its track until the loop is finished. We start numbering these
+// tracks well above the track numbers used for the parts of the score, so that
+// they don't collide.
+const firstLoopTrack int32 = 1000
+
+// The MIDI channel used for percussion.
+const percussionChannel int32 = 9
+
+var patternNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// A patternLoop is a pattern that is looping on a track of the player.
+type patternLoop struct {
+	track   int32
+	channel int32
+}
+
+// Parses and evaluates `input` as the contents of a pattern.
+func (server *Server) patternScore(input string) (*model.Score, error) {
+	ast, err := parser.ParseString(input, parser.RecoverFromErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	scoreUpdates, err := ast.Updates()
+	if err != nil {
+		return nil, err
+	}
+
+	score := model.NewScore()
+
+	// Patterns can refer to variables defined in the REPL session.
+	for name, value := range server.score.Variables {
+		score.SetVariable(name, value)
+	}
+
+	if err := score.Update(scoreUpdates...); err != nil {
+		return nil, err
+	}
+
+	if _, err := transmitter.PatternInstrument(score); err != nil {
+		return nil, err
+	}
+
+	return score, nil
+}
+
+// Defines (or redefines) the pattern `name` as the Alda code `input`.
+//
+// If the pattern is currently looping, the new contents are sent to the player
+// right away, and the player picks them up on the next iteration of the loop.
+func (server *Server) definePattern(name string, input string) error {
+	if !patternNameRegex.MatchString(name) {
+		return fmt.Errorf(
+			"invalid pattern name `%s` (pattern names may only contain letters, "+
+				"digits, `-` and `_`)",
+			name,
+		)
+	}
+
+	score, err := server.patternScore(input)
+	if err != nil {
+		return err
+	}
+
+	loop, looping := server.loops[name]
+
+	if looping {
+		// The patch (program) of a loop's channel is set when the loop starts, and
+		// it can't be changed while the pattern occupies the loop's track.
+		previous, _ := transmitter.PatternInstrument(server.patterns[name])
+		current, _ := transmitter.PatternInstrument(score)
+		if current.IsPercussion != previous.IsPercussion ||
+			(!current.IsPercussion && current.PatchNumber != previous.PatchNumber) {
+			return fmt.Errorf(
+				"can't change the instrument of pattern `%s` while it's looping; "+
+					"stop the loop first",
+				name,
+			)
+		}
+	}
+
+	server.patterns[name] = score
+
+	if !looping {
+		return nil
+	}
+
+	return server.withTransmitter(
+		func(t transmitter.OSCTransmitter) error {
+			log.Info().
+				Interface("player", server.player).
+				Str("pattern", name).
+				Int32("track", loop.track).
+				Msg("Redefining looping pattern.")
+
+			return t.TransmitPattern(name, score)
+		},
+	)
+}
+
+// Returns the MIDI channels that the parts of `score` use.
+func scoreChannels(score *model.Score) map[int32]bool {
+	channels := map[int32]bool{}
+
+	for _, part := range score.Parts {
+		if part.MidiChannel >= 0 {
+			channels[part.MidiChannel] = true
+		}
+	}
+
+	// A part can move to a different channel over the course of the score, when
+	// its channel is in use by another part at that point in time.
+	for _, event := range score.Events {
+		if note, ok := event.(model.NoteEvent); ok {
+			channels[note.MidiChannel] = true
+		}
+	}
+
+	return channels
+}
+
+// Returns a MIDI channel for a new loop of a pattern played by `instrument`,
+// avoiding the channels in `used`.
+//
+// All percussion loops share the percussion channel. Every other loop gets a
+// channel to itself, allocated from the top down, because the parts of the
+// score are allocated channels from the bottom up.
+func loopChannel(
+	instrument model.MidiInstrument, used map[int32]bool,
+) (int32, error) {
+	if instrument.IsPercussion {
+		return percussionChannel, nil
+	}
+
+	for channel := int32(15); channel >= 0; channel-- {
+		if channel != percussionChannel && !used[channel] {
+			return channel, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no MIDI channels are available for another loop")
+}
+
+// Returns a MIDI channel for a new loop of a pattern played by `instrument`
+// that isn't used by the score or by another loop.
+func (server *Server) loopChannel(
+	instrument model.MidiInstrument,
+) (int32, error) {
+	used := scoreChannels(server.score)
+	for _, loop := range server.loops {
+		used[loop.channel] = true
+	}
+
+	return loopChannel(instrument, used)
+}
+
+// Starts looping the pattern `name` indefinitely.
+func (server *Server) loopPattern(name string) error {
+	score, defined := server.patterns[name]
+	if !defined {
+		return fmt.Errorf("pattern `%s` is not defined", name)
+	}
+
+	if _, looping := server.loops[name]; looping {
+		return fmt.Errorf("pattern `%s` is already looping", name)
+	}
+
+	instrument, err := transmitter.PatternInstrument(score)
+	if err != nil {
+		return err
+	}
+
+	channel, err := server.loopChannel(instrument)
+	if err != nil {
+		return err
+	}
+
+	loop := patternLoop{track: server.nextLoopTrack, channel: channel}
+
+	if err := server.withTransmitter(
+		func(t transmitter.OSCTransmitter) error {
+			log.Info().
+				Interface("player", server.player).
+				Str("pattern", name).
+				Int32("track", loop.track).
+				Int32("channel", loop.channel).
+				Msg("Looping pattern.")
+
+			return t.TransmitPatternLoop(name, score, loop.track, loop.channel)
+		},
+	); err != nil {
+		return err
+	}
+
+	server.loops[name] = loop
+	server.nextLoopTrack++
+
+	return nil
+}
+
+// Stops looping the pattern `name` once the current iteration of the loop is
+// complete.
+func (server *Server) stopLoop(name string) error {
+	loop, looping := server.loops[name]
+	if !looping {
+		return fmt.Errorf("pattern `%s` is not looping", name)
+	}
+
+	if err := server.withTransmitter(
+		func(t transmitter.OSCTransmitter) error {
+			log.Info().
+				Interface("player", server.player).
+				Str("pattern", name).
+				Int32("track", loop.track).
+				Msg("Finishing pattern loop.")
+
+			return t.TransmitFinishLoop(loop.track)
+		},
+	); err != nil {
+		return err
+	}
+
+	delete(server.loops, name)
+
+	return nil
+}
+
+// Returns the sorted names of all defined patterns, and of the patterns that
+// are currently looping.
+func (server *Server) patternNames() ([]string, []string) {
+	patterns := []string{}
+	for name := range server.patterns {
+		patterns = append(patterns, name)
+	}
+	sort.Strings(patterns)
+
+	looping := []string{}
+	for name := range server.loops {
+		looping = append(looping, name)
+	}
+	sort.Strings(looping)
+
+	return patterns, looping
+}
@@ -0,0 +1,109 @@
+package repl
+
+import (
+	"testing"
+
+	"alda.io/client/model"
+	"alda.io/client/parser"
+	_ "alda.io/client/testing"
+)
+
+func evaluate(t *testing.T, input string) *model.Score {
+	ast, err := parser.ParseString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updates, err := ast.Updates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	score := model.NewScore()
+	if err := score.Update(updates...); err != nil {
+		t.Fatal(err)
+	}
+
+	return score
+}
+
+func TestScoreChannels(t *testing.T) {
+	score := evaluate(t, "piano: c\nviolin: (midi-channel 15) d\npercussion: o2 c")
+
+	channels := scoreChannels(score)
+
+	for _, channel := range []int32{0, 9, 15} {
+		if !channels[channel] {
+			t.Errorf("expected channel %d to be used, got %v", channel, channels)
+		}
+	}
+
+	if len(channels) != 3 {
+		t.Errorf("expected 3 channels to be used, got %v", channels)
+	}
+}
+
+func TestLoopChannel(t *testing.T) {
+	piano := model.MidiInstrument{NameImpl: "midi-acoustic-grand-piano"}
+	drums := model.MidiInstrument{NameImpl: "midi-percussion", IsPercussion: true}
+
+	allChannels := map[int32]bool{}
+	for channel := int32(0); channel < 16; channel++ {
+		allChannels[channel] = true
+	}
+
+	for _, testCase := range []struct {
+		label      string
+		instrument model.MidiInstrument
+		used       map[int32]bool
+		expected   int32
+		err        bool
+	}{
+		{
+			label:      "the top channel when nothing is used",
+			instrument: piano,
+			used:       map[int32]bool{},
+			expected:   15,
+		},
+		{
+			label:      "the highest free channel",
+			instrument: piano,
+			used:       map[int32]bool{0: true, 15: true, 14: true},
+			expected:   13,
+		},
+		{
+			label:      "skips the percussion channel",
+			instrument: piano,
+			used: map[int32]bool{
+				15: true, 14: true, 13: true, 12: true, 11: true, 10: true,
+			},
+			expected: 8,
+		},
+		{
+			label:      "percussion shares the percussion channel",
+			instrument: drums,
+			used:       allChannels,
+			expected:   9,
+		},
+		{
+			label:      "no free channels",
+			instrument: piano,
+			used:       allChannels,
+			err:        true,
+		},
+	} {
+		channel, err := loopChannel(testCase.instrument, testCase.used)
+
+		switch {
+		case testCase.err && err == nil:
+			t.Errorf("%s: expected an error, got channel %d", testCase.label, channel)
+		case !testCase.err && err != nil:
+			t.Errorf("%s: unexpected error: %v", testCase.label, err)
+		case !testCase.err && channel != testCase.expected:
+			t.Errorf(
+				"%s: expected channel %d, got %d",
+				testCase.label, testCase.expected, channel,
+			)
+		}
+	}
+}
@@ -2,10 +2,12 @@ package repl
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
 	log "alda.io/client/logging"
+	"alda.io/client/model"
 	"alda.io/client/system"
 	"alda.io/client/transmitter"
 	"alda.io/client/util"
@@ -16,6 +18,10 @@ const playerPoolFillInterval = 10 * time.Second
 const pingTimeout = 5 * time.Second
 const pingInterval = 1 * time.Second
 
+// The player adds this many milliseconds to the offset of events that would
+// otherwise be due too soon.
+const scheduleBuffer = 500
+
 func findAvailablePlayer() (system.PlayerState, error) {
 	var player system.PlayerState
 
@@ -43,7 +49,11 @@ func (server *Server) transmitter() (transmitter.OSCTransmitter, error) {
 			fmt.Errorf("no player process is available")
 	}
 
-	return transmitter.OSCTransmitter{Port: server.player.Port}, nil
+	return transmitter.OSCTransmitter{
+		Host:     server.player.Host,
+		Port:     server.player.Port,
+		Protocol: server.player.Protocol,
+	}, nil
 }
 
 // Player management happens asynchronously (see the loop in `managePlayers`),
@@ -114,6 +124,11 @@ func (server *Server) unsetPlayer() {
 //     the server is responsible for recovering by switching to use another
 //     player process.
 func (server *Server) managePlayers() {
+	if server.configuredPlayer != nil {
+		server.manageConfiguredPlayer()
+		return
+	}
+
 	// When the `alda` process is started, we automatically fill the player pool,
 	// so we can hold off on immediately filling it again here.
 	playerPoolLastFilled := time.Now()
@@ -196,6 +211,54 @@ func (server *Server) managePlayers() {
 	}
 }
 
+// manageConfiguredPlayer takes the place of the `managePlayers` loop when the
+// server is configured to use a specific player process, which might be on
+// another machine.
+//
+// We can't read the state file of such a player process, and we don't want to
+// replace it with a local one, so we ping it at regular intervals instead.
+// While it's unreachable, the server has no player, and requests wait for it
+// (see `withTransmitter`). As soon as a ping goes through again, the server
+// reconnects to it.
+//
+// NB: Over UDP, pings go through whether or not the player process is
+// listening, so we can't tell when it becomes unreachable.
+func (server *Server) manageConfiguredPlayer() {
+	player := *server.configuredPlayer
+	xmitter := transmitter.OSCTransmitter{
+		Host: player.Host, Port: player.Port, Protocol: player.Protocol,
+	}
+
+	for {
+		err := xmitter.TransmitPingMessage()
+
+		switch {
+		case err != nil && server.hasPlayer():
+			log.Warn().
+				Err(err).
+				Interface("player", player).
+				Msg("Player process unreachable. Will keep trying to reconnect.")
+
+			server.unsetPlayer()
+
+		case err != nil:
+			log.Debug().
+				Err(err).
+				Interface("player", player).
+				Msg("Player process still unreachable.")
+
+		case !server.hasPlayer():
+			log.Info().
+				Interface("player", player).
+				Msg("Connected to player process.")
+
+			server.player = player
+		}
+
+		time.Sleep(pingInterval)
+	}
+}
+
 func (server *Server) shutdownPlayer() error {
 	if err := server.withTransmitter(
 		func(transmitter transmitter.OSCTransmitter) error {
@@ -219,3 +282,81 @@ func (server *Server) shutdownPlayer() error {
 
 	return nil
 }
+
+// Returns the number of milliseconds from the start of the earliest of
+// `events` to the end of the last one.
+func eventsLength(events []model.ScoreEvent) float64 {
+	if len(events) == 0 {
+		return 0
+	}
+
+	start, end := math.MaxFloat64, 0.0
+
+	for _, event := range events {
+		offset := event.EventOffset()
+		start = math.Min(start, offset)
+
+		if note, ok := event.(model.NoteEvent); ok {
+			offset += math.Max(note.Duration, note.AudibleDuration)
+		}
+
+		end = math.Max(end, offset)
+	}
+
+	return end - start
+}
+
+// Returns an upper bound on the position in the player's sequence, and on the
+// end of the last event that the server has sent to the player.
+//
+// The sequence can't advance faster than real time, so the bound grows by the
+// amount of time that has passed since we last scheduled events.
+func (server *Server) sequenceBound() float64 {
+	return server.scheduledUntil +
+		float64(time.Since(server.scheduledAt).Milliseconds())
+}
+
+// Records that we've sent the player `length` milliseconds worth of events.
+//
+// The player schedules them no later than the current sequence bound (see
+// above), plus a small buffer if it's already playing.
+func (server *Server) recordScheduled(length float64) {
+	server.scheduledUntil = server.sequenceBound() + scheduleBuffer + length
+	server.scheduledAt = time.Now()
+}
+
+// Stops playback without shutting down the player process, so that the server
+// can start playing a new score right away.
+//
+// The player keeps the events that it has already scheduled, so we also move
+// the player's sequence past the end of them, where there is nothing left to
+// play. Upcoming events in looping patterns are cleared, but the current
+// iteration might still be scheduled, so we skip past that, too.
+func (server *Server) resetPlayback() error {
+	offset := server.sequenceBound()
+	for name := range server.loops {
+		if pattern, ok := server.patterns[name]; ok {
+			offset += eventsLength(pattern.Events) + scheduleBuffer
+		}
+	}
+
+	offset = math.Ceil(offset)
+
+	if err := server.withTransmitter(
+		func(transmitter transmitter.OSCTransmitter) error {
+			log.Info().
+				Interface("player", server.player).
+				Float64("offset", offset).
+				Msg("Resetting the player's playback position.")
+
+			return transmitter.TransmitResetMessage(int32(offset))
+		},
+	); err != nil {
+		return err
+	}
+
+	server.scheduledUntil = offset
+	server.scheduledAt = time.Now()
+
+	return nil
+}
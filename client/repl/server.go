@@ -1,6 +1,7 @@
 package repl
 
 import (
+	"bytes"
 	encjson "encoding/json"
 	"fmt"
 	"io"
@@ -17,17 +18,13 @@ import (
 	bencode "github.com/jackpal/bencode-go"
 
 	"alda.io/client/generated"
-	"alda.io/client/json"
 	log "alda.io/client/logging"
 	"alda.io/client/model"
 	"alda.io/client/parser"
 	"alda.io/client/system"
 	"alda.io/client/transmitter"
-	"alda.io/client/util"
 )
 
-const midiExportTimeout = 20 * time.Second
-
 type nREPLRequest struct {
 	conn net.Conn
 	msg  map[string]interface{}
@@ -42,11 +39,30 @@ type Server struct {
 	// The string of input that is built up over time as clients submit code, line
 	// by line, to be evaluated and added to the score.
 	input string
+	// The score file that the input was loaded from, if any. Paths in `include`
+	// and `import` S-expressions are relative to this file.
+	filename string
 	// The stateful score object that should correspond to the input received so
 	// far.
 	score *model.Score
+	// Named patterns that have been defined in this session, each of which is
+	// a single-part score. (See patterns.go.)
+	patterns map[string]*model.Score
+	// The patterns that are currently looping, keyed by pattern name.
+	loops map[string]patternLoop
+	// The track number to use for the next pattern loop.
+	nextLoopTrack int32
 	// The server's most recent information about the player process it is using.
 	player system.PlayerState
+	// The player process that the server was configured to use (see
+	// WithPlayer), if any. Otherwise, the server finds and manages player
+	// processes on the local machine.
+	configuredPlayer *system.PlayerState
+	// An upper bound on the position in the player's sequence, and on the end of
+	// the last event that the server has sent to the player, as of
+	// `scheduledAt`. (See `resetPlayback`.)
+	scheduledUntil float64
+	scheduledAt    time.Time
 	// A queue onto which bdecoded messages from clients are placed in one
 	// routine. In another routine, the messages are handled synchronously, one at
 	// a time. Therefore, messages can be received asynchronously, but results are
@@ -106,18 +122,36 @@ func (server *Server) respondError(
 }
 
 func (server *Server) resetState() error {
-	if server.hasPlayer() {
+	switch {
+	// A configured player process (see WithPlayer) can't be replaced once it's
+	// shut down, so we stop its playback and keep using it instead.
+	case server.configuredPlayer != nil && server.hasPlayer():
+		if err := server.resetPlayback(); err != nil {
+			return err
+		}
+	case server.hasPlayer():
 		if err := server.shutdownPlayer(); err != nil {
 			return err
 		}
 	}
 
-	server.input = ""
-	server.score = model.NewScore()
+	server.resetScore()
 
 	return nil
 }
 
+// Starts over with an empty score, while keeping the pattern definitions, so
+// that they can be looped again.
+//
+// This should only be done once the player has stopped playing the score and
+// any patterns that were looping.
+func (server *Server) resetScore() {
+	server.input = ""
+	server.score = model.NewScore()
+	server.loops = map[string]patternLoop{}
+	server.nextLoopTrack = firstLoopTrack
+}
+
 // Adapted from: https://www.calhoun.io/creating-random-strings-in-go/
 func generateId() string {
 	const charset = "abcdefghijklmnopqrstuvwxyz"
@@ -131,13 +165,30 @@ func generateId() string {
 	return string(b)
 }
 
+// A ServerOption configures an Alda REPL server.
+type ServerOption func(*Server)
+
+// WithPlayer configures a server to use the provided player process, e.g. one
+// running on another machine, instead of finding a player process on the local
+// machine.
+func WithPlayer(player system.PlayerState) ServerOption {
+	return func(server *Server) {
+		server.configuredPlayer = &player
+	}
+}
+
 // NewServer returns an initialized instance of an Alda REPL server.
-func NewServer(port int) *Server {
+func NewServer(port int, opts ...ServerOption) *Server {
 	server := &Server{
 		id:           generateId(),
 		Port:         port,
+		patterns:     map[string]*model.Score{},
+		scheduledAt:  time.Now(),
 		requestQueue: make(chan nREPLRequest),
 	}
+	for _, opt := range opts {
+		opt(server)
+	}
 	server.resetState()
 	return server
 }
@@ -229,8 +280,8 @@ func (server *Server) Close() {
 // NOTE: The caller is responsible for calling `Close()` on the server instance
 // when it is no longer needed. Otherwise, resources like the .alda-nrepl-port
 // file will not be cleaned up.
-func RunServer(port int) (*Server, error) {
-	server := NewServer(port)
+func RunServer(port int, opts ...ServerOption) (*Server, error) {
+	server := NewServer(port, opts...)
 
 	l, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(server.Port))
 	if err != nil {
@@ -419,6 +470,7 @@ var ops = map[string]func(*Server, nREPLRequest){
 		errors := validateRequest(
 			req.msg,
 			requestFieldSpec{name: "code", valueType: typeString, required: true},
+			requestFieldSpec{name: "filename", valueType: typeString},
 		)
 		if len(errors) > 0 {
 			server.respondErrors(req, errors, nil)
@@ -427,7 +479,29 @@ var ops = map[string]func(*Server, nREPLRequest){
 
 		input := req.msg["code"].(string)
 
-		if err := server.load(input); err != nil {
+		filename, _ := req.msg["filename"].(string)
+
+		if err := server.load(filename, input); err != nil {
+			server.respondError(req, err.Error(), nil)
+			return
+		}
+
+		server.respondDone(req, nil)
+	},
+
+	"loop": func(server *Server, req nREPLRequest) {
+		errors := validateRequest(
+			req.msg,
+			requestFieldSpec{name: "name", valueType: typeString, required: true},
+		)
+		if len(errors) > 0 {
+			server.respondErrors(req, errors, nil)
+			return
+		}
+
+		name := req.msg["name"].(string)
+
+		if err := server.loopPattern(name); err != nil {
 			server.respondError(req, err.Error(), nil)
 			return
 		}
@@ -441,9 +515,43 @@ var ops = map[string]func(*Server, nREPLRequest){
 			return
 		}
 
+		server.filename = ""
+		server.patterns = map[string]*model.Score{}
+
 		server.respondDone(req, nil)
 	},
 
+	"pattern": func(server *Server, req nREPLRequest) {
+		errors := validateRequest(
+			req.msg,
+			requestFieldSpec{name: "name", valueType: typeString, required: true},
+			requestFieldSpec{name: "code", valueType: typeString, required: true},
+		)
+		if len(errors) > 0 {
+			server.respondErrors(req, errors, nil)
+			return
+		}
+
+		name := req.msg["name"].(string)
+		input := req.msg["code"].(string)
+
+		if err := server.definePattern(name, input); err != nil {
+			server.respondError(req, err.Error(), nil)
+			return
+		}
+
+		server.respondDone(req, nil)
+	},
+
+	"patterns": func(server *Server, req nREPLRequest) {
+		patterns, looping := server.patternNames()
+
+		server.respondDone(req, map[string]interface{}{
+			"patterns": patterns,
+			"looping":  looping,
+		})
+	},
+
 	"replay": func(server *Server, req nREPLRequest) {
 		transmitOpts := []transmitter.TransmissionOption{}
 
@@ -478,7 +586,7 @@ var ops = map[string]func(*Server, nREPLRequest){
 	},
 
 	"score-events": func(server *Server, req nREPLRequest) {
-		ast, err := parser.ParseString(server.input)
+		ast, err := parser.Parse(server.filename, server.input)
 		if err != nil {
 			server.respondError(req, err.Error(), nil)
 			return
@@ -490,16 +598,13 @@ var ops = map[string]func(*Server, nREPLRequest){
 			return
 		}
 
-		updates := json.Array()
-		for _, update := range scoreUpdates {
-			updates.ArrayAppend(update.JSON())
-		}
-
-		server.respondDone(req, map[string]interface{}{"events": updates.String()})
+		server.respondDone(req, map[string]interface{}{
+			"events": model.ScoreUpdatesJSON(scoreUpdates).String(),
+		})
 	},
 
 	"score-ast": func(server *Server, req nREPLRequest) {
-		ast, err := parser.ParseString(server.input)
+		ast, err := parser.Parse(server.filename, server.input)
 		if err != nil {
 			server.respondError(req, err.Error(), nil)
 			return
@@ -527,6 +632,26 @@ var ops = map[string]func(*Server, nREPLRequest){
 
 		server.respondDone(req, nil)
 	},
+
+	"stop-loop": func(server *Server, req nREPLRequest) {
+		errors := validateRequest(
+			req.msg,
+			requestFieldSpec{name: "name", valueType: typeString, required: true},
+		)
+		if len(errors) > 0 {
+			server.respondErrors(req, errors, nil)
+			return
+		}
+
+		name := req.msg["name"].(string)
+
+		if err := server.stopLoop(name); err != nil {
+			server.respondError(req, err.Error(), nil)
+			return
+		}
+
+		server.respondDone(req, nil)
+	},
 }
 
 // Runs in a loop, handling requests from the queue as they come in in a
@@ -562,7 +687,12 @@ func (server *Server) updateScoreWithInput(
 ) ([]transmitter.TransmissionOption, error) {
 	eventCountBefore := len(server.score.Events)
 
-	ast, err := parser.ParseString(input)
+	// We parse with error recovery so that if the input (e.g. a score file
+	// loaded via the `load` op) contains several syntax errors, the user sees
+	// all of them at once.
+	ast, err := parser.Parse(
+		server.filename, input, parser.RecoverFromErrors,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -618,6 +748,8 @@ func (server *Server) evalAndPlay(
 ) error {
 	return server.withTransmitter(
 		func(transmitter transmitter.OSCTransmitter) error {
+			eventCountBefore := len(server.score.Events)
+
 			transmitOpts, err := server.updateScoreWithInput(input)
 			if err != nil {
 				return err
@@ -627,19 +759,36 @@ func (server *Server) evalAndPlay(
 				Interface("player", server.player).
 				Msg("Sending OSC messages to player.")
 
-			return transmitter.TransmitScore(
+			if err := transmitter.TransmitScore(
 				server.score,
 				(append(transmitOpts, additionalTransmitOpts...))...,
+			); err != nil {
+				return err
+			}
+
+			server.recordScheduled(
+				eventsLength(server.score.Events[eventCountBefore:]),
 			)
+
+			return nil
 		},
 	)
 }
 
-func (server *Server) load(input string) error {
+func (server *Server) load(filename string, input string) error {
 	if err := server.resetState(); err != nil {
 		return err
 	}
 
+	server.filename = filename
+
+	// A configured player process isn't replaced when we reset the state, so its
+	// sequence picks up where we moved it to, instead of starting over at 0.
+	startOffset := int32(0)
+	if server.configuredPlayer != nil {
+		startOffset = int32(server.scheduledUntil)
+	}
+
 	return server.withTransmitter(
 		func(t transmitter.OSCTransmitter) error {
 			transmitOpts, err := server.updateScoreWithInput(input)
@@ -665,21 +814,24 @@ func (server *Server) load(input string) error {
 					newOffset = offsetRounded
 				}
 			}
+			newOffset += startOffset
 
 			log.Info().
 				Interface("player", server.player).
 				Int32("newOffset", newOffset).
 				Msg("Transmitting new offset to player.")
 
-			return t.TransmitOffsetMessage(newOffset)
+			if err := t.TransmitOffsetMessage(newOffset); err != nil {
+				return err
+			}
+
+			server.recordScheduled(eventsLength(server.score.Events))
+
+			return nil
 		},
 	)
 }
 
-func (server *Server) reload() error {
-	return server.load(server.input)
-}
-
 func (server *Server) replay(
 	transmitOpts ...transmitter.TransmissionOption,
 ) error {
@@ -710,62 +862,16 @@ func (server *Server) replay(
 	return server.evalAndPlay(input, transmitOpts...)
 }
 
-// Reloads the score into a fresh player, sends a "MIDI export" message to the
-// player, waits for the player to write the MIDI file, reads the file, and
-// returns the bytes in the file.
+// Writes the current score as a MIDI file and returns the bytes in the file.
 //
-// Returns an error if something goes wrong somewhere along the way.
+// Returns an error if something goes wrong.
 func (server *Server) export() ([]byte, error) {
-	// Reloading the score is important because of the subtleties of the tempo
-	// messages in the MIDI sequence.
-	//
-	// When we're evaluating Alda code interactively at the REPL, we suppress
-	// tempo messages because they serve no immediate purpose.
-	//
-	// When it comes time to export the score, we reload the input into the MIDI
-	// sequencer, which does include sending tempo messages, so that the MIDI
-	// sequence includes tempo changes in the places where we want them.
-	if err := server.reload(); err != nil {
-		return nil, err
-	}
-
-	tmpdir, err := os.MkdirTemp("", "alda-repl-server")
-	if err != nil {
-		return nil, err
-	}
+	var buf bytes.Buffer
 
-	midiFilename := filepath.Join(
-		tmpdir, fmt.Sprintf(
-			"export-%d-%d.mid",
-			time.Now().Unix(),
-			rand.Intn(10000),
-		),
-	)
-
-	if err := server.withTransmitter(
-		func(transmitter transmitter.OSCTransmitter) error {
-			return transmitter.TransmitMidiExportMessage(midiFilename)
-		},
-	); err != nil {
-		return nil, err
-	}
-
-	var midiFile *os.File
-
-	if err := util.Await(
-		func() error {
-			mf, err := os.Open(midiFilename)
-			if err != nil {
-				return err
-			}
-
-			midiFile = mf
-			return nil
-		},
-		midiExportTimeout,
-	); err != nil {
+	xmitter := transmitter.MidiFileTransmitter{Writer: &buf}
+	if err := xmitter.TransmitScore(server.score); err != nil {
 		return nil, err
 	}
 
-	return io.ReadAll(midiFile)
+	return buf.Bytes(), nil
 }
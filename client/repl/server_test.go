@@ -0,0 +1,159 @@
+package repl
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"alda.io/client/model"
+	"alda.io/client/system"
+	_ "alda.io/client/testing"
+	"github.com/go-test/deep"
+	bencode "github.com/jackpal/bencode-go"
+)
+
+// Handles a request message (e.g. {"op": "eval"}), and returns the response.
+func request(
+	t *testing.T, server *Server, msg map[string]interface{},
+) map[string]interface{} {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	go func() {
+		defer serverConn.Close()
+		ops[msg["op"].(string)](server, nREPLRequest{conn: serverConn, msg: msg})
+	}()
+
+	response, err := bencode.Decode(clientConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return response.(map[string]interface{})
+}
+
+func TestScoreEventsSourceContexts(t *testing.T) {
+	server := NewServer(0)
+	server.input = "foo = c d\npiano: foo*2"
+
+	response := request(t, server, map[string]interface{}{"op": "score-events"})
+
+	events, ok := response["events"].(string)
+	if !ok {
+		t.Fatalf("expected events in the response, got %v", response)
+	}
+
+	var updates []struct {
+		ScoreEvents []struct {
+			SourceContext []model.AldaSourceContext `json:"source-context"`
+		} `json:"score-events"`
+	}
+	if err := json.Unmarshal([]byte(events), &updates); err != nil {
+		t.Fatalf("%v: %s", err, events)
+	}
+
+	if len(updates) != 3 {
+		t.Fatalf("expected 3 updates, got %d: %s", len(updates), events)
+	}
+
+	// Each note in `foo*2` leads from the variable reference to the note in the
+	// variable definition.
+	reference := model.AldaSourceContext{Line: 2, Column: 8}
+	c := model.AldaSourceContext{Line: 1, Column: 7}
+	d := model.AldaSourceContext{Line: 1, Column: 9}
+	expected := [][]model.AldaSourceContext{
+		{reference, c}, {reference, d}, {reference, c}, {reference, d},
+	}
+
+	actual := [][]model.AldaSourceContext{}
+	for _, event := range updates[2].ScoreEvents {
+		actual = append(actual, event.SourceContext)
+	}
+
+	if diff := deep.Equal(expected, actual); diff != nil {
+		t.Errorf("unexpected source contexts: %v", diff)
+	}
+}
+
+func TestEvalAndPlay(t *testing.T) {
+	server, player := serverWithFakePlayer(t)
+
+	response := request(t, server, map[string]interface{}{
+		"op": "eval-and-play", "code": "piano: c d",
+	})
+
+	if problems, ok := response["problems"]; ok {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+
+	if err := player.AwaitPackets(1, awaitTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	stream := player.MessageStream()
+
+	for _, address := range []string{"/track/1/midi/patch", "/system/play"} {
+		if !strings.Contains(stream, address) {
+			t.Errorf("expected a %s message, got:\n%s", address, stream)
+		}
+	}
+
+	for _, note := range []string{
+		"/track/1/midi/note ,iiiiii 0 0 60",
+		"/track/1/midi/note ,iiiiii 0 500 62",
+	} {
+		if !strings.Contains(stream, note) {
+			t.Errorf("expected %q, got:\n%s", note, stream)
+		}
+	}
+
+	if server.input != "piano: c d\n" {
+		t.Errorf("expected the code to be added to the score, got %q", server.input)
+	}
+}
+
+func TestConfiguredPlayerIsNotShutDown(t *testing.T) {
+	_, player := serverWithFakePlayer(t)
+
+	playerState := system.PlayerState{
+		ID: "unknown", State: "unknown", Port: player.Port, Protocol: "tcp",
+	}
+	server := NewServer(0, WithPlayer(playerState))
+	// This would otherwise be done by `manageConfiguredPlayer`, once it reaches
+	// the player process.
+	server.player = playerState
+
+	for _, msg := range []map[string]interface{}{
+		{"op": "eval-and-play", "code": "piano: c d"},
+		{"op": "load", "code": "piano: e f"},
+		{"op": "new-score"},
+	} {
+		response := request(t, server, msg)
+		if problems, ok := response["problems"]; ok {
+			t.Fatalf("%s: unexpected problems: %v", msg["op"], problems)
+		}
+	}
+
+	if err := player.AwaitPackets(4, awaitTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	if !server.hasPlayer() {
+		t.Error("expected the server to keep using the configured player")
+	}
+
+	stream := player.MessageStream()
+
+	if strings.Contains(stream, "/system/shutdown") {
+		t.Errorf("expected the player not to be shut down, got:\n%s", stream)
+	}
+
+	for _, address := range []string{
+		"/system/stop", "/system/clear", "/system/offset",
+	} {
+		if !strings.Contains(stream, address) {
+			t.Errorf("expected a %s message, got:\n%s", address, stream)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package repl
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	log "alda.io/client/logging"
+	"alda.io/client/model"
+	"alda.io/client/parser"
+	"alda.io/client/transmitter"
+)
+
+const watchInterval = 250 * time.Millisecond
+
+// A Watcher plays a score file and replays it each time the file changes.
+//
+// Under the hood, a Watcher uses a REPL server that runs in the same process,
+// without listening for nREPL messages. The server keeps a player process warm
+// (see `managePlayers`), so each replay starts quickly.
+type Watcher struct {
+	// The path to the score file being watched.
+	Filename string
+	// A time marking (e.g. 0:30) or marker from which to replay the score. When
+	// this is omitted, the score is replayed from the point where it was edited.
+	From string
+
+	server *Server
+	// The contents of the score file the last time that it was played.
+	played string
+}
+
+// NewWatcher returns a Watcher for the provided score file.
+func NewWatcher(filename string, from string) *Watcher {
+	server := NewServer(0)
+	// Files included by the score are relative to the score file.
+	server.filename = filename
+
+	return &Watcher{
+		Filename: filename,
+		From:     from,
+		server:   server,
+	}
+}
+
+// Returns the number of the first line that differs between `previous` and
+// `current`. Lines are numbered starting from 1.
+func firstEditedLine(previous string, current string) int {
+	previousLines := strings.Split(previous, "\n")
+	currentLines := strings.Split(current, "\n")
+
+	for i, line := range currentLines {
+		if i >= len(previousLines) || line != previousLines[i] {
+			return i + 1
+		}
+	}
+
+	return len(currentLines)
+}
+
+// Parses and evaluates `input` as the contents of the score file `filename`,
+// and returns the offset (in milliseconds) where the events produced by the
+// given line begin.
+//
+// Only the score updates in `filename` itself are matched against `line`, not
+// the ones that come from files that it includes.
+//
+// If there are no score updates on `line`, the next line that has score
+// updates is used instead. If those updates don't produce any events (e.g. an
+// attribute change), the offset is the earliest current offset of the parts
+// that are being updated at that point.
+//
+// Returns an error if `input` isn't a valid score.
+func lineOffset(filename string, input string, line int) (float64, error) {
+	ast, err := parser.Parse(filename, input, parser.RecoverFromErrors)
+	if err != nil {
+		return 0, err
+	}
+
+	scoreUpdates, err := ast.Updates()
+	if err != nil {
+		return 0, err
+	}
+
+	score := model.NewScore()
+	partsOffset := -1.0
+	eventsOffset := math.MaxFloat64
+
+	for _, update := range scoreUpdates {
+		context := update.GetSourceContext()
+		inFile := context.Filename == filename
+		updateLine := context.Line
+
+		if partsOffset == -1 && inFile && updateLine >= line {
+			line = updateLine
+			partsOffset = math.MaxFloat64
+			for _, part := range score.CurrentParts {
+				partsOffset = math.Min(partsOffset, part.CalculateEffectiveOffset())
+			}
+		}
+
+		eventCountBefore := len(score.Events)
+
+		if err := score.Update(update); err != nil {
+			return 0, err
+		}
+
+		if partsOffset != -1 && inFile && updateLine == line {
+			for _, event := range score.Events[eventCountBefore:] {
+				eventsOffset = math.Min(eventsOffset, event.EventOffset())
+			}
+		}
+	}
+
+	if eventsOffset != math.MaxFloat64 {
+		return eventsOffset, nil
+	}
+
+	if partsOffset == -1 || partsOffset == math.MaxFloat64 {
+		return 0, nil
+	}
+
+	return partsOffset, nil
+}
+
+// Plays the current contents of the score file.
+func (watcher *Watcher) play(input string) error {
+	offset, err := lineOffset(
+		watcher.Filename, input, firstEditedLine(watcher.played, input),
+	)
+	if err != nil {
+		return err
+	}
+
+	transmitOpts := []transmitter.TransmissionOption{}
+
+	if watcher.From != "" {
+		transmitOpts = append(transmitOpts, transmitter.TransmitFrom(watcher.From))
+		fmt.Fprintf(os.Stderr, "Playing from %s...\n", watcher.From)
+	} else {
+		transmitOpts = append(transmitOpts, transmitter.TransmitFromOffset(offset))
+		fmt.Fprintf(
+			os.Stderr, "Playing from %s...\n", formatOffset(offset),
+		)
+	}
+
+	// We've already established that the input is a valid score, so by this
+	// point, we can commit to replacing the score that is currently playing.
+	//
+	// Unlike `server.replay`, we don't shut down the player here. Starting a new
+	// player process on every save would be wasteful, so we keep using the same
+	// one and move its playback position past the previous score.
+	if watcher.server.hasPlayer() {
+		if err := watcher.server.resetPlayback(); err != nil {
+			return err
+		}
+	}
+
+	watcher.server.resetScore()
+	return watcher.server.evalAndPlay(input, transmitOpts...)
+}
+
+// Formats an offset in milliseconds as a minute-and-second time marking, e.g.
+// 1:05.
+func formatOffset(offset float64) string {
+	seconds := int(offset / 1000)
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// Run plays the score file, then watches it and replays it whenever it
+// changes. Run blocks until the process is terminated.
+//
+// If the score has problems (e.g. syntax errors), they are printed, and the
+// watcher waits for the file to be saved again.
+//
+// Returns an error if the score file can't be read initially.
+func (watcher *Watcher) Run() error {
+	// See repl/player_management.go
+	go watcher.server.managePlayers()
+
+	var lastModified time.Time
+
+	for {
+		info, err := os.Stat(watcher.Filename)
+		// Some editors save a file by replacing it, so it might briefly not exist.
+		// We only treat this as an error if we haven't read the file yet.
+		if err != nil && lastModified.IsZero() {
+			return err
+		}
+
+		if err == nil && info.ModTime() != lastModified {
+			lastModified = info.ModTime()
+
+			contents, err := os.ReadFile(watcher.Filename)
+			if err != nil {
+				log.Warn().Err(err).Msg("Failed to read score file.")
+			} else if input := string(contents); input != watcher.played {
+				log.Info().Str("filename", watcher.Filename).Msg("Playing score.")
+
+				if err := watcher.play(input); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+				} else {
+					watcher.played = input
+				}
+			}
+		}
+
+		time.Sleep(watchInterval)
+	}
+}
+
+// Close stops playback by shutting down the player process.
+func (watcher *Watcher) Close() error {
+	if !watcher.server.hasPlayer() {
+		return nil
+	}
+
+	return watcher.server.shutdownPlayer()
+}
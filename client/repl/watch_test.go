@@ -0,0 +1,181 @@
+package repl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"alda.io/client/fakeplayer"
+	"alda.io/client/system"
+	_ "alda.io/client/testing"
+)
+
+const awaitTimeout = 5 * time.Second
+
+// Starts a fake player and returns a server that uses it.
+func serverWithFakePlayer(t *testing.T) (*Server, *fakeplayer.Player) {
+	cacheDir := system.CacheDir
+	system.CacheDir = t.TempDir()
+	t.Cleanup(func() { system.CacheDir = cacheDir })
+
+	player, err := fakeplayer.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { player.Stop() })
+
+	server := NewServer(0)
+	server.player = system.PlayerState{
+		ID: player.ID, Port: player.Port, State: "ready",
+	}
+
+	return server, player
+}
+
+func TestFirstEditedLine(t *testing.T) {
+	for _, testCase := range []struct {
+		label    string
+		previous string
+		current  string
+		expected int
+	}{
+		{"first line", "piano: c d e", "piano: c d f", 1},
+		{"middle line", "piano:\nc d e\nf g a", "piano:\nc d e\nf g b", 3},
+		{"appended line", "piano:\nc d e", "piano:\nc d e\nf g a", 3},
+		{"removed line", "piano:\nc d e\nf g a", "piano:\nc d e", 2},
+		{"no previous score", "", "piano:\nc d e", 1},
+	} {
+		if actual := firstEditedLine(
+			testCase.previous, testCase.current,
+		); actual != testCase.expected {
+			t.Errorf(
+				"%s: expected line %d, got %d",
+				testCase.label, testCase.expected, actual,
+			)
+		}
+	}
+}
+
+func TestLineOffset(t *testing.T) {
+	// At the default tempo (120 BPM), each quarter note lasts 500 ms.
+	input := `piano:
+c d
+e f
+violin: g
+(tempo! 60)
+
+piano: a`
+
+	for _, testCase := range []struct {
+		label    string
+		line     int
+		expected float64
+	}{
+		{"part declaration", 1, 0},
+		{"first line of notes", 2, 0},
+		{"second line of notes", 3, 1000},
+		{"new part", 4, 0},
+		{"attribute change", 5, 500},
+		{"blank line", 6, 2000},
+		{"past the end", 8, 0},
+	} {
+		actual, err := lineOffset("score.alda", input, testCase.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual != testCase.expected {
+			t.Errorf(
+				"%s: expected line %d to start at %f ms, got %f",
+				testCase.label, testCase.line, testCase.expected, actual,
+			)
+		}
+	}
+
+	if _, err := lineOffset("score.alda", "piano: c d (", 1); err == nil {
+		t.Error("expected an error for an invalid score")
+	}
+}
+
+func TestLineOffsetWithIncludes(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "score.alda")
+
+	// Line 2 of the included file starts at 1000 ms, and line 2 of the score
+	// starts at 2500 ms.
+	intro := "piano: c d\ne f g"
+	if err := os.WriteFile(
+		filepath.Join(dir, "intro.alda"), []byte(intro), 0644,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	input := "(include \"intro.alda\")\npiano: a b"
+
+	actual, err := lineOffset(filename, input, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual != 2500 {
+		t.Errorf("expected line 2 to start at 2500 ms, got %f", actual)
+	}
+}
+
+func TestWatcherKeepsThePlayer(t *testing.T) {
+	server, player := serverWithFakePlayer(t)
+	watcher := &Watcher{server: server}
+
+	original := "piano:\nc d\ne f"
+	if err := watcher.play(original); err != nil {
+		t.Fatal(err)
+	}
+	watcher.played = original
+
+	if err := player.AwaitPackets(2, awaitTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := watcher.play("piano:\nc d\ne g"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := player.AwaitPackets(4, awaitTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	if !server.hasPlayer() {
+		t.Error("expected the watcher to keep using the same player")
+	}
+
+	stream := player.MessageStream()
+
+	if strings.Contains(stream, "/system/shutdown") {
+		t.Errorf("expected the player not to be shut down, got:\n%s", stream)
+	}
+
+	for _, address := range []string{
+		"/system/stop", "/system/clear", "/system/offset",
+	} {
+		if !strings.Contains(stream, address) {
+			t.Errorf("expected a %s message, got:\n%s", address, stream)
+		}
+	}
+
+	// We only need to hear the score from the edited line onwards.
+	replayed := stream[strings.LastIndex(stream, "/system/offset"):]
+	for _, note := range []string{
+		"/track/1/midi/note ,iiiiii 0 0 64",
+		"/track/1/midi/note ,iiiiii 0 500 67",
+	} {
+		if !strings.Contains(replayed, note) {
+			t.Errorf("expected the edited line to be replayed, got:\n%s", replayed)
+		}
+	}
+
+	if strings.Contains(replayed, "/track/1/midi/note ,iiiiii 0 0 60") {
+		t.Errorf("expected the unedited lines to be skipped, got:\n%s", replayed)
+	}
+}
@@ -0,0 +1,648 @@
+package synth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// SoundFont generator operators (see section 8.1.2 of the SoundFont 2.04
+// specification) that SoundFont understands. Other generators are ignored.
+const (
+	genStartAddrsOffset           = 0
+	genEndAddrsOffset             = 1
+	genStartloopAddrsOffset       = 2
+	genEndloopAddrsOffset         = 3
+	genStartAddrsCoarseOffset     = 4
+	genEndAddrsCoarseOffset       = 12
+	genPan                        = 17
+	genDelayVolEnv                = 33
+	genAttackVolEnv               = 34
+	genHoldVolEnv                 = 35
+	genDecayVolEnv                = 36
+	genSustainVolEnv              = 37
+	genReleaseVolEnv              = 38
+	genInstrument                 = 41
+	genKeyRange                   = 43
+	genVelRange                   = 44
+	genStartloopAddrsCoarseOffset = 45
+	genInitialAttenuation         = 48
+	genEndloopAddrsCoarseOffset   = 50
+	genCoarseTune                 = 51
+	genFineTune                   = 52
+	genSampleID                   = 53
+	genSampleModes                = 54
+	genScaleTuning                = 56
+	genOverridingRootKey          = 58
+	genCount                      = 61
+)
+
+// generators holds the values of the generators in a zone. A zone only
+// overrides the generators that it sets.
+type generators struct {
+	values [genCount]int16
+	set    [genCount]bool
+}
+
+func (g *generators) put(oper uint16, amount int16) {
+	if int(oper) < genCount {
+		g.values[oper] = amount
+		g.set[oper] = true
+	}
+}
+
+// get returns the value of a generator, or the provided default if the
+// generator isn't set.
+func (g *generators) get(oper int, def int16) int16 {
+	if g.set[oper] {
+		return g.values[oper]
+	}
+	return def
+}
+
+// inRange reports whether a value is within a key or velocity range generator.
+// Ranges are stored as two bytes: the low end, then the high end.
+func (g *generators) inRange(oper int, value int32) bool {
+	if !g.set[oper] {
+		return true
+	}
+	lo := int32(uint16(g.values[oper]) & 0xff)
+	hi := int32(uint16(g.values[oper]) >> 8)
+	return value >= lo && value <= hi
+}
+
+// A zone is a set of generators that apply to a range of keys and velocities.
+type zone struct {
+	generators
+}
+
+// A preset is a SoundFont instrument as it is presented to the user, i.e. what
+// a MIDI program change selects.
+type preset struct {
+	name   string
+	bank   uint16
+	number uint16
+	global *zone
+	zones  []*zone
+}
+
+// An instrument is a collection of zones, each of which plays a sample.
+type instrument struct {
+	name   string
+	global *zone
+	zones  []*zone
+}
+
+// A sampleHeader describes a sample within the SoundFont's sample data.
+type sampleHeader struct {
+	name            string
+	start           uint32
+	end             uint32
+	startLoop       uint32
+	endLoop         uint32
+	sampleRate      uint32
+	originalPitch   uint8
+	pitchCorrection int8
+}
+
+// SoundFont is a Synthesizer that plays the samples in a SoundFont 2 (.sf2)
+// file.
+//
+// Only the parts of the format needed for basic playback are supported:
+// sample playback with looping, key and velocity ranges, tuning, panning,
+// attenuation and the volume envelope. Modulators, filters and the modulation
+// envelope and LFOs are ignored.
+type SoundFont struct {
+	presets     []*preset
+	instruments []*instrument
+	samples     []sampleHeader
+	data        []int16
+}
+
+// A riffChunk is a chunk of a RIFF file.
+type riffChunk struct {
+	id   string
+	data []byte
+}
+
+// readChunks splits RIFF data into chunks.
+func readChunks(data []byte) ([]riffChunk, error) {
+	chunks := []riffChunk{}
+
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("truncated RIFF chunk header")
+		}
+
+		id := string(data[0:4])
+		size := binary.LittleEndian.Uint32(data[4:8])
+		data = data[8:]
+
+		if uint32(len(data)) < size {
+			return nil, fmt.Errorf("truncated RIFF chunk: %s", id)
+		}
+
+		chunks = append(chunks, riffChunk{id: id, data: data[:size]})
+
+		// Chunks are padded to an even number of bytes.
+		if size%2 == 1 && uint32(len(data)) > size {
+			size++
+		}
+		data = data[size:]
+	}
+
+	return chunks, nil
+}
+
+// readList returns the chunks within the LIST chunk with the provided type.
+func readList(chunks []riffChunk, listType string) ([]riffChunk, error) {
+	for _, chunk := range chunks {
+		if chunk.id == "LIST" && len(chunk.data) >= 4 &&
+			string(chunk.data[0:4]) == listType {
+			return readChunks(chunk.data[4:])
+		}
+	}
+
+	return nil, fmt.Errorf("missing LIST chunk: %s", listType)
+}
+
+// findChunk returns the data of the chunk with the provided ID.
+func findChunk(chunks []riffChunk, id string) ([]byte, error) {
+	for _, chunk := range chunks {
+		if chunk.id == id {
+			return chunk.data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("missing chunk: %s", id)
+}
+
+// records splits a chunk into fixed-size records. SoundFont record lists end
+// with a terminal record, which is not included.
+func records(data []byte, size int, id string) ([][]byte, error) {
+	if len(data)%size != 0 || len(data) < size {
+		return nil, fmt.Errorf("malformed %s chunk", id)
+	}
+
+	result := [][]byte{}
+	for i := 0; i+size < len(data); i += size {
+		result = append(result, data[i:i+size])
+	}
+	return result, nil
+}
+
+// name decodes a fixed-length, NUL-padded name.
+func name(data []byte) string {
+	return strings.TrimRight(string(bytes.SplitN(data, []byte{0}, 2)[0]), " ")
+}
+
+// readZones reads the zones referenced by a list of bag indexes. bags[i] is
+// the index of the first bag of the i-th preset or instrument; the last entry
+// (from the terminal record) marks the end of the final one's bags.
+//
+// Returns the global zone (or nil) and the other zones for each preset or
+// instrument. A zone is global if it is the first zone and does not end with
+// the terminal generator (instrument or sampleID).
+func readZones(
+	bagIndexes []uint16, bagData []byte, genData []byte, terminal uint16,
+) ([]*zone, [][]*zone, error) {
+	bags, err := records(bagData, 4, "bag")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gens, err := records(genData, 4, "gen")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The terminal bag record is needed to find the generators for the last
+	// bag, so we read the generator index straight from bagData.
+	genIndex := func(bag int) (int, error) {
+		if bag*4+2 > len(bagData) {
+			return 0, fmt.Errorf("bag index out of range: %d", bag)
+		}
+		return int(binary.LittleEndian.Uint16(bagData[bag*4:])), nil
+	}
+
+	globals := make([]*zone, len(bagIndexes)-1)
+	zones := make([][]*zone, len(bagIndexes)-1)
+
+	for i := 0; i+1 < len(bagIndexes); i++ {
+		first, last := int(bagIndexes[i]), int(bagIndexes[i+1])
+		if first > last || last > len(bags) {
+			return nil, nil, fmt.Errorf("bag indexes out of range")
+		}
+
+		for bag := first; bag < last; bag++ {
+			start, err := genIndex(bag)
+			if err != nil {
+				return nil, nil, err
+			}
+			end, err := genIndex(bag + 1)
+			if err != nil {
+				return nil, nil, err
+			}
+			if start > end || end > len(gens) {
+				return nil, nil, fmt.Errorf("generator indexes out of range")
+			}
+
+			z := &zone{}
+			hasTerminal := false
+
+			for _, gen := range gens[start:end] {
+				oper := binary.LittleEndian.Uint16(gen[0:2])
+				amount := int16(binary.LittleEndian.Uint16(gen[2:4]))
+				z.put(oper, amount)
+				if oper == terminal {
+					hasTerminal = true
+				}
+			}
+
+			switch {
+			case hasTerminal:
+				zones[i] = append(zones[i], z)
+			case bag == first:
+				globals[i] = z
+			}
+		}
+	}
+
+	return globals, zones, nil
+}
+
+// LoadSoundFont reads a SoundFont 2 (.sf2) file.
+func LoadSoundFont(r io.Reader) (*SoundFont, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 12 || string(data[0:4]) != "RIFF" ||
+		string(data[8:12]) != "sfbk" {
+		return nil, fmt.Errorf("not a SoundFont 2 file")
+	}
+
+	size := binary.LittleEndian.Uint32(data[4:8])
+	if uint32(len(data)-8) < size {
+		return nil, fmt.Errorf("truncated SoundFont file")
+	}
+
+	chunks, err := readChunks(data[12 : 8+size])
+	if err != nil {
+		return nil, err
+	}
+
+	sdta, err := readList(chunks, "sdta")
+	if err != nil {
+		return nil, err
+	}
+
+	smpl, err := findChunk(sdta, "smpl")
+	if err != nil {
+		return nil, err
+	}
+
+	pdta, err := readList(chunks, "pdta")
+	if err != nil {
+		return nil, err
+	}
+
+	pdtaChunks := map[string][]byte{}
+	for _, id := range []string{
+		"phdr", "pbag", "pgen", "inst", "ibag", "igen", "shdr",
+	} {
+		chunk, err := findChunk(pdta, id)
+		if err != nil {
+			return nil, err
+		}
+		pdtaChunks[id] = chunk
+	}
+
+	sf := &SoundFont{data: make([]int16, len(smpl)/2)}
+	for i := range sf.data {
+		sf.data[i] = int16(binary.LittleEndian.Uint16(smpl[i*2:]))
+	}
+
+	shdrs, err := records(pdtaChunks["shdr"], 46, "shdr")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range shdrs {
+		sf.samples = append(sf.samples, sampleHeader{
+			name:            name(record[0:20]),
+			start:           binary.LittleEndian.Uint32(record[20:24]),
+			end:             binary.LittleEndian.Uint32(record[24:28]),
+			startLoop:       binary.LittleEndian.Uint32(record[28:32]),
+			endLoop:         binary.LittleEndian.Uint32(record[32:36]),
+			sampleRate:      binary.LittleEndian.Uint32(record[36:40]),
+			originalPitch:   record[40],
+			pitchCorrection: int8(record[41]),
+		})
+	}
+
+	// The terminal records are included here, because the bag index of each
+	// terminal record marks the end of the last preset's/instrument's bags.
+	phdrs := pdtaChunks["phdr"]
+	if len(phdrs)%38 != 0 || len(phdrs) < 38 {
+		return nil, fmt.Errorf("malformed phdr chunk")
+	}
+	presetBags := []uint16{}
+	for i := 0; i < len(phdrs); i += 38 {
+		presetBags = append(presetBags, binary.LittleEndian.Uint16(phdrs[i+24:]))
+	}
+
+	insts := pdtaChunks["inst"]
+	if len(insts)%22 != 0 || len(insts) < 22 {
+		return nil, fmt.Errorf("malformed inst chunk")
+	}
+	instrumentBags := []uint16{}
+	for i := 0; i < len(insts); i += 22 {
+		instrumentBags = append(
+			instrumentBags, binary.LittleEndian.Uint16(insts[i+20:]),
+		)
+	}
+
+	instGlobals, instZones, err := readZones(
+		instrumentBags, pdtaChunks["ibag"], pdtaChunks["igen"], genSampleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range instZones {
+		sf.instruments = append(sf.instruments, &instrument{
+			name:   name(insts[i*22 : i*22+20]),
+			global: instGlobals[i],
+			zones:  instZones[i],
+		})
+
+		for _, z := range instZones[i] {
+			if int(z.values[genSampleID]) >= len(sf.samples) ||
+				z.values[genSampleID] < 0 {
+				return nil, fmt.Errorf(
+					"instrument %q refers to a missing sample", sf.instruments[i].name,
+				)
+			}
+		}
+	}
+
+	presetGlobals, presetZones, err := readZones(
+		presetBags, pdtaChunks["pbag"], pdtaChunks["pgen"], genInstrument,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range presetZones {
+		record := phdrs[i*38 : i*38+38]
+		p := &preset{
+			name:   name(record[0:20]),
+			number: binary.LittleEndian.Uint16(record[20:22]),
+			bank:   binary.LittleEndian.Uint16(record[22:24]),
+			global: presetGlobals[i],
+			zones:  presetZones[i],
+		}
+
+		for _, z := range p.zones {
+			if int(z.values[genInstrument]) >= len(sf.instruments) ||
+				z.values[genInstrument] < 0 {
+				return nil, fmt.Errorf(
+					"preset %q refers to a missing instrument", p.name,
+				)
+			}
+		}
+
+		sf.presets = append(sf.presets, p)
+	}
+
+	if len(sf.presets) == 0 {
+		return nil, fmt.Errorf("the SoundFont has no presets")
+	}
+
+	return sf, nil
+}
+
+// percussionBank is the bank that, by convention, holds percussion kits.
+const percussionBank = 128
+
+// findPreset returns the preset for a General MIDI program, falling back to the
+// first preset in the same bank (or the first preset overall) when there isn't
+// an exact match.
+func (sf *SoundFont) findPreset(program int32, percussion bool) *preset {
+	bank := uint16(0)
+	if percussion {
+		bank = percussionBank
+		program = 0
+	}
+
+	var fallback *preset
+	for _, p := range sf.presets {
+		if p.bank == bank && int32(p.number) == program {
+			return p
+		}
+		if fallback == nil && p.bank == bank {
+			fallback = p
+		}
+	}
+
+	if fallback != nil {
+		return fallback
+	}
+
+	return sf.presets[0]
+}
+
+// A region is the combination of a preset zone and an instrument zone that
+// together determine how a sample is played for a note.
+type region struct {
+	sample sampleHeader
+	// The instrument zone's generators, with the instrument's global zone
+	// applied.
+	instrument generators
+	// The preset zone's generators, with the preset's global zone applied.
+	// These are added to the instrument's values.
+	preset generators
+}
+
+// merge returns the generators in a zone, with the defaults from a global zone
+// filled in.
+func merge(global *zone, local *zone) generators {
+	g := local.generators
+	if global != nil {
+		for oper := 0; oper < genCount; oper++ {
+			if !g.set[oper] && global.set[oper] {
+				g.values[oper] = global.values[oper]
+				g.set[oper] = true
+			}
+		}
+	}
+	return g
+}
+
+// regions returns the regions that play for a note.
+func (sf *SoundFont) regions(note Note) []region {
+	velocity := int32(math.Round(note.Velocity * 127))
+	p := sf.findPreset(note.Program, note.Percussion)
+
+	result := []region{}
+
+	for _, pz := range p.zones {
+		presetGens := merge(p.global, pz)
+		if !presetGens.inRange(genKeyRange, note.Key) ||
+			!presetGens.inRange(genVelRange, velocity) {
+			continue
+		}
+
+		inst := sf.instruments[presetGens.values[genInstrument]]
+
+		for _, iz := range inst.zones {
+			instGens := merge(inst.global, iz)
+			if !instGens.inRange(genKeyRange, note.Key) ||
+				!instGens.inRange(genVelRange, velocity) {
+				continue
+			}
+
+			result = append(result, region{
+				sample:     sf.samples[instGens.values[genSampleID]],
+				instrument: instGens,
+				preset:     presetGens,
+			})
+		}
+	}
+
+	return result
+}
+
+// value returns the effective value of an additive generator in a region.
+func (r region) value(oper int, def int16) float64 {
+	return float64(r.instrument.get(oper, def)) + float64(r.preset.get(oper, 0))
+}
+
+// timecents converts a SoundFont time in timecents into seconds.
+func timecents(tc float64) float64 {
+	if tc <= -12000 {
+		return 0
+	}
+	return math.Pow(2, tc/1200)
+}
+
+// envelope returns the volume envelope for a region.
+func (r region) envelope() envelope {
+	return envelope{
+		delay:   timecents(r.value(genDelayVolEnv, -12000)),
+		attack:  timecents(r.value(genAttackVolEnv, -12000)),
+		hold:    timecents(r.value(genHoldVolEnv, -12000)),
+		decay:   timecents(r.value(genDecayVolEnv, -12000)),
+		sustain: math.Max(0, math.Min(silence, r.value(genSustainVolEnv, 0)/10)),
+		// A release of 0 would cause an audible click, so we make sure there is
+		// at least a very short release.
+		release: math.Max(0.005, timecents(r.value(genReleaseVolEnv, -12000))),
+	}
+}
+
+// RenderNote implements Synthesizer.RenderNote.
+func (sf *SoundFont) RenderNote(buf *Buffer, note Note) {
+	for _, r := range sf.regions(note) {
+		sf.renderRegion(buf, note, r)
+	}
+}
+
+func (sf *SoundFont) renderRegion(buf *Buffer, note Note, r region) {
+	offset := func(fine int, coarse int) int64 {
+		return int64(r.value(fine, 0)) + int64(r.value(coarse, 0))*32768
+	}
+
+	start := int64(r.sample.start) +
+		offset(genStartAddrsOffset, genStartAddrsCoarseOffset)
+	end := int64(r.sample.end) +
+		offset(genEndAddrsOffset, genEndAddrsCoarseOffset)
+	loopStart := int64(r.sample.startLoop) +
+		offset(genStartloopAddrsOffset, genStartloopAddrsCoarseOffset)
+	loopEnd := int64(r.sample.endLoop) +
+		offset(genEndloopAddrsOffset, genEndloopAddrsCoarseOffset)
+
+	start = clampInt64(start, 0, int64(len(sf.data)))
+	end = clampInt64(end, start, int64(len(sf.data)))
+	if end-start < 2 {
+		return
+	}
+
+	// Sample modes: 0 = no loop, 1 = loop continuously, 3 = loop while the note
+	// is held, then play the rest of the sample.
+	mode := r.instrument.get(genSampleModes, 0) & 3
+	looping := (mode == 1 || mode == 3) &&
+		loopStart >= start && loopEnd <= end && loopEnd-loopStart >= 2
+
+	rootKey := int32(r.instrument.get(genOverridingRootKey, -1))
+	if rootKey < 0 || rootKey > 127 {
+		rootKey = int32(r.sample.originalPitch)
+	}
+
+	cents := (float64(note.Key-rootKey)+note.Cents/100)*
+		r.value(genScaleTuning, 100) +
+		r.value(genCoarseTune, 0)*100 +
+		r.value(genFineTune, 0) +
+		float64(r.sample.pitchCorrection)
+
+	step := math.Pow(2, cents/1200) *
+		float64(r.sample.sampleRate) / float64(buf.SampleRate)
+
+	env := r.envelope()
+	length := env.length(note.Duration)
+	if !looping {
+		length = math.Min(length, float64(end-start)/step/float64(buf.SampleRate))
+	}
+
+	signal := make([]float64, int(math.Ceil(length*float64(buf.SampleRate))))
+	position := float64(start)
+
+	for i := range signal {
+		t := float64(i) / float64(buf.SampleRate)
+		inLoop := looping && (mode == 1 || t < note.Duration)
+
+		index := int64(position)
+		next := index + 1
+		if inLoop && next >= loopEnd {
+			next = loopStart
+		}
+		if index >= end || next >= end {
+			signal = signal[:i]
+			break
+		}
+
+		frac := position - float64(index)
+		a, b := float64(sf.data[index]), float64(sf.data[next])
+
+		signal[i] = (a + (b-a)*frac) / 32768 * env.level(t, note.Duration)
+
+		position += step
+		if inLoop && position >= float64(loopEnd) {
+			position -= float64(loopEnd - loopStart)
+		}
+	}
+
+	// Attenuation is in centibels.
+	attenuation := math.Max(0, r.value(genInitialAttenuation, 0)/10)
+	gain := math.Pow(10, -attenuation/20) * noteGain(note) / maxGain * 0.5
+
+	pan := note.Panning + r.value(genPan, 0)/1000
+	left, right := panGains(pan)
+
+	frame := int(math.Round(note.Start * float64(buf.SampleRate)))
+	buf.mix(frame, signal, gain*left, gain*right)
+}
+
+func clampInt64(n, lo, hi int64) int64 {
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+	return n
+}
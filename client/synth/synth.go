@@ -0,0 +1,208 @@
+// Package synth renders notes into audio without relying on a player process
+// or any audio hardware.
+//
+// Rendering is fully deterministic: the same notes always produce exactly the
+// same samples, which makes it possible to compare rendered audio against
+// known-good ("golden") renders in tests.
+package synth
+
+import (
+	"math"
+)
+
+// DefaultSampleRate is the sample rate (in Hz) used when none is specified.
+const DefaultSampleRate = 44100
+
+// A Note is a single note to be rendered.
+type Note struct {
+	// The MIDI note number (0-127).
+	Key int32
+	// The number of cents by which the note is raised (or lowered, if
+	// negative), for pitches between MIDI note numbers.
+	Cents float64
+	// The velocity of the note, from 0 to 1.
+	Velocity float64
+	// The overall volume of the track that the note belongs to, from 0 to 1.
+	TrackVolume float64
+	// The stereo position of the note, from 0 (hard left) to 1 (hard right).
+	Panning float64
+	// The time (in seconds) at which the note starts.
+	Start float64
+	// The length of time (in seconds) for which the note is held. Depending on
+	// the instrument, the note may continue to sound for a short time after
+	// that.
+	Duration float64
+	// The General MIDI program number (0-127) of the instrument.
+	Program int32
+	// When true, the note is played on a percussion instrument and Key
+	// determines which percussion sound is played (see the General MIDI
+	// percussion key map).
+	Percussion bool
+}
+
+// frequency returns the frequency (in Hz) of a MIDI note number, offset by a
+// number of cents.
+func frequency(key int32, cents float64) float64 {
+	return 440 * math.Pow(2, (float64(key-69)*100+cents)/1200)
+}
+
+// panGains returns the left and right gain for a stereo position from 0 (hard
+// left) to 1 (hard right), using an equal-power pan law.
+func panGains(panning float64) (float64, float64) {
+	panning = math.Max(0, math.Min(1, panning))
+	return math.Cos(panning * math.Pi / 2), math.Sin(panning * math.Pi / 2)
+}
+
+// A Buffer holds stereo audio as floating point samples, nominally in the range
+// -1 to 1.
+type Buffer struct {
+	SampleRate int
+	Left       []float64
+	Right      []float64
+}
+
+// NewBuffer returns an empty Buffer with the provided sample rate.
+func NewBuffer(sampleRate int) *Buffer {
+	return &Buffer{SampleRate: sampleRate}
+}
+
+// Len returns the number of (stereo) frames in the buffer.
+func (b *Buffer) Len() int {
+	return len(b.Left)
+}
+
+// Duration returns the length of the buffer in seconds.
+func (b *Buffer) Duration() float64 {
+	return float64(b.Len()) / float64(b.SampleRate)
+}
+
+// grow ensures that the buffer holds at least n frames.
+func (b *Buffer) grow(n int) {
+	if n <= len(b.Left) {
+		return
+	}
+
+	b.Left = append(b.Left, make([]float64, n-len(b.Left))...)
+	b.Right = append(b.Right, make([]float64, n-len(b.Right))...)
+}
+
+// mix adds a mono signal to the buffer, starting at the provided frame, with
+// separate gains for the left and right channels.
+func (b *Buffer) mix(start int, signal []float64, left, right float64) {
+	if start < 0 {
+		if -start >= len(signal) {
+			return
+		}
+		signal = signal[-start:]
+		start = 0
+	}
+
+	b.grow(start + len(signal))
+
+	for i, sample := range signal {
+		b.Left[start+i] += sample * left
+		b.Right[start+i] += sample * right
+	}
+}
+
+// A Synthesizer renders individual notes into a Buffer.
+type Synthesizer interface {
+	// RenderNote mixes a note into the buffer, growing the buffer as needed.
+	RenderNote(buf *Buffer, note Note)
+}
+
+// Render renders notes into a new Buffer with the provided sample rate.
+//
+// The notes are mixed in the order provided. Because floating point addition
+// is not associative, the same notes in a different order may produce very
+// slightly different samples, so callers who want reproducible output should
+// provide the notes in a consistent order.
+func Render(synth Synthesizer, sampleRate int, notes ...Note) *Buffer {
+	buf := NewBuffer(sampleRate)
+
+	for _, note := range notes {
+		if note.Velocity <= 0 || note.Duration <= 0 {
+			continue
+		}
+
+		synth.RenderNote(buf, note)
+	}
+
+	return buf
+}
+
+// An envelope describes how the volume of a note changes over time.
+//
+// The semantics follow the SoundFont 2 volume envelope: after an optional
+// delay, the volume rises linearly to full volume over the attack time, stays
+// there for the hold time, then falls (linearly in decibels) towards the
+// sustain level. The decay time is the time that it would take to fall by
+// 100 dB. When the note is released, the volume falls from its current level
+// at the same rate, such that a fall of 100 dB takes the release time.
+type envelope struct {
+	delay   float64 // seconds
+	attack  float64 // seconds
+	hold    float64 // seconds
+	decay   float64 // seconds per 100 dB
+	sustain float64 // attenuation in dB
+	release float64 // seconds per 100 dB
+}
+
+// silence is the attenuation (in dB) beyond which a note is inaudible.
+const silence = 100.0
+
+// attenuation returns the attenuation (in dB) of the envelope at time t
+// (seconds since the start of the note), while the note is held.
+func (e envelope) attenuation(t float64) float64 {
+	t -= e.delay + e.attack + e.hold
+	if t <= 0 {
+		return 0
+	}
+
+	if e.decay <= 0 {
+		return e.sustain
+	}
+
+	return math.Min(e.sustain, silence*t/e.decay)
+}
+
+// level returns the gain (0 to 1) of the envelope at time t (seconds since the
+// start of the note) for a note released at time off.
+func (e envelope) level(t float64, off float64) float64 {
+	held := math.Min(t, off)
+
+	gain := 1.0
+	switch {
+	case held < e.delay:
+		gain = 0
+	case held < e.delay+e.attack:
+		gain = (held - e.delay) / e.attack
+	}
+
+	db := e.attenuation(held)
+
+	if t > off {
+		if e.release <= 0 {
+			return 0
+		}
+		db += silence * (t - off) / e.release
+	}
+
+	if db >= silence {
+		return 0
+	}
+
+	return gain * math.Pow(10, -db/20)
+}
+
+// length returns the number of seconds for which a note held for the provided
+// duration is audible.
+func (e envelope) length(duration float64) float64 {
+	if e.sustain >= silence && e.decay > 0 {
+		duration = math.Min(
+			duration, e.delay+e.attack+e.hold+e.decay*e.sustain/silence,
+		)
+	}
+
+	return duration + math.Max(0, e.release*(silence-e.attenuation(duration))/silence)
+}
@@ -0,0 +1,457 @@
+package synth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+var testNotes = []Note{
+	{Key: 60, Velocity: 0.8, TrackVolume: 0.8, Panning: 0.5, Start: 0,
+		Duration: 0.5, Program: 0},
+	{Key: 64, Velocity: 0.6, TrackVolume: 0.8, Panning: 0.2, Start: 0.5,
+		Duration: 0.5, Program: 40},
+	{Key: 36, Velocity: 1, TrackVolume: 0.8, Panning: 0.5, Start: 0.25,
+		Duration: 0.1, Percussion: true},
+	{Key: 42, Velocity: 0.7, TrackVolume: 0.8, Panning: 0.7, Start: 0.75,
+		Duration: 0.1, Percussion: true},
+}
+
+func wav(t *testing.T, buf *Buffer) []byte {
+	var b bytes.Buffer
+	if err := buf.WriteWAV(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestWAVHeader(t *testing.T) {
+	buf := Render(WavetableSynth{}, 22050, testNotes...)
+	b := wav(t, buf)
+
+	le := binary.LittleEndian
+
+	for _, check := range []struct {
+		label    string
+		actual   interface{}
+		expected interface{}
+	}{
+		{"RIFF", string(b[0:4]), "RIFF"},
+		{"RIFF size", le.Uint32(b[4:8]), uint32(len(b) - 8)},
+		{"WAVE", string(b[8:12]), "WAVE"},
+		{"fmt", string(b[12:16]), "fmt "},
+		{"format", le.Uint16(b[20:22]), uint16(1)},
+		{"channels", le.Uint16(b[22:24]), uint16(2)},
+		{"sample rate", le.Uint32(b[24:28]), uint32(22050)},
+		{"byte rate", le.Uint32(b[28:32]), uint32(22050 * 4)},
+		{"bits per sample", le.Uint16(b[34:36]), uint16(16)},
+		{"data", string(b[36:40]), "data"},
+		{"data size", le.Uint32(b[40:44]), uint32(buf.Len() * 4)},
+	} {
+		if check.actual != check.expected {
+			t.Errorf(
+				"%s: expected %v, got %v", check.label, check.expected, check.actual,
+			)
+		}
+	}
+}
+
+func TestDeterminism(t *testing.T) {
+	a := wav(t, Render(WavetableSynth{}, DefaultSampleRate, testNotes...))
+	b := wav(t, Render(WavetableSynth{}, DefaultSampleRate, testNotes...))
+
+	if !bytes.Equal(a, b) {
+		t.Error("rendering the same notes twice produced different output")
+	}
+}
+
+func TestNoteTiming(t *testing.T) {
+	note := Note{Key: 69, Velocity: 1, TrackVolume: 1, Panning: 0.5,
+		Start: 0.5, Duration: 0.25, Program: 80}
+	buf := Render(WavetableSynth{}, 1000*10, note)
+
+	for i := 0; i < 5000; i++ {
+		if buf.Left[i] != 0 || buf.Right[i] != 0 {
+			t.Fatalf("expected silence before the note starts, got sound at %d", i)
+		}
+	}
+
+	// The synth lead's release is 0.1 seconds per 100 dB.
+	expected := 0.5 + 0.25 + 0.1
+	if math.Abs(buf.Duration()-expected) > 0.001 {
+		t.Errorf("expected duration %f, got %f", expected, buf.Duration())
+	}
+}
+
+func energy(samples []float64) float64 {
+	sum := 0.0
+	for _, sample := range samples {
+		sum += sample * sample
+	}
+	return sum
+}
+
+func TestPanning(t *testing.T) {
+	for _, testCase := range []struct {
+		panning    float64
+		leftLouder bool
+	}{
+		{0, true},
+		{0.25, true},
+		{0.75, false},
+		{1, false},
+	} {
+		note := testNotes[0]
+		note.Panning = testCase.panning
+		buf := Render(WavetableSynth{}, DefaultSampleRate, note)
+
+		left, right := energy(buf.Left), energy(buf.Right)
+		if (left > right) != testCase.leftLouder {
+			t.Errorf(
+				"panning %f: left energy %f, right energy %f",
+				testCase.panning, left, right,
+			)
+		}
+	}
+
+	note := testNotes[0]
+	buf := Render(WavetableSynth{}, DefaultSampleRate, note)
+	if math.Abs(energy(buf.Left)-energy(buf.Right)) > 1e-9 {
+		t.Error("expected a centered note to be equally loud on both sides")
+	}
+}
+
+func TestVolume(t *testing.T) {
+	quiet, loud := testNotes[0], testNotes[0]
+	quiet.Velocity = 0.3
+	quieter := quiet
+	quieter.TrackVolume = 0.4
+
+	energies := []float64{}
+	for _, note := range []Note{quieter, quiet, loud} {
+		energies = append(
+			energies,
+			energy(Render(WavetableSynth{}, DefaultSampleRate, note).Left),
+		)
+	}
+
+	if !(energies[0] < energies[1] && energies[1] < energies[2]) {
+		t.Errorf("expected increasing energies, got %v", energies)
+	}
+}
+
+func TestClippingIsAvoided(t *testing.T) {
+	notes := []Note{}
+	for key := int32(40); key < 80; key++ {
+		notes = append(notes, Note{Key: key, Velocity: 1, TrackVolume: 1,
+			Panning: 0, Duration: 0.2, Program: 80})
+	}
+
+	buf := Render(WavetableSynth{}, DefaultSampleRate, notes...)
+	if buf.peak() <= 1 {
+		t.Fatal("expected the test notes to exceed full scale")
+	}
+
+	b := wav(t, buf)
+	maxSample := 0
+	for i := 44; i+1 < len(b); i += 2 {
+		sample := int(int16(binary.LittleEndian.Uint16(b[i:])))
+		if sample < 0 {
+			sample = -sample
+		}
+		if sample > maxSample {
+			maxSample = sample
+		}
+	}
+
+	if maxSample != 32767 {
+		t.Errorf("expected the output to be normalized, peak was %d", maxSample)
+	}
+}
+
+// soundFontBuilder builds a minimal SoundFont 2 file for testing.
+type soundFontBuilder struct {
+	samples []int16
+	shdr    bytes.Buffer
+	inst    bytes.Buffer
+	ibag    bytes.Buffer
+	igen    bytes.Buffer
+	phdr    bytes.Buffer
+	pbag    bytes.Buffer
+	pgen    bytes.Buffer
+	bags    uint16
+	gens    uint16
+	pbags   uint16
+	pgens   uint16
+}
+
+func fixedName(name string) []byte {
+	b := make([]byte, 20)
+	copy(b, name)
+	return b
+}
+
+func put(buf *bytes.Buffer, values ...interface{}) {
+	for _, value := range values {
+		if err := binary.Write(buf, binary.LittleEndian, value); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// addSample adds one second of a sine wave with the provided root key.
+func (sfb *soundFontBuilder) addSample(name string, rootKey uint8) {
+	const rate = 8000
+	start := uint32(len(sfb.samples))
+	for i := 0; i < rate; i++ {
+		sfb.samples = append(sfb.samples, int16(16000*math.Sin(
+			2*math.Pi*frequency(int32(rootKey), 0)*float64(i)/rate,
+		)))
+	}
+	end := uint32(len(sfb.samples))
+	// SoundFont samples are followed by at least 46 zero samples.
+	sfb.samples = append(sfb.samples, make([]int16, 46)...)
+
+	sfb.shdr.Write(fixedName(name))
+	put(&sfb.shdr, start, end, start+rate/2, end, uint32(rate), rootKey,
+		int8(0), uint16(0), uint16(1))
+}
+
+// addInstrument adds an instrument with a zone for each sample. Each zone is a
+// list of generators (operator, amount) ending with the sample ID.
+func (sfb *soundFontBuilder) addInstrument(name string, zones ...[][2]int16) {
+	sfb.inst.Write(fixedName(name))
+	put(&sfb.inst, sfb.bags)
+
+	for _, zone := range zones {
+		put(&sfb.ibag, sfb.gens, uint16(0))
+		sfb.bags++
+		for _, gen := range zone {
+			put(&sfb.igen, uint16(gen[0]), gen[1])
+			sfb.gens++
+		}
+	}
+}
+
+// addPreset adds a preset with a single zone that plays an instrument.
+func (sfb *soundFontBuilder) addPreset(
+	name string, bank uint16, number uint16, instrument int16,
+) {
+	sfb.phdr.Write(fixedName(name))
+	put(&sfb.phdr, number, bank, sfb.pbags, uint32(0), uint32(0), uint32(0))
+
+	put(&sfb.pbag, sfb.pgens, uint16(0))
+	sfb.pbags++
+	put(&sfb.pgen, uint16(genInstrument), instrument)
+	sfb.pgens++
+}
+
+func chunk(id string, data []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(id)
+	put(&b, uint32(len(data)))
+	b.Write(data)
+	if len(data)%2 == 1 {
+		b.WriteByte(0)
+	}
+	return b.Bytes()
+}
+
+func list(listType string, chunks ...[]byte) []byte {
+	data := []byte(listType)
+	for _, c := range chunks {
+		data = append(data, c...)
+	}
+	return chunk("LIST", data)
+}
+
+func (sfb *soundFontBuilder) bytes() []byte {
+	// Terminal records
+	shdr, inst, ibag, igen := sfb.shdr, sfb.inst, sfb.ibag, sfb.igen
+	phdr, pbag, pgen := sfb.phdr, sfb.pbag, sfb.pgen
+
+	shdr.Write(make([]byte, 46))
+	inst.Write(fixedName("EOI"))
+	put(&inst, sfb.bags)
+	put(&ibag, sfb.gens, uint16(0))
+	put(&igen, uint16(0), int16(0))
+	phdr.Write(fixedName("EOP"))
+	put(&phdr, uint16(0), uint16(0), sfb.pbags, uint32(0), uint32(0), uint32(0))
+	put(&pbag, sfb.pgens, uint16(0))
+	put(&pgen, uint16(0), int16(0))
+
+	var smpl bytes.Buffer
+	put(&smpl, sfb.samples)
+
+	body := []byte("sfbk")
+	body = append(body, list("INFO", chunk("ifil", []byte{2, 0, 1, 0}))...)
+	body = append(body, list("sdta", chunk("smpl", smpl.Bytes()))...)
+	body = append(body, list(
+		"pdta",
+		chunk("phdr", phdr.Bytes()),
+		chunk("pbag", pbag.Bytes()),
+		chunk("pmod", make([]byte, 10)),
+		chunk("pgen", pgen.Bytes()),
+		chunk("inst", inst.Bytes()),
+		chunk("ibag", ibag.Bytes()),
+		chunk("imod", make([]byte, 10)),
+		chunk("igen", igen.Bytes()),
+		chunk("shdr", shdr.Bytes()),
+	)...)
+
+	return chunk("RIFF", body)
+}
+
+func testSoundFont(t *testing.T) *SoundFont {
+	sfb := &soundFontBuilder{}
+	sfb.addSample("low", 48)
+	sfb.addSample("high", 72)
+
+	sfb.addInstrument(
+		"Keys",
+		[][2]int16{
+			{genKeyRange, 0 | 59<<8},
+			{genSampleModes, 1},
+			{genReleaseVolEnv, -1200},
+			{genSampleID, 0},
+		},
+		[][2]int16{
+			{genKeyRange, 60 | 127<<8},
+			{genReleaseVolEnv, -1200},
+			{genSampleID, 1},
+		},
+	)
+	sfb.addInstrument(
+		"Hard Left",
+		[][2]int16{{genPan, -500}, {genSampleID, 0}},
+	)
+
+	sfb.addPreset("Piano", 0, 0, 0)
+	sfb.addPreset("Left", 0, 5, 1)
+
+	sf, err := LoadSoundFont(bytes.NewReader(sfb.bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sf
+}
+
+func TestLoadSoundFont(t *testing.T) {
+	sf := testSoundFont(t)
+
+	if len(sf.presets) != 2 {
+		t.Fatalf("expected 2 presets, got %d", len(sf.presets))
+	}
+
+	if len(sf.instruments) != 2 {
+		t.Fatalf("expected 2 instruments, got %d", len(sf.instruments))
+	}
+
+	if len(sf.samples) != 2 || sf.samples[1].name != "high" {
+		t.Fatalf("unexpected samples: %#v", sf.samples)
+	}
+
+	if p := sf.presets[1]; p.name != "Left" || p.number != 5 || p.bank != 0 {
+		t.Errorf("unexpected preset: %#v", p)
+	}
+
+	for _, testCase := range []struct {
+		key    int32
+		sample string
+	}{
+		{40, "low"},
+		{59, "low"},
+		{60, "high"},
+		{100, "high"},
+	} {
+		regions := sf.regions(Note{Key: testCase.key, Velocity: 1})
+		if len(regions) != 1 || regions[0].sample.name != testCase.sample {
+			t.Errorf(
+				"key %d: expected the %s sample, got %#v",
+				testCase.key, testCase.sample, regions,
+			)
+		}
+	}
+
+	// Unknown programs fall back to the first preset in the bank.
+	if p := sf.findPreset(42, false); p.name != "Piano" {
+		t.Errorf("expected fallback to Piano, got %s", p.name)
+	}
+}
+
+func TestLoadSoundFontErrors(t *testing.T) {
+	for _, input := range [][]byte{
+		[]byte(""),
+		[]byte("RIFF\x04\x00\x00\x00WAVE"),
+		chunk("RIFF", []byte("sfbk")),
+	} {
+		if _, err := LoadSoundFont(bytes.NewReader(input)); err == nil {
+			t.Errorf("expected an error loading %q", input)
+		}
+	}
+}
+
+// zeroCrossings estimates the frequency of a signal by counting the number of
+// times it crosses zero.
+func zeroCrossings(samples []float64) int {
+	crossings := 0
+	for i := 1; i < len(samples); i++ {
+		if (samples[i-1] < 0) != (samples[i] < 0) {
+			crossings++
+		}
+	}
+	return crossings
+}
+
+func TestSoundFontRendering(t *testing.T) {
+	sf := testSoundFont(t)
+
+	// The "low" sample is looped, so it can be held longer than the sample.
+	held := Note{Key: 48, Velocity: 1, TrackVolume: 1, Panning: 0.5,
+		Duration: 1.5}
+	buf := Render(sf, 8000, held)
+	if buf.Duration() < 1.5 {
+		t.Errorf("expected a looped note to last 1.5s, got %f", buf.Duration())
+	}
+
+	// Playing the sample an octave above its root key doubles the frequency.
+	root := Render(sf, 8000, Note{Key: 72, Velocity: 1, TrackVolume: 1,
+		Panning: 0.5, Duration: 0.25})
+	octave := Render(sf, 8000, Note{Key: 84, Velocity: 1, TrackVolume: 1,
+		Panning: 0.5, Duration: 0.25})
+
+	rootCrossings := zeroCrossings(root.Left[:2000])
+	octaveCrossings := zeroCrossings(octave.Left[:2000])
+	if math.Abs(float64(octaveCrossings)/float64(rootCrossings)-2) > 0.05 {
+		t.Errorf(
+			"expected twice as many zero crossings an octave up, got %d and %d",
+			rootCrossings, octaveCrossings,
+		)
+	}
+
+	// Raising a note by 1200 cents is the same as playing it an octave up.
+	cents := Render(sf, 8000, Note{Key: 72, Cents: 1200, Velocity: 1,
+		TrackVolume: 1, Panning: 0.5, Duration: 0.25})
+	if centsCrossings := zeroCrossings(cents.Left[:2000]); centsCrossings !=
+		octaveCrossings {
+		t.Errorf(
+			"expected %d zero crossings 1200 cents up, got %d",
+			octaveCrossings, centsCrossings,
+		)
+	}
+
+	// The "Left" preset's instrument is panned hard left.
+	left := Render(sf, 8000, Note{Key: 60, Velocity: 1, TrackVolume: 1,
+		Panning: 0.5, Duration: 0.25, Program: 5})
+	if energy(left.Right) > energy(left.Left)*1e-6 {
+		t.Errorf("expected the note to be panned hard left")
+	}
+
+	a := wav(t, Render(sf, DefaultSampleRate, testNotes...))
+	b := wav(t, Render(sf, DefaultSampleRate, testNotes...))
+	if !bytes.Equal(a, b) {
+		t.Error("rendering the same notes twice produced different output")
+	}
+}
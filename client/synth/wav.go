@@ -0,0 +1,73 @@
+package synth
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// peak returns the largest absolute sample value in the buffer.
+func (b *Buffer) peak() float64 {
+	peak := 0.0
+	for i := range b.Left {
+		peak = math.Max(peak, math.Abs(b.Left[i]))
+		peak = math.Max(peak, math.Abs(b.Right[i]))
+	}
+	return peak
+}
+
+// WriteWAV writes the buffer to w as a 16-bit stereo PCM WAV file.
+//
+// If any samples would clip, the whole buffer is scaled down so that the
+// loudest sample is at full scale.
+func (b *Buffer) WriteWAV(w io.Writer) error {
+	const channels = 2
+	const bytesPerSample = 2
+
+	gain := 1.0
+	if peak := b.peak(); peak > 1 {
+		gain = 1 / peak
+	}
+
+	dataSize := uint32(b.Len() * channels * bytesPerSample)
+
+	header := []interface{}{
+		[4]byte{'R', 'I', 'F', 'F'},
+		uint32(36 + dataSize),
+		[4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '},
+		uint32(16),
+		uint16(1), // PCM
+		uint16(channels),
+		uint32(b.SampleRate),
+		uint32(b.SampleRate * channels * bytesPerSample),
+		uint16(channels * bytesPerSample),
+		uint16(bytesPerSample * 8),
+		[4]byte{'d', 'a', 't', 'a'},
+		dataSize,
+	}
+
+	for _, field := range header {
+		if err := binary.Write(w, binary.LittleEndian, field); err != nil {
+			return err
+		}
+	}
+
+	data := make([]byte, dataSize)
+	for i := range b.Left {
+		binary.LittleEndian.PutUint16(
+			data[i*4:], uint16(pcm16(b.Left[i]*gain)),
+		)
+		binary.LittleEndian.PutUint16(
+			data[i*4+2:], uint16(pcm16(b.Right[i]*gain)),
+		)
+	}
+
+	_, err := w.Write(data)
+	return err
+}
+
+// pcm16 converts a sample in the range -1 to 1 into a 16-bit signed integer.
+func pcm16(sample float64) int16 {
+	return int16(math.Max(-32768, math.Min(32767, math.Round(sample*32767))))
+}
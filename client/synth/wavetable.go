@@ -0,0 +1,292 @@
+package synth
+
+import (
+	"math"
+)
+
+// tableSize is the number of samples in a single cycle of a wavetable.
+const tableSize = 2048
+
+// A timbre is a simple approximation of the sound of a family of General MIDI
+// instruments: a single-cycle waveform built from harmonic partials, shaped by
+// a volume envelope.
+type timbre struct {
+	partials []float64
+	envelope envelope
+	table    []float64
+}
+
+// sawtooth returns the amplitudes of the first n partials of a sawtooth wave.
+func sawtooth(n int) []float64 {
+	partials := make([]float64, n)
+	for i := range partials {
+		partials[i] = 1 / float64(i+1)
+	}
+	return partials
+}
+
+// square returns the amplitudes of the first n partials of a square wave.
+func square(n int) []float64 {
+	partials := make([]float64, n)
+	for i := 0; i < n; i += 2 {
+		partials[i] = 1 / float64(i+1)
+	}
+	return partials
+}
+
+// gmFamilies contains a timbre for each of the 16 families of 8 instruments in
+// the General MIDI instrument list, in order.
+var gmFamilies = [16]*timbre{
+	// Piano
+	{partials: []float64{1, 0.5, 0.3, 0.2, 0.1, 0.05},
+		envelope: envelope{attack: 0.002, decay: 6, sustain: silence, release: 0.25}},
+	// Chromatic percussion
+	{partials: []float64{1, 0, 0.2, 0, 0.05},
+		envelope: envelope{attack: 0.001, decay: 2.5, sustain: silence, release: 0.3}},
+	// Organ
+	{partials: []float64{1, 0.6, 0.4, 0, 0.3, 0, 0, 0.2},
+		envelope: envelope{attack: 0.01, release: 0.05}},
+	// Guitar
+	{partials: []float64{1, 0.6, 0.35, 0.2, 0.15, 0.1},
+		envelope: envelope{attack: 0.002, decay: 3, sustain: silence, release: 0.15}},
+	// Bass
+	{partials: []float64{1, 0.5, 0.25, 0.12},
+		envelope: envelope{attack: 0.004, decay: 4, sustain: silence, release: 0.1}},
+	// Strings
+	{partials: sawtooth(10),
+		envelope: envelope{attack: 0.08, decay: 3, sustain: 3, release: 0.3}},
+	// Ensemble
+	{partials: sawtooth(8),
+		envelope: envelope{attack: 0.1, decay: 3, sustain: 3, release: 0.4}},
+	// Brass
+	{partials: sawtooth(8),
+		envelope: envelope{attack: 0.04, decay: 2, sustain: 2, release: 0.15}},
+	// Reed
+	{partials: []float64{1, 0, 0.5, 0, 0.3, 0, 0.2},
+		envelope: envelope{attack: 0.03, decay: 2, sustain: 1, release: 0.1}},
+	// Pipe
+	{partials: []float64{1, 0.1, 0.05},
+		envelope: envelope{attack: 0.05, release: 0.15}},
+	// Synth lead
+	{partials: square(15),
+		envelope: envelope{attack: 0.005, release: 0.1}},
+	// Synth pad
+	{partials: []float64{1, 0.5, 0.33, 0.25},
+		envelope: envelope{attack: 0.4, release: 0.8}},
+	// Synth effects
+	{partials: []float64{1, 0.3, 0.5, 0.1, 0.2},
+		envelope: envelope{attack: 0.2, release: 0.6}},
+	// Ethnic
+	{partials: []float64{1, 0.7, 0.3, 0.25, 0.1},
+		envelope: envelope{attack: 0.002, decay: 3, sustain: silence, release: 0.15}},
+	// Percussive
+	{partials: []float64{1, 0, 0.3, 0.1},
+		envelope: envelope{attack: 0.001, decay: 1.5, sustain: silence, release: 0.2}},
+	// Sound effects
+	{partials: []float64{1, 0.5, 0.5, 0.5},
+		envelope: envelope{attack: 0.05, release: 0.3}},
+}
+
+func init() {
+	for _, timbre := range gmFamilies {
+		timbre.table = wavetable(timbre.partials)
+	}
+}
+
+// wavetable returns a single cycle of the waveform made up of the provided
+// harmonic partials, normalized so that its peak is 1.
+func wavetable(partials []float64) []float64 {
+	table := make([]float64, tableSize)
+	peak := 0.0
+
+	for i := range table {
+		phase := 2 * math.Pi * float64(i) / tableSize
+		for n, amplitude := range partials {
+			table[i] += amplitude * math.Sin(float64(n+1)*phase)
+		}
+		peak = math.Max(peak, math.Abs(table[i]))
+	}
+
+	for i := range table {
+		table[i] /= peak
+	}
+
+	return table
+}
+
+// WavetableSynth is a small built-in synthesizer that approximates each family
+// of General MIDI instruments with a simple waveform, and synthesizes the
+// General MIDI percussion sounds from noise and swept sine waves.
+//
+// It sounds nothing like a real instrument, but it requires no external files,
+// which makes it a reasonable default when no SoundFont is available.
+type WavetableSynth struct{}
+
+// maxGain is the gain applied to a note at full velocity and track volume. It
+// leaves some headroom for several notes to sound at the same time.
+const maxGain = 0.25
+
+// noteGain returns the gain for a note, combining its velocity and track volume
+// (each of which are squared, approximating the MIDI volume curves).
+func noteGain(note Note) float64 {
+	return maxGain * note.Velocity * note.Velocity *
+		note.TrackVolume * note.TrackVolume
+}
+
+// RenderNote implements Synthesizer.RenderNote.
+func (WavetableSynth) RenderNote(buf *Buffer, note Note) {
+	var signal []float64
+	if note.Percussion {
+		signal = drum(note, buf.SampleRate)
+	} else {
+		signal = tone(note, buf.SampleRate)
+	}
+
+	gain := noteGain(note)
+	left, right := panGains(note.Panning)
+	start := int(math.Round(note.Start * float64(buf.SampleRate)))
+
+	buf.mix(start, signal, gain*left, gain*right)
+}
+
+// tone renders a pitched note using the timbre for the note's instrument.
+func tone(note Note, sampleRate int) []float64 {
+	timbre := gmFamilies[(note.Program&127)/8]
+	env := timbre.envelope
+
+	signal := make(
+		[]float64, int(math.Ceil(env.length(note.Duration)*float64(sampleRate))),
+	)
+
+	step := frequency(note.Key, note.Cents) * tableSize / float64(sampleRate)
+	phase := 0.0
+
+	for i := range signal {
+		index := int(phase)
+		frac := phase - float64(index)
+		a, b := timbre.table[index], timbre.table[(index+1)%tableSize]
+
+		t := float64(i) / float64(sampleRate)
+		signal[i] = (a + (b-a)*frac) * env.level(t, note.Duration)
+
+		phase = math.Mod(phase+step, tableSize)
+	}
+
+	return signal
+}
+
+// noise is a deterministic source of white noise (a xorshift generator).
+type noise uint32
+
+// next returns the next noise sample, in the range -1 to 1.
+func (n *noise) next() float64 {
+	x := uint32(*n)
+	x ^= x << 13
+	x ^= x >> 17
+	x ^= x << 5
+	*n = noise(x)
+	return float64(x)/math.MaxUint32*2 - 1
+}
+
+// A drumSound describes a percussion sound as a mixture of a sine wave, which
+// sweeps from a starting frequency to an ending frequency, and noise.
+type drumSound struct {
+	startFrequency float64 // Hz
+	endFrequency   float64 // Hz
+	sweep          float64 // seconds
+	tone           float64 // amplitude of the sine wave
+	noise          float64 // amplitude of the noise
+	// When true, the noise is high-pass filtered, for a brighter sound.
+	bright bool
+	// The time (in seconds) that the sound takes to fall by 60 dB.
+	decay float64
+}
+
+var (
+	kick   = drumSound{150, 45, 0.08, 1, 0.05, false, 0.4}
+	snare  = drumSound{220, 180, 0.02, 0.5, 0.8, false, 0.25}
+	hihat  = drumSound{0, 0, 0, 0, 0.6, true, 0.08}
+	openHH = drumSound{0, 0, 0, 0, 0.6, true, 0.5}
+	cymbal = drumSound{0, 0, 0, 0, 0.7, true, 1.5}
+	clap   = drumSound{0, 0, 0, 0, 0.8, false, 0.15}
+	block  = drumSound{900, 800, 0.01, 1, 0.1, false, 0.08}
+	shaker = drumSound{0, 0, 0, 0, 0.4, true, 0.12}
+)
+
+// drumSoundFor returns the percussion sound for a key in the General MIDI
+// percussion key map.
+func drumSoundFor(key int32) drumSound {
+	switch key {
+	case 35, 36:
+		return kick
+	case 37, 38, 40:
+		return snare
+	case 39:
+		return clap
+	case 42, 44:
+		return hihat
+	case 46:
+		return openHH
+	case 49, 51, 52, 53, 55, 57, 59:
+		return cymbal
+	case 41, 43, 45, 47, 48, 50:
+		// Toms, tuned from low (41) to high (50).
+		f := 80 * math.Pow(2, float64(key-41)/9)
+		return drumSound{f * 1.5, f, 0.1, 1, 0.1, false, 0.5}
+	case 60, 61, 62, 63, 64, 65, 66, 78, 79:
+		// Bongos, congas, timbales and cuicas
+		f := 180 * math.Pow(2, float64(key-60)/12)
+		return drumSound{f * 1.2, f, 0.03, 1, 0.15, false, 0.25}
+	case 54, 69, 70, 82:
+		return shaker
+	case 76, 77, 75, 56, 67, 68:
+		return block
+	default:
+		return drumSound{400, 300, 0.02, 0.5, 0.5, false, 0.2}
+	}
+}
+
+// drum renders a percussion note. Percussion sounds always play out in full,
+// regardless of how long the note is held.
+func drum(note Note, sampleRate int) []float64 {
+	sound := drumSoundFor(note.Key)
+
+	// The noise generator is seeded by the key, so that the same note always
+	// sounds exactly the same.
+	n := noise(2463534242 + uint32(note.Key))
+
+	// 60 dB of decay is a gain of 1/1000.
+	length := sound.decay * silence / 60
+	signal := make([]float64, int(math.Ceil(length*float64(sampleRate))))
+
+	phase := 0.0
+	previous := 0.0
+
+	for i := range signal {
+		t := float64(i) / float64(sampleRate)
+
+		f := sound.endFrequency
+		if t < sound.sweep {
+			f = sound.startFrequency +
+				(sound.endFrequency-sound.startFrequency)*t/sound.sweep
+		}
+		phase += 2 * math.Pi * f / float64(sampleRate)
+
+		white := n.next()
+		noiseSample := white
+		if sound.bright {
+			noiseSample = (white - previous) / 2
+		}
+		previous = white
+
+		gain := math.Pow(10, -3*t/sound.decay)
+		if t < 0.001 {
+			gain *= t / 0.001
+		}
+
+		signal[i] = gain *
+			(sound.tone*math.Sin(phase) + sound.noise*noiseSample)
+	}
+
+	return signal
+}
@@ -122,12 +122,24 @@ func CleanUpStaleStateFiles() error {
 
 // PlayerState describes the current state of a player process. These states are
 // continuously written to files by each player process. (See: StateManager.kt.)
+//
+// A player process can also be specified explicitly by its host and port,
+// e.g. one running on another machine. We don't have state files for such
+// player processes, so we only know how to reach them.
 type PlayerState struct {
 	State  string `json:"state"`
 	Port   int    `json:"port"`
 	Expiry int64  `json:"expiry"`
 	PID    int    `json:"pid"`
 	ID     string
+	// The host where the player process is listening. This is empty for player
+	// processes on the local machine.
+	Host string `json:"host,omitempty"`
+	// The network protocol ("tcp" or "udp") over which the player process
+	// receives OSC messages, e.g. `alda-player run --protocol udp`. This is
+	// empty for older player processes, which only receive OSC messages over
+	// TCP.
+	Protocol string `json:"protocol,omitempty"`
 }
 
 // REPLServerState describes the current state of an Alda REPL server process.
@@ -389,6 +401,13 @@ func FindAvailablePlayer() (PlayerState, error) {
 			continue
 		}
 
+		// We can only ping a player process over TCP. A player process that
+		// receives OSC messages over UDP was started by hand, so it's only used
+		// when it's specified explicitly, e.g. `alda play --protocol udp`.
+		if player.Protocol == "udp" {
+			continue
+		}
+
 		if _, err := PingPlayer(player.Port); err != nil {
 			log.Warn().
 				Interface("player", player).
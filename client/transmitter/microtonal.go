@@ -0,0 +1,260 @@
+package transmitter
+
+import (
+	"math"
+	"sort"
+
+	log "alda.io/client/logging"
+	"alda.io/client/model"
+)
+
+// The range (in semitones, up or down) of a full pitch bend. This is the
+// default range of the player and most synthesizers.
+const pitchBendRange = 2.0
+
+// microtonalKey returns the MIDI note number closest to the pitch of a note,
+// and the pitch bend (from -8192 to 8191) that makes up the difference.
+//
+// For notes in 12-tone equal temperament, the pitch bend is 0.
+func microtonalKey(event model.NoteEvent) (int32, int32) {
+	key := math.Round(event.Pitch)
+
+	bend := int32(math.Round((event.Pitch - key) / pitchBendRange * 8192))
+	if bend > 8191 {
+		bend = 8191
+	}
+
+	return int32(key), bend
+}
+
+// In MIDI, pitch bend applies to every note on a channel. To play notes that
+// are bent by different amounts at the same time, we do what MIDI Polyphonic
+// Expression (MPE) does and play each bent note on a channel of its own.
+//
+// mpeChannels keeps track of the channels that aren't used by the score, which
+// are available for bent notes, and the pitch bend that we've applied to each
+// channel.
+//
+// A bent note should still be affected by its part's control changes (e.g. the
+// sustain pedal) and aftertouch, so each available channel follows the part
+// channel of the last note that was played on it, and mirrors its controls.
+type mpeChannels struct {
+	available []int32
+	// The offset at which each available channel is no longer playing a note.
+	busyUntil map[int32]float64
+	// The pitch bend that we've applied to each channel. A channel isn't
+	// recorded if we haven't bent it, or the score bent it via a pitch bend
+	// event.
+	bend map[int32]int32
+	// The part channel that each available channel is following.
+	following map[int32]int32
+	// The latest value of each controller (see channelControl) on each channel.
+	controls map[int32]map[int32]int32
+}
+
+// Aftertouch isn't a control change, but we keep track of it the same way, as
+// if it were a controller with this number.
+const aftertouchController = -1
+
+// A MIDI controller that resets the other controllers of a channel, e.g. the
+// sustain pedal and modulation, but not its volume or panning. (See MIDI
+// RP-015.)
+const resetAllControllers = 121
+
+// A channelControl is the value of a MIDI controller on a channel, or the
+// channel's aftertouch.
+type channelControl struct {
+	channel    int32
+	controller int32
+	value      int32
+}
+
+func (cc channelControl) isAftertouch() bool {
+	return cc.controller == aftertouchController
+}
+
+func newMPEChannels(events []model.ScoreEvent) *mpeChannels {
+	used := map[int32]bool{9: true}
+
+	for _, event := range events {
+		switch event := event.(type) {
+		case model.NoteEvent:
+			used[event.MidiChannel] = true
+		case model.ControlChangeEvent:
+			used[event.MidiChannel] = true
+		case model.PitchBendEvent:
+			used[event.MidiChannel] = true
+		case model.AftertouchEvent:
+			used[event.MidiChannel] = true
+		}
+	}
+
+	mpe := &mpeChannels{
+		busyUntil: map[int32]float64{},
+		bend:      map[int32]int32{},
+		following: map[int32]int32{},
+		controls:  map[int32]map[int32]int32{},
+	}
+
+	for channel := int32(0); channel < 16; channel++ {
+		if !used[channel] {
+			mpe.available = append(mpe.available, channel)
+		}
+	}
+
+	return mpe
+}
+
+// channel returns the channel on which to play a note with the provided pitch
+// bend.
+//
+// A bent note is played on an available channel that isn't playing another
+// note, preferring one that is already bent by the same amount, and otherwise
+// the channel that has been idle the longest. When all of the available
+// channels are busy, the note is played on its part's channel, where its pitch
+// bend also applies to the other notes that are playing, so we log a warning.
+func (mpe *mpeChannels) channel(event model.NoteEvent, bend int32) int32 {
+	if bend == 0 {
+		return event.MidiChannel
+	}
+
+	channel := event.MidiChannel
+	found := false
+
+	for _, candidate := range mpe.available {
+		if mpe.busyUntil[candidate] > event.Offset {
+			continue
+		}
+
+		if currentBend, recorded := mpe.bend[candidate]; recorded &&
+			currentBend == bend {
+			channel, found = candidate, true
+			break
+		}
+
+		if !found || mpe.busyUntil[candidate] < mpe.busyUntil[channel] {
+			channel, found = candidate, true
+		}
+	}
+
+	if !found {
+		log.Warn().
+			Int32("channel", channel).
+			Float64("offset", event.Offset).
+			Msg("No MIDI channel is available for a microtonal note. Playing it " +
+				"on its part's channel, which bends the other notes on that channel.")
+
+		return channel
+	}
+
+	mpe.busyUntil[channel] = event.Offset + event.AudibleDuration
+
+	return channel
+}
+
+// follow makes an available channel follow a part channel, and returns the
+// control changes needed for its controls to match those of the part channel.
+//
+// Returns nil if the channel is the part channel itself, or if it is already
+// following the part channel, in which case its controls already match.
+func (mpe *mpeChannels) follow(
+	channel int32, partChannel int32,
+) []channelControl {
+	if channel == partChannel {
+		return nil
+	}
+
+	if following, ok := mpe.following[channel]; ok && following == partChannel {
+		return nil
+	}
+
+	mpe.following[channel] = partChannel
+
+	changes := []channelControl{}
+
+	// The channel might still have the controls of the part that it was
+	// following before, e.g. a sustain pedal that is down, so we start over.
+	if len(mpe.controls[channel]) > 0 {
+		changes = append(changes, channelControl{
+			channel: channel, controller: resetAllControllers,
+		})
+
+		delete(mpe.controls, channel)
+		// Resetting all controllers also resets the pitch bend.
+		mpe.forgetBend(channel)
+	}
+
+	controllers := []int32{}
+	for controller := range mpe.controls[partChannel] {
+		controllers = append(controllers, controller)
+	}
+	sort.Slice(controllers, func(i, j int) bool {
+		return controllers[i] < controllers[j]
+	})
+
+	for _, controller := range controllers {
+		value := mpe.controls[partChannel][controller]
+		mpe.record(channel, controller, value)
+		changes = append(changes, channelControl{
+			channel: channel, controller: controller, value: value,
+		})
+	}
+
+	return changes
+}
+
+// controlChange records the value of a controller (or the aftertouch) on a
+// part channel, and returns the same change for each available channel that
+// is following the part channel.
+func (mpe *mpeChannels) controlChange(
+	partChannel int32, controller int32, value int32,
+) []channelControl {
+	mpe.record(partChannel, controller, value)
+
+	changes := []channelControl{}
+
+	for _, channel := range mpe.available {
+		if following, ok := mpe.following[channel]; ok && following == partChannel {
+			mpe.record(channel, controller, value)
+			changes = append(changes, channelControl{
+				channel: channel, controller: controller, value: value,
+			})
+		}
+	}
+
+	return changes
+}
+
+func (mpe *mpeChannels) record(channel int32, controller int32, value int32) {
+	if _, ok := mpe.controls[channel]; !ok {
+		mpe.controls[channel] = map[int32]int32{}
+	}
+
+	mpe.controls[channel][controller] = value
+}
+
+// setBend records the pitch bend of a channel before a note is played on it,
+// and returns true if a pitch bend message is needed.
+//
+// When a note isn't bent, we only reset the pitch bend of its channel if we
+// bent the channel ourselves, so that we don't undo pitch bend events in the
+// score.
+func (mpe *mpeChannels) setBend(channel int32, bend int32) bool {
+	currentBend, recorded := mpe.bend[channel]
+
+	if bend == 0 && !recorded {
+		return false
+	}
+
+	if recorded && currentBend == bend {
+		return false
+	}
+
+	mpe.bend[channel] = bend
+	return true
+}
+
+// forgetBend is called when the score bends a channel via a pitch bend event.
+func (mpe *mpeChannels) forgetBend(channel int32) {
+	delete(mpe.bend, channel)
+}
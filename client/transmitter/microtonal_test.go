@@ -0,0 +1,70 @@
+package transmitter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	_ "alda.io/client/testing"
+)
+
+func TestBorrowedChannelsMirrorControls(t *testing.T) {
+	score := parseScore(t, `piano: (sustain-pedal true) c d+50& (sustain-pedal false) e+50&
+violin: (modulation 40) g+25&`)
+
+	var dump bytes.Buffer
+	if err := (OSCDumpTransmitter{Writer: &dump, Format: OSCDumpText}).TransmitScore(score); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := dump.String()
+
+	// The piano plays on channel 0 and the violin on channel 1. The microtonal
+	// notes are played on channels 2 and 3, which follow the controls of their
+	// parts' channels.
+	for _, expected := range []string{
+		// The violin's modulation, when its note starts on channel 2.
+		"/track/2/midi/control-change ,iiii 2 0 1 51",
+		// The piano's sustain pedal, when its note starts on channel 3.
+		"/track/1/midi/control-change ,iiii 3 500 64 127",
+		// Releasing the piano's sustain pedal releases it on channel 3, too.
+		"/track/1/midi/control-change ,iiii 0 1000 64 0",
+		"/track/1/midi/control-change ,iiii 3 1000 64 0",
+	} {
+		if !strings.Contains(messages, expected) {
+			t.Errorf("expected %q, got:\n%s", expected, messages)
+		}
+	}
+}
+
+func TestBorrowedChannelsStartOver(t *testing.T) {
+	// The piano, the violin and the 12 other parts use up all of the channels
+	// except for channel 9 (which is for percussion) and channel 15, so the
+	// violin's note is played on the same channel as the piano's note before it.
+	parts := []string{"piano: (sustain-pedal true) c+50&", "violin: r2 g+25&"}
+	for _, instrument := range []string{
+		"flute", "oboe", "clarinet", "bassoon", "trumpet", "trombone", "tuba",
+		"viola", "cello", "harp", "marimba", "guitar",
+	} {
+		parts = append(parts, instrument+": c")
+	}
+
+	score := parseScore(t, strings.Join(parts, "\n"))
+
+	var dump bytes.Buffer
+	if err := (OSCDumpTransmitter{Writer: &dump, Format: OSCDumpText}).TransmitScore(score); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := dump.String()
+
+	note := strings.Index(messages, "/midi/note ,iiiiii 15 1000 67")
+	reset := strings.Index(messages, "/midi/control-change ,iiii 15 1000 121 0")
+
+	if note == -1 || reset == -1 || reset > note {
+		t.Errorf(
+			"expected channel 15 to be reset before the violin's note, got:\n%s",
+			messages,
+		)
+	}
+}
@@ -0,0 +1,357 @@
+package transmitter
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	log "alda.io/client/logging"
+	"alda.io/client/model"
+	"gitlab.com/gomidi/midi"
+	"gitlab.com/gomidi/midi/midimessage/channel"
+	"gitlab.com/gomidi/midi/midimessage/meta"
+	"gitlab.com/gomidi/midi/smf"
+	"gitlab.com/gomidi/midi/smf/smfwriter"
+)
+
+// MidiFileTicksPerQuarterNote is the resolution of the MIDI files written by
+// MidiFileTransmitter.
+const MidiFileTicksPerQuarterNote = 960
+
+// MidiFileTransmitter writes a score as a Standard MIDI File (format 1) to a
+// Writer.
+//
+// Unlike OSCTransmitter, no player process is involved. This makes it possible
+// to export MIDI files in environments where the player (and the JVM that it
+// requires) isn't available.
+//
+// The first track of the MIDI file is a "conductor" track that contains the
+// tempo changes in the score. Each part in the score is written to its own
+// track after that, in the same order as the parts' track numbers (see
+// *Score.Tracks).
+type MidiFileTransmitter struct {
+	Writer io.Writer
+}
+
+// tempoMap is a chronologically ordered list of tempo changes that we use to
+// convert offsets in milliseconds into MIDI ticks.
+type tempoMap []tempoChange
+
+// ticks converts an offset in milliseconds into a number of MIDI ticks since
+// the beginning of the MIDI sequence.
+func (tm tempoMap) ticks(offset float64) uint32 {
+	ticks := 0.0
+	segmentStart := 0.0
+	tempo := 120.0
+
+	for _, change := range tm {
+		if change.offset >= offset {
+			break
+		}
+
+		ticks += msToTicks(change.offset-segmentStart, tempo)
+		segmentStart = change.offset
+		tempo = change.tempo
+	}
+
+	ticks += msToTicks(offset-segmentStart, tempo)
+
+	return uint32(math.Round(ticks))
+}
+
+func msToTicks(ms float64, tempo float64) float64 {
+	return ms * tempo / 60000 * MidiFileTicksPerQuarterNote
+}
+
+// channelControlMessage returns a control change or aftertouch message that
+// sets a control of a channel. See mpeChannels.
+func channelControlMessage(control channelControl) midi.Message {
+	ch := channel.Channel(uint8(control.channel))
+
+	if control.isAftertouch() {
+		return ch.Aftertouch(uint8(control.value))
+	}
+
+	return ch.ControlChange(uint8(control.controller), uint8(control.value))
+}
+
+// midiValue converts a value between 0 and 1 into a MIDI data byte (0-127).
+func midiValue(value float64) uint8 {
+	return uint8(math.Max(0, math.Min(127, math.Round(value*127))))
+}
+
+// timedMidiMessage is a MIDI message scheduled at a point in time (in ticks).
+type timedMidiMessage struct {
+	ticks uint32
+	// When two messages are scheduled at the same time, the one with the lower
+	// priority is written first. This ensures that, e.g., a note-off message for
+	// a note is written before a note-on message for the next note with the same
+	// pitch, and that program and control changes take effect before the notes
+	// at the same offset.
+	priority int
+	message  midi.Message
+}
+
+const (
+	priorityMeta = iota
+	priorityNoteOff
+	priorityProgramChange
+	priorityControlChange
+	priorityNoteOn
+)
+
+// scoreToMidiTracks returns the MIDI messages to be written to each track of a
+// MIDI file for the provided score. The first track is the conductor track,
+// which contains the tempo changes.
+//
+// The messages within each track are sorted chronologically.
+func scoreToMidiTracks(
+	score *model.Score, opts ...TransmissionOption,
+) ([][]timedMidiMessage, error) {
+	t, err := prepareTransmission(score, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, events := t.ctx, t.events
+	startOffset, endOffset := t.startOffset, t.endOffset
+
+	tracks := score.Tracks()
+	midiTracks := make([][]timedMidiMessage, len(score.Parts)+1)
+
+	for part, track := range tracks {
+		midiTracks[track] = append(midiTracks[track], timedMidiMessage{
+			priority: priorityMeta,
+			message:  meta.TrackSequenceName(part.Name),
+		})
+	}
+
+	// As with the OSC transmitter, we omit tempo changes when there is a sync
+	// offset. In practice, MIDI files are written for entire scores (or slices of
+	// scores via `--from` and `--to`), so this shouldn't come up.
+	tempos := tempoMap{}
+	if ctx.syncOffset == 0 {
+		tempos = tempoChanges(score, startOffset, endOffset)
+	}
+
+	for _, change := range tempos {
+		midiTracks[0] = append(midiTracks[0], timedMidiMessage{
+			ticks:    tempos.ticks(change.offset),
+			priority: priorityMeta,
+			message:  meta.FractionalBPM(change.tempo),
+		})
+	}
+
+	// See the comment in ScoreToOSCBundle about why we keep track of these
+	// values per channel.
+	channelPatch := map[int32]int32{}
+	channelVolume := map[int32]float64{}
+	channelPanning := map[int32]float64{}
+
+	// Notes with microtonal pitches are bent on channels of their own. See
+	// mpeChannels.
+	mpe := newMPEChannels(score.Events)
+
+	for _, event := range events {
+		eventOffset := event.EventOffset()
+
+		// Filter out events before the `--from` time marking / marker, when
+		// supplied.
+		if eventOffset < startOffset {
+			continue
+		}
+
+		// Filter out events after the `--to` time marking / marker, when supplied.
+		if eventOffset >= endOffset {
+			break
+		}
+
+		switch event := event.(type) {
+		case model.NoteEvent:
+			track := tracks[event.Part]
+			key, bend := microtonalKey(event)
+			partChannel := event.MidiChannel
+			event.MidiChannel = mpe.channel(event, bend)
+			ch := channel.Channel(uint8(event.MidiChannel))
+			offset := event.Offset - startOffset - ctx.syncOffset
+			ticks := tempos.ticks(offset)
+
+			schedule := func(ticks uint32, priority int, message midi.Message) {
+				midiTracks[track] = append(midiTracks[track], timedMidiMessage{
+					ticks: ticks, priority: priority, message: message,
+				})
+			}
+
+			// Channel 9 is for percussion only; program changes are not relevant on
+			// that channel.
+			if event.MidiChannel != 9 {
+				thisPatch := event.Part.StockInstrument.(model.MidiInstrument).PatchNumber
+
+				currentPatch, recorded := channelPatch[event.MidiChannel]
+
+				if !recorded || thisPatch != currentPatch {
+					channelPatch[event.MidiChannel] = thisPatch
+					schedule(
+						ticks, priorityProgramChange, ch.ProgramChange(uint8(thisPatch)),
+					)
+				}
+			}
+
+			currentVolume, recorded := channelVolume[event.MidiChannel]
+
+			if !recorded || event.TrackVolume != currentVolume {
+				channelVolume[event.MidiChannel] = event.TrackVolume
+				schedule(
+					ticks,
+					priorityControlChange,
+					ch.ControlChange(7, midiValue(event.TrackVolume)),
+				)
+			}
+
+			currentPanning, recorded := channelPanning[event.MidiChannel]
+
+			if !recorded || event.Panning != currentPanning {
+				channelPanning[event.MidiChannel] = event.Panning
+				schedule(
+					ticks,
+					priorityControlChange,
+					ch.ControlChange(10, midiValue(event.Panning)),
+				)
+			}
+
+			for _, control := range mpe.follow(event.MidiChannel, partChannel) {
+				schedule(ticks, priorityControlChange, channelControlMessage(control))
+			}
+
+			if mpe.setBend(event.MidiChannel, bend) {
+				schedule(ticks, priorityControlChange, ch.Pitchbend(int16(bend)))
+			}
+
+			// A note with a velocity of 0 is interpreted as a note-off message, so
+			// we skip notes that would be silent anyway.
+			velocity := midiValue(event.Volume)
+			if velocity == 0 {
+				continue
+			}
+
+			schedule(ticks, priorityNoteOn, ch.NoteOn(uint8(key), velocity))
+			schedule(
+				tempos.ticks(offset+event.AudibleDuration),
+				priorityNoteOff,
+				ch.NoteOff(uint8(key)),
+			)
+		case model.ControlChangeEvent:
+			track := tracks[event.Part]
+			ch := channel.Channel(uint8(event.MidiChannel))
+			ticks := tempos.ticks(event.Offset - startOffset - ctx.syncOffset)
+
+			midiTracks[track] = append(midiTracks[track], timedMidiMessage{
+				ticks:    ticks,
+				priority: priorityControlChange,
+				message: ch.ControlChange(
+					uint8(event.Controller), uint8(event.Value),
+				),
+			})
+
+			for _, control := range mpe.controlChange(
+				event.MidiChannel, event.Controller, event.Value,
+			) {
+				midiTracks[track] = append(midiTracks[track], timedMidiMessage{
+					ticks:    ticks,
+					priority: priorityControlChange,
+					message:  channelControlMessage(control),
+				})
+			}
+		case model.PitchBendEvent:
+			mpe.forgetBend(event.MidiChannel)
+
+			track := tracks[event.Part]
+			ch := channel.Channel(uint8(event.MidiChannel))
+			ticks := tempos.ticks(event.Offset - startOffset - ctx.syncOffset)
+
+			midiTracks[track] = append(midiTracks[track], timedMidiMessage{
+				ticks:    ticks,
+				priority: priorityControlChange,
+				message:  ch.Pitchbend(int16(event.Value)),
+			})
+		case model.AftertouchEvent:
+			track := tracks[event.Part]
+			ch := channel.Channel(uint8(event.MidiChannel))
+			ticks := tempos.ticks(event.Offset - startOffset - ctx.syncOffset)
+
+			midiTracks[track] = append(midiTracks[track], timedMidiMessage{
+				ticks:    ticks,
+				priority: priorityControlChange,
+				message:  ch.Aftertouch(uint8(event.Value)),
+			})
+
+			for _, control := range mpe.controlChange(
+				event.MidiChannel, aftertouchController, event.Value,
+			) {
+				midiTracks[track] = append(midiTracks[track], timedMidiMessage{
+					ticks:    ticks,
+					priority: priorityControlChange,
+					message:  channelControlMessage(control),
+				})
+			}
+		default:
+			return nil, fmt.Errorf("unsupported event: %#v", event)
+		}
+	}
+
+	for _, messages := range midiTracks {
+		sort.SliceStable(messages, func(i, j int) bool {
+			if messages[i].ticks != messages[j].ticks {
+				return messages[i].ticks < messages[j].ticks
+			}
+
+			return messages[i].priority < messages[j].priority
+		})
+	}
+
+	return midiTracks, nil
+}
+
+// TransmitScore implements Transmitter.TransmitScore by writing the score to
+// the transmitter's Writer as a Standard MIDI File.
+func (mft MidiFileTransmitter) TransmitScore(
+	score *model.Score, opts ...TransmissionOption,
+) error {
+	midiTracks, err := scoreToMidiTracks(score, opts...)
+	if err != nil {
+		return err
+	}
+
+	wr := smfwriter.New(
+		mft.Writer,
+		smfwriter.Format(smf.SMF1),
+		smfwriter.NumTracks(uint16(len(midiTracks))),
+		smfwriter.TimeFormat(smf.MetricTicks(MidiFileTicksPerQuarterNote)),
+	)
+
+	for _, messages := range midiTracks {
+		lastTicks := uint32(0)
+
+		for _, msg := range messages {
+			wr.SetDelta(msg.ticks - lastTicks)
+			lastTicks = msg.ticks
+
+			if err := wr.Write(msg.message); err != nil {
+				return err
+			}
+		}
+
+		// smfwriter returns smf.ErrFinished after the last track is written, which
+		// is what we expect.
+		if err := wr.Write(meta.EndOfTrack); err != nil && err != smf.ErrFinished {
+			return err
+		}
+	}
+
+	log.Debug().
+		Int("tracks", len(midiTracks)).
+		Msg("Wrote MIDI file.")
+
+	return nil
+}
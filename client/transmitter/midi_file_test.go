@@ -0,0 +1,209 @@
+package transmitter
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"alda.io/client/model"
+	"alda.io/client/parser"
+	_ "alda.io/client/testing"
+	"gitlab.com/gomidi/midi/midimessage/channel"
+	"gitlab.com/gomidi/midi/midimessage/meta"
+)
+
+func parseScore(t *testing.T, input string) *model.Score {
+	ast, err := parser.ParseString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updates, err := ast.Updates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	score := model.NewScore()
+	if err := score.Update(updates...); err != nil {
+		t.Fatal(err)
+	}
+
+	return score
+}
+
+func TestTempoMapTicks(t *testing.T) {
+	tempos := tempoMap{
+		{offset: 0, tempo: 120},
+		{offset: 1000, tempo: 60},
+		{offset: 3000, tempo: 240},
+	}
+
+	for _, testCase := range []struct {
+		offset   float64
+		expected uint32
+	}{
+		{0, 0},
+		{500, 960},
+		{1000, 1920},
+		// At 60 BPM, a quarter note lasts 1000 ms.
+		{2000, 2880},
+		{3000, 3840},
+		// At 240 BPM, a quarter note lasts 250 ms.
+		{3250, 4800},
+	} {
+		if actual := tempos.ticks(testCase.offset); actual != testCase.expected {
+			t.Errorf(
+				"expected offset %f to be at %d ticks, got %d",
+				testCase.offset, testCase.expected, actual,
+			)
+		}
+	}
+
+	// Without any tempo changes, the default tempo (120 BPM) is assumed.
+	if actual := (tempoMap{}).ticks(1500); actual != 2880 {
+		t.Errorf("expected 2880 ticks at the default tempo, got %d", actual)
+	}
+}
+
+func TestScoreToMidiTracks(t *testing.T) {
+	score := parseScore(t, "piano: c d (tempo 60) e")
+
+	tracks, err := scoreToMidiTracks(score)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracks) != 2 {
+		t.Fatalf("expected 2 tracks (conductor + piano), got %d", len(tracks))
+	}
+
+	tempos := []float64{}
+	tempoTicks := []uint32{}
+	for _, msg := range tracks[0] {
+		if tempo, ok := msg.message.(meta.Tempo); ok {
+			tempos = append(tempos, tempo.FractionalBPM())
+			tempoTicks = append(tempoTicks, msg.ticks)
+		}
+	}
+
+	if len(tempos) != 2 ||
+		tempos[0] != 120 || tempoTicks[0] != 0 ||
+		tempos[1] != 60 || tempoTicks[1] != 1920 {
+		t.Errorf(
+			"unexpected tempo changes: %v at ticks %v", tempos, tempoTicks,
+		)
+	}
+
+	if name, ok := tracks[1][0].message.(meta.TrackSequenceName); !ok ||
+		string(name) != "piano" {
+		t.Errorf(
+			"expected the piano track to start with its name, got %v",
+			tracks[1][0].message,
+		)
+	}
+
+	noteOnTicks := map[uint8]uint32{}
+	noteOffTicks := map[uint8]uint32{}
+	for i, msg := range tracks[1] {
+		if i > 0 && msg.ticks < tracks[1][i-1].ticks {
+			t.Errorf("messages are not in chronological order: %v", tracks[1])
+		}
+
+		switch msg := msg.message.(type) {
+		case channel.NoteOn:
+			noteOnTicks[msg.Key()] = tracks[1][i].ticks
+		case channel.NoteOff:
+			noteOffTicks[msg.Key()] = tracks[1][i].ticks
+		}
+	}
+
+	// The tempo change at the third note doubles the number of milliseconds in
+	// each tick, so its note-off message is twice as far away (in ms) as it would
+	// be at 120 BPM, but the same number of ticks away.
+	expectedNoteOn := map[uint8]uint32{60: 0, 62: 960, 64: 1920}
+	expectedNoteOff := map[uint8]uint32{60: 864, 62: 1824, 64: 2784}
+
+	for key, ticks := range expectedNoteOn {
+		if noteOnTicks[key] != ticks {
+			t.Errorf(
+				"expected note %d to start at %d ticks, got %d",
+				key, ticks, noteOnTicks[key],
+			)
+		}
+	}
+
+	for key, ticks := range expectedNoteOff {
+		if noteOffTicks[key] != ticks {
+			t.Errorf(
+				"expected note %d to end at %d ticks, got %d",
+				key, ticks, noteOffTicks[key],
+			)
+		}
+	}
+}
+
+func TestMidiFileBytes(t *testing.T) {
+	score := parseScore(t, "piano: c")
+
+	buf := bytes.Buffer{}
+	if err := (MidiFileTransmitter{Writer: &buf}).TransmitScore(score); err != nil {
+		t.Fatal(err)
+	}
+
+	actual := buf.Bytes()
+
+	header := []byte{
+		'M', 'T', 'h', 'd',
+		0, 0, 0, 6, // header length
+		0, 1, // format 1
+		0, 2, // 2 tracks
+		0x03, 0xc0, // 960 ticks per quarter note
+	}
+
+	conductorTrack := []byte{
+		'M', 'T', 'r', 'k',
+		0, 0, 0, 11, // track length
+		0, 0xff, 0x51, 3, 0x07, 0xa1, 0x20, // tempo: 500000 μs per quarter note
+		0, 0xff, 0x2f, 0, // end of track
+	}
+
+	expectedPrefix := append(header, conductorTrack...)
+
+	if !bytes.HasPrefix(actual, expectedPrefix) {
+		t.Fatalf(
+			"unexpected MIDI file header/conductor track:\n%s",
+			hex.Dump(actual),
+		)
+	}
+
+	pianoTrack := actual[len(expectedPrefix):]
+
+	if !bytes.HasPrefix(pianoTrack, []byte("MTrk")) {
+		t.Fatalf("expected a second track chunk:\n%s", hex.Dump(pianoTrack))
+	}
+
+	trackName := []byte{0, 0xff, 0x03, 5, 'p', 'i', 'a', 'n', 'o'}
+	if !bytes.HasPrefix(pianoTrack[8:], trackName) {
+		t.Errorf(
+			"expected the piano track to start with its name:\n%s",
+			hex.Dump(pianoTrack),
+		)
+	}
+
+	endOfTrack := []byte{0xff, 0x2f, 0}
+	if !bytes.HasSuffix(pianoTrack, endOfTrack) {
+		t.Errorf(
+			"expected the piano track to end with an end-of-track event:\n%s",
+			hex.Dump(pianoTrack),
+		)
+	}
+
+	length := int(pianoTrack[4])<<24 | int(pianoTrack[5])<<16 |
+		int(pianoTrack[6])<<8 | int(pianoTrack[7])
+	if length != len(pianoTrack)-8 {
+		t.Errorf(
+			"expected the piano track length to be %d, got %d",
+			len(pianoTrack)-8, length,
+		)
+	}
+}
@@ -27,6 +27,9 @@ func (nte NoteTimingTransmitter) TransmitScore(score *model.Score) error {
 			duration := int32(math.Round(event.AudibleDuration))
 
 			fmt.Printf("%d,%d,%d\n", offset, duration, event.MidiNote)
+		case model.ControlChangeEvent, model.PitchBendEvent, model.AftertouchEvent:
+			// We're only interested in notes here.
+			continue
 		default:
 			return fmt.Errorf("unsupported event: %#v", event)
 		}
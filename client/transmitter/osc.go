@@ -13,19 +13,19 @@ import (
 
 // OSCTransmitter sends OSC messages to a player process.
 type OSCTransmitter struct {
+	// The host where the player process is listening. When empty, the player
+	// process is assumed to be on the local machine.
+	Host string
 	Port int
+	// The network protocol over which to send the messages, "tcp" or "udp".
+	// When empty, TCP is used.
+	Protocol string
 }
 
 func pingMsg() *osc.Message {
 	return osc.NewMessage("/ping")
 }
 
-func systemMidiExportMsg(filename string) *osc.Message {
-	msg := osc.NewMessage("/system/midi/export")
-	msg.Append(filename)
-	return msg
-}
-
 func systemPlayMsg() *osc.Message {
 	return osc.NewMessage("/system/play")
 }
@@ -34,6 +34,10 @@ func systemStopMsg() *osc.Message {
 	return osc.NewMessage("/system/stop")
 }
 
+func systemClearMsg() *osc.Message {
+	return osc.NewMessage("/system/clear")
+}
+
 func systemPlaybackFinishedMsg(offset int32) *osc.Message {
 	msg := osc.NewMessage("/system/playback-finished")
 	msg.Append(offset)
@@ -97,105 +101,186 @@ func midiPanningMsg(track int32, channel int32, offset int32, panning int32) *os
 	return msg
 }
 
-func oscClient(port int) *osc.Client {
-	return osc.NewClient("127.0.0.1", int(port), osc.ClientProtocol(osc.TCP))
+func midiControlChangeMsg(
+	track int32, channel int32, offset int32, controller int32, value int32,
+) *osc.Message {
+	msg := osc.NewMessage(fmt.Sprintf("/track/%d/midi/control-change", track))
+	msg.Append(channel)
+	msg.Append(offset)
+	msg.Append(controller)
+	msg.Append(value)
+	return msg
+}
+
+func midiPitchBendMsg(
+	track int32, channel int32, offset int32, value int32,
+) *osc.Message {
+	msg := osc.NewMessage(fmt.Sprintf("/track/%d/midi/pitch-bend", track))
+	msg.Append(channel)
+	msg.Append(offset)
+	msg.Append(value)
+	return msg
+}
+
+func midiAftertouchMsg(
+	track int32, channel int32, offset int32, value int32,
+) *osc.Message {
+	msg := osc.NewMessage(fmt.Sprintf("/track/%d/midi/aftertouch", track))
+	msg.Append(channel)
+	msg.Append(offset)
+	msg.Append(value)
+	return msg
+}
+
+// midiChannelControlMsg returns a control change or aftertouch message that
+// sets a control of a channel. See mpeChannels.
+func midiChannelControlMsg(
+	track int32, offset int32, control channelControl,
+) *osc.Message {
+	if control.isAftertouch() {
+		return midiAftertouchMsg(track, control.channel, offset, control.value)
+	}
+
+	return midiControlChangeMsg(
+		track, control.channel, offset, control.controller, control.value,
+	)
+}
+
+func trackPatternLoopMsg(
+	track int32, channel int32, offset int32, pattern string,
+) *osc.Message {
+	msg := osc.NewMessage(fmt.Sprintf("/track/%d/pattern-loop", track))
+	msg.Append(channel)
+	msg.Append(offset)
+	msg.Append(pattern)
+	return msg
+}
+
+func trackFinishLoopMsg(track int32, offset int32) *osc.Message {
+	msg := osc.NewMessage(fmt.Sprintf("/track/%d/finish-loop", track))
+	msg.Append(offset)
+	return msg
+}
+
+func patternClearMsg(pattern string) *osc.Message {
+	return osc.NewMessage(fmt.Sprintf("/pattern/%s/clear", pattern))
+}
+
+func patternMidiNoteMsg(
+	pattern string, offset int32, note int32, duration int32,
+	audibleDuration int32, velocity int32,
+) *osc.Message {
+	msg := osc.NewMessage(fmt.Sprintf("/pattern/%s/midi/note", pattern))
+	msg.Append(offset)
+	msg.Append(note)
+	msg.Append(duration)
+	msg.Append(audibleDuration)
+	msg.Append(velocity)
+	return msg
+}
+
+func patternMidiVolumeMsg(
+	pattern string, offset int32, volume int32,
+) *osc.Message {
+	msg := osc.NewMessage(fmt.Sprintf("/pattern/%s/midi/volume", pattern))
+	msg.Append(offset)
+	msg.Append(volume)
+	return msg
+}
+
+func patternMidiPanningMsg(
+	pattern string, offset int32, panning int32,
+) *osc.Message {
+	msg := osc.NewMessage(fmt.Sprintf("/pattern/%s/midi/panning", pattern))
+	msg.Append(offset)
+	msg.Append(panning)
+	return msg
+}
+
+func patternMidiControlChangeMsg(
+	pattern string, offset int32, controller int32, value int32,
+) *osc.Message {
+	msg := osc.NewMessage(
+		fmt.Sprintf("/pattern/%s/midi/control-change", pattern),
+	)
+	msg.Append(offset)
+	msg.Append(controller)
+	msg.Append(value)
+	return msg
 }
 
-// TransmitMidiExportMessage sends a "MIDI export" message to a player process.
-func (oe OSCTransmitter) TransmitMidiExportMessage(filename string) error {
-	return oscClient(oe.Port).Send(systemMidiExportMsg(filename))
+func patternMidiPitchBendMsg(
+	pattern string, offset int32, value int32,
+) *osc.Message {
+	msg := osc.NewMessage(fmt.Sprintf("/pattern/%s/midi/pitch-bend", pattern))
+	msg.Append(offset)
+	msg.Append(value)
+	return msg
+}
+
+func patternMidiAftertouchMsg(
+	pattern string, offset int32, value int32,
+) *osc.Message {
+	msg := osc.NewMessage(fmt.Sprintf("/pattern/%s/midi/aftertouch", pattern))
+	msg.Append(offset)
+	msg.Append(value)
+	return msg
+}
+
+// The OSC API represents pitch bend the way that MIDI does, as a number from 0
+// to 16383, where 8192 means no pitch bend.
+func pitchBendValue(value int32) int32 {
+	return value + 8192
 }
 
 // TransmitPingMessage sends a "ping" message to a player process.
 func (oe OSCTransmitter) TransmitPingMessage() error {
-	return oscClient(oe.Port).Send(pingMsg())
+	return oe.send(pingMsg())
 }
 
 // TransmitPlayMessage sends a "play" message to a player process.
 func (oe OSCTransmitter) TransmitPlayMessage() error {
-	return oscClient(oe.Port).Send(systemPlayMsg())
+	return oe.send(systemPlayMsg())
 }
 
 // TransmitStopMessage sends a "stop" message to a player process.
 func (oe OSCTransmitter) TransmitStopMessage() error {
-	return oscClient(oe.Port).Send(systemStopMsg())
+	return oe.send(systemStopMsg())
+}
+
+// TransmitResetMessage sends a bundle to a player process that stops playback,
+// clears all tracks of upcoming events, and moves the sequence to `offset`.
+//
+// Events that the player has already scheduled stay in its sequence, so
+// `offset` should be past the end of them.
+func (oe OSCTransmitter) TransmitResetMessage(offset int32) error {
+	bundle := osc.NewBundle(time.Now())
+	bundle.Append(systemStopMsg())
+	bundle.Append(systemClearMsg())
+	bundle.Append(systemOffsetMsg(offset))
+	return oe.send(bundle)
 }
 
 // TransmitShutdownMessage sends a "shutdown" message to a player process.
 func (oe OSCTransmitter) TransmitShutdownMessage(offset int32) error {
-	return oscClient(oe.Port).Send(systemShutdownMsg(offset))
+	return oe.send(systemShutdownMsg(offset))
 }
 
 // TransmitOffsetMessage sends an "offset" message to a player process.
 func (oe OSCTransmitter) TransmitOffsetMessage(offset int32) error {
-	return oscClient(oe.Port).Send(systemOffsetMsg(offset))
+	return oe.send(systemOffsetMsg(offset))
 }
 
 func tempoMessages(
 	score *model.Score, startOffset float64, endOffset float64,
 ) []*osc.Message {
-	tempoItinerary := score.TempoItinerary()
-
-	tempoOffsets := []float64{}
-	for offset := range tempoItinerary {
-		tempoOffsets = append(tempoOffsets, offset)
-	}
-	sort.Float64s(tempoOffsets)
-
-	// In the case where we're starting a ways into the score (i.e. if the
-	// `--from` option is supplied), we want to skip any extraneous tempo changes
-	// that happened before that point in the score. Except we do want the last
-	// one before or at the start offset, so that the initial tempo is correct.
-	firstTempoOffset := 0.0
-	for _, tempoOffset := range tempoOffsets {
-		if tempoOffset > startOffset {
-			break
-		}
-
-		// Keep going until we reach a tempo offset past the start offset. At that
-		// point, we'll use the previous tempo offset recorded here, because that
-		// would be the last tempo change before the start offset.
-		firstTempoOffset = tempoOffset
-	}
-
 	messages := []*osc.Message{}
 
-	// Now, we want to emit tempo messages for each tempo change within the time
-	// range of the excerpt of the score that we're playing.
-	for _, tempoOffset := range tempoOffsets {
-		// Filter out any tempo changes prior to the `--from` time marking / marker,
-		// when supplied. (...except for the last tempo offset prior to that point
-		// in time; see the comment where we defined `firstTempoOffset` above.)
-		if tempoOffset < firstTempoOffset {
-			continue
-		}
-
-		// Filter out any tempo changes after the `--to` time marking / marker, when
-		// supplied.
-		if tempoOffset >= endOffset {
-			break
-		}
-
-		tempo := tempoItinerary[tempoOffset]
-
-		// We subtract `startOffset` from the offset because we're about to do the
-		// same thing to the offset of every note event, for reasons that are
-		// explained below.
-		//
-		// By default, `startOffset` is 0, so the usual scenario is that the offset
-		// is not adjusted.
-		offset := tempoOffset - startOffset
-
-		// If the effective offset is earlier than the notional start offset (0),
-		// then we'll place the tempo change right at the beginning (0).
-		if offset < 0 {
-			offset = 0
-		}
-
+	for _, change := range tempoChanges(score, startOffset, endOffset) {
 		// The OSC API works with int offsets and float tempos, so we do the
 		// necessary conversions here.
-		offsetRounded := int32(math.Round(offset))
-		tempo32 := float32(tempo)
+		offsetRounded := int32(math.Round(change.offset))
+		tempo32 := float32(change.tempo)
 		messages = append(messages, systemTempoMsg(offsetRounded, tempo32))
 	}
 
@@ -207,57 +292,16 @@ func tempoMessages(
 func (oe OSCTransmitter) ScoreToOSCBundle(
 	score *model.Score, opts ...TransmissionOption,
 ) (*osc.Bundle, error) {
-	ctx := &TransmissionContext{toIndex: -1}
-	for _, opt := range opts {
-		opt(ctx)
-	}
-
-	if ctx.toIndex == -1 {
-		ctx.toIndex = len(score.Events)
-	}
-
-	log.Debug().
-		Str("ctx", fmt.Sprintf("%#v", ctx)).
-		Msg("Transmission options applied.")
-
-	events := score.Events[ctx.fromIndex:ctx.toIndex]
-
-	startOffset := 0.0
-	endOffset := math.MaxFloat64
-
-	if ctx.from != "" {
-		offset, err := score.InterpretOffsetReference(ctx.from)
-		if err != nil {
-			return nil, err
-		}
-
-		startOffset = offset
+	t, err := prepareTransmission(score, opts...)
+	if err != nil {
+		return nil, err
 	}
 
-	if ctx.to != "" {
-		offset, err := score.InterpretOffsetReference(ctx.to)
-		if err != nil {
-			return nil, err
-		}
-
-		endOffset = offset
-	}
+	ctx, events := t.ctx, t.events
+	startOffset, endOffset := t.startOffset, t.endOffset
 
 	bundle := osc.NewBundle(time.Now())
 
-	// In order to support features like:
-	//
-	// * Avoiding scheduling more program, volume, and panning control change
-	//   messages than we have to (see below).
-	//
-	// * Playing just a slice of a score, e.g. `alda play --from 0:05 --to 0:10`
-	//
-	// ...we sort the events in the score by offset and schedule them in
-	// chronological order.
-	sort.Slice(events, func(i, j int) bool {
-		return events[i].EventOffset() < events[j].EventOffset()
-	})
-
 	// Append tempo messages to the score, based on the tempo changes in the
 	// score. (See *Score.TempoItinerary.)
 	//
@@ -296,6 +340,10 @@ func (oe OSCTransmitter) ScoreToOSCBundle(
 	channelVolume := map[int32]float64{}
 	channelPanning := map[int32]float64{}
 
+	// Notes with microtonal pitches are bent on channels of their own. See
+	// mpeChannels.
+	mpe := newMPEChannels(score.Events)
+
 	tracks := score.Tracks()
 
 	for _, event := range events {
@@ -338,6 +386,10 @@ func (oe OSCTransmitter) ScoreToOSCBundle(
 			// rounding here and work with the int value from here onward.
 			offsetRounded := int32(math.Round(offset))
 
+			key, bend := microtonalKey(event)
+			partChannel := event.MidiChannel
+			event.MidiChannel = mpe.channel(event, bend)
+
 			/////////////////////////////////////////////////////////////////////////
 			// Insert a program control change message, if needed
 			/////////////////////////////////////////////////////////////////////////
@@ -400,6 +452,25 @@ func (oe OSCTransmitter) ScoreToOSCBundle(
 				)
 			}
 
+			/////////////////////////////////////////////////////////////////////////
+			// Insert control changes so that a channel borrowed for a microtonal
+			// note matches its part's channel, if needed
+			/////////////////////////////////////////////////////////////////////////
+
+			for _, control := range mpe.follow(event.MidiChannel, partChannel) {
+				bundle.Append(midiChannelControlMsg(track, offsetRounded, control))
+			}
+
+			/////////////////////////////////////////////////////////////////////////
+			// Insert a pitch bend message, if needed
+			/////////////////////////////////////////////////////////////////////////
+
+			if mpe.setBend(event.MidiChannel, bend) {
+				bundle.Append(midiPitchBendMsg(
+					track, event.MidiChannel, offsetRounded, pitchBendValue(bend),
+				))
+			}
+
 			/////////////////////////////////////////////////////////////////////////
 			// Insert a message for the note
 			/////////////////////////////////////////////////////////////////////////
@@ -408,13 +479,66 @@ func (oe OSCTransmitter) ScoreToOSCBundle(
 				track,
 				event.MidiChannel,
 				offsetRounded,
-				event.MidiNote,
+				key,
 				int32(math.Round(event.Duration)),
 				int32(math.Round(event.AudibleDuration)),
 				int32(math.Round(event.Volume*127)),
 			))
 
 			scoreLength = math.Max(scoreLength, offset+event.AudibleDuration)
+		case model.ControlChangeEvent:
+			// See the comments above about adjusting and rounding the offset.
+			offsetRounded := int32(
+				math.Round(event.Offset - startOffset - ctx.syncOffset),
+			)
+
+			bundle.Append(midiControlChangeMsg(
+				tracks[event.Part],
+				event.MidiChannel,
+				offsetRounded,
+				event.Controller,
+				event.Value,
+			))
+
+			for _, control := range mpe.controlChange(
+				event.MidiChannel, event.Controller, event.Value,
+			) {
+				bundle.Append(
+					midiChannelControlMsg(tracks[event.Part], offsetRounded, control),
+				)
+			}
+		case model.PitchBendEvent:
+			mpe.forgetBend(event.MidiChannel)
+
+			offsetRounded := int32(
+				math.Round(event.Offset - startOffset - ctx.syncOffset),
+			)
+
+			bundle.Append(midiPitchBendMsg(
+				tracks[event.Part],
+				event.MidiChannel,
+				offsetRounded,
+				pitchBendValue(event.Value),
+			))
+		case model.AftertouchEvent:
+			offsetRounded := int32(
+				math.Round(event.Offset - startOffset - ctx.syncOffset),
+			)
+
+			bundle.Append(midiAftertouchMsg(
+				tracks[event.Part],
+				event.MidiChannel,
+				offsetRounded,
+				event.Value,
+			))
+
+			for _, control := range mpe.controlChange(
+				event.MidiChannel, aftertouchController, event.Value,
+			) {
+				bundle.Append(
+					midiChannelControlMsg(tracks[event.Part], offsetRounded, control),
+				)
+			}
 		default:
 			return nil, fmt.Errorf("unsupported event: %#v", event)
 		}
@@ -447,5 +571,199 @@ func (oe OSCTransmitter) TransmitScore(
 		Interface("bundle", bundle).
 		Msg("Sending OSC bundle.")
 
-	return oscClient(oe.Port).Send(bundle)
+	return oe.send(bundle)
+}
+
+// TransmitOSCBundle sends a prepared OSC bundle to a player process, e.g. one
+// that was read from a dump via ReadOSCDump.
+func (oe OSCTransmitter) TransmitOSCBundle(bundle *osc.Bundle) error {
+	return oe.send(bundle)
+}
+
+// PatternInstrument returns the instrument of the single part whose notes make
+// up a pattern.
+//
+// Returns an error if the score does not consist of exactly one part.
+func PatternInstrument(score *model.Score) (model.MidiInstrument, error) {
+	if len(score.Parts) != 1 {
+		return model.MidiInstrument{}, fmt.Errorf(
+			"a pattern must consist of exactly one part, but %d parts were found",
+			len(score.Parts),
+		)
+	}
+
+	// See the note in ScoreToOSCBundle about all instruments being MIDI
+	// instruments.
+	return score.Parts[0].StockInstrument.(model.MidiInstrument), nil
+}
+
+// patternMessages returns the OSC messages that define (or redefine) the
+// pattern `name` as the notes of the provided score.
+func patternMessages(name string, score *model.Score) ([]*osc.Message, error) {
+	if _, err := PatternInstrument(score); err != nil {
+		return nil, err
+	}
+
+	patternLength := score.Parts[0].CalculateEffectiveOffset()
+	if math.Round(patternLength) <= 0 {
+		return nil, fmt.Errorf("pattern `%s` is empty", name)
+	}
+
+	events := append([]model.ScoreEvent{}, score.Events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EventOffset() < events[j].EventOffset()
+	})
+
+	messages := []*osc.Message{patternClearMsg(name)}
+
+	// The events in a pattern aren't tied to a channel, so we only need to keep
+	// track of a single volume and panning value. (See the comment about control
+	// change messages in ScoreToOSCBundle.)
+	volume, panning := -1.0, -1.0
+
+	notesEnd := 0.0
+
+	for _, event := range events {
+		switch event := event.(type) {
+		case model.NoteEvent:
+			offsetRounded := int32(math.Round(event.Offset))
+
+			if event.TrackVolume != volume {
+				volume = event.TrackVolume
+				messages = append(
+					messages,
+					patternMidiVolumeMsg(
+						name, offsetRounded, int32(math.Round(volume*127)),
+					),
+				)
+			}
+
+			if event.Panning != panning {
+				panning = event.Panning
+				messages = append(
+					messages,
+					patternMidiPanningMsg(
+						name, offsetRounded, int32(math.Round(panning*127)),
+					),
+				)
+			}
+
+			// A pattern isn't played on a channel of its own, so we can't bend the
+			// pitch of individual notes. Instead, we play the closest MIDI note.
+			key, _ := microtonalKey(event)
+
+			messages = append(messages, patternMidiNoteMsg(
+				name,
+				offsetRounded,
+				key,
+				int32(math.Round(event.Duration)),
+				int32(math.Round(event.AudibleDuration)),
+				int32(math.Round(event.Volume*127)),
+			))
+
+			notesEnd = math.Max(notesEnd, event.Offset+event.Duration)
+		case model.ControlChangeEvent:
+			messages = append(messages, patternMidiControlChangeMsg(
+				name,
+				int32(math.Round(event.Offset)),
+				event.Controller,
+				event.Value,
+			))
+		case model.PitchBendEvent:
+			messages = append(messages, patternMidiPitchBendMsg(
+				name, int32(math.Round(event.Offset)), pitchBendValue(event.Value),
+			))
+		case model.AftertouchEvent:
+			messages = append(messages, patternMidiAftertouchMsg(
+				name, int32(math.Round(event.Offset)), event.Value,
+			))
+		default:
+			return nil, fmt.Errorf("unsupported event: %#v", event)
+		}
+	}
+
+	// The player determines the length of each iteration of a pattern from the
+	// point where its last note ends, which means that any rests at the end of
+	// the pattern would be lost. To preserve them, we pad the pattern out to its
+	// full length with a silent note.
+	if lengthRounded := int32(math.Round(patternLength)); lengthRounded >
+		int32(math.Round(notesEnd)) {
+		messages = append(
+			messages, patternMidiNoteMsg(name, lengthRounded-1, 0, 1, 1, 0),
+		)
+	}
+
+	return messages, nil
+}
+
+// TransmitPattern sends OSC messages to a player process that define (or
+// redefine) the pattern `name` as the notes of the provided score, which must
+// consist of a single part.
+//
+// If the pattern is currently looping, the player picks up the new contents on
+// the next iteration of the loop.
+func (oe OSCTransmitter) TransmitPattern(
+	name string, score *model.Score,
+) error {
+	messages, err := patternMessages(name, score)
+	if err != nil {
+		return err
+	}
+
+	bundle := osc.NewBundle(time.Now())
+	for _, msg := range messages {
+		bundle.Append(msg)
+	}
+
+	log.Debug().
+		Interface("bundle", bundle).
+		Msg("Sending OSC bundle.")
+
+	return oe.send(bundle)
+}
+
+// TransmitPatternLoop sends OSC messages to a player process that define the
+// pattern `name` as the notes of the provided score, and then loop the pattern
+// indefinitely on the provided track and channel, starting right away.
+//
+// The player schedules the events on a track in order, so a looping pattern
+// occupies its track until the loop is finished. (See TransmitFinishLoop.)
+func (oe OSCTransmitter) TransmitPatternLoop(
+	name string, score *model.Score, track int32, channel int32,
+) error {
+	messages, err := patternMessages(name, score)
+	if err != nil {
+		return err
+	}
+
+	instrument, err := PatternInstrument(score)
+	if err != nil {
+		return err
+	}
+
+	bundle := osc.NewBundle(time.Now())
+	for _, msg := range messages {
+		bundle.Append(msg)
+	}
+
+	// Channel 9 is for percussion only; program changes are not relevant on that
+	// channel.
+	if channel != 9 {
+		bundle.Append(midiPatchMsg(track, channel, 0, instrument.PatchNumber))
+	}
+
+	bundle.Append(trackPatternLoopMsg(track, channel, 0, name))
+	bundle.Append(systemPlayMsg())
+
+	log.Debug().
+		Interface("bundle", bundle).
+		Msg("Sending OSC bundle.")
+
+	return oe.send(bundle)
+}
+
+// TransmitFinishLoop sends a message to a player process that stops the
+// patterns looping on a track once their current iteration is complete.
+func (oe OSCTransmitter) TransmitFinishLoop(track int32) error {
+	return oe.send(trackFinishLoopMsg(track, 0))
 }
@@ -0,0 +1,110 @@
+package transmitter
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	log "alda.io/client/logging"
+	"github.com/daveyarwood/go-osc/osc"
+)
+
+// Without these timeouts, sending a message to a player process on a host
+// that isn't reachable (e.g. another machine that has gone offline) could hang
+// until the operating system gives up on the connection.
+const oscDialTimeout = 5 * time.Second
+const oscWriteTimeout = 10 * time.Second
+
+// When we can't connect to a player process, we try to reconnect a couple of
+// times, waiting a little longer each time, before giving up. This smooths over
+// brief network hiccups when the player process is on another machine.
+const oscConnectAttempts = 3
+const oscReconnectDelay = 200 * time.Millisecond
+
+// Over UDP, each OSC packet is sent as a single datagram, which can't be larger
+// than this.
+const maxUDPPacketSize = 65507
+
+const localHost = "127.0.0.1"
+
+func (oe OSCTransmitter) address() string {
+	host := oe.Host
+	if host == "" {
+		host = localHost
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(oe.Port))
+}
+
+func (oe OSCTransmitter) network() (string, error) {
+	switch oe.Protocol {
+	case "", "tcp":
+		return "tcp", nil
+	case "udp":
+		return "udp", nil
+	default:
+		return "", fmt.Errorf("unsupported network protocol: %q", oe.Protocol)
+	}
+}
+
+// connect opens a connection to the player process, retrying a few times if
+// the connection can't be established.
+func (oe OSCTransmitter) connect(network string) (net.Conn, error) {
+	delay := oscReconnectDelay
+
+	for attempt := 1; ; attempt++ {
+		conn, err := net.DialTimeout(network, oe.address(), oscDialTimeout)
+		if err == nil || attempt == oscConnectAttempts {
+			return conn, err
+		}
+
+		log.Debug().
+			Err(err).
+			Str("address", oe.address()).
+			Int("attempt", attempt).
+			Msg("Failed to connect to player process. Retrying.")
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// send sends an OSC packet to the player process.
+//
+// Each packet is sent over a new connection. Over TCP, this is what the player
+// process expects: it reads a single packet from each connection.
+//
+// NB: Over UDP, a successful send doesn't mean that the player process received
+// the packet, or even that it's running.
+func (oe OSCTransmitter) send(packet osc.Packet) error {
+	network, err := oe.network()
+	if err != nil {
+		return err
+	}
+
+	data, err := packet.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	if network == "udp" && len(data) > maxUDPPacketSize {
+		return fmt.Errorf(
+			"OSC packet is too large to send over UDP (%d bytes); use TCP instead",
+			len(data),
+		)
+	}
+
+	conn, err := oe.connect(network)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetWriteDeadline(time.Now().Add(oscWriteTimeout)); err != nil {
+		return err
+	}
+
+	_, err = conn.Write(data)
+	return err
+}
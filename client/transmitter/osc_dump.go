@@ -0,0 +1,320 @@
+package transmitter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"alda.io/client/model"
+	"github.com/daveyarwood/go-osc/osc"
+)
+
+// OSCDumpFormat is a format in which OSCDumpTransmitter can write the OSC
+// messages for a score.
+type OSCDumpFormat string
+
+const (
+	// OSCDumpText is a readable format with one message per line, e.g.:
+	//
+	//	/track/1/midi/note ,iiiiii 0 0 60 500 450 69
+	//
+	// The address is followed by the OSC type tags and then the arguments.
+	// String arguments are double-quoted. Blank lines and lines starting with #
+	// are ignored when reading a dump, so that comments can be added by hand.
+	OSCDumpText OSCDumpFormat = "text"
+
+	// OSCDumpJSON is a JSON array of messages, e.g.:
+	//
+	//	[{"address": "/system/tempo", "types": "if", "arguments": [0, 120]}]
+	OSCDumpJSON OSCDumpFormat = "json"
+)
+
+// OSCDumpTransmitter writes the OSC messages that OSCTransmitter would send to
+// a player process to a Writer, instead of sending them.
+//
+// This is useful for telling whether a problem with playback is caused by the
+// client or the player. A dump can be sent to a player later via
+// ReadOSCDump and OSCTransmitter.TransmitOSCBundle.
+type OSCDumpTransmitter struct {
+	Writer io.Writer
+	Format OSCDumpFormat
+}
+
+// TransmitScore implements Transmitter.TransmitScore by writing the messages
+// in the OSC bundle for the score to the transmitter's Writer.
+func (dt OSCDumpTransmitter) TransmitScore(
+	score *model.Score, opts ...TransmissionOption,
+) error {
+	bundle, err := OSCTransmitter{}.ScoreToOSCBundle(score, opts...)
+	if err != nil {
+		return err
+	}
+
+	return WriteOSCDump(dt.Writer, bundle, dt.Format)
+}
+
+type oscDumpMessage struct {
+	Address   string        `json:"address"`
+	Types     string        `json:"types"`
+	Arguments []interface{} `json:"arguments"`
+}
+
+// bundleMessages returns the messages in a bundle, including the messages in
+// any nested bundles, in order.
+func bundleMessages(bundle *osc.Bundle) []*osc.Message {
+	messages := append([]*osc.Message{}, bundle.Messages...)
+
+	for _, nested := range bundle.Bundles {
+		messages = append(messages, bundleMessages(nested)...)
+	}
+
+	return messages
+}
+
+func formatOSCArgument(arg interface{}) (string, error) {
+	switch arg := arg.(type) {
+	case string:
+		return strconv.Quote(arg), nil
+	case bool, int32, int64, float32, float64:
+		return fmt.Sprintf("%v", arg), nil
+	default:
+		return "", fmt.Errorf("unsupported OSC argument: %#v", arg)
+	}
+}
+
+// WriteOSCDump writes the messages in an OSC bundle to a Writer in the
+// provided format.
+func WriteOSCDump(w io.Writer, bundle *osc.Bundle, format OSCDumpFormat) error {
+	messages := bundleMessages(bundle)
+
+	switch format {
+	case OSCDumpJSON:
+		dump := []oscDumpMessage{}
+
+		for _, msg := range messages {
+			types, err := msg.TypeTags()
+			if err != nil {
+				return err
+			}
+
+			dump = append(dump, oscDumpMessage{
+				Address:   msg.Address,
+				Types:     strings.TrimPrefix(types, ","),
+				Arguments: append([]interface{}{}, msg.Arguments...),
+			})
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(dump)
+
+	case OSCDumpText:
+		for _, msg := range messages {
+			types, err := msg.TypeTags()
+			if err != nil {
+				return err
+			}
+
+			fields := []string{msg.Address, types}
+			for _, arg := range msg.Arguments {
+				field, err := formatOSCArgument(arg)
+				if err != nil {
+					return err
+				}
+
+				fields = append(fields, field)
+			}
+
+			if _, err := fmt.Fprintln(w, strings.Join(fields, " ")); err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported OSC dump format: %q", format)
+	}
+}
+
+// parseOSCArgument parses the next argument of the type indicated by `tag`
+// from the beginning of `input`, returning the argument and the rest of the
+// input.
+func parseOSCArgument(tag rune, input string) (interface{}, string, error) {
+	input = strings.TrimLeft(input, " \t")
+
+	if tag == 's' {
+		quoted, err := strconv.QuotedPrefix(input)
+		if err != nil {
+			return nil, "", fmt.Errorf("expected a quoted string: %s", input)
+		}
+
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return value, input[len(quoted):], nil
+	}
+
+	field, rest, _ := strings.Cut(input, " ")
+
+	value, err := oscArgumentFromString(tag, field)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return value, rest, nil
+}
+
+// oscArgumentFromString converts the string representation of a non-string
+// argument into a value of the type indicated by `tag`.
+func oscArgumentFromString(tag rune, s string) (interface{}, error) {
+	switch tag {
+	case 'i':
+		i, err := strconv.ParseInt(s, 10, 32)
+		return int32(i), err
+	case 'h':
+		return strconv.ParseInt(s, 10, 64)
+	case 'f':
+		f, err := strconv.ParseFloat(s, 32)
+		return float32(f), err
+	case 'd':
+		return strconv.ParseFloat(s, 64)
+	case 'T', 'F':
+		return strconv.ParseBool(s)
+	default:
+		return nil, fmt.Errorf("unsupported OSC type tag: %q", tag)
+	}
+}
+
+func parseOSCDumpLine(line string) (*osc.Message, error) {
+	address, rest, _ := strings.Cut(line, " ")
+	types, rest, _ := strings.Cut(strings.TrimLeft(rest, " \t"), " ")
+
+	if !strings.HasPrefix(types, ",") {
+		return nil, fmt.Errorf("expected type tags after the address: %s", line)
+	}
+
+	msg := osc.NewMessage(address)
+
+	for _, tag := range strings.TrimPrefix(types, ",") {
+		arg, remaining, err := parseOSCArgument(tag, rest)
+		if err != nil {
+			return nil, err
+		}
+
+		msg.Append(arg)
+		rest = remaining
+	}
+
+	if strings.TrimSpace(rest) != "" {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.TrimSpace(rest))
+	}
+
+	return msg, nil
+}
+
+func readOSCDumpText(input []byte) ([]*osc.Message, error) {
+	messages := []*osc.Message{}
+	scanner := bufio.NewScanner(bytes.NewReader(input))
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		msg, err := parseOSCDumpLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, scanner.Err()
+}
+
+func readOSCDumpJSON(input []byte) ([]*osc.Message, error) {
+	var dump []struct {
+		Address   string            `json:"address"`
+		Types     string            `json:"types"`
+		Arguments []json.RawMessage `json:"arguments"`
+	}
+
+	if err := json.Unmarshal(input, &dump); err != nil {
+		return nil, err
+	}
+
+	messages := []*osc.Message{}
+
+	for i, entry := range dump {
+		tags := []rune(entry.Types)
+		if len(tags) != len(entry.Arguments) {
+			return nil, fmt.Errorf(
+				"message #%d: %d type tags, but %d arguments",
+				i+1, len(tags), len(entry.Arguments),
+			)
+		}
+
+		msg := osc.NewMessage(entry.Address)
+
+		for j, tag := range tags {
+			var arg interface{}
+			var err error
+
+			if tag == 's' {
+				var s string
+				err = json.Unmarshal(entry.Arguments[j], &s)
+				arg = s
+			} else {
+				arg, err = oscArgumentFromString(tag, string(entry.Arguments[j]))
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("message #%d: %w", i+1, err)
+			}
+
+			msg.Append(arg)
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// ReadOSCDump reads OSC messages written by WriteOSCDump, in either format,
+// and returns a bundle containing them.
+func ReadOSCDump(r io.Reader) (*osc.Bundle, error) {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*osc.Message
+
+	if trimmed := bytes.TrimSpace(input); len(trimmed) > 0 && trimmed[0] == '[' {
+		messages, err = readOSCDumpJSON(input)
+	} else {
+		messages, err = readOSCDumpText(input)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := osc.NewBundle(time.Now())
+	bundle.Messages = messages
+
+	return bundle, nil
+}
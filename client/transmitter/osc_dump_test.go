@@ -0,0 +1,114 @@
+package transmitter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"alda.io/client/fakeplayer"
+	"alda.io/client/system"
+	_ "alda.io/client/testing"
+)
+
+// The messages sent to a player for the one-off score "piano: c d".
+var pianoCDMessages = strings.Join([]string{
+	"/system/tempo ,if 0 120",
+	"/track/1/midi/patch ,iii 0 0 0",
+	"/track/1/midi/volume ,iii 0 0 100",
+	"/track/1/midi/panning ,iii 0 0 64",
+	"/track/1/midi/note ,iiiiii 0 0 60 500 450 69",
+	"/track/1/midi/note ,iiiiii 0 500 62 500 450 69",
+	"/system/play ,",
+	"/system/playback-finished ,i 950",
+	"/system/shutdown ,i 10950",
+}, "\n")
+
+func TestReplayOSCDump(t *testing.T) {
+	cacheDir := system.CacheDir
+	system.CacheDir = t.TempDir()
+	t.Cleanup(func() { system.CacheDir = cacheDir })
+
+	score := parseScore(t, "piano: c d")
+
+	for _, format := range []OSCDumpFormat{OSCDumpText, OSCDumpJSON} {
+		var dump bytes.Buffer
+
+		if err := (OSCDumpTransmitter{
+			Writer: &dump, Format: format,
+		}).TransmitScore(score, OneOff()); err != nil {
+			t.Fatal(err)
+		}
+
+		if format == OSCDumpText &&
+			strings.TrimSpace(dump.String()) != pianoCDMessages {
+			t.Errorf(
+				"expected dump:\n%s\n\ngot:\n%s", pianoCDMessages, dump.String(),
+			)
+		}
+
+		bundle, err := ReadOSCDump(&dump)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		player, err := fakeplayer.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { player.Stop() })
+
+		if err := (OSCTransmitter{Port: player.Port}).
+			TransmitOSCBundle(bundle); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := player.AwaitPackets(1, 5*time.Second); err != nil {
+			t.Fatal(err)
+		}
+
+		if actual := player.MessageStream(); actual != pianoCDMessages {
+			t.Errorf(
+				"%s: expected messages:\n%s\n\ngot:\n%s",
+				format, pianoCDMessages, actual,
+			)
+		}
+	}
+}
+
+func TestReadOSCDump(t *testing.T) {
+	bundle, err := ReadOSCDump(strings.NewReader(`
+# A comment
+/track/1/pattern ,iis 0 0 "a \"quoted\" pattern"
+
+/system/tempo ,if 0 92.5
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bundle.Messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(bundle.Messages))
+	}
+
+	args := bundle.Messages[0].Arguments
+	if pattern := args[2]; pattern != `a "quoted" pattern` {
+		t.Errorf("unexpected string argument: %#v", pattern)
+	}
+
+	if tempo := bundle.Messages[1].Arguments[1]; tempo != float32(92.5) {
+		t.Errorf("unexpected float argument: %#v", tempo)
+	}
+
+	for _, invalid := range []string{
+		"/system/tempo 0 120",
+		"/system/tempo ,if 0",
+		"/system/tempo ,if 0 fast",
+		"/track/1/pattern ,iis 0 0 unquoted",
+		`[{"address": "/system/tempo", "types": "if", "arguments": [0]}]`,
+	} {
+		if _, err := ReadOSCDump(strings.NewReader(invalid)); err == nil {
+			t.Errorf("expected an error reading %q", invalid)
+		}
+	}
+}
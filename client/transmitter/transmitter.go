@@ -1,6 +1,10 @@
 package transmitter
 
 import (
+	"fmt"
+	"math"
+	"sort"
+
 	log "alda.io/client/logging"
 	"alda.io/client/model"
 )
@@ -10,6 +14,9 @@ import (
 type TransmissionContext struct {
 	// A time marking (e.g. 0:30) or marker from which to start.
 	from string
+	// An offset (in milliseconds) from which to start. This is used when `from`
+	// is not provided.
+	fromOffset float64
 	// A time marking (e.g. 1:00) or marker at which to end.
 	to string
 	// The index of the first event to transmit. (default: 0)
@@ -47,6 +54,19 @@ func TransmitFrom(from string) TransmissionOption {
 	}
 }
 
+// TransmitFromOffset sets the offset (in milliseconds) from which to start.
+//
+// A time marking or marker set via TransmitFrom takes precedence.
+func TransmitFromOffset(offset float64) TransmissionOption {
+	return func(ctx *TransmissionContext) {
+		log.Debug().
+			Float64("fromOffset", offset).
+			Msg("Applying transmission option")
+
+		ctx.fromOffset = offset
+	}
+}
+
 // TransmitTo sets the time marking or marker at which to end.
 func TransmitTo(to string) TransmissionOption {
 	log.Debug().
@@ -125,3 +145,163 @@ type Transmitter interface {
 	// TransmitScore sends score data somewhere.
 	TransmitScore(score *model.Score, opts ...TransmissionOption) error
 }
+
+// A transmission is the result of applying a list of TransmissionOptions to a
+// score, i.e. the slice of the score's events that a transmitter should
+// transmit, along with the range of offsets (in milliseconds) within which
+// events should be transmitted.
+type transmission struct {
+	ctx         *TransmissionContext
+	events      []model.ScoreEvent
+	startOffset float64
+	endOffset   float64
+}
+
+// prepareTransmission applies the provided options to a new TransmissionContext
+// and determines which events should be transmitted.
+//
+// This is shared between transmitters so that options like `--from` and `--to`
+// behave the same way regardless of where the score is being sent.
+//
+// Returns an error if the `from` or `to` options cannot be interpreted as
+// offsets in the score.
+func prepareTransmission(
+	score *model.Score, opts ...TransmissionOption,
+) (transmission, error) {
+	ctx := &TransmissionContext{toIndex: -1}
+	for _, opt := range opts {
+		opt(ctx)
+	}
+
+	if ctx.toIndex == -1 {
+		ctx.toIndex = len(score.Events)
+	}
+
+	log.Debug().
+		Str("ctx", fmt.Sprintf("%#v", ctx)).
+		Msg("Transmission options applied.")
+
+	t := transmission{
+		ctx:         ctx,
+		events:      score.Events[ctx.fromIndex:ctx.toIndex],
+		startOffset: ctx.fromOffset,
+		endOffset:   math.MaxFloat64,
+	}
+
+	if ctx.from != "" {
+		offset, err := score.InterpretOffsetReference(ctx.from)
+		if err != nil {
+			return transmission{}, err
+		}
+
+		t.startOffset = offset
+	}
+
+	if ctx.to != "" {
+		offset, err := score.InterpretOffsetReference(ctx.to)
+		if err != nil {
+			return transmission{}, err
+		}
+
+		t.endOffset = offset
+	}
+
+	// In order to support features like:
+	//
+	// * Avoiding scheduling more program, volume, and panning control change
+	//   messages than we have to.
+	//
+	// * Playing just a slice of a score, e.g. `alda play --from 0:05 --to 0:10`
+	//
+	// ...we sort the events in the score by offset and schedule them in
+	// chronological order.
+	//
+	// The sort is stable so that events at the same offset stay in the order in
+	// which they were added to the score, e.g. a sustain pedal control change
+	// that comes right before a note is transmitted before the note.
+	sort.SliceStable(t.events, func(i, j int) bool {
+		return t.events[i].EventOffset() < t.events[j].EventOffset()
+	})
+
+	return t, nil
+}
+
+// A tempoChange is a change in tempo (in BPM) at an offset (in milliseconds).
+type tempoChange struct {
+	offset float64
+	tempo  float64
+}
+
+// tempoChanges returns the tempo changes in the score (see
+// *Score.TempoItinerary) that fall within the range of offsets being
+// transmitted, in chronological order.
+//
+// The offsets are adjusted relative to `startOffset`, the same way that the
+// offsets of the events being transmitted are adjusted.
+func tempoChanges(
+	score *model.Score, startOffset float64, endOffset float64,
+) []tempoChange {
+	tempoItinerary := score.TempoItinerary()
+
+	tempoOffsets := []float64{}
+	for offset := range tempoItinerary {
+		tempoOffsets = append(tempoOffsets, offset)
+	}
+	sort.Float64s(tempoOffsets)
+
+	// In the case where we're starting a ways into the score (i.e. if the
+	// `--from` option is supplied), we want to skip any extraneous tempo changes
+	// that happened before that point in the score. Except we do want the last
+	// one before or at the start offset, so that the initial tempo is correct.
+	firstTempoOffset := 0.0
+	for _, tempoOffset := range tempoOffsets {
+		if tempoOffset > startOffset {
+			break
+		}
+
+		// Keep going until we reach a tempo offset past the start offset. At that
+		// point, we'll use the previous tempo offset recorded here, because that
+		// would be the last tempo change before the start offset.
+		firstTempoOffset = tempoOffset
+	}
+
+	changes := []tempoChange{}
+
+	// Now, we want to include each tempo change within the time range of the
+	// excerpt of the score that we're transmitting.
+	for _, tempoOffset := range tempoOffsets {
+		// Filter out any tempo changes prior to the `--from` time marking / marker,
+		// when supplied. (...except for the last tempo offset prior to that point
+		// in time; see the comment where we defined `firstTempoOffset` above.)
+		if tempoOffset < firstTempoOffset {
+			continue
+		}
+
+		// Filter out any tempo changes after the `--to` time marking / marker, when
+		// supplied.
+		if tempoOffset >= endOffset {
+			break
+		}
+
+		// We subtract `startOffset` from the offset because transmitters do the
+		// same thing to the offset of every event. This is so that when the
+		// `--from` option is used, the excerpt starts as if it were at the
+		// beginning of the score.
+		//
+		// By default, `startOffset` is 0, so the usual scenario is that the offset
+		// is not adjusted.
+		offset := tempoOffset - startOffset
+
+		// If the effective offset is earlier than the notional start offset (0),
+		// then we'll place the tempo change right at the beginning (0).
+		if offset < 0 {
+			offset = 0
+		}
+
+		changes = append(
+			changes, tempoChange{offset: offset, tempo: tempoItinerary[tempoOffset]},
+		)
+	}
+
+	return changes
+}
@@ -0,0 +1,109 @@
+package transmitter
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"alda.io/client/model"
+	"alda.io/client/synth"
+)
+
+// WavFileTransmitter renders a score to audio using a built-in synthesizer and
+// writes it to a Writer as a WAV file (16-bit stereo PCM).
+//
+// Like MidiFileTransmitter, no player process is involved. Rendering is
+// deterministic, so the same score always produces the same WAV file.
+type WavFileTransmitter struct {
+	Writer io.Writer
+	// The synthesizer used to render notes. When nil, synth.WavetableSynth is
+	// used.
+	Synth synth.Synthesizer
+	// The sample rate (in Hz) of the WAV file. When 0, synth.DefaultSampleRate is
+	// used.
+	SampleRate int
+}
+
+// scoreToSynthNotes returns the notes in a score, in a form that can be
+// rendered by a synthesizer.
+//
+// Note offsets in the score are already in milliseconds (i.e. tempo changes
+// have already been taken into account), so all we need to do is convert them
+// into seconds, relative to the start of the transmission.
+func scoreToSynthNotes(
+	score *model.Score, opts ...TransmissionOption,
+) ([]synth.Note, error) {
+	t, err := prepareTransmission(score, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, events := t.ctx, t.events
+	startOffset, endOffset := t.startOffset, t.endOffset
+
+	notes := []synth.Note{}
+
+	for _, event := range events {
+		eventOffset := event.EventOffset()
+
+		// Filter out events before the `--from` time marking / marker, when
+		// supplied.
+		if eventOffset < startOffset {
+			continue
+		}
+
+		// Filter out events after the `--to` time marking / marker, when supplied.
+		if eventOffset >= endOffset {
+			break
+		}
+
+		switch event := event.(type) {
+		case model.NoteEvent:
+			instrument := event.Part.StockInstrument.(model.MidiInstrument)
+
+			key := math.Round(event.Pitch)
+
+			notes = append(notes, synth.Note{
+				Key:         int32(key),
+				Cents:       (event.Pitch - key) * 100,
+				Velocity:    event.Volume,
+				TrackVolume: event.TrackVolume,
+				Panning:     event.Panning,
+				Start:       (event.Offset - startOffset - ctx.syncOffset) / 1000,
+				Duration:    event.AudibleDuration / 1000,
+				Program:     instrument.PatchNumber,
+				Percussion:  instrument.IsPercussion,
+			})
+		case model.ControlChangeEvent, model.PitchBendEvent, model.AftertouchEvent:
+			// The synthesizer only plays notes, so we skip MIDI control events.
+			continue
+		default:
+			return nil, fmt.Errorf("unsupported event: %#v", event)
+		}
+	}
+
+	return notes, nil
+}
+
+// TransmitScore implements Transmitter.TransmitScore by rendering the score and
+// writing it to the transmitter's Writer as a WAV file.
+func (wft WavFileTransmitter) TransmitScore(
+	score *model.Score, opts ...TransmissionOption,
+) error {
+	notes, err := scoreToSynthNotes(score, opts...)
+	if err != nil {
+		return err
+	}
+
+	synthesizer := wft.Synth
+	if synthesizer == nil {
+		synthesizer = synth.WavetableSynth{}
+	}
+
+	sampleRate := wft.SampleRate
+	if sampleRate == 0 {
+		sampleRate = synth.DefaultSampleRate
+	}
+
+	return synth.Render(synthesizer, sampleRate, notes...).WriteWAV(wft.Writer)
+}
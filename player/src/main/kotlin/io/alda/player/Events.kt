@@ -53,6 +53,19 @@ fun schedule(event: Event) {
       )
     }
 
+    "midi-aftertouch" -> midi().aftertouch(
+      event["offset"] as Int,
+      event["channel"] as Int,
+      event["value"] as Int
+    )
+
+    "midi-control-change" -> midi().controlChange(
+      event["offset"] as Int,
+      event["channel"] as Int,
+      event["controller"] as Int,
+      event["value"] as Int
+    )
+
     "midi-panning" -> midi().panning(
       event["offset"] as Int,
       event["channel"] as Int,
@@ -65,6 +78,12 @@ fun schedule(event: Event) {
       event["patch"] as Int
     )
 
+    "midi-pitch-bend" -> midi().pitchBend(
+      event["offset"] as Int,
+      event["channel"] as Int,
+      event["value"] as Int
+    )
+
     "midi-volume" -> midi().volume(
       event["offset"] as Int,
       event["channel"] as Int,
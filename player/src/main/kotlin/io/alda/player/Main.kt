@@ -5,6 +5,7 @@ import com.github.ajalt.clikt.core.subcommands
 import com.github.ajalt.clikt.parameters.options.default
 import com.github.ajalt.clikt.parameters.options.flag
 import com.github.ajalt.clikt.parameters.options.option
+import com.github.ajalt.clikt.parameters.types.choice
 import com.github.ajalt.clikt.parameters.types.int
 import io.github.soc.directories.ProjectDirectories
 import java.net.ServerSocket
@@ -64,6 +65,12 @@ class Run : CliktCommand(
   ).int()
    .default(findOpenPort())
 
+  val protocol by option(
+    "--protocol",
+    help = "the network protocol over which to receive OSC messages"
+  ).choice("tcp", "udp")
+   .default("tcp")
+
   val lazyAudio by option(
     "--lazy-audio", help = "don't immediately set up audio device resources"
   ).flag(default = false)
@@ -71,11 +78,11 @@ class Run : CliktCommand(
   override fun run() {
     val log = logger!!
 
-    stateManager = StateManager(port)
+    stateManager = StateManager(port, protocol)
     stateManager!!.start()
 
-    log.info { "Starting receiver, listening on port $port..." }
-    val receiver = receiver(port)
+    log.info { "Starting receiver, listening on $protocol port $port..." }
+    val receiver = receiver(port, protocol)
     receiver.startListening()
 
     if (lazyAudio) {
@@ -469,6 +469,29 @@ class MidiEngine {
     )
   }
 
+  fun controlChange(
+    offset : Int, channel : Int, controller : Int, value : Int
+  ) {
+    scheduleShortMsg(
+      offset, ShortMessage.CONTROL_CHANGE, channel, controller, value
+    )
+  }
+
+  // `value` is a 14-bit number from 0 to 16383, where 8192 means no pitch bend.
+  // The MIDI message splits it into 7 least significant bits and 7 most
+  // significant bits.
+  fun pitchBend(offset : Int, channel : Int, value : Int) {
+    scheduleShortMsg(
+      offset, ShortMessage.PITCH_BEND, channel, value and 0x7F, value shr 7
+    )
+  }
+
+  fun aftertouch(offset : Int, channel : Int, value : Int) {
+    scheduleShortMsg(
+      offset, ShortMessage.CHANNEL_PRESSURE, channel, value, 0
+    )
+  }
+
   // Schedules an event to occur at the desired offset.
   //
   // Returns a CountDownLatch that will count down from 1 to 0 when the event is
@@ -238,6 +238,56 @@ class Updates() {
           )
         }
 
+        Regex("/track/\\d+/midi/control-change").matches(address) -> {
+          val channel    = args.get(0) as Int
+          val offset     = args.get(1) as Int
+          val controller = args.get(2) as Int
+          val value      = args.get(3) as Int
+
+          addTrackEvent(
+            trackNumber(address),
+            mapOf(
+              "type" to "midi-control-change",
+              "channel" to channel,
+              "offset" to offset,
+              "controller" to controller,
+              "value" to value
+            )
+          )
+        }
+
+        Regex("/track/\\d+/midi/pitch-bend").matches(address) -> {
+          val channel = args.get(0) as Int
+          val offset  = args.get(1) as Int
+          val value   = args.get(2) as Int
+
+          addTrackEvent(
+            trackNumber(address),
+            mapOf(
+              "type" to "midi-pitch-bend",
+              "channel" to channel,
+              "offset" to offset,
+              "value" to value
+            )
+          )
+        }
+
+        Regex("/track/\\d+/midi/aftertouch").matches(address) -> {
+          val channel = args.get(0) as Int
+          val offset  = args.get(1) as Int
+          val value   = args.get(2) as Int
+
+          addTrackEvent(
+            trackNumber(address),
+            mapOf(
+              "type" to "midi-aftertouch",
+              "channel" to channel,
+              "offset" to offset,
+              "value" to value
+            )
+          )
+        }
+
         Regex("/track/\\d+/pattern").matches(address) -> {
           val channel     = args.get(0) as Int
           val offset      = args.get(1) as Int
@@ -333,6 +383,50 @@ class Updates() {
           )
         }
 
+        Regex("/pattern/[^/]+/midi/control-change").matches(address) -> {
+          val offset     = args.get(0) as Int
+          val controller = args.get(1) as Int
+          val value      = args.get(2) as Int
+
+          addPatternEvent(
+            patternName(address),
+            mapOf(
+              "type" to "midi-control-change",
+              "offset" to offset,
+              "controller" to controller,
+              "value" to value
+            )
+          )
+        }
+
+        Regex("/pattern/[^/]+/midi/pitch-bend").matches(address) -> {
+          val offset = args.get(0) as Int
+          val value  = args.get(1) as Int
+
+          addPatternEvent(
+            patternName(address),
+            mapOf(
+              "type" to "midi-pitch-bend",
+              "offset" to offset,
+              "value" to value
+            )
+          )
+        }
+
+        Regex("/pattern/[^/]+/midi/aftertouch").matches(address) -> {
+          val offset = args.get(0) as Int
+          val value  = args.get(1) as Int
+
+          addPatternEvent(
+            patternName(address),
+            mapOf(
+              "type" to "midi-aftertouch",
+              "offset" to offset,
+              "value" to value
+            )
+          )
+        }
+
         Regex("/pattern/[^/]+/pattern").matches(address) -> {
           val offset      = args.get(0) as Int
           val patternName = args.get(1) as String
@@ -21,10 +21,12 @@ private fun instructions(packet : OSCPacket) : List<OSCMessage> {
   return (packet as OSCBundle).getPackets().flatMap { instructions(it) }
 }
 
-fun receiver(port : Int) : OSCPortIn {
+fun receiver(port : Int, protocol : String) : OSCPortIn {
   return OSCPortInBuilder()
     .setPort(port)
-    .setNetworkProtocol(NetworkProtocol.TCP)
+    .setNetworkProtocol(
+      if (protocol == "udp") NetworkProtocol.UDP else NetworkProtocol.TCP
+    )
     .setPacketListener(object : OSCPacketListener {
     override fun handlePacket(event : OSCPacketEvent) {
       stateManager!!.delayExpiration()
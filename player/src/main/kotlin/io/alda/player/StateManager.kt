@@ -15,10 +15,14 @@ private val json = Klaxon()
 private val log = KotlinLogging.logger {}
 
 class PlayerState(
-  val port : Int, var expiry : Long, var state : String, val pid: Long?
+  val port : Int,
+  val protocol : String,
+  var expiry : Long,
+  var state : String,
+  val pid: Long?
 )
 
-class StateManager(val port : Int) {
+class StateManager(val port : Int, val protocol : String) {
   val thread = thread(start = false) {
     while (!Thread.currentThread().isInterrupted()) {
       try {
@@ -61,6 +65,7 @@ class StateManager(val port : Int) {
 
   val state = PlayerState(
     port,
+    protocol,
     System.currentTimeMillis() + inactivityTimeoutMs,
     "starting",
     currentPid()